@@ -10,8 +10,12 @@ import (
 
 	"github.com/collabreef/collabreef/internal/bootstrap"
 	"github.com/collabreef/collabreef/internal/config"
+	"github.com/collabreef/collabreef/internal/events"
 	grpcserver "github.com/collabreef/collabreef/internal/grpc"
+	"github.com/collabreef/collabreef/internal/resumable"
+	"github.com/collabreef/collabreef/internal/rsscache"
 	"github.com/collabreef/collabreef/internal/server"
+	"github.com/collabreef/collabreef/internal/snapshot"
 )
 
 // Version is set at build time via ldflags
@@ -36,7 +40,9 @@ func main() {
 		log.Fatalf("Failed to initialize storage: %v", err)
 	}
 
-	e, err := server.New(db, storage)
+	bus := events.NewBus()
+
+	e, err := server.New(db, storage, bus)
 	if err != nil {
 		log.Fatalf("Failed to setup server: %v", err)
 	}
@@ -47,7 +53,16 @@ func main() {
 	}
 
 	grpcPort := config.C.GetString(config.GRPC_PORT)
-	go grpcserver.Start(db, grpcPort)
+	go grpcserver.Start(db, bus, grpcPort)
+
+	// Take automatic view snapshots in the background
+	go snapshot.Schedule(db, config.C.GetDuration(config.VIEW_SNAPSHOT_INTERVAL), config.C.GetInt(config.VIEW_SNAPSHOT_RETENTION))
+
+	// Refresh subscribed RSS feeds in the background
+	go rsscache.Poll(db, config.C.GetDuration(config.RSS_POLL_INTERVAL))
+
+	// Discard resumable uploads that were abandoned
+	go resumable.Schedule(db, storage, config.C.GetDuration(config.UPLOAD_EXPIRY))
 
 	// Start server in a goroutine
 	go func() {
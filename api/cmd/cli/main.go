@@ -2,16 +2,28 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/collabreef/collabreef/internal/bootstrap"
 	"github.com/collabreef/collabreef/internal/config"
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/importer"
 	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/quota"
+	"github.com/collabreef/collabreef/internal/storage"
+	"github.com/collabreef/collabreef/internal/storage/migrate"
+	"github.com/collabreef/collabreef/internal/whiteboard"
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/term"
 )
@@ -32,6 +44,18 @@ func main() {
 	switch command {
 	case "reset-password":
 		resetPassword()
+	case "index-whiteboards":
+		indexWhiteboards()
+	case "dedupe-files":
+		dedupeFiles()
+	case "storage":
+		storageCommand(os.Args[2:])
+	case "import-enex":
+		importENEX(os.Args[2:])
+	case "import-notion":
+		importNotion(os.Args[2:])
+	case "import-trello":
+		importTrello(os.Args[2:])
 	case "help", "--help", "-h":
 		printUsage()
 	default:
@@ -49,6 +73,12 @@ func printUsage() {
 	fmt.Println()
 	fmt.Println("Available commands:")
 	fmt.Println("  reset-password    Reset user password interactively")
+	fmt.Println("  index-whiteboards Simplify strokes and record object bounds of all whiteboards")
+	fmt.Println("  dedupe-files      Move uploaded files into blobs shared by files with the same content")
+	fmt.Println("  storage migrate   Copy stored files from one storage backend to another")
+	fmt.Println("  import-enex       Import Evernote .enex exports into a workspace")
+	fmt.Println("  import-notion     Import Notion Markdown & CSV exports into a workspace")
+	fmt.Println("  import-trello     Import Trello board JSON exports into a workspace")
 	fmt.Println("  help              Show this help message")
 	fmt.Println()
 }
@@ -140,3 +170,471 @@ func resetPassword() {
 	fmt.Println()
 	fmt.Printf("✓ Password successfully reset for user: %s\n", user.Name)
 }
+
+// indexWhiteboards prepares whiteboards saved before viewport queries
+// existed: strokes are simplified and the bounds of view objects and canvas
+// objects are recorded. Objects without bounds are returned by every
+// viewport query, and a canvas that is not indexed is loaded in full, so the
+// command is optional, but it makes existing boards load faster.
+func indexWhiteboards() {
+	config.Init()
+
+	db, err := bootstrap.NewDB()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	// A page size of -1 disables the limit.
+	views, err := db.FindViews(model.ViewFilter{ViewType: "whiteboard", PageNumber: 1, PageSize: -1})
+	if err != nil {
+		log.Fatalf("Error finding whiteboards: %v", err)
+	}
+
+	objectCount := 0
+	for _, view := range views {
+		data := whiteboard.SimplifyCanvas(view.Data)
+		if data != view.Data {
+			if err := db.UpdateView(model.View{ID: view.ID, Data: data}); err != nil {
+				log.Fatalf("Failed to update whiteboard %s: %v", view.ID, err)
+			}
+		}
+		if err := whiteboard.IndexCanvas(db, view.ID, data); err != nil {
+			log.Fatalf("Failed to index the canvas of whiteboard %s: %v", view.ID, err)
+		}
+
+		objects, err := db.FindViewObjects(model.ViewObjectFilter{ViewID: view.ID, PageNumber: 1, PageSize: -1})
+		if err != nil {
+			log.Fatalf("Error finding objects of whiteboard %s: %v", view.ID, err)
+		}
+		for _, o := range objects {
+			prepared := whiteboard.PrepareObject(o)
+			indexed := o.MinX != nil || prepared.MinX == nil
+			if indexed && prepared.Data == o.Data {
+				continue
+			}
+			if err := db.UpdateViewObject(prepared); err != nil {
+				log.Fatalf("Failed to update view object %s: %v", o.ID, err)
+			}
+			objectCount++
+		}
+	}
+
+	fmt.Printf("✓ Indexed %d whiteboards (%d objects)\n", len(views), objectCount)
+}
+
+// dedupeFiles moves files uploaded before uploads were deduplicated into
+// blobs named by their content hash, so that copies of the same content
+// share one blob. Files that were moved are skipped, so the command can be
+// run again after an interruption.
+func dedupeFiles() {
+	config.Init()
+
+	d, err := bootstrap.NewDB()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	s, err := bootstrap.NewStorage()
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	files, err := d.FindFiles(model.FileFilter{})
+	if err != nil {
+		log.Fatalf("Error finding files: %v", err)
+	}
+
+	moved, duplicates, missing := 0, 0, 0
+	var saved int64
+	for _, f := range files {
+		if f.Hash != "" {
+			continue
+		}
+		segments := []string{f.WorkspaceID, f.Name}
+
+		hash, err := putBlob(s, segments)
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Skipping %s: file is missing from storage", f.Name)
+			missing++
+			continue
+		}
+		if err != nil {
+			log.Fatalf("Failed to store %s: %v", f.Name, err)
+		}
+
+		refs, err := d.FindFiles(model.FileFilter{Hash: hash, PageSize: 1, PageNumber: 1})
+		if err != nil {
+			log.Fatalf("Error finding files: %v", err)
+		}
+		if len(refs) > 0 {
+			duplicates++
+			saved += f.Size
+		}
+
+		// The record points to the blob before the file is deleted, so an
+		// interruption leaves no file without content.
+		f.Hash = hash
+		if err := d.UpdateFile(f); err != nil {
+			log.Fatalf("Failed to update file %s: %v", f.Name, err)
+		}
+		if err := s.Delete(segments); err != nil {
+			log.Printf("Failed to delete %s: %v", f.Name, err)
+		}
+		moved++
+	}
+
+	fmt.Printf("✓ Moved %d files (%d duplicates, %d bytes saved, %d missing)\n", moved, duplicates, saved, missing)
+}
+
+// putBlob copies a stored file into its blob. The file is spooled to disk,
+// since blobs are hashed before they are written.
+func putBlob(s storage.Storage, segments []string) (string, error) {
+	// Stat reports missing files the same way for every storage.
+	if _, err := s.Stat(segments); err != nil {
+		return "", err
+	}
+	r, err := s.Load(segments)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	tmp, err := os.CreateTemp("", "collabreef-blob-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		return "", err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	hash, unlock, err := storage.PutBlob(s, tmp)
+	if err != nil {
+		return "", err
+	}
+	unlock()
+	return hash, nil
+}
+
+func storageCommand(args []string) {
+	if len(args) == 0 || args[0] != "migrate" {
+		fmt.Println("Usage: cli storage migrate -from <type> -to <type> [-dry-run] [-verify]")
+		os.Exit(1)
+	}
+	migrateStorage(args[1:])
+}
+
+// migrateStorage copies the files, and their variants, from one storage
+// backend to another, before STORAGE_TYPE is switched. Both backends are
+// configured as the API would be with each type. Uploads in progress are
+// not copied.
+func migrateStorage(args []string) {
+	fs := flag.NewFlagSet("storage migrate", flag.ExitOnError)
+	from := fs.String("from", "", "storage type to copy from: local or s3")
+	to := fs.String("to", "", "storage type to copy to: local or s3")
+	dryRun := fs.Bool("dry-run", false, "report what would be copied without copying")
+	verify := fs.Bool("verify", false, "check files already copied by checksum rather than by size")
+	fs.Usage = func() {
+		fmt.Println("Usage: cli storage migrate -from <type> -to <type> [-dry-run] [-verify]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *from == "" || *to == "" || fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *from == *to {
+		log.Fatalf("Storage types to copy from and to must differ")
+	}
+
+	config.Init()
+
+	d, err := bootstrap.NewDB()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	src, err := bootstrap.NewStorageOfType(*from)
+	if err != nil {
+		log.Fatalf("Failed to initialize %s storage: %v", *from, err)
+	}
+	dst, err := bootstrap.NewStorageOfType(*to)
+	if err != nil {
+		log.Fatalf("Failed to initialize %s storage: %v", *to, err)
+	}
+
+	m := migrate.Migration{DB: d, From: src, To: dst, DryRun: *dryRun, Verify: *verify}
+	r, err := m.Run()
+	if err != nil {
+		log.Fatalf("Failed to migrate storage: %v", err)
+	}
+
+	if *dryRun {
+		fmt.Printf("Would copy %d objects (%s), %d already copied\n", r.Copied, quota.FormatSize(r.Bytes), r.Present)
+	} else {
+		fmt.Printf("✓ Copied %d objects (%s), %d already copied\n", r.Copied, quota.FormatSize(r.Bytes), r.Present)
+	}
+	printStorageProblems("Missing", r.Missing)
+	printStorageProblems("Mismatched", r.Mismatched)
+	if len(r.Missing) > 0 || len(r.Mismatched) > 0 {
+		os.Exit(1)
+	}
+}
+
+func printStorageProblems(title string, problems []migrate.Problem) {
+	if len(problems) == 0 {
+		return
+	}
+	fmt.Printf("%s (%d):\n", title, len(problems))
+	for _, p := range problems {
+		fmt.Printf("  %s\t%s\t%s\n", p.File, p.Object, p.Reason)
+	}
+}
+
+// importENEX imports Evernote exports for a user, with one note per
+// notebook file, the way the import endpoint does.
+func importENEX(args []string) {
+	fs := flag.NewFlagSet("import-enex", flag.ExitOnError)
+	workspaceID := fs.String("workspace", "", "id of the workspace to import into")
+	userName := fs.String("user", "", "username or email of the user the notes belong to")
+	parentID := fs.String("parent", "", "id of a note to nest the notebooks under")
+	visibility := fs.String("visibility", "private", "visibility of the imported notes: private, workspace or public")
+	fs.Usage = func() {
+		fmt.Println("Usage: cli import-enex -workspace <id> -user <username or email> [-parent <note id>] [-visibility <visibility>] <file.enex>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *workspaceID == "" || *userName == "" || fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	config.Init()
+
+	d, err := bootstrap.NewDB()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	s, err := bootstrap.NewStorage()
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	im := newImporter(d, s, *workspaceID, *userName, *visibility)
+	tx := beginImport(im)
+
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			abortImport(im, tx, "Failed to open %s: %v", path, err)
+		}
+		notebook := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		book, err := im.ImportENEX(f, notebook, *parentID)
+		f.Close()
+		if err != nil {
+			abortImport(im, tx, "Failed to import %s: %v", path, err)
+		}
+		fmt.Printf("Imported notebook %s as note %s\n", notebook, book.ID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		abortImport(im, nil, "Failed to commit import: %v", err)
+	}
+	im.End(true)
+
+	printImportResult(im.Result())
+}
+
+// importNotion imports Notion "Markdown & CSV" exports for a user, the way
+// the import endpoint does.
+func importNotion(args []string) {
+	fs := flag.NewFlagSet("import-notion", flag.ExitOnError)
+	workspaceID := fs.String("workspace", "", "id of the workspace to import into")
+	userName := fs.String("user", "", "username or email of the user the notes belong to")
+	parentID := fs.String("parent", "", "id of a note to nest the pages under")
+	visibility := fs.String("visibility", "private", "visibility of the imported notes: private, workspace or public")
+	fs.Usage = func() {
+		fmt.Println("Usage: cli import-notion -workspace <id> -user <username or email> [-parent <note id>] [-visibility <visibility>] <export.zip>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *workspaceID == "" || *userName == "" || fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	config.Init()
+
+	d, err := bootstrap.NewDB()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	s, err := bootstrap.NewStorage()
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	im := newImporter(d, s, *workspaceID, *userName, *visibility)
+	tx := beginImport(im)
+
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			abortImport(im, tx, "Failed to open %s: %v", path, err)
+		}
+		info, err := f.Stat()
+		if err != nil {
+			abortImport(im, tx, "Failed to read %s: %v", path, err)
+		}
+		roots, err := im.ImportNotion(f, info.Size(), *parentID)
+		f.Close()
+		if err != nil {
+			abortImport(im, tx, "Failed to import %s: %v", path, err)
+		}
+		for _, n := range roots {
+			fmt.Printf("Imported page %s as note %s\n", n.Title, n.ID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		abortImport(im, nil, "Failed to commit import: %v", err)
+	}
+	im.End(true)
+
+	printImportResult(im.Result())
+}
+
+// importTrello imports Trello board exports for a user, with one note and
+// kanban view per board, the way the import endpoint does.
+func importTrello(args []string) {
+	fs := flag.NewFlagSet("import-trello", flag.ExitOnError)
+	workspaceID := fs.String("workspace", "", "id of the workspace to import into")
+	userName := fs.String("user", "", "username or email of the user the notes belong to")
+	parentID := fs.String("parent", "", "id of a note to nest the boards under")
+	visibility := fs.String("visibility", "private", "visibility of the imported notes: private, workspace or public")
+	fs.Usage = func() {
+		fmt.Println("Usage: cli import-trello -workspace <id> -user <username or email> [-parent <note id>] [-visibility <visibility>] <board.json>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *workspaceID == "" || *userName == "" || fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	config.Init()
+
+	d, err := bootstrap.NewDB()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	s, err := bootstrap.NewStorage()
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	im := newImporter(d, s, *workspaceID, *userName, *visibility)
+
+	// Attachments are downloaded before the transaction starts.
+	boards := make([]*importer.TrelloBoard, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			abortImport(im, nil, "Failed to open %s: %v", path, err)
+		}
+		board, err := im.ReadTrello(context.Background(), f)
+		f.Close()
+		if err != nil {
+			abortImport(im, nil, "Failed to import %s: %v", path, err)
+		}
+		boards = append(boards, board)
+	}
+
+	tx := beginImport(im)
+	for i, board := range boards {
+		note, err := im.ImportTrello(board, *parentID)
+		if err != nil {
+			abortImport(im, tx, "Failed to import %s: %v", fs.Arg(i), err)
+		}
+		fmt.Printf("Imported board %s as note %s\n", note.Title, note.ID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		abortImport(im, nil, "Failed to commit import: %v", err)
+	}
+	im.End(true)
+
+	printImportResult(im.Result())
+}
+
+// newImporter prepares an import for a workspace member. The caller writes
+// it in a transaction started with beginImport.
+func newImporter(d db.DB, s storage.Storage, workspaceID, userName, visibility string) *importer.Importer {
+	switch visibility {
+	case "private", "workspace", "public":
+	default:
+		log.Fatalf("Invalid visibility: %s", visibility)
+	}
+
+	users, err := d.FindUsers(model.UserFilter{NameOrEmail: userName})
+	if err != nil {
+		log.Fatalf("Error finding user: %v", err)
+	}
+	if len(users) == 0 {
+		log.Fatalf("User not found: %s", userName)
+	}
+	user := users[0]
+
+	members, err := d.FindWorkspaceUsers(model.WorkspaceUserFilter{WorkspaceID: workspaceID})
+	if err != nil {
+		log.Fatalf("Error finding workspace members: %v", err)
+	}
+	isMember := false
+	for _, m := range members {
+		isMember = isMember || m.UserID == user.ID
+	}
+	if !isMember {
+		log.Fatalf("User %s is not a member of workspace %s", user.Name, workspaceID)
+	}
+
+	return &importer.Importer{
+		DB:          d,
+		Storage:     s,
+		WorkspaceID: workspaceID,
+		UserID:      user.ID,
+		Visibility:  visibility,
+		APIRoot:     config.C.GetString(config.SERVER_API_ROOT_PATH),
+	}
+}
+
+// beginImport starts the transaction an import is written in.
+func beginImport(im *importer.Importer) db.DB {
+	tx, err := im.DB.Begin(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to begin transaction: %v", err)
+	}
+	im.DB = tx
+	return tx
+}
+
+// abortImport rolls an import back, deletes the files it stored and exits.
+func abortImport(im *importer.Importer, tx db.DB, format string, v ...interface{}) {
+	if tx != nil {
+		tx.Rollback()
+	}
+	im.End(false)
+	log.Fatalf(format, v...)
+}
+
+func printImportResult(r importer.Result) {
+	for _, w := range r.Warnings {
+		fmt.Printf("Warning: %s\n", w)
+	}
+	fmt.Printf("✓ Imported %d notes, %d files and %d views\n", r.Notes, r.Files, r.Views)
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/config"
+)
+
+func TestVerifyFile(t *testing.T) {
+	if config.C == nil {
+		config.Init()
+	}
+	expires := time.Now().Add(time.Minute)
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	signature := SignFile("w1", "a.png", expires)
+	past := time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name      string
+		workspace string
+		file      string
+		expires   string
+		signature string
+		want      bool
+	}{
+		{name: "valid", workspace: "w1", file: "a.png", expires: exp, signature: signature, want: true},
+		{name: "other file", workspace: "w1", file: "b.png", expires: exp, signature: signature},
+		{name: "other workspace", workspace: "w2", file: "a.png", expires: exp, signature: signature},
+		{name: "extended expiry", workspace: "w1", file: "a.png", expires: strconv.FormatInt(expires.Add(time.Hour).Unix(), 10), signature: signature},
+		{name: "expired", workspace: "w1", file: "a.png", expires: strconv.FormatInt(past.Unix(), 10), signature: SignFile("w1", "a.png", past)},
+		{name: "malformed expiry", workspace: "w1", file: "a.png", expires: exp + "x", signature: signature},
+		{name: "missing expiry", workspace: "w1", file: "a.png", signature: signature},
+		{name: "tampered signature", workspace: "w1", file: "a.png", expires: exp, signature: signature[:len(signature)-1] + "A"},
+		{name: "empty signature", workspace: "w1", file: "a.png", expires: exp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyFile(tt.workspace, tt.file, tt.expires, tt.signature); got != tt.want {
+				t.Errorf("VerifyFile = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignFileSecret(t *testing.T) {
+	if config.C == nil {
+		config.Init()
+	}
+	saved := config.C.GetString(config.APP_SECRET)
+	defer config.C.Set(config.APP_SECRET, saved)
+
+	expires := time.Now().Add(time.Minute)
+	signature := SignFile("w1", "a.png", expires)
+	config.C.Set(config.APP_SECRET, saved+"-rotated")
+	if VerifyFile("w1", "a.png", strconv.FormatInt(expires.Unix(), 10), signature) {
+		t.Error("signature made with another secret was accepted")
+	}
+}
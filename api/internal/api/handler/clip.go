@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/clipper"
+	"github.com/collabreef/collabreef/internal/config"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/quota"
+	"github.com/collabreef/collabreef/internal/storage"
+	"github.com/collabreef/collabreef/internal/util"
+
+	"github.com/labstack/echo/v4"
+)
+
+// clipTimeout bounds a clip, including the download of its images.
+const clipTimeout = 2 * time.Minute
+
+type ClipRequest struct {
+	URL        string `json:"url"`
+	RSSItemID  string `json:"rss_item_id"`
+	Visibility string `json:"visibility"`
+	ParentID   string `json:"parent_id"`
+}
+
+// Clip saves the readable content of a web page, or of the page an RSS item
+// links to, as a note. Images are copied into the workspace files and the
+// note records the address it was clipped from. Clipping an RSS item marks
+// it as read.
+func (h Handler) Clip(c echo.Context) error {
+	workspaceId := c.Param("workspaceId")
+	if workspaceId == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "workspace id is required")
+	}
+
+	var req ClipRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if (req.URL == "") == (req.RSSItemID == "") {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: one of url or rss_item_id is required",
+		})
+	}
+
+	if req.Visibility == "" {
+		req.Visibility = "private"
+	}
+	switch req.Visibility {
+	case "private", "workspace", "public":
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: invalid visibility",
+		})
+	}
+
+	user := c.Get("user").(model.User)
+
+	if !h.isUserWorkspaceMember(user.ID, workspaceId) {
+		return echo.NewHTTPError(http.StatusForbidden, "only workspace members can clip pages")
+	}
+
+	if req.ParentID != "" {
+		parent, err := h.db.FindNote(model.Note{ID: req.ParentID})
+		if err != nil || parent.WorkspaceID != workspaceId {
+			return echo.NewHTTPError(http.StatusBadRequest, "parent note not found")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), clipTimeout)
+	defer cancel()
+
+	saveImage := h.clipImageSaver(workspaceId, user.ID)
+
+	var clip clipper.Clip
+	if req.RSSItemID != "" {
+		item, err := h.findUserRSSItem(user.ID, req.RSSItemID)
+		if err != nil {
+			return err
+		}
+
+		clip, err = clipRSSItem(ctx, item, saveImage)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		if err := h.db.MarkRSSItemsRead(user.ID, []string{item.ID}, time.Now().UTC().String()); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	} else {
+		var err error
+		clip, err = clipper.ClipURL(ctx, req.URL, saveImage)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "failed to clip page: "+err.Error())
+		}
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	n := model.Note{
+		WorkspaceID: workspaceId,
+		ID:          util.NewId(),
+		ParentID:    req.ParentID,
+		Visibility:  req.Visibility,
+		Title:       clip.Title,
+		Content:     clip.Content,
+		SourceURL:   clip.SourceURL,
+		CreatedAt:   now,
+		CreatedBy:   user.ID,
+		UpdatedAt:   now,
+		UpdatedBy:   user.ID,
+	}
+
+	if err := h.db.CreateNote(n); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, n)
+}
+
+// clipRSSItem clips the page an item links to. When the page cannot be
+// clipped, the item description is used as the content instead.
+func clipRSSItem(ctx context.Context, item model.RSSItem, saveImage clipper.SaveImageFunc) (clipper.Clip, error) {
+	clip, err := clipper.ClipURL(ctx, item.Link, saveImage)
+	if err == nil {
+		if item.Title != "" {
+			clip.Title = item.Title
+		}
+		return clip, nil
+	}
+
+	if item.Description == "" {
+		return clipper.Clip{}, errors.New("failed to clip item: " + err.Error())
+	}
+
+	base, _ := url.Parse(item.Link)
+	if base == nil {
+		base = &url.URL{}
+	}
+	clip, err = clipper.ClipHTML(ctx, base, []byte(item.Description), "text/html; charset=utf-8", saveImage)
+	if errors.Is(err, clipper.ErrNoContent) {
+		// Short descriptions hold too little prose to pass as an article.
+		content, convErr := util.HTMLToTipTap(item.Description)
+		if convErr != nil {
+			return clipper.Clip{}, convErr
+		}
+		clip, err = clipper.Clip{Content: content}, nil
+	}
+	if err != nil {
+		return clipper.Clip{}, err
+	}
+
+	clip.Title = item.Title
+	clip.SourceURL = item.Link
+	return clip, nil
+}
+
+// clipImageSaver stores clipped images as workspace files, named the way
+// uploads are.
+func (h Handler) clipImageSaver(workspaceId, userID string) clipper.SaveImageFunc {
+	apiRoot := config.C.GetString(config.SERVER_API_ROOT_PATH)
+
+	return func(name, contentType string, data []byte) (string, error) {
+		reservation, err := quota.Reserve(h.db, workspaceId, userID, int64(len(data)))
+		if err != nil {
+			return "", err
+		}
+		defer reservation.Release()
+
+		ext := filepath.Ext(name)
+		fileName := time.Now().Format("20060102150405") + "_" + randStringRunes(4) + ext
+
+		hash, unlock, err := storage.PutBlob(h.storage, bytes.NewReader(data))
+		if err != nil {
+			return "", err
+		}
+		defer unlock()
+
+		now := time.Now().Format(time.RFC3339)
+		if err := h.db.CreateFile(model.File{
+			WorkspaceID:      workspaceId,
+			ID:               util.NewId(),
+			Name:             fileName,
+			Ext:              ext,
+			Size:             int64(len(data)),
+			OriginalFilename: name,
+			ContentType:      util.DetectContentType(name, data),
+			Hash:             hash,
+			CreatedAt:        now,
+			CreatedBy:        userID,
+			UpdatedAt:        now,
+			UpdatedBy:        userID,
+		}); err != nil {
+			return "", err
+		}
+
+		return apiRoot + "/workspaces/" + workspaceId + "/files/" + fileName, nil
+	}
+}
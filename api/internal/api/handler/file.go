@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"errors"
+	"io"
+	"io/fs"
 	"math/rand"
+	"mime"
 	"net/http"
 	"path/filepath"
 	"strconv"
@@ -9,7 +13,10 @@ import (
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/collabreef/collabreef/internal/imageproc"
 	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/quota"
+	"github.com/collabreef/collabreef/internal/storage"
 	"github.com/collabreef/collabreef/internal/util"
 )
 
@@ -18,10 +25,26 @@ func (h Handler) Upload(c echo.Context) error {
 	if workspaceId == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Workspace id is required")
 	}
+	limits := quota.Current()
+	if limits.MaxFileSize > 0 {
+		// Stop reading bodies that cannot hold an allowed file, leaving room
+		// for the multipart headers.
+		c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, limits.MaxFileSize+1<<20)
+	}
 	file, err := c.FormFile("file")
 	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return quotaError(&quota.Error{Scope: quota.ScopeFile, Limit: limits.MaxFileSize})
+		}
 		return c.String(http.StatusBadRequest, "")
 	}
+	user := c.Get("user").(model.User)
+	reservation, err := quota.Reserve(h.db, workspaceId, user.ID, file.Size)
+	if err != nil {
+		return quotaError(err)
+	}
+	defer reservation.Release()
 
 	f, err := file.Open()
 	if err != nil {
@@ -29,19 +52,22 @@ func (h Handler) Upload(c echo.Context) error {
 	}
 	defer f.Close()
 
-	segments := []string{workspaceId}
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	contentType := util.DetectContentType(file.Filename, head[:n])
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return c.String(http.StatusInternalServerError, "")
+	}
 
 	ext := filepath.Ext(file.Filename)
 	randomStr := randStringRunes(4)
 	newFileName := time.Now().Format("20060102150405") + "_" + randomStr + ext
 
-	segments = append(segments, newFileName)
-
-	err = h.storage.Save(segments, f)
+	hash, unlock, err := storage.PutBlob(h.storage, f)
 	if err != nil {
 		return c.String(http.StatusInternalServerError, "")
 	}
-	user := c.Get("user").(model.User)
+	defer unlock()
 
 	now := time.Now().Format(time.RFC3339)
 	fileModel := model.File{
@@ -51,6 +77,8 @@ func (h Handler) Upload(c echo.Context) error {
 		Ext:              ext,
 		Size:             file.Size,
 		OriginalFilename: file.Filename,
+		ContentType:      contentType,
+		Hash:             hash,
 		CreatedAt:        now,
 		CreatedBy:        user.ID,
 		UpdatedAt:        now,
@@ -59,16 +87,14 @@ func (h Handler) Upload(c echo.Context) error {
 	if err := h.db.CreateFile(fileModel); err != nil {
 		return c.String(http.StatusInternalServerError, "failed to save file record")
 	}
+	// The file counts towards the quotas now.
+	reservation.Release()
 
-	return c.JSON(http.StatusOK, echo.Map{
-		"id":            fileModel.ID,
-		"filename":      newFileName,
-		"original_name": file.Filename,
-		"size":          file.Size,
-		"ext":           ext,
-		"created_at":    fileModel.CreatedAt,
-		"updated_at":    fileModel.UpdatedAt,
-	})
+	if imageproc.IsSupported(contentType) {
+		go h.createThumbnail(fileModel)
+	}
+
+	return c.JSON(http.StatusOK, uploadedFileResponse(fileModel))
 }
 
 func (h Handler) Download(c echo.Context) error {
@@ -78,16 +104,100 @@ func (h Handler) Download(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Workspace id and filename are required")
 	}
 
-	segments := []string{workspaceId, filename}
+	var record model.File
+	files, err := h.db.FindFiles(model.FileFilter{WorkspaceID: workspaceId, Name: filename})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if len(files) > 0 {
+		record = files[0]
+	}
+	if err := h.checkFileAccess(c, workspaceId, filename, record); err != nil {
+		return err
+	}
+	record.WorkspaceID, record.Name = workspaceId, filename
+
+	if c.QueryParam("w") != "" || c.QueryParam("h") != "" {
+		if served, err := h.serveImageVariant(c, record); served || err != nil {
+			return err
+		}
+	}
+
+	// Files are read from the storage directly when it presigns URLs. Files
+	// without a recorded content type are still sniffed and served here.
+	if p, ok := h.presigner(); ok && record.ContentType != "" {
+		return redirectToFile(c, p, record)
+	}
 
-	f, err := h.storage.Load(segments)
+	segments := fileSegments(record)
 
+	info, err := h.storage.Stat(segments)
 	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return echo.NewHTTPError(http.StatusNotFound, "File not found")
+		}
 		return err
 	}
+	// Blobs are named by their content, which makes a strong entity tag.
+	if record.Hash != "" {
+		info.ETag = strconv.Quote(record.Hash)
+	}
+
+	f := storage.NewReadSeeker(h.storage, segments, info.Size)
 	defer f.Close()
 
-	return c.Stream(http.StatusOK, "application/octet-stream", f)
+	name := filename
+	if record.OriginalFilename != "" {
+		name = record.OriginalFilename
+	}
+	contentType := record.ContentType
+	// Files uploaded before content types were recorded are sniffed.
+	if contentType == "" {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(f, head)
+		contentType = util.DetectContentType(filename, head[:n])
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	return serveFile(c, name, contentType, info, f)
+}
+
+// serveFile writes stored content with the headers of file downloads.
+func serveFile(c echo.Context, name, contentType string, info storage.FileInfo, content io.ReadSeeker) error {
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, contentType)
+	header.Set("X-Content-Type-Options", "nosniff")
+	if disposition := mime.FormatMediaType(contentDisposition(contentType), map[string]string{"filename": name}); disposition != "" {
+		header.Set(echo.HeaderContentDisposition, disposition)
+	}
+	if info.ETag != "" {
+		header.Set("ETag", info.ETag)
+	}
+	header.Set("Cache-Control", "private, no-cache")
+
+	// ServeContent answers range and conditional requests.
+	http.ServeContent(c.Response(), c.Request(), name, info.ModTime, content)
+	return nil
+}
+
+// contentDisposition shows media, PDFs and plain text in the browser, and
+// downloads other files, so that uploaded pages and scripts are not run as
+// part of the site.
+func contentDisposition(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "image/svg+xml":
+		return "attachment"
+	case strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "video/"),
+		mediaType == "application/pdf",
+		mediaType == "text/plain":
+		return "inline"
+	}
+	return "attachment"
 }
 
 func (h Handler) Delete(c echo.Context) error {
@@ -103,22 +213,29 @@ func (h Handler) Delete(c echo.Context) error {
 
 	f, err := h.db.FindFileByID(id)
 
-	if err != nil {
+	if err != nil || f.WorkspaceID != workspaceId {
 		return echo.NewHTTPError(http.StatusNotFound, "Failed to find file")
 	}
 
-	if err := h.db.DeleteFile(filter); err != nil {
+	deleted, err := h.db.DeleteFile(filter)
+	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete file record")
 	}
+	// A concurrent delete removed the file first, and its content with it.
+	if deleted == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, "Failed to find file")
+	}
 
-	segments := []string{workspaceId, f.Name}
-
-	err = h.storage.Delete(segments)
+	err = h.deleteFileContent(f)
 
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, "failed to delete file")
 	}
 
+	if err := h.deleteFileVariants(workspaceId, f.Name); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete file variants")
+	}
+
 	return c.JSON(http.StatusOK, echo.Map{"message": "File deleted"})
 }
 
@@ -179,6 +296,7 @@ func (h Handler) List(c echo.Context) error {
 			"original_name": f.OriginalFilename,
 			"size":          f.Size,
 			"ext":           f.Ext,
+			"content_type":  f.ContentType,
 			"created_at":    f.CreatedAt,
 			"updated_at":    f.UpdatedAt,
 		})
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/api/auth"
+	"github.com/collabreef/collabreef/internal/config"
+	"github.com/collabreef/collabreef/internal/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SignedFileURLResponse is a URL that downloads a file without the session
+// cookie until ExpiresAt.
+type SignedFileURLResponse struct {
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// SignFileURL mints a short-lived signed URL for a workspace file, for
+// public embeds that cannot send the session cookie. URLs are valid for
+// FILE_URL_TTL.
+func (h Handler) SignFileURL(c echo.Context) error {
+	workspaceId := c.Param("workspaceId")
+	filename := c.Param("id")
+	if workspaceId == "" || filename == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Workspace id and filename are required")
+	}
+
+	user := c.Get("user").(model.User)
+	if !h.isUserWorkspaceMember(user.ID, workspaceId) {
+		return echo.NewHTTPError(http.StatusForbidden, "only workspace members can share files")
+	}
+
+	files, err := h.db.FindFiles(model.FileFilter{WorkspaceID: workspaceId, Name: filename})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if len(files) == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, "File not found")
+	}
+
+	expires := time.Now().Add(config.C.GetDuration(config.FILE_URL_TTL)).UTC()
+	url := config.C.GetString(config.SERVER_API_ROOT_PATH) + "/workspaces/" + workspaceId + "/files/" + filename +
+		"?expires=" + strconv.FormatInt(expires.Unix(), 10) +
+		"&signature=" + auth.SignFile(workspaceId, filename, expires)
+
+	return c.JSON(http.StatusOK, SignedFileURLResponse{
+		URL:       url,
+		ExpiresAt: expires.Format(time.RFC3339),
+	})
+}
+
+// checkFileAccess allows a download with a valid signature, by workspace
+// members, and of files that are public or referenced by a public note of
+// the workspace. Other files are reported as not found, so that their names
+// cannot be probed.
+func (h Handler) checkFileAccess(c echo.Context, workspaceId, filename string, f model.File) error {
+	if signature := c.QueryParam("signature"); signature != "" {
+		if auth.VerifyFile(workspaceId, filename, c.QueryParam("expires"), signature) {
+			return nil
+		}
+		return echo.NewHTTPError(http.StatusForbidden, "invalid or expired signature")
+	}
+
+	if user, ok := c.Get("user").(model.User); ok && h.isUserWorkspaceMember(user.ID, workspaceId) {
+		return nil
+	}
+
+	if f.Visibility == "public" {
+		return nil
+	}
+
+	// Without a user, only public notes are found.
+	notes, err := h.db.FindNotes(model.NoteFilter{
+		WorkspaceID:     workspaceId,
+		ContentContains: "/files/" + filename,
+		PageSize:        1,
+		PageNumber:      1,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if len(notes) > 0 {
+		return nil
+	}
+
+	return echo.NewHTTPError(http.StatusNotFound, "File not found")
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/api/auth"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/labstack/echo/v4"
+)
+
+func TestCheckFileAccess(t *testing.T) {
+	h, d, _ := newFileHandler(t)
+	for _, n := range []model.Note{
+		{WorkspaceID: "w1", ID: "n1", Visibility: "public", Content: `{"src":"/api/v1/workspaces/w1/files/shared.png"}`},
+		{WorkspaceID: "w1", ID: "n2", Visibility: "private", Content: `{"src":"/api/v1/workspaces/w1/files/private.png"}`},
+		{WorkspaceID: "w1", ID: "n3", Visibility: "workspace", Content: `{"src":"/api/v1/workspaces/w1/files/team.png"}`},
+		{WorkspaceID: "w2", ID: "n4", Visibility: "public", Content: `{"src":"/api/v1/workspaces/w1/files/elsewhere.png"}`},
+		{WorkspaceID: "w1", ID: "n5", Visibility: "public", Content: `{"src":"/api/v1/workspaces/w1/files/a1b.png"} 50% off`},
+	} {
+		if err := d.CreateNote(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expires := time.Now().Add(time.Minute)
+	signed := url.Values{
+		"expires":   {strconv.FormatInt(expires.Unix(), 10)},
+		"signature": {auth.SignFile("w1", "secret.png", expires)},
+	}
+	past := time.Now().Add(-time.Minute)
+	expired := url.Values{
+		"expires":   {strconv.FormatInt(past.Unix(), 10)},
+		"signature": {auth.SignFile("w1", "secret.png", past)},
+	}
+
+	member := &model.User{ID: "u1"}
+	outsider := &model.User{ID: "u2"}
+
+	tests := []struct {
+		name   string
+		file   string
+		record model.File
+		user   *model.User
+		query  url.Values
+		status int
+	}{
+		{name: "member", file: "secret.png", user: member, status: http.StatusOK},
+		{name: "outsider", file: "secret.png", user: outsider, status: http.StatusNotFound},
+		{name: "anonymous", file: "secret.png", status: http.StatusNotFound},
+		{name: "public file", file: "open.png", record: model.File{Visibility: "public"}, status: http.StatusOK},
+		{name: "file of a public note", file: "shared.png", status: http.StatusOK},
+		{name: "outsider and a public note", file: "shared.png", user: outsider, status: http.StatusOK},
+		{name: "file of a private note", file: "private.png", status: http.StatusNotFound},
+		{name: "file of a workspace note", file: "team.png", status: http.StatusNotFound},
+		{name: "public note of another workspace", file: "elsewhere.png", status: http.StatusNotFound},
+		// Names are matched literally, not as LIKE patterns.
+		{name: "underscore is not a wildcard", file: "a_b.png", status: http.StatusNotFound},
+		{name: "percent is not a wildcard", file: "a%.png", status: http.StatusNotFound},
+		{name: "backslash is not an escape", file: `a\1b.png`, status: http.StatusNotFound},
+		{name: "valid signature", file: "secret.png", query: signed, status: http.StatusOK},
+		{name: "signature of another file", file: "other.png", query: signed, status: http.StatusForbidden},
+		{name: "expired signature", file: "secret.png", query: expired, status: http.StatusForbidden},
+		{name: "invalid signature of a member", file: "secret.png", user: member, query: url.Values{"expires": signed["expires"], "signature": {"x"}}, status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := func(c echo.Context) error {
+				if err := h.checkFileAccess(c, "w1", tt.file, tt.record); err != nil {
+					return err
+				}
+				return c.NoContent(http.StatusOK)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query.Encode(), nil)
+			rec := serve(check, req, tt.user)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/storage"
+)
+
+// fileSegments addresses the content of a file: its blob, or the file in
+// its workspace for files stored before uploads were deduplicated.
+func fileSegments(f model.File) []string {
+	if f.Hash != "" {
+		return storage.BlobSegments(f.Hash)
+	}
+	return []string{f.WorkspaceID, f.Name}
+}
+
+// deleteFileContent deletes the content of a deleted file, unless it is a
+// blob that other files still refer to. The blob is locked so that a file
+// added with the same content meanwhile is either counted or stores the
+// blob again.
+func (h Handler) deleteFileContent(f model.File) error {
+	if f.Hash != "" {
+		unlock := storage.LockBlob(f.Hash)
+		defer unlock()
+		refs, err := h.db.FindFiles(model.FileFilter{Hash: f.Hash, PageSize: 1, PageNumber: 1})
+		if err != nil {
+			return err
+		}
+		if len(refs) > 0 {
+			return nil
+		}
+	}
+	return h.storage.Delete(fileSegments(f))
+}
@@ -0,0 +1,308 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/db/dbtest"
+	"github.com/collabreef/collabreef/internal/events"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/storage"
+	"github.com/collabreef/collabreef/internal/storage/localfile"
+)
+
+// newFileHandler returns a handler with local storage, and a workspace w1
+// that user u1 is a member of.
+func newFileHandler(t *testing.T) (*Handler, db.DB, storage.Storage) {
+	t.Helper()
+	d := dbtest.New(t)
+	if err := d.CreateWorkspaceUser(model.WorkspaceUser{WorkspaceID: "w1", UserID: "u1", Role: "owner"}); err != nil {
+		t.Fatal(err)
+	}
+	s := localfile.NewLocalFileStorage(t.TempDir())
+	return NewHandler(d, s, events.NewBus()), d, s
+}
+
+// storeFile stores content as a blob and records a file of w1 for it.
+func storeFile(t *testing.T, d db.DB, s storage.Storage, f model.File, content string) model.File {
+	t.Helper()
+	f.Hash = hashOf(content)
+	if _, err := storage.SaveBlob(s, f.Hash, strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	if f.WorkspaceID == "" {
+		f.WorkspaceID = "w1"
+	}
+	f.Size = int64(len(content))
+	if err := d.CreateFile(f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestDownload(t *testing.T) {
+	const content = "0123456789"
+	h, d, s := newFileHandler(t)
+	storeFile(t, d, s, model.File{ID: "f1", Name: "report.txt", ContentType: "text/plain; charset=utf-8"}, content)
+	storeFile(t, d, s, model.File{ID: "f2", Name: "b.pdf", OriginalFilename: "Résumé 2024.pdf", ContentType: "application/pdf"}, "%PDF-1.4")
+	storeFile(t, d, s, model.File{ID: "f3", Name: "page.html", ContentType: "text/html; charset=utf-8"}, "<p>hi</p>")
+	storeFile(t, d, s, model.File{ID: "f4", Name: "missing.txt", ContentType: "text/plain"}, "gone")
+	if err := s.Delete(storage.BlobSegments(hashOf("gone"))); err != nil {
+		t.Fatal(err)
+	}
+	// Files stored before blobs and content types are found by name and
+	// sniffed.
+	if err := s.Save([]string{"w1", "old.png"}, strings.NewReader("\x89PNG\r\n\x1a\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.CreateFile(model.File{WorkspaceID: "w1", ID: "f5", Name: "old.png"}); err != nil {
+		t.Fatal(err)
+	}
+	etag := strconv.Quote(hashOf(content))
+
+	tests := []struct {
+		name        string
+		file        string
+		header      map[string]string
+		status      int
+		body        string
+		contentType string
+		disposition string
+		wantHeader  map[string]string
+	}{
+		{
+			name:        "whole file",
+			file:        "report.txt",
+			status:      http.StatusOK,
+			body:        content,
+			contentType: "text/plain; charset=utf-8",
+			disposition: `inline; filename=report.txt`,
+			wantHeader:  map[string]string{"ETag": etag, "Accept-Ranges": "bytes", "X-Content-Type-Options": "nosniff"},
+		},
+		{
+			name:       "range",
+			file:       "report.txt",
+			header:     map[string]string{"Range": "bytes=2-4"},
+			status:     http.StatusPartialContent,
+			body:       "234",
+			wantHeader: map[string]string{"Content-Range": "bytes 2-4/10", "Content-Length": "3"},
+		},
+		{
+			name:   "suffix range",
+			file:   "report.txt",
+			header: map[string]string{"Range": "bytes=-3"},
+			status: http.StatusPartialContent,
+			body:   "789",
+		},
+		{
+			name:       "unsatisfiable range",
+			file:       "report.txt",
+			header:     map[string]string{"Range": "bytes=20-30"},
+			status:     http.StatusRequestedRangeNotSatisfiable,
+			wantHeader: map[string]string{"Content-Range": "bytes */10"},
+		},
+		{
+			name:   "matching entity tag",
+			file:   "report.txt",
+			header: map[string]string{"If-None-Match": etag},
+			status: http.StatusNotModified,
+		},
+		{
+			name:   "other entity tag",
+			file:   "report.txt",
+			header: map[string]string{"If-None-Match": `"other"`},
+			status: http.StatusOK,
+			body:   content,
+		},
+		{
+			name:   "range of a changed file",
+			file:   "report.txt",
+			header: map[string]string{"Range": "bytes=2-4", "If-Range": `"other"`},
+			status: http.StatusOK,
+			body:   content,
+		},
+		{
+			name:        "original file name",
+			file:        "b.pdf",
+			status:      http.StatusOK,
+			contentType: "application/pdf",
+			disposition: `inline; filename*=utf-8''R%C3%A9sum%C3%A9%202024.pdf`,
+		},
+		{
+			name:        "pages are downloaded",
+			file:        "page.html",
+			status:      http.StatusOK,
+			contentType: "text/html; charset=utf-8",
+			disposition: `attachment; filename=page.html`,
+		},
+		{
+			name:        "sniffed content type",
+			file:        "old.png",
+			status:      http.StatusOK,
+			contentType: "image/png",
+			disposition: `inline; filename=old.png`,
+		},
+		{
+			name:   "missing content",
+			file:   "missing.txt",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := serve(h.Download, req, &model.User{ID: "u1"}, "workspaceId", "w1", "id", tt.file)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", rec.Body, tt.body)
+			}
+			if tt.status == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("304 response has a body %q", rec.Body)
+			}
+			if tt.contentType != "" && rec.Header().Get("Content-Type") != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", rec.Header().Get("Content-Type"), tt.contentType)
+			}
+			if tt.disposition != "" && rec.Header().Get("Content-Disposition") != tt.disposition {
+				t.Errorf("Content-Disposition = %q, want %q", rec.Header().Get("Content-Disposition"), tt.disposition)
+			}
+			for k, v := range tt.wantHeader {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("%s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{"image/png", "inline"},
+		{"image/svg+xml", "attachment"},
+		{"video/mp4", "inline"},
+		{"audio/mpeg", "inline"},
+		{"application/pdf", "inline"},
+		{"text/plain; charset=utf-8", "inline"},
+		{"text/html; charset=utf-8", "attachment"},
+		{"application/javascript", "attachment"},
+		{"", "attachment"},
+	}
+	for _, tt := range tests {
+		if got := contentDisposition(tt.contentType); got != tt.want {
+			t.Errorf("contentDisposition(%q) = %q, want %q", tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func hashOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name        string
+		workspaceID string
+		id          string
+		status      int
+		// remaining are the files left, and kept the stored contents.
+		remaining []string
+		kept      []string
+	}{
+		{
+			name:        "file with its own blob",
+			workspaceID: "w1",
+			id:          "f1",
+			status:      http.StatusOK,
+			remaining:   []string{"f2", "f3", "f4"},
+			kept:        []string{"shared", "w2/old.txt"},
+		},
+		{
+			name:        "file sharing its blob",
+			workspaceID: "w1",
+			id:          "f2",
+			status:      http.StatusOK,
+			remaining:   []string{"f1", "f3", "f4"},
+			kept:        []string{"own", "shared", "w2/old.txt"},
+		},
+		{
+			name:        "file of another workspace",
+			workspaceID: "w1",
+			id:          "f4",
+			status:      http.StatusNotFound,
+			remaining:   []string{"f1", "f2", "f3", "f4"},
+			kept:        []string{"own", "shared", "w2/old.txt"},
+		},
+		{
+			name:        "missing file",
+			workspaceID: "w1",
+			id:          "missing",
+			status:      http.StatusNotFound,
+			remaining:   []string{"f1", "f2", "f3", "f4"},
+			kept:        []string{"own", "shared", "w2/old.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, d, s := newFileHandler(t)
+			storeFile(t, d, s, model.File{ID: "f1", Name: "a.txt"}, "own")
+			storeFile(t, d, s, model.File{ID: "f2", Name: "b.txt"}, "shared")
+			storeFile(t, d, s, model.File{ID: "f3", Name: "c.txt"}, "shared")
+			// A file stored before blobs, by workspace and name.
+			if err := s.Save([]string{"w2", "old.txt"}, strings.NewReader("old")); err != nil {
+				t.Fatal(err)
+			}
+			if err := d.CreateFile(model.File{WorkspaceID: "w2", ID: "f4", Name: "old.txt"}); err != nil {
+				t.Fatal(err)
+			}
+
+			rec := serve(h.Delete, httptest.NewRequest(http.MethodDelete, "/", nil), &model.User{ID: "u1"},
+				"workspaceId", tt.workspaceID, "id", tt.id)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+
+			for _, id := range []string{"f1", "f2", "f3", "f4"} {
+				_, err := d.FindFileByID(id)
+				if want := contains(tt.remaining, id); (err == nil) != want {
+					t.Errorf("file %s remaining = %v, want %v", id, err == nil, want)
+				}
+			}
+			for content, segments := range map[string][]string{
+				"own":        storage.BlobSegments(hashOf("own")),
+				"shared":     storage.BlobSegments(hashOf("shared")),
+				"w2/old.txt": {"w2", "old.txt"},
+			} {
+				_, err := s.Stat(segments)
+				if want := contains(tt.kept, content); (err == nil) != want {
+					t.Errorf("content %s kept = %v, want %v", content, err == nil, want)
+				}
+			}
+		})
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/imageproc"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/storage"
+
+	"github.com/labstack/echo/v4"
+)
+
+// variantSegments is where a variant of a file is stored. Variants are kept
+// apart from the workspace files, out of reach of the download route.
+func variantSegments(workspaceId, name string) []string {
+	return []string{workspaceId, "variants", name}
+}
+
+// serveImageVariant answers a download resized with the w, h and fit query
+// parameters. It reports false for files that are not images that can be
+// resized, or that fail to resize, which are served as they are.
+func (h Handler) serveImageVariant(c echo.Context, record model.File) (bool, error) {
+	o, err := imageproc.ParseOptions(c.QueryParam("w"), c.QueryParam("h"), c.QueryParam("fit"))
+	if err != nil {
+		return true, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	contentType := record.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(strings.ToLower(filepath.Ext(record.Name)))
+	}
+	if !imageproc.IsSupported(contentType) {
+		return false, nil
+	}
+
+	v, err := h.imageVariant(record, o)
+	if err != nil {
+		c.Logger().Errorf("Failed to resize %s: %v", record.Name, err)
+		return false, nil
+	}
+
+	segments := variantSegments(record.WorkspaceID, v.Name)
+	info, err := h.storage.Stat(segments)
+	if err != nil {
+		c.Logger().Errorf("Failed to find variant %s: %v", v.Name, err)
+		return false, nil
+	}
+	f := storage.NewReadSeeker(h.storage, segments, info.Size)
+	defer f.Close()
+
+	name := record.Name
+	if record.OriginalFilename != "" {
+		name = record.OriginalFilename
+	}
+	name = strings.TrimSuffix(name, filepath.Ext(name)) + filepath.Ext(v.Name)
+
+	return true, serveFile(c, name, v.ContentType, info, f)
+}
+
+// imageVariant returns the variant of an image made with the options,
+// resizing the image and storing the result the first time.
+func (h Handler) imageVariant(f model.File, o imageproc.Options) (model.FileVariant, error) {
+	key := o.Key()
+	if v, err := h.db.FindFileVariant(f.WorkspaceID, f.Name, key); err == nil {
+		return v, nil
+	}
+
+	r, err := h.storage.Load(fileSegments(f))
+	if err != nil {
+		return model.FileVariant{}, err
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return model.FileVariant{}, err
+	}
+
+	out, contentType, err := imageproc.Resize(data, o)
+	if err != nil {
+		return model.FileVariant{}, err
+	}
+	ext := ".png"
+	if contentType == "image/jpeg" {
+		ext = ".jpg"
+	}
+
+	v := model.FileVariant{
+		WorkspaceID: f.WorkspaceID,
+		FileName:    f.Name,
+		Variant:     key,
+		Name:        strings.TrimSuffix(f.Name, filepath.Ext(f.Name)) + "_" + key + ext,
+		ContentType: contentType,
+		Size:        int64(len(out)),
+		CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := h.storage.Save(variantSegments(f.WorkspaceID, v.Name), bytes.NewReader(out)); err != nil {
+		return model.FileVariant{}, err
+	}
+	if err := h.db.SaveFileVariant(v); err != nil {
+		return model.FileVariant{}, err
+	}
+	return v, nil
+}
+
+// createThumbnail makes the thumbnail of an uploaded image, so that it is
+// ready when the image is first shown.
+func (h Handler) createThumbnail(f model.File) {
+	if _, err := h.imageVariant(f, imageproc.Thumbnail); err != nil {
+		log.Printf("thumbnail: %s: %v", f.Name, err)
+	}
+}
+
+// deleteFileVariants removes the variants of a deleted file.
+func (h Handler) deleteFileVariants(workspaceId, filename string) error {
+	variants, err := h.db.FindFileVariants(workspaceId, filename)
+	if err != nil {
+		return err
+	}
+	for _, v := range variants {
+		if err := h.storage.Delete(variantSegments(workspaceId, v.Name)); err != nil {
+			log.Printf("file variants: %s: %v", v.Name, err)
+		}
+	}
+	return h.db.DeleteFileVariants(workspaceId, filename)
+}
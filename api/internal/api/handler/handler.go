@@ -2,17 +2,20 @@ package handler
 
 import (
 	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/events"
 	"github.com/collabreef/collabreef/internal/storage"
 )
 
 type Handler struct {
 	db      db.DB
 	storage storage.Storage
+	events  *events.Bus
 }
 
-func NewHandler(r db.DB, s storage.Storage) *Handler {
+func NewHandler(r db.DB, s storage.Storage, e *events.Bus) *Handler {
 	return &Handler{
 		db:      r,
 		storage: s,
+		events:  e,
 	}
 }
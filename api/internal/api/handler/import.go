@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/collabreef/collabreef/internal/config"
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/importer"
+	"github.com/collabreef/collabreef/internal/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxExportFileSize caps the size of an uploaded export of another
+// application. Exports carry their attachments, so they can be large.
+const maxExportFileSize = 512 << 20
+
+// ImportResponse reports an import. Roots are the notes created at the top
+// of the import, such as one note per notebook.
+type ImportResponse struct {
+	importer.Result
+	Roots []model.Note `json:"roots"`
+}
+
+// ImportENEX imports Evernote exports. The multipart form holds one or more
+// .enex files, each a notebook that becomes a note named after the file.
+// The form may set parent_id to nest the notebooks under a note, and the
+// visibility of the imported notes, private by default.
+func (h Handler) ImportENEX(c echo.Context) error {
+	files, err := exportFiles(c)
+	if err != nil {
+		return err
+	}
+
+	db, err := h.db.Begin(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer db.Rollback()
+
+	im, err := h.newImporter(c, db)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() { im.End(committed) }()
+	parentID := c.FormValue("parent_id")
+
+	res := ImportResponse{Roots: []model.Note{}}
+	for _, file := range files {
+		src, err := file.Open()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		notebook := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+		book, err := im.ImportENEX(src, notebook, parentID)
+		src.Close()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, file.Filename+": "+err.Error())
+		}
+		res.Roots = append(res.Roots, book)
+	}
+
+	if err := db.Commit(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	committed = true
+
+	res.Result = im.Result()
+	return c.JSON(http.StatusCreated, res)
+}
+
+// ImportNotion imports a Notion "Markdown & CSV" export, sent as a zip
+// file. Pages keep their nesting, under the note given by parent_id if any,
+// and databases become spreadsheet views of the note of their parent page.
+func (h Handler) ImportNotion(c echo.Context) error {
+	files, err := exportFiles(c)
+	if err != nil {
+		return err
+	}
+
+	db, err := h.db.Begin(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer db.Rollback()
+
+	im, err := h.newImporter(c, db)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() { im.End(committed) }()
+	parentID := c.FormValue("parent_id")
+
+	res := ImportResponse{Roots: []model.Note{}}
+	for _, file := range files {
+		src, err := file.Open()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		roots, err := im.ImportNotion(src, file.Size, parentID)
+		src.Close()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, file.Filename+": "+err.Error())
+		}
+		res.Roots = append(res.Roots, roots...)
+	}
+
+	if err := db.Commit(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	committed = true
+
+	res.Result = im.Result()
+	return c.JSON(http.StatusCreated, res)
+}
+
+// ImportTrello imports Trello boards, sent as the JSON export of each board.
+// Every board becomes a note holding a kanban view of its lists and cards,
+// under the note given by parent_id if any. Attachments are downloaded
+// before the import transaction starts.
+func (h Handler) ImportTrello(c echo.Context) error {
+	files, err := exportFiles(c)
+	if err != nil {
+		return err
+	}
+
+	im, err := h.newImporter(c, h.db)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() { im.End(committed) }()
+	parentID := c.FormValue("parent_id")
+
+	boards := make([]*importer.TrelloBoard, 0, len(files))
+	for _, file := range files {
+		src, err := file.Open()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		board, err := im.ReadTrello(c.Request().Context(), src)
+		src.Close()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, file.Filename+": "+err.Error())
+		}
+		boards = append(boards, board)
+	}
+
+	db, err := h.db.Begin(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer db.Rollback()
+	im.DB = db
+
+	res := ImportResponse{Roots: []model.Note{}}
+	for i, board := range boards {
+		note, err := im.ImportTrello(board, parentID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, files[i].Filename+": "+err.Error())
+		}
+		res.Roots = append(res.Roots, note)
+	}
+
+	if err := db.Commit(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	committed = true
+
+	res.Result = im.Result()
+	return c.JSON(http.StatusCreated, res)
+}
+
+// exportFiles returns the files of an import form, sent as "files" or as a
+// single "file".
+func exportFiles(c echo.Context) ([]*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "file is required")
+	}
+	files := append(form.File["files"], form.File["file"]...)
+	if len(files) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "file is required")
+	}
+	for _, file := range files {
+		if file.Size > maxExportFileSize {
+			return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, file.Filename+" is too large")
+		}
+	}
+	return files, nil
+}
+
+// newImporter prepares an import into the workspace of the request, written
+// through d. Only members can import, and a parent note given in the form
+// must belong to the workspace.
+func (h Handler) newImporter(c echo.Context, d db.DB) (*importer.Importer, error) {
+	workspaceId := c.Param("workspaceId")
+	if workspaceId == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "workspace id is required")
+	}
+
+	user := c.Get("user").(model.User)
+	if !h.isUserWorkspaceMember(user.ID, workspaceId) {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "only workspace members can import notes")
+	}
+
+	visibility := c.FormValue("visibility")
+	if visibility == "" {
+		visibility = "private"
+	}
+	switch visibility {
+	case "private", "workspace", "public":
+	default:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid visibility")
+	}
+
+	if parentID := c.FormValue("parent_id"); parentID != "" {
+		parent, err := h.db.FindNote(model.Note{ID: parentID})
+		if err != nil || parent.WorkspaceID != workspaceId {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "parent note not found")
+		}
+	}
+
+	return &importer.Importer{
+		DB:          d,
+		Storage:     h.storage,
+		WorkspaceID: workspaceId,
+		UserID:      user.ID,
+		Visibility:  visibility,
+		APIRoot:     config.C.GetString(config.SERVER_API_ROOT_PATH),
+	}, nil
+}
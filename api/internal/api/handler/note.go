@@ -32,6 +32,7 @@ type GetNoteResponse struct {
 	Visibility  string   `json:"visibility"`
 	Title       string   `json:"title"`
 	Content     string   `json:"content"`
+	SourceURL   string   `json:"source_url,omitempty"`
 	Tags        []string `json:"tags"`
 	Files       []string `json:"files"`
 	CreatedAt   string   `json:"created_at"`
@@ -109,6 +110,7 @@ func (h Handler) GetPublicNotes(c echo.Context) error {
 			Visibility:  b.Visibility,
 			Title:       b.Title,
 			Content:     b.Content,
+			SourceURL:   b.SourceURL,
 			CreatedAt:   b.CreatedAt,
 			CreatedBy:   h.getUserNameByID(b.CreatedBy),
 			UpdatedAt:   b.UpdatedAt,
@@ -140,6 +142,7 @@ func (h Handler) GetNotes(c echo.Context) error {
 		sortBy = "created_at"
 	}
 	parentID := c.QueryParam("parentId")
+	clipped, _ := strconv.ParseBool(c.QueryParam("clipped"))
 
 	user := c.Get("user").(model.User)
 
@@ -151,6 +154,7 @@ func (h Handler) GetNotes(c echo.Context) error {
 		Query:       query,
 		SortBy:      sortBy,
 		ParentID:    parentID,
+		Clipped:     clipped,
 	}
 
 	notes, err := h.db.FindNotes(filter)
@@ -168,6 +172,7 @@ func (h Handler) GetNotes(c echo.Context) error {
 			Visibility:  b.Visibility,
 			Title:       b.Title,
 			Content:     b.Content,
+			SourceURL:   b.SourceURL,
 			CreatedAt:   b.CreatedAt,
 			CreatedBy:   h.getUserNameByID(b.CreatedBy),
 			UpdatedAt:   b.UpdatedAt,
@@ -216,6 +221,7 @@ func (h Handler) GetNote(c echo.Context) error {
 		Visibility:  b.Visibility,
 		Title:       b.Title,
 		Content:     b.Content,
+		SourceURL:   b.SourceURL,
 		CreatedAt:   b.CreatedAt,
 		CreatedBy:   h.getUserNameByID(b.CreatedBy),
 		UpdatedAt:   b.UpdatedAt,
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/config"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/resumable"
+	"github.com/collabreef/collabreef/internal/storage"
+
+	"github.com/labstack/echo/v4"
+)
+
+type CreatePresignedUploadRequest struct {
+	Filename string `json:"filename"`
+	Length   int64  `json:"length"`
+}
+
+// PresignedUploadResponse holds the URL the client uploads the file to,
+// with a PUT request, before it finalizes the upload.
+type PresignedUploadResponse struct {
+	model.Upload
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// CreatePresignedUpload starts an upload that the client sends directly to
+// the storage, when the storage presigns URLs.
+func (h Handler) CreatePresignedUpload(c echo.Context) error {
+	if _, ok := h.presigner(); !ok {
+		return echo.NewHTTPError(http.StatusNotImplemented, "presigned uploads are not enabled")
+	}
+	u, err := resumable.New(h.db, h.storage)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotImplemented, err.Error())
+	}
+	workspaceId := c.Param("workspaceId")
+	user := c.Get("user").(model.User)
+	if !h.isUserWorkspaceMember(user.ID, workspaceId) {
+		return echo.NewHTTPError(http.StatusForbidden, "only workspace members can upload files")
+	}
+
+	var req CreatePresignedUploadRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if req.Length < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "length must not be negative")
+	}
+	filename := filepath.Base(req.Filename)
+	if filename == "." || filename == "/" {
+		filename = "upload"
+	}
+
+	up, url, err := u.CreatePresigned(workspaceId, user.ID, filename, req.Length)
+	if err != nil {
+		return uploadError(err)
+	}
+
+	return c.JSON(http.StatusCreated, PresignedUploadResponse{
+		Upload:    up,
+		URL:       url,
+		ExpiresAt: time.Now().Add(resumable.PresignExpiry).UTC().Format(time.RFC3339),
+	})
+}
+
+// FinalizePresignedUpload adds the file uploaded to the storage to the
+// workspace, and answers as Upload does.
+func (h Handler) FinalizePresignedUpload(c echo.Context) error {
+	u, err := resumable.New(h.db, h.storage)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotImplemented, err.Error())
+	}
+	up, err := h.findUpload(c)
+	if err != nil {
+		return err
+	}
+
+	up, err = u.Finish(up)
+	if err != nil {
+		return uploadError(err)
+	}
+	h.uploadComplete(up)
+
+	f, err := h.db.FindFileByID(up.FileID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, uploadedFileResponse(f))
+}
+
+// presigner returns the storage when files are uploaded to and downloaded
+// from it directly, with presigned URLs.
+func (h Handler) presigner() (storage.Presigner, bool) {
+	if !config.C.GetBool(config.STORAGE_S3_PRESIGN) {
+		return nil, false
+	}
+	p, ok := h.storage.(storage.Presigner)
+	return p, ok
+}
+
+// redirectToFile answers a download with a redirect to a presigned URL of
+// the file. The redirect is cached briefly, as every URL is signed anew.
+func redirectToFile(c echo.Context, p storage.Presigner, record model.File) error {
+	name := record.Name
+	if record.OriginalFilename != "" {
+		name = record.OriginalFilename
+	}
+	disposition := mime.FormatMediaType(contentDisposition(record.ContentType), map[string]string{"filename": name})
+
+	url, err := p.PresignGet(fileSegments(record), config.C.GetDuration(config.FILE_URL_TTL), record.ContentType, disposition)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	c.Response().Header().Set("Cache-Control", "private, max-age=60")
+	return c.Redirect(http.StatusFound, url)
+}
+
+func uploadedFileResponse(f model.File) echo.Map {
+	return echo.Map{
+		"id":            f.ID,
+		"filename":      f.Name,
+		"original_name": f.OriginalFilename,
+		"size":          f.Size,
+		"ext":           f.Ext,
+		"content_type":  f.ContentType,
+		"created_at":    f.CreatedAt,
+		"updated_at":    f.UpdatedAt,
+	}
+}
@@ -0,0 +1,331 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/config"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/rsscache"
+
+	"github.com/labstack/echo/v4"
+)
+
+type SubscribeRSSFeedRequest struct {
+	URL string `json:"url" validate:"required"`
+}
+
+type RSSFeedResponse struct {
+	ID          string `json:"id"`
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Link        string `json:"link,omitempty"`
+	FetchedAt   string `json:"fetched_at"`
+	LastError   string `json:"last_error,omitempty"`
+	Unread      int64  `json:"unread"`
+}
+
+// RSSFeedItemsResponse is a feed with a page of its items. Its fields match
+// the feeds the RSS widget used to fetch directly.
+type RSSFeedItemsResponse struct {
+	RSSFeedResponse
+	Items []RSSItemResponse `json:"items"`
+}
+
+type RSSItemResponse struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Link        string `json:"link"`
+	Description string `json:"description,omitempty"`
+	PubDate     string `json:"pubDate,omitempty"`
+	GUID        string `json:"guid,omitempty"`
+	ImageURL    string `json:"imageUrl,omitempty"`
+	Read        bool   `json:"read"`
+}
+
+// GetRSSFeeds lists the feeds the user subscribes to, with unread counts.
+func (h Handler) GetRSSFeeds(c echo.Context) error {
+	user := c.Get("user").(model.User)
+
+	feeds, err := h.db.FindRSSFeeds(model.RSSFeedFilter{UserID: user.ID})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	res := []RSSFeedResponse{}
+	for _, feed := range feeds {
+		r, err := h.rssFeedResponse(user.ID, feed)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		res = append(res, r)
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// SubscribeRSSFeed subscribes the user to a feed. A feed that cannot be
+// fetched is still subscribed to; its error is reported in last_error and
+// it is retried in the background.
+func (h Handler) SubscribeRSSFeed(c echo.Context) error {
+	var req SubscribeRSSFeedRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	user := c.Get("user").(model.User)
+
+	feed, err := rsscache.Subscribe(c.Request().Context(), h.db, user.ID, req.URL, rssPollInterval())
+	if err != nil && feed.ID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	res, err := h.rssFeedResponse(user.ID, feed)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, res)
+}
+
+// GetRSSFeed returns a subscribed feed with a page of its items, newest
+// first. With unread=true only unread items are listed.
+func (h Handler) GetRSSFeed(c echo.Context) error {
+	feed, err := h.findRSSFeed(c)
+	if err != nil {
+		return err
+	}
+
+	user := c.Get("user").(model.User)
+
+	pageSize := 50
+	pageNumber := 1
+	if ps := c.QueryParam("pageSize"); ps != "" {
+		if v, err := strconv.Atoi(ps); err == nil && v > 0 {
+			pageSize = v
+		}
+	}
+	if pn := c.QueryParam("pageNumber"); pn != "" {
+		if v, err := strconv.Atoi(pn); err == nil && v > 0 {
+			pageNumber = v
+		}
+	}
+	unread, _ := strconv.ParseBool(c.QueryParam("unread"))
+
+	res, err := h.rssFeedItemsResponse(user.ID, feed, model.RSSItemFilter{
+		Unread:     unread,
+		PageSize:   pageSize,
+		PageNumber: pageNumber,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// UnsubscribeRSSFeed removes a subscription. A feed left without
+// subscribers is deleted along with its items.
+func (h Handler) UnsubscribeRSSFeed(c echo.Context) error {
+	feed, err := h.findRSSFeed(c)
+	if err != nil {
+		return err
+	}
+
+	user := c.Get("user").(model.User)
+
+	if err := h.db.DeleteRSSSubscription(model.RSSSubscription{UserID: user.ID, FeedID: feed.ID}); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	remaining, err := h.db.FindRSSSubscriptions(model.RSSSubscriptionFilter{FeedID: feed.ID})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if len(remaining) == 0 {
+		if err := h.db.DeleteRSSFeed(feed); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// MarkRSSFeedRead marks every item of a feed as read.
+func (h Handler) MarkRSSFeedRead(c echo.Context) error {
+	feed, err := h.findRSSFeed(c)
+	if err != nil {
+		return err
+	}
+
+	user := c.Get("user").(model.User)
+
+	// A page size of -1 disables the limit.
+	items, err := h.db.FindRSSItems(model.RSSItemFilter{FeedID: feed.ID, UserID: user.ID, Unread: true, PageNumber: 1, PageSize: -1})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+
+	if err := h.db.MarkRSSItemsRead(user.ID, ids, time.Now().UTC().String()); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h Handler) MarkRSSItemRead(c echo.Context) error {
+	item, err := h.findRSSItem(c)
+	if err != nil {
+		return err
+	}
+
+	user := c.Get("user").(model.User)
+
+	if err := h.db.MarkRSSItemsRead(user.ID, []string{item.ID}, time.Now().UTC().String()); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h Handler) MarkRSSItemUnread(c echo.Context) error {
+	item, err := h.findRSSItem(c)
+	if err != nil {
+		return err
+	}
+
+	user := c.Get("user").(model.User)
+
+	if err := h.db.MarkRSSItemsUnread(user.ID, []string{item.ID}); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// findRSSFeed loads the feed of the request. Users only see the feeds they
+// subscribe to.
+func (h Handler) findRSSFeed(c echo.Context) (model.RSSFeed, error) {
+	id := c.Param("id")
+	if id == "" {
+		return model.RSSFeed{}, echo.NewHTTPError(http.StatusBadRequest, "feed id is required")
+	}
+
+	user := c.Get("user").(model.User)
+
+	subs, err := h.db.FindRSSSubscriptions(model.RSSSubscriptionFilter{UserID: user.ID, FeedID: id})
+	if err != nil || len(subs) == 0 {
+		return model.RSSFeed{}, echo.NewHTTPError(http.StatusNotFound, "feed not found")
+	}
+
+	feed, err := h.db.FindRSSFeed(model.RSSFeed{ID: id})
+	if err != nil {
+		return model.RSSFeed{}, echo.NewHTTPError(http.StatusNotFound, "feed not found")
+	}
+
+	return feed, nil
+}
+
+func (h Handler) findRSSItem(c echo.Context) (model.RSSItem, error) {
+	id := c.Param("id")
+	if id == "" {
+		return model.RSSItem{}, echo.NewHTTPError(http.StatusBadRequest, "item id is required")
+	}
+
+	user := c.Get("user").(model.User)
+
+	return h.findUserRSSItem(user.ID, id)
+}
+
+// findUserRSSItem loads an item of a feed the user subscribes to.
+func (h Handler) findUserRSSItem(userID, id string) (model.RSSItem, error) {
+	item, err := h.db.FindRSSItem(model.RSSItem{ID: id})
+	if err != nil {
+		return model.RSSItem{}, echo.NewHTTPError(http.StatusNotFound, "item not found")
+	}
+
+	subs, err := h.db.FindRSSSubscriptions(model.RSSSubscriptionFilter{UserID: userID, FeedID: item.FeedID})
+	if err != nil || len(subs) == 0 {
+		return model.RSSItem{}, echo.NewHTTPError(http.StatusNotFound, "item not found")
+	}
+
+	return item, nil
+}
+
+func (h Handler) rssFeedResponse(userID string, feed model.RSSFeed) (RSSFeedResponse, error) {
+	unread, err := h.db.CountUnreadRSSItems(userID, feed.ID)
+	if err != nil {
+		return RSSFeedResponse{}, err
+	}
+	return RSSFeedResponse{
+		ID:          feed.ID,
+		URL:         feed.URL,
+		Title:       feed.Title,
+		Description: feed.Description,
+		Link:        feed.Link,
+		FetchedAt:   feed.FetchedAt,
+		LastError:   feed.LastError,
+		Unread:      unread,
+	}, nil
+}
+
+// rssFeedItemsResponse lists the items of a feed selected by f, with the
+// read state of the user.
+func (h Handler) rssFeedItemsResponse(userID string, feed model.RSSFeed, f model.RSSItemFilter) (RSSFeedItemsResponse, error) {
+	r, err := h.rssFeedResponse(userID, feed)
+	if err != nil {
+		return RSSFeedItemsResponse{}, err
+	}
+
+	f.FeedID = feed.ID
+	f.UserID = userID
+	items, err := h.db.FindRSSItems(f)
+	if err != nil {
+		return RSSFeedItemsResponse{}, err
+	}
+
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	reads, err := h.db.FindRSSItemReads(userID, ids)
+	if err != nil {
+		return RSSFeedItemsResponse{}, err
+	}
+	read := make(map[string]bool, len(reads))
+	for _, r := range reads {
+		read[r.ItemID] = true
+	}
+
+	res := RSSFeedItemsResponse{RSSFeedResponse: r, Items: []RSSItemResponse{}}
+	for _, item := range items {
+		res.Items = append(res.Items, RSSItemResponse{
+			ID:          item.ID,
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			PubDate:     item.PubDate,
+			GUID:        item.GUID,
+			ImageURL:    item.ImageURL,
+			Read:        read[item.ID],
+		})
+	}
+
+	return res, nil
+}
+
+func rssPollInterval() time.Duration {
+	return config.C.GetDuration(config.RSS_POLL_INTERVAL)
+}
@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/events"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/spreadsheet"
+	"github.com/collabreef/collabreef/internal/util"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	contentTypeCSV  = "text/csv; charset=utf-8"
+	contentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// ExportSpreadsheet downloads a spreadsheet view as CSV or XLSX.
+// CSV holds a single sheet (the "sheet" query parameter, by id or name,
+// defaulting to the first one); XLSX holds all sheets unless one is selected.
+func (h Handler) ExportSpreadsheet(c echo.Context) error {
+	view, sheets, err := h.findSpreadsheet(c)
+	if err != nil {
+		return err
+	}
+
+	sheetKey := c.QueryParam("sheet")
+	format := strings.ToLower(c.QueryParam("format"))
+	if format == "" {
+		format = "xlsx"
+	}
+
+	var buf bytes.Buffer
+	var contentType, filename string
+
+	switch format {
+	case "csv":
+		sheet, err := spreadsheet.FindSheet(sheets, sheetKey)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		if err := spreadsheet.WriteCSV(sheet, &buf); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		contentType = contentTypeCSV
+		filename = view.Name
+		if len(sheets) > 1 {
+			filename += " - " + sheet.Name
+		}
+		filename += ".csv"
+	case "xlsx":
+		selected := sheets
+		if sheetKey != "" {
+			sheet, err := spreadsheet.FindSheet(sheets, sheetKey)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusNotFound, err.Error())
+			}
+			selected = []spreadsheet.Sheet{*sheet}
+		}
+		if err := spreadsheet.WriteXLSX(selected, &buf); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		contentType = contentTypeXLSX
+		filename = view.Name + ".xlsx"
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "format must be 'csv' or 'xlsx'")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	return c.Blob(http.StatusOK, contentType, buf.Bytes())
+}
+
+// ImportSpreadsheet creates a new spreadsheet view from an uploaded CSV or
+// XLSX file. Optional form fields: name, note_id and visibility.
+func (h Handler) ImportSpreadsheet(c echo.Context) error {
+	workspaceId := c.Param("workspaceId")
+	if workspaceId == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "workspace id is required")
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "file is required")
+	}
+
+	visibility := c.FormValue("visibility")
+	if visibility == "" {
+		visibility = "private"
+	}
+	switch visibility {
+	case "public", "workspace", "private":
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "View visibility must be 'public', 'workspace', or 'private'")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer src.Close()
+
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	baseName := strings.TrimSuffix(filepath.Base(file.Filename), filepath.Ext(file.Filename))
+
+	var sheets []spreadsheet.Sheet
+	switch ext {
+	case ".csv":
+		sheet, err := spreadsheet.ReadCSV(src, baseName)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Failed to parse CSV: "+err.Error())
+		}
+		sheets = []spreadsheet.Sheet{sheet}
+	case ".xlsx":
+		sheets, err = spreadsheet.ReadXLSX(src)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Failed to parse XLSX: "+err.Error())
+		}
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "file must be a .csv or .xlsx file")
+	}
+
+	data, err := spreadsheet.MarshalSheets(sheets)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	name := c.FormValue("name")
+	if name == "" {
+		name = baseName
+	}
+
+	user := c.Get("user").(model.User)
+
+	v := model.View{
+		WorkspaceID: workspaceId,
+		NoteID:      c.FormValue("note_id"),
+		ID:          util.NewId(),
+		Name:        name,
+		Type:        "spreadsheet",
+		Data:        data,
+		Visibility:  visibility,
+		CreatedAt:   time.Now().UTC().String(),
+		CreatedBy:   user.ID,
+		UpdatedAt:   time.Now().UTC().String(),
+		UpdatedBy:   user.ID,
+	}
+
+	if err := h.db.CreateView(v); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, v)
+}
+
+type UpdateSpreadsheetCellsRequest struct {
+	Range  string          `json:"range" validate:"required"`
+	Values [][]interface{} `json:"values" validate:"required"`
+}
+
+// GetSpreadsheetCells returns a cell range in A1 notation ("range" query
+// parameter, e.g. Sheet1!A1:D20) with stored formulas and computed values.
+func (h Handler) GetSpreadsheetCells(c echo.Context) error {
+	_, sheets, err := h.findSpreadsheet(c)
+	if err != nil {
+		return err
+	}
+
+	rng, err := spreadsheet.ParseRange(c.QueryParam("range"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	values, err := spreadsheet.ReadRange(sheets, rng)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, values)
+}
+
+// UpdateSpreadsheetCells writes values into a cell range. Strings starting
+// with "=" are stored as formulas and null clears a cell. The change is
+// forwarded to connected collab clients.
+func (h Handler) UpdateSpreadsheetCells(c echo.Context) error {
+	var req UpdateSpreadsheetCellsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Validation failed: " + err.Error()})
+	}
+
+	view, sheets, err := h.findSpreadsheet(c)
+	if err != nil {
+		return err
+	}
+
+	rng, err := spreadsheet.ParseRange(req.Range)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	sheet, updates, err := spreadsheet.WriteRange(sheets, rng, req.Values)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	data, err := spreadsheet.MarshalSheets(sheets)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	user := c.Get("user").(model.User)
+
+	view.Data = data
+	view.UpdatedAt = time.Now().UTC().String()
+	view.UpdatedBy = user.ID
+	if err := h.db.UpdateView(view); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	ops := make([]map[string]interface{}, 0, len(updates))
+	for _, u := range updates {
+		ops = append(ops, u.Op())
+	}
+	h.events.Publish(events.ViewDataChanged{
+		ViewID: view.ID,
+		Type:   view.Type,
+		Data:   data,
+		Ops:    ops,
+	})
+
+	// Respond with the written block, which may extend past a single-cell anchor.
+	written := rng
+	written.Sheet = sheet.ID
+	written.EndRow = max(rng.EndRow, rng.StartRow+len(req.Values)-1)
+	for _, row := range req.Values {
+		written.EndCol = max(written.EndCol, rng.StartCol+len(row)-1)
+	}
+
+	values, err := spreadsheet.ReadRange(sheets, written)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, values)
+}
+
+// findSpreadsheet loads the spreadsheet view addressed by the request and
+// parses its sheets.
+func (h Handler) findSpreadsheet(c echo.Context) (model.View, []spreadsheet.Sheet, error) {
+	workspaceId := c.Param("workspaceId")
+	id := c.Param("id")
+	if workspaceId == "" || id == "" {
+		return model.View{}, nil, echo.NewHTTPError(http.StatusBadRequest, "workspace id and view id are required")
+	}
+
+	view, err := h.db.FindView(model.View{WorkspaceID: workspaceId, ID: id})
+	if err != nil || view.WorkspaceID != workspaceId {
+		return model.View{}, nil, echo.NewHTTPError(http.StatusNotFound, "view not found")
+	}
+	if view.Type != "spreadsheet" {
+		return model.View{}, nil, echo.NewHTTPError(http.StatusBadRequest, "view is not a spreadsheet")
+	}
+
+	sheets, err := spreadsheet.ParseSheets(view.Data)
+	if err != nil {
+		return model.View{}, nil, echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	return view, sheets, nil
+}
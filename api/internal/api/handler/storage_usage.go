@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/quota"
+
+	"github.com/labstack/echo/v4"
+)
+
+// StorageUsage is how much storage uploaded files take, in total and by
+// workspace, by the user who uploaded them and by content type, largest
+// first.
+type StorageUsage struct {
+	Limits     quota.Limits            `json:"limits"`
+	Files      int64                   `json:"files"`
+	Size       int64                   `json:"size"`
+	Workspaces []WorkspaceStorageUsage `json:"workspaces"`
+	Users      []UserStorageUsage      `json:"users"`
+	Types      []TypeStorageUsage      `json:"types"`
+}
+
+type WorkspaceStorageUsage struct {
+	WorkspaceID string `json:"workspace_id"`
+	Name        string `json:"name"`
+	Files       int64  `json:"files"`
+	Size        int64  `json:"size"`
+}
+
+type UserStorageUsage struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Files  int64  `json:"files"`
+	Size   int64  `json:"size"`
+}
+
+type TypeStorageUsage struct {
+	ContentType string `json:"content_type"`
+	Files       int64  `json:"files"`
+	Size        int64  `json:"size"`
+}
+
+// GetStorageUsage reports the storage used on the server, for admins to see
+// who is filling the disk.
+func (h Handler) GetStorageUsage(c echo.Context) error {
+	usage, err := h.db.FindFileUsage(model.FileFilter{})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	res := StorageUsage{
+		Limits:     quota.Current(),
+		Workspaces: []WorkspaceStorageUsage{},
+		Users:      []UserStorageUsage{},
+		Types:      []TypeStorageUsage{},
+	}
+	workspaces := map[string]*WorkspaceStorageUsage{}
+	users := map[string]*UserStorageUsage{}
+	types := map[string]*TypeStorageUsage{}
+
+	for _, u := range usage {
+		res.Files += u.Files
+		res.Size += u.Size
+
+		w, ok := workspaces[u.WorkspaceID]
+		if !ok {
+			w = &WorkspaceStorageUsage{WorkspaceID: u.WorkspaceID}
+			workspaces[u.WorkspaceID] = w
+		}
+		w.Files += u.Files
+		w.Size += u.Size
+
+		usr, ok := users[u.CreatedBy]
+		if !ok {
+			usr = &UserStorageUsage{UserID: u.CreatedBy}
+			users[u.CreatedBy] = usr
+		}
+		usr.Files += u.Files
+		usr.Size += u.Size
+
+		// Files uploaded before content types were recorded have none.
+		contentType := u.ContentType
+		if contentType == "" {
+			contentType = "unknown"
+		}
+		t, ok := types[contentType]
+		if !ok {
+			t = &TypeStorageUsage{ContentType: contentType}
+			types[contentType] = t
+		}
+		t.Files += u.Files
+		t.Size += u.Size
+	}
+
+	for id, w := range workspaces {
+		if ws, err := h.db.FindWorkspaceByID(id); err == nil {
+			w.Name = ws.Name
+		}
+		res.Workspaces = append(res.Workspaces, *w)
+	}
+	for id, u := range users {
+		if user, err := h.db.FindUserByID(id); err == nil {
+			u.Name = user.Name
+			u.Email = user.Email
+		}
+		res.Users = append(res.Users, *u)
+	}
+	for _, t := range types {
+		res.Types = append(res.Types, *t)
+	}
+
+	sort.Slice(res.Workspaces, func(i, j int) bool { return res.Workspaces[i].Size > res.Workspaces[j].Size })
+	sort.Slice(res.Users, func(i, j int) bool { return res.Users[i].Size > res.Users[j].Size })
+	sort.Slice(res.Types, func(i, j int) bool { return res.Types[i].Size > res.Types[j].Size })
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// quotaError answers an upload refused by a storage quota.
+func quotaError(err error) error {
+	var qe *quota.Error
+	if !errors.As(err, &qe) {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if qe.Scope == quota.ScopeFile {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, qe.Error())
+	}
+	return echo.NewHTTPError(http.StatusInsufficientStorage, qe.Error())
+}
@@ -0,0 +1,475 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/table"
+	"github.com/collabreef/collabreef/internal/util"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AddTableRowRequest struct {
+	// NoteID adds an existing note. Without it a new note is created with
+	// Title and the visibility of the table.
+	NoteID     string                     `json:"note_id"`
+	Title      string                     `json:"title"`
+	Properties map[string]json.RawMessage `json:"properties"`
+}
+
+type UpdateTableRowRequest struct {
+	// Properties maps property ids to values; null clears a value.
+	Properties map[string]json.RawMessage `json:"properties" validate:"required"`
+}
+
+type TableRowResponse struct {
+	ID         string                 `json:"id"`
+	Title      string                 `json:"title"`
+	Visibility string                 `json:"visibility"`
+	CreatedAt  string                 `json:"created_at"`
+	CreatedBy  string                 `json:"created_by"`
+	UpdatedAt  string                 `json:"updated_at"`
+	UpdatedBy  string                 `json:"updated_by"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type TableGroupResponse struct {
+	Key   string             `json:"key"`
+	Value interface{}        `json:"value"`
+	Rows  []TableRowResponse `json:"rows"`
+}
+
+type QueryTableResponse struct {
+	Rows   []TableRowResponse   `json:"rows"`
+	Groups []TableGroupResponse `json:"groups,omitempty"`
+}
+
+// QueryTable returns the rows of a table view filtered, sorted and grouped
+// by the query in the request body. Rows are limited to the notes the
+// requester can see.
+func (h Handler) QueryTable(c echo.Context) error {
+	view, props, err := h.findTable(c)
+	if err != nil {
+		return err
+	}
+
+	var q table.Query
+	if err := c.Bind(&q); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	tableRows, err := h.db.FindTableRows(model.TableRowFilter{ViewID: view.ID})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	var userID string
+	if user, ok := c.Get("user").(model.User); ok {
+		userID = user.ID
+	}
+	rows, err := h.loadTableRows(view.WorkspaceID, userID, props, tableRows)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	result, err := table.Run(props, rows, q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	res := QueryTableResponse{Rows: tableRowResponses(result.Rows)}
+	for _, g := range result.Groups {
+		res.Groups = append(res.Groups, TableGroupResponse{
+			Key:   g.Key,
+			Value: g.Value,
+			Rows:  tableRowResponses(g.Rows),
+		})
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// AddTableRow adds a note to a table view, creating the note when no note id
+// is given, and sets its initial property values.
+func (h Handler) AddTableRow(c echo.Context) error {
+	view, props, err := h.findTable(c)
+	if err != nil {
+		return err
+	}
+
+	user := c.Get("user").(model.User)
+	if !h.isUserWorkspaceMember(user.ID, view.WorkspaceID) {
+		return echo.NewHTTPError(http.StatusForbidden, "only workspace members can edit tables")
+	}
+
+	var req AddTableRowRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	now := time.Now().UTC()
+
+	var note model.Note
+	if req.NoteID != "" {
+		note, err = h.db.FindNote(model.Note{ID: req.NoteID})
+		if err != nil || note.WorkspaceID != view.WorkspaceID || (note.Visibility == "private" && note.CreatedBy != user.ID) {
+			return echo.NewHTTPError(http.StatusNotFound, "note not found")
+		}
+	} else {
+		note = model.Note{
+			WorkspaceID: view.WorkspaceID,
+			ID:          util.NewId(),
+			Title:       req.Title,
+			Visibility:  view.Visibility,
+			CreatedAt:   now.Format(time.RFC3339),
+			CreatedBy:   user.ID,
+			UpdatedAt:   now.Format(time.RFC3339),
+			UpdatedBy:   user.ID,
+		}
+	}
+
+	values, err := h.parseTableValues(view, props, user.ID, req.Properties)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	tx, err := h.db.Begin(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer tx.Rollback()
+
+	if req.NoteID == "" {
+		if err := tx.CreateNote(note); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+	if err := tx.CreateTableRow(model.TableRow{
+		ViewID:    view.ID,
+		NoteID:    note.ID,
+		CreatedAt: now.String(),
+		CreatedBy: user.ID,
+	}); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if err := saveTableValues(tx, note.ID, user.ID, values); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	if err := tx.Commit(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return h.tableRowResponse(c, http.StatusCreated, view, props, note)
+}
+
+// UpdateTableRow sets property values of a row.
+func (h Handler) UpdateTableRow(c echo.Context) error {
+	view, props, err := h.findTable(c)
+	if err != nil {
+		return err
+	}
+
+	user := c.Get("user").(model.User)
+	if !h.isUserWorkspaceMember(user.ID, view.WorkspaceID) {
+		return echo.NewHTTPError(http.StatusForbidden, "only workspace members can edit tables")
+	}
+
+	var req UpdateTableRowRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Validation failed: " + err.Error()})
+	}
+
+	note, err := h.findTableRow(c, view)
+	if err != nil {
+		return err
+	}
+
+	values, err := h.parseTableValues(view, props, user.ID, req.Properties)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	tx, err := h.db.Begin(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer tx.Rollback()
+
+	if err := saveTableValues(tx, note.ID, user.ID, values); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	if err := tx.Commit(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return h.tableRowResponse(c, http.StatusOK, view, props, note)
+}
+
+// RemoveTableRow removes a note from a table view. The note and its property
+// values are kept.
+func (h Handler) RemoveTableRow(c echo.Context) error {
+	view, _, err := h.findTable(c)
+	if err != nil {
+		return err
+	}
+
+	user := c.Get("user").(model.User)
+	if !h.isUserWorkspaceMember(user.ID, view.WorkspaceID) {
+		return echo.NewHTTPError(http.StatusForbidden, "only workspace members can edit tables")
+	}
+
+	note, err := h.findTableRow(c, view)
+	if err != nil {
+		return err
+	}
+
+	if err := h.db.DeleteTableRow(model.TableRow{ViewID: view.ID, NoteID: note.ID}); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// findTable loads the table view of the request and its properties.
+func (h Handler) findTable(c echo.Context) (model.View, []table.Property, error) {
+	workspaceId := c.Param("workspaceId")
+	id := c.Param("id")
+	if workspaceId == "" || id == "" {
+		return model.View{}, nil, echo.NewHTTPError(http.StatusBadRequest, "workspace id and view id are required")
+	}
+
+	view, err := h.db.FindView(model.View{WorkspaceID: workspaceId, ID: id})
+	if err != nil || view.WorkspaceID != workspaceId || !h.canReadView(c, view) {
+		return model.View{}, nil, echo.NewHTTPError(http.StatusNotFound, "view not found")
+	}
+	if view.Type != "table" {
+		return model.View{}, nil, echo.NewHTTPError(http.StatusBadRequest, "view is not a table")
+	}
+
+	// A page size of -1 disables the limit.
+	objects, err := h.db.FindViewObjects(model.ViewObjectFilter{ViewID: view.ID, ObjectType: table.ObjectType, PageNumber: 1, PageSize: -1})
+	if err != nil {
+		return model.View{}, nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return view, table.LoadProperties(objects), nil
+}
+
+// findTableRow returns the note of the :noteId row of a table.
+func (h Handler) findTableRow(c echo.Context, view model.View) (model.Note, error) {
+	noteId := c.Param("noteId")
+	rows, err := h.db.FindTableRows(model.TableRowFilter{ViewID: view.ID, NoteID: noteId})
+	if err != nil {
+		return model.Note{}, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if len(rows) == 0 {
+		return model.Note{}, echo.NewHTTPError(http.StatusNotFound, "row not found")
+	}
+
+	note, err := h.db.FindNote(model.Note{ID: noteId})
+	if err != nil {
+		return model.Note{}, echo.NewHTTPError(http.StatusNotFound, "row not found")
+	}
+	return note, nil
+}
+
+// loadTableRows reads the notes of table rows that the user can see, with
+// their property values, in row order.
+func (h Handler) loadTableRows(workspaceID, userID string, props []table.Property, tableRows []model.TableRow) ([]table.Row, error) {
+	if len(tableRows) == 0 {
+		return nil, nil
+	}
+
+	noteIDs := make([]string, len(tableRows))
+	for i, r := range tableRows {
+		noteIDs[i] = r.NoteID
+	}
+	notes, err := h.db.FindNotes(model.NoteFilter{
+		WorkspaceID: workspaceID,
+		NoteIDs:     noteIDs,
+		UserID:      userID,
+		PageNumber:  1,
+		PageSize:    -1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]model.Note, len(notes))
+	for _, n := range notes {
+		byID[n.ID] = n
+	}
+
+	values := make(map[string]map[string]interface{}, len(notes))
+	if len(props) > 0 {
+		propByID := make(map[string]table.Property, len(props))
+		propIDs := make([]string, len(props))
+		for i, p := range props {
+			propByID[p.ID] = p
+			propIDs[i] = p.ID
+		}
+		stored, err := h.db.FindNoteProperties(model.NotePropertyFilter{NoteIDs: noteIDs, PropertyIDs: propIDs})
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range stored {
+			v := propByID[s.PropertyID].DecodeValue(s.Value)
+			if v == nil {
+				continue
+			}
+			if values[s.NoteID] == nil {
+				values[s.NoteID] = map[string]interface{}{}
+			}
+			values[s.NoteID][s.PropertyID] = v
+		}
+	}
+
+	var rows []table.Row
+	for _, r := range tableRows {
+		note, ok := byID[r.NoteID]
+		if !ok {
+			continue
+		}
+		rows = append(rows, table.Row{Note: note, Values: values[note.ID]})
+	}
+	return rows, nil
+}
+
+// parseTableValues checks property values from a request. People must be
+// workspace members and relations must point to notes of the workspace, or
+// to rows of the related table when the property names one.
+func (h Handler) parseTableValues(view model.View, props []table.Property, userID string, raw map[string]json.RawMessage) (map[string]interface{}, error) {
+	propByID := make(map[string]table.Property, len(props))
+	for _, p := range props {
+		propByID[p.ID] = p
+	}
+
+	values := make(map[string]interface{}, len(raw))
+	for id, r := range raw {
+		p, ok := propByID[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown property %q", id)
+		}
+		v, err := p.ParseValue(r)
+		if err != nil {
+			return nil, fmt.Errorf("property %q: %w", p.Name, err)
+		}
+
+		ids, _ := v.([]string)
+		switch p.Kind {
+		case table.KindPerson:
+			for _, uid := range ids {
+				if !h.isUserWorkspaceMember(uid, view.WorkspaceID) {
+					return nil, fmt.Errorf("property %q: %q is not a workspace member", p.Name, uid)
+				}
+			}
+		case table.KindRelation:
+			if err := h.checkRelatedNotes(view.WorkspaceID, userID, p, ids); err != nil {
+				return nil, fmt.Errorf("property %q: %w", p.Name, err)
+			}
+		}
+		values[id] = v
+	}
+	return values, nil
+}
+
+func (h Handler) checkRelatedNotes(workspaceID, userID string, p table.Property, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	notes, err := h.db.FindNotes(model.NoteFilter{WorkspaceID: workspaceID, NoteIDs: ids, UserID: userID, PageNumber: 1, PageSize: -1})
+	if err != nil {
+		return err
+	}
+	if len(notes) != len(ids) {
+		return errors.New("related notes must exist in the workspace")
+	}
+
+	if p.ViewID != "" {
+		rows, err := h.db.FindTableRows(model.TableRowFilter{ViewID: p.ViewID})
+		if err != nil {
+			return err
+		}
+		inTable := make(map[string]bool, len(rows))
+		for _, r := range rows {
+			inTable[r.NoteID] = true
+		}
+		for _, id := range ids {
+			if !inTable[id] {
+				return fmt.Errorf("note %q is not a row of the related table", id)
+			}
+		}
+	}
+	return nil
+}
+
+// saveTableValues stores property values of a note; nil values are removed.
+func saveTableValues(tx db.DB, noteID, userID string, values map[string]interface{}) error {
+	now := time.Now().UTC().String()
+	for id, v := range values {
+		if v == nil {
+			if err := tx.DeleteNoteProperty(model.NoteProperty{NoteID: noteID, PropertyID: id}); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if err := tx.SaveNoteProperty(model.NoteProperty{
+			NoteID:     noteID,
+			PropertyID: id,
+			Value:      string(data),
+			UpdatedAt:  now,
+			UpdatedBy:  userID,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h Handler) tableRowResponse(c echo.Context, status int, view model.View, props []table.Property, note model.Note) error {
+	user := c.Get("user").(model.User)
+	rows, err := h.loadTableRows(view.WorkspaceID, user.ID, props, []model.TableRow{{ViewID: view.ID, NoteID: note.ID}})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if len(rows) == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, "row not found")
+	}
+	return c.JSON(status, tableRowResponses(rows)[0])
+}
+
+func tableRowResponses(rows []table.Row) []TableRowResponse {
+	res := make([]TableRowResponse, 0, len(rows))
+	for _, r := range rows {
+		props := r.Values
+		if props == nil {
+			props = map[string]interface{}{}
+		}
+		res = append(res, TableRowResponse{
+			ID:         r.Note.ID,
+			Title:      r.Note.Title,
+			Visibility: r.Note.Visibility,
+			CreatedAt:  r.Note.CreatedAt,
+			CreatedBy:  r.Note.CreatedBy,
+			UpdatedAt:  r.Note.UpdatedAt,
+			UpdatedBy:  r.Note.UpdatedBy,
+			Properties: props,
+		})
+	}
+	return res
+}
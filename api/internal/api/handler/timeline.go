@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/timeline"
+
+	"github.com/labstack/echo/v4"
+)
+
+type RescheduleTimelineTaskRequest struct {
+	Start string `json:"start" validate:"required"`
+	// End defaults to the start plus the current duration of the task.
+	End string `json:"end"`
+	// Mode is push (default) or shift, see timeline.Mode.
+	Mode string `json:"mode"`
+}
+
+type RescheduleTimelineTaskResponse struct {
+	// Tasks are the tasks whose dates changed, the moved task first.
+	Tasks []model.ViewObject `json:"tasks"`
+}
+
+// RescheduleTimelineTask moves a task of a timeline view and shifts the tasks
+// that depend on it, directly or indirectly, so that each still starts after
+// its predecessors end. All changes are saved in one transaction.
+func (h Handler) RescheduleTimelineTask(c echo.Context) error {
+	workspaceId := c.Param("workspaceId")
+	id := c.Param("id")
+	taskId := c.Param("taskId")
+	if workspaceId == "" || id == "" || taskId == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "workspace id, view id, and task id are required")
+	}
+
+	var req RescheduleTimelineTaskRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Validation failed: " + err.Error()})
+	}
+
+	mode := timeline.Mode(req.Mode)
+	switch mode {
+	case "":
+		mode = timeline.ModePush
+	case timeline.ModePush, timeline.ModeShift:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "mode must be push or shift")
+	}
+	start, err := timeline.ParseDate(req.Start)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "start must be a date in YYYY-MM-DD format")
+	}
+
+	view, err := h.db.FindView(model.View{WorkspaceID: workspaceId, ID: id})
+	if err != nil || view.WorkspaceID != workspaceId {
+		return echo.NewHTTPError(http.StatusNotFound, "view not found")
+	}
+	if view.Type != "timeline" {
+		return echo.NewHTTPError(http.StatusBadRequest, "view is not a timeline")
+	}
+
+	user := c.Get("user").(model.User)
+	if !h.isUserWorkspaceMember(user.ID, view.WorkspaceID) {
+		return echo.NewHTTPError(http.StatusForbidden, "only workspace members can edit timelines")
+	}
+
+	db, err := h.db.Begin(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer db.Rollback()
+
+	objects, err := db.FindViewObjects(model.ViewObjectFilter{ViewID: view.ID, ObjectType: timeline.ObjectType, PageNumber: 1, PageSize: -1})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	tasks := timeline.Load(objects)
+
+	var moved *timeline.Task
+	for _, t := range tasks {
+		if t.ID == taskId {
+			moved = t
+		}
+	}
+	if moved == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "task not found")
+	}
+
+	end := start.Add(moved.End.Sub(moved.Start))
+	if req.End != "" {
+		if end, err = timeline.ParseDate(req.End); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "end must be a date in YYYY-MM-DD format")
+		}
+	}
+
+	changed, err := timeline.Reschedule(tasks, taskId, start, end, mode)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	now := time.Now().UTC().String()
+	res := RescheduleTimelineTaskResponse{Tasks: make([]model.ViewObject, 0, len(changed))}
+	for _, t := range changed {
+		o := t.Object()
+		o.UpdatedAt = now
+		o.UpdatedBy = user.ID
+		if err := db.UpdateViewObject(o); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		res.Tasks = append(res.Tasks, o)
+	}
+
+	if err := db.Commit(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// checkTimelineLinks checks the parent and dependencies of a timeline task
+// that is about to be saved against the other tasks of its view.
+func (h Handler) checkTimelineLinks(o model.ViewObject) error {
+	if o.Type != timeline.ObjectType {
+		return nil
+	}
+	objects, err := h.db.FindViewObjects(model.ViewObjectFilter{ViewID: o.ViewID, ObjectType: timeline.ObjectType, PageNumber: 1, PageSize: -1})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if err := timeline.CheckObject(objects, o); err != nil {
+		return viewObjectValidationError(err)
+	}
+	return nil
+}
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
-	"github.com/collabreef/collabreef/internal/rssfetcher"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/rsscache"
 	"github.com/collabreef/collabreef/internal/urlfetcher"
 )
 
@@ -56,12 +58,33 @@ func (h Handler) FetchRSS(c echo.Context) error {
 		})
 	}
 
-	feed, err := rssfetcher.FetchAndParseFeed(c.Request().Context(), feedURL)
-	if err != nil {
+	user := c.Get("user").(model.User)
+
+	// Widgets subscribe to the feeds they show and read them from the cache,
+	// which is refreshed in the background.
+	feed, err := rsscache.Subscribe(c.Request().Context(), h.db, user.ID, feedURL, rssPollInterval())
+	if err != nil && feed.FetchedAt == "" {
 		return c.JSON(http.StatusBadRequest, map[string]interface{}{
 			"error": "Failed to fetch RSS feed: " + err.Error(),
 		})
 	}
+	if feed.FetchedAt == "" && feed.LastError != "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Failed to fetch RSS feed: " + feed.LastError,
+		})
+	}
+
+	pageSize := 50
+	if ps := c.QueryParam("pageSize"); ps != "" {
+		if v, err := strconv.Atoi(ps); err == nil && v > 0 {
+			pageSize = v
+		}
+	}
+
+	res, err := h.rssFeedItemsResponse(user.ID, feed, model.RSSItemFilter{PageSize: pageSize, PageNumber: 1})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
 
-	return c.JSON(http.StatusOK, feed)
+	return c.JSON(http.StatusOK, res)
 }
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/collabreef/collabreef/internal/config"
+	"github.com/collabreef/collabreef/internal/unfurl"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Unfurl returns the title, description, image, favicon and oEmbed HTML of
+// a link. Results are cached for UNFURL_CACHE_TTL.
+func (h Handler) Unfurl(c echo.Context) error {
+	rawURL := c.QueryParam("url")
+	if rawURL == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "URL is required",
+		})
+	}
+
+	m, err := unfurl.New().Lookup(c.Request().Context(), h.db, rawURL, config.C.GetDuration(config.UNFURL_CACHE_TTL))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Failed to unfurl URL: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, m)
+}
@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/config"
+	"github.com/collabreef/collabreef/internal/imageproc"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/quota"
+	"github.com/collabreef/collabreef/internal/resumable"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Resumable uploads follow the tus protocol, version 1.0.0, with the
+// creation, termination and expiration extensions. See https://tus.io.
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,expiration"
+)
+
+// UploadOptions describes the resumable uploads the server supports.
+func (h Handler) UploadOptions(c echo.Context) error {
+	header := c.Response().Header()
+	header.Set("Tus-Resumable", tusVersion)
+	header.Set("Tus-Version", tusVersion)
+	header.Set("Tus-Extension", tusExtensions)
+	header.Set("Tus-Max-Size", strconv.FormatInt(maxUploadLength(), 10))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// CreateUpload starts a resumable upload of a file to the workspace. The
+// file name is read from the filename entry of Upload-Metadata.
+func (h Handler) CreateUpload(c echo.Context) error {
+	u, err := h.uploader(c)
+	if err != nil {
+		return err
+	}
+	workspaceId := c.Param("workspaceId")
+	user := c.Get("user").(model.User)
+	if !h.isUserWorkspaceMember(user.ID, workspaceId) {
+		return echo.NewHTTPError(http.StatusForbidden, "only workspace members can upload files")
+	}
+
+	if c.Request().Header.Get("Upload-Defer-Length") != "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Upload-Defer-Length is not supported")
+	}
+	length, err := strconv.ParseInt(c.Request().Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Upload-Length is required")
+	}
+	if length > maxUploadLength() {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "file exceeds the maximum file size of "+quota.FormatSize(maxUploadLength()))
+	}
+
+	metadata := parseUploadMetadata(c.Request().Header.Get("Upload-Metadata"))
+	filename := filepath.Base(metadata["filename"])
+	if filename == "." || filename == "/" {
+		filename = "upload"
+	}
+
+	up, err := u.Create(workspaceId, user.ID, filename, length)
+	if err != nil {
+		return uploadError(err)
+	}
+	if up.FileID != "" {
+		h.uploadComplete(up)
+	}
+
+	setUploadHeaders(c, up)
+	c.Response().Header().Set(echo.HeaderLocation, config.C.GetString(config.SERVER_API_ROOT_PATH)+"/workspaces/"+workspaceId+"/uploads/"+up.ID)
+	return c.NoContent(http.StatusCreated)
+}
+
+// GetUploadOffset answers the HEAD requests clients resume an upload with.
+func (h Handler) GetUploadOffset(c echo.Context) error {
+	if _, err := h.uploader(c); err != nil {
+		return err
+	}
+	up, err := h.findUpload(c)
+	if err != nil {
+		return err
+	}
+
+	setUploadHeaders(c, up)
+	c.Response().Header().Set("Upload-Length", strconv.FormatInt(up.Length, 10))
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.NoContent(http.StatusOK)
+}
+
+// GetUpload describes an upload, with the name of the uploaded file once it
+// is complete.
+func (h Handler) GetUpload(c echo.Context) error {
+	if _, err := h.uploader(c); err != nil {
+		return err
+	}
+	up, err := h.findUpload(c)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, up)
+}
+
+// PatchUpload appends the request body to an upload, from Upload-Offset.
+func (h Handler) PatchUpload(c echo.Context) error {
+	u, err := h.uploader(c)
+	if err != nil {
+		return err
+	}
+	if c.Request().Header.Get(echo.HeaderContentType) != "application/offset+octet-stream" {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, "Content-Type must be application/offset+octet-stream")
+	}
+	offset, err := strconv.ParseInt(c.Request().Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Upload-Offset is required")
+	}
+	up, err := h.findUpload(c)
+	if err != nil {
+		return err
+	}
+
+	up, err = u.Write(up, offset, c.Request().Body)
+	if err != nil {
+		return uploadError(err)
+	}
+	if up.FileID != "" {
+		h.uploadComplete(up)
+	}
+
+	setUploadHeaders(c, up)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// TerminateUpload discards an upload.
+func (h Handler) TerminateUpload(c echo.Context) error {
+	u, err := h.uploader(c)
+	if err != nil {
+		return err
+	}
+	up, err := h.findUpload(c)
+	if err != nil {
+		return err
+	}
+
+	if err := u.Terminate(up); err != nil {
+		return uploadError(err)
+	}
+
+	c.Response().Header().Set("Tus-Resumable", tusVersion)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// uploader checks the protocol version of a request, and returns the
+// uploader of the storage.
+func (h Handler) uploader(c echo.Context) (resumable.Uploader, error) {
+	c.Response().Header().Set("Tus-Resumable", tusVersion)
+	if c.Request().Header.Get("Tus-Resumable") != tusVersion {
+		c.Response().Header().Set("Tus-Version", tusVersion)
+		return resumable.Uploader{}, echo.NewHTTPError(http.StatusPreconditionFailed, "Tus-Resumable must be "+tusVersion)
+	}
+
+	u, err := resumable.New(h.db, h.storage)
+	if err != nil {
+		return resumable.Uploader{}, echo.NewHTTPError(http.StatusNotImplemented, err.Error())
+	}
+	return u, nil
+}
+
+// findUpload returns the upload of the request. Uploads are only found by
+// the user who created them.
+func (h Handler) findUpload(c echo.Context) (model.Upload, error) {
+	user := c.Get("user").(model.User)
+	up, err := h.db.FindUpload(c.Param("id"))
+	if err != nil || up.WorkspaceID != c.Param("workspaceId") || up.CreatedBy != user.ID {
+		return model.Upload{}, echo.NewHTTPError(http.StatusNotFound, "Upload not found")
+	}
+	return up, nil
+}
+
+// uploadComplete makes the thumbnail of an uploaded image, as Upload does.
+func (h Handler) uploadComplete(up model.Upload) {
+	f, err := h.db.FindFileByID(up.FileID)
+	if err == nil && imageproc.IsSupported(f.ContentType) {
+		go h.createThumbnail(f)
+	}
+}
+
+func setUploadHeaders(c echo.Context, up model.Upload) {
+	header := c.Response().Header()
+	header.Set("Upload-Offset", strconv.FormatInt(up.Received, 10))
+	if up.FileID != "" {
+		return
+	}
+	if updated, err := time.Parse(time.RFC3339, up.UpdatedAt); err == nil {
+		if expiry := config.C.GetDuration(config.UPLOAD_EXPIRY); expiry > 0 {
+			header.Set("Upload-Expires", updated.Add(expiry).UTC().Format(http.TimeFormat))
+		}
+	}
+}
+
+// maxUploadLength is the size of the largest file that can be uploaded.
+func maxUploadLength() int64 {
+	if limit := quota.Current().MaxFileSize; limit > 0 && limit < resumable.MaxLength {
+		return limit
+	}
+	return resumable.MaxLength
+}
+
+// parseUploadMetadata reads the comma separated keys and base64 encoded
+// values of Upload-Metadata.
+func parseUploadMetadata(s string) map[string]string {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			continue
+		}
+		metadata[key] = string(decoded)
+	}
+	return metadata
+}
+
+func uploadError(err error) error {
+	var qe *quota.Error
+	switch {
+	case errors.As(err, &qe):
+		return quotaError(err)
+	case errors.Is(err, resumable.ErrTooLarge):
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, resumable.ErrOffsetMismatch), errors.Is(err, resumable.ErrComplete):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, resumable.ErrMethod), errors.Is(err, resumable.ErrNotUploaded), errors.Is(err, resumable.ErrLengthMismatch):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, resumable.ErrPresignedTooLarge):
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, resumable.ErrPresignNotSupported):
+		return echo.NewHTTPError(http.StatusNotImplemented, err.Error())
+	case errors.Is(err, resumable.ErrLocked):
+		return echo.NewHTTPError(http.StatusLocked, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/collabreef/collabreef/internal/model"
 	"github.com/collabreef/collabreef/internal/util"
+	"github.com/collabreef/collabreef/internal/whiteboard"
 
 	"github.com/labstack/echo/v4"
 )
@@ -189,9 +190,9 @@ func (h Handler) CreateView(c echo.Context) error {
 
 	// Validate view type
 	switch req.Type {
-	case "map", "calendar", "kanban", "whiteboard", "spreadsheet":
+	case "map", "calendar", "kanban", "whiteboard", "spreadsheet", "table", "timeline":
 	default:
-		return echo.NewHTTPError(http.StatusBadRequest, "View type must be 'map', 'calendar', 'kanban', 'whiteboard', or 'spreadsheet'")
+		return echo.NewHTTPError(http.StatusBadRequest, "View type must be 'map', 'calendar', 'kanban', 'whiteboard', 'spreadsheet', 'table', or 'timeline'")
 	}
 
 	user := c.Get("user").(model.User)
@@ -223,12 +224,29 @@ func (h Handler) CreateView(c echo.Context) error {
 		UpdatedBy:   user.ID,
 	}
 
-	err := h.db.CreateView(v)
+	// The canvas objects of a whiteboard are indexed with its data.
+	db, err := h.db.Begin(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer db.Rollback()
+
+	err = db.CreateView(v)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
+	if v.Type == "whiteboard" {
+		if err := whiteboard.IndexCanvas(db, v.ID, v.Data); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	if err := db.Commit(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
 	return c.JSON(http.StatusCreated, v)
 }
 
@@ -263,9 +281,9 @@ func (h Handler) UpdateView(c echo.Context) error {
 	// Validate view type if provided
 	if req.Type != "" {
 		switch req.Type {
-		case "map", "calendar", "kanban", "whiteboard", "spreadsheet":
+		case "map", "calendar", "kanban", "whiteboard", "spreadsheet", "table", "timeline":
 		default:
-			return echo.NewHTTPError(http.StatusBadRequest, "View type must be 'map', 'calendar', 'kanban', 'whiteboard', or 'spreadsheet'")
+			return echo.NewHTTPError(http.StatusBadRequest, "View type must be 'map', 'calendar', 'kanban', 'whiteboard', 'spreadsheet', 'table', or 'timeline'")
 		}
 	}
 
@@ -307,12 +325,37 @@ func (h Handler) UpdateView(c echo.Context) error {
 	// Note: Data can be explicitly set to empty string to clear it
 	// So we don't check if it's empty here
 
-	err = h.db.UpdateView(v)
+	db, err := h.db.Begin(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer db.Rollback()
+
+	err = db.UpdateView(v)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
+	// The canvas objects follow the data of a whiteboard, which is kept when
+	// empty, and are dropped when the view stops being one.
+	if v.Type == "whiteboard" || existingView.Type == "whiteboard" {
+		data := ""
+		if v.Type == "whiteboard" {
+			data = v.Data
+			if data == "" {
+				data = existingView.Data
+			}
+		}
+		if err := whiteboard.IndexCanvas(db, v.ID, data); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	if err := db.Commit(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
 	return c.JSON(http.StatusOK, v)
 }
 
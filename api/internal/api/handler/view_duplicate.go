@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/util"
+	"github.com/collabreef/collabreef/internal/viewcopy"
+
+	"github.com/labstack/echo/v4"
+)
+
+type DuplicateViewRequest struct {
+	// Name defaults to the name of the view followed by "(copy)".
+	Name string `json:"name"`
+	// WorkspaceID copies the view into another workspace the user is a
+	// member of. It defaults to the workspace of the view.
+	WorkspaceID string `json:"workspace_id"`
+	// NoteID attaches the copy to a note of the target workspace. Copies in
+	// the same workspace default to the note of the view.
+	NoteID string `json:"note_id"`
+	// Visibility defaults to the visibility of the view.
+	Visibility string `json:"visibility"`
+}
+
+// DuplicateView copies a view, its data and all of its view objects under
+// new ids. Table rows and notes linked to objects belong to the original
+// and are not copied.
+func (h Handler) DuplicateView(c echo.Context) error {
+	workspaceId := c.Param("workspaceId")
+	id := c.Param("id")
+	if workspaceId == "" || id == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "workspace id and view id are required")
+	}
+
+	var req DuplicateViewRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	source, err := h.db.FindView(model.View{WorkspaceID: workspaceId, ID: id})
+	if err != nil || source.WorkspaceID != workspaceId || !h.canReadView(c, source) {
+		return echo.NewHTTPError(http.StatusNotFound, "view not found")
+	}
+
+	user := c.Get("user").(model.User)
+
+	target := model.View{
+		WorkspaceID: req.WorkspaceID,
+		NoteID:      req.NoteID,
+		Name:        req.Name,
+		Type:        source.Type,
+		Data:        source.Data,
+		Visibility:  req.Visibility,
+	}
+	if target.WorkspaceID == "" {
+		target.WorkspaceID = source.WorkspaceID
+	}
+	if target.NoteID == "" && target.WorkspaceID == source.WorkspaceID {
+		target.NoteID = source.NoteID
+	}
+	if target.Name == "" {
+		target.Name = source.Name + " (copy)"
+	}
+	if target.Visibility == "" {
+		target.Visibility = source.Visibility
+	}
+	if err := h.checkViewCopyTarget(user, &target); err != nil {
+		return err
+	}
+
+	objects, err := viewcopy.Load(h.db, source.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	db, err := h.db.Begin(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer db.Rollback()
+
+	v, err := viewcopy.Create(db, target, objects)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	if err := db.Commit(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, v)
+}
+
+// checkViewCopyTarget checks that the user may create the copied view v and
+// fills in its id and metadata.
+func (h Handler) checkViewCopyTarget(user model.User, v *model.View) error {
+	if !h.isUserWorkspaceMember(user.ID, v.WorkspaceID) {
+		return echo.NewHTTPError(http.StatusForbidden, "only workspace members can create views")
+	}
+
+	switch v.Visibility {
+	case "public", "workspace", "private":
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "View visibility must be 'public', 'workspace', or 'private'")
+	}
+
+	if v.NoteID != "" {
+		note, err := h.db.FindNote(model.Note{ID: v.NoteID})
+		if err != nil || note.WorkspaceID != v.WorkspaceID {
+			return echo.NewHTTPError(http.StatusBadRequest, "note not found in the target workspace")
+		}
+	}
+
+	now := time.Now().UTC().String()
+	v.ID = util.NewId()
+	v.CreatedAt, v.CreatedBy = now, user.ID
+	v.UpdatedAt, v.UpdatedBy = now, user.ID
+	return nil
+}
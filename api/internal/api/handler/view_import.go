@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/csvimport"
+	"github.com/collabreef/collabreef/internal/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxImportFileSize caps the size of an imported CSV file.
+const maxImportFileSize = 10 << 20
+
+type ImportViewObjectsResponse struct {
+	DryRun    bool                 `json:"dry_run"`
+	Committed bool                 `json:"committed"`
+	Rows      int                  `json:"rows"`
+	Valid     int                  `json:"valid"`
+	Errors    []csvimport.RowError `json:"errors"`
+	Created   []model.ViewObject   `json:"created"`
+	Updated   []model.ViewObject   `json:"updated"`
+}
+
+// ImportViewObjects creates view objects from the rows of a CSV file. The
+// multipart form holds the file and a JSON column mapping. With dry_run the
+// import is only planned and row errors are reported; otherwise rows with
+// errors fail the whole import with 400, unless skip_invalid is set.
+func (h Handler) ImportViewObjects(c echo.Context) error {
+	workspaceId := c.Param("workspaceId")
+	viewId := c.Param("viewId")
+
+	if workspaceId == "" || viewId == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "workspace id and view id are required")
+	}
+
+	view, err := h.db.FindView(model.View{WorkspaceID: workspaceId, ID: viewId})
+	if err != nil || view.WorkspaceID != workspaceId {
+		return echo.NewHTTPError(http.StatusNotFound, "view not found")
+	}
+
+	user := c.Get("user").(model.User)
+	if !h.isUserWorkspaceMember(user.ID, view.WorkspaceID) {
+		return echo.NewHTTPError(http.StatusForbidden, "only workspace members can import into views")
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "file is required")
+	}
+	if file.Size > maxImportFileSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "file is too large")
+	}
+
+	var mapping csvimport.Mapping
+	if err := json.Unmarshal([]byte(c.FormValue("mapping")), &mapping); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "mapping must be a JSON object")
+	}
+
+	dryRun, _ := strconv.ParseBool(c.QueryParam("dry_run"))
+	skipInvalid, _ := strconv.ParseBool(c.QueryParam("skip_invalid"))
+
+	src, err := file.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer src.Close()
+
+	db, err := h.db.Begin(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer db.Rollback()
+
+	// A page size of -1 disables the limit.
+	existing, err := db.FindViewObjects(model.ViewObjectFilter{ViewID: view.ID, PageNumber: 1, PageSize: -1})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	plan, err := csvimport.Plan(view.Type, src, mapping, existing)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Import failed: " + err.Error()})
+	}
+
+	now := time.Now().UTC().String()
+	for i := range plan.Create {
+		o := &plan.Create[i]
+		o.ViewID = view.ID
+		o.CreatedAt, o.CreatedBy = now, user.ID
+		o.UpdatedAt, o.UpdatedBy = now, user.ID
+	}
+	for i := range plan.Update {
+		o := &plan.Update[i]
+		o.UpdatedAt, o.UpdatedBy = now, user.ID
+	}
+
+	res := ImportViewObjectsResponse{
+		DryRun:  dryRun,
+		Rows:    plan.Rows,
+		Valid:   plan.Valid,
+		Errors:  plan.Errors,
+		Created: plan.Create,
+		Updated: plan.Update,
+	}
+	if res.Errors == nil {
+		res.Errors = []csvimport.RowError{}
+	}
+	if res.Created == nil {
+		res.Created = []model.ViewObject{}
+	}
+	if res.Updated == nil {
+		res.Updated = []model.ViewObject{}
+	}
+
+	if dryRun {
+		return c.JSON(http.StatusOK, res)
+	}
+	if len(plan.Errors) > 0 && !skipInvalid {
+		return c.JSON(http.StatusBadRequest, res)
+	}
+
+	for _, o := range plan.Create {
+		if err := db.CreateViewObject(o); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+	for _, o := range plan.Update {
+		if err := db.UpdateViewObject(o); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	if view.Type == "kanban" && len(plan.Create) > 0 {
+		data, err := appendKanbanColumns(view.Data, existing, plan.Create)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if err := db.SetViewData(model.View{ID: view.ID, Data: data, UpdatedAt: now, UpdatedBy: user.ID}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	if err := db.Commit(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	res.Committed = true
+	return c.JSON(http.StatusCreated, res)
+}
+
+// appendKanbanColumns adds new columns to the end of the column order kept
+// in the data of a kanban view. Like the board, a view without an order
+// starts from its existing columns.
+func appendKanbanColumns(viewData string, existing, created []model.ViewObject) (string, error) {
+	data := map[string]interface{}{}
+	if viewData != "" {
+		if err := json.Unmarshal([]byte(viewData), &data); err != nil {
+			data = map[string]interface{}{}
+		}
+	}
+
+	columns, ok := data["columns"].([]interface{})
+	if !ok {
+		columns = []interface{}{}
+		for _, o := range existing {
+			if o.Type == "kanban_column" {
+				columns = append(columns, o.ID)
+			}
+		}
+	}
+	for _, o := range created {
+		columns = append(columns, o.ID)
+	}
+	data["columns"] = columns
+
+	b, err := json.Marshal(data)
+	return string(b), err
+}
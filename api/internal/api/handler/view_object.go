@@ -1,12 +1,16 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/timeline"
 	"github.com/collabreef/collabreef/internal/util"
+	"github.com/collabreef/collabreef/internal/viewobject"
+	"github.com/collabreef/collabreef/internal/whiteboard"
 
 	"github.com/labstack/echo/v4"
 )
@@ -54,6 +58,16 @@ func (h Handler) GetViewObjects(c echo.Context) error {
 
 	objectType := c.QueryParam("type")
 
+	// bbox limits a whiteboard to the objects in the viewport.
+	var bbox *model.BBox
+	if b := c.QueryParam("bbox"); b != "" {
+		parsed, err := whiteboard.ParseBBox(b)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		bbox = &parsed
+	}
+
 	// Verify the view belongs to the workspace
 	if _, err := h.db.FindView(model.View{WorkspaceID: workspaceId, ID: viewId}); err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, "view not found")
@@ -62,6 +76,7 @@ func (h Handler) GetViewObjects(c echo.Context) error {
 	objects, err := h.db.FindViewObjects(model.ViewObjectFilter{
 		ViewID:     viewId,
 		ObjectType: objectType,
+		BBox:       bbox,
 		PageSize:   pageSize,
 		PageNumber: pageNumber,
 	})
@@ -143,10 +158,14 @@ func (h Handler) CreateViewObject(c echo.Context) error {
 	}
 
 	// Validate object type is compatible with view type
-	if !isCompatibleObjectType(view.Type, req.Type) {
+	if !viewobject.IsCompatible(view.Type, req.Type) {
 		return echo.NewHTTPError(http.StatusBadRequest, "object type is not compatible with view type")
 	}
 
+	if err := viewobject.Validate(req.Type, req.Data); err != nil {
+		return viewObjectValidationError(err)
+	}
+
 	user := c.Get("user").(model.User)
 
 	o := model.ViewObject{
@@ -161,6 +180,11 @@ func (h Handler) CreateViewObject(c echo.Context) error {
 		UpdatedBy: user.ID,
 	}
 
+	if err := h.checkTimelineLinks(o); err != nil {
+		return err
+	}
+
+	o = whiteboard.PrepareObject(o)
 	if err := h.db.CreateViewObject(o); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -183,7 +207,8 @@ func (h Handler) UpdateViewObject(c echo.Context) error {
 	}
 
 	// Verify the view belongs to the workspace
-	if _, err := h.db.FindView(model.View{WorkspaceID: workspaceId, ID: viewId}); err != nil {
+	view, err := h.db.FindView(model.View{WorkspaceID: workspaceId, ID: viewId})
+	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, "view not found")
 	}
 
@@ -213,6 +238,31 @@ func (h Handler) UpdateViewObject(c echo.Context) error {
 		updated.Type = existing.Type
 	}
 
+	// Empty data is left unchanged, so only validate when the data or the
+	// type changes.
+	if req.Type != "" || req.Data != "" {
+		if !viewobject.IsCompatible(view.Type, updated.Type) {
+			return echo.NewHTTPError(http.StatusBadRequest, "object type is not compatible with view type")
+		}
+		data := updated.Data
+		if data == "" {
+			data = existing.Data
+		}
+		if err := viewobject.Validate(updated.Type, data); err != nil {
+			return viewObjectValidationError(err)
+		}
+		next := updated
+		next.Data = data
+		if err := h.checkTimelineLinks(next); err != nil {
+			return err
+		}
+
+		// The data is written with the bounds derived from it.
+		next = whiteboard.PrepareObject(next)
+		updated.Data = next.Data
+		updated.MinX, updated.MinY, updated.MaxX, updated.MaxY = next.MinX, next.MinY, next.MaxX, next.MaxY
+	}
+
 	if err := h.db.UpdateViewObject(updated); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -230,7 +280,8 @@ func (h Handler) DeleteViewObject(c echo.Context) error {
 	}
 
 	// Verify the view belongs to the workspace
-	if _, err := h.db.FindView(model.View{WorkspaceID: workspaceId, ID: viewId}); err != nil {
+	view, err := h.db.FindView(model.View{WorkspaceID: workspaceId, ID: viewId})
+	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, "view not found")
 	}
 
@@ -238,6 +289,16 @@ func (h Handler) DeleteViewObject(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "view object not found")
 	}
 
+	if view.Type == "timeline" {
+		objects, err := h.db.FindViewObjects(model.ViewObjectFilter{ViewID: view.ID, ObjectType: timeline.ObjectType, PageNumber: 1, PageSize: -1})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if err := timeline.CheckDelete(timeline.Load(objects), id); err != nil {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+	}
+
 	if err := h.db.DeleteViewObject(model.ViewObject{ID: id}); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -245,23 +306,15 @@ func (h Handler) DeleteViewObject(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
-// isCompatibleObjectType checks if an object type is valid for a given view type
-func isCompatibleObjectType(viewType, objectType string) bool {
-	compatible := map[string][]string{
-		"calendar":    {"calendar_slot"},
-		"map":         {"map_marker"},
-		"kanban":      {"kanban_column"},
-		"whiteboard":  {"whiteboard_stroke", "whiteboard_shape", "whiteboard_text", "whiteboard_note", "whiteboard_view", "whiteboard_edge"},
-		"spreadsheet": {},
-	}
-	allowed, ok := compatible[viewType]
-	if !ok {
-		return false
-	}
-	for _, t := range allowed {
-		if t == objectType {
-			return true
-		}
+// viewObjectValidationError converts a failed data validation into a 400
+// response that lists the invalid fields.
+func viewObjectValidationError(err error) error {
+	var verr *viewobject.ValidationError
+	if !errors.As(err, &verr) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
-	return false
+	return echo.NewHTTPError(http.StatusBadRequest, map[string]interface{}{
+		"error":  "Validation failed: " + verr.Error(),
+		"fields": verr.Fields,
+	})
 }
@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/events"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/timeline"
+	"github.com/collabreef/collabreef/internal/util"
+	"github.com/collabreef/collabreef/internal/viewobject"
+	"github.com/collabreef/collabreef/internal/whiteboard"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxBatchOperations caps the number of operations in one batch request.
+const maxBatchOperations = 1000
+
+type BatchViewObjectOperation struct {
+	Op   string `json:"op"` // create, update or delete
+	ID   string `json:"id"` // update and delete only
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+type BatchViewObjectsRequest struct {
+	Operations []BatchViewObjectOperation `json:"operations"`
+	// Atomic rolls back the whole batch when any operation fails. Otherwise
+	// failed operations are skipped and the others are applied.
+	Atomic bool `json:"atomic"`
+}
+
+type BatchViewObjectResult struct {
+	Index  int                     `json:"index"`
+	Op     string                  `json:"op"`
+	ID     string                  `json:"id,omitempty"`
+	Status int                     `json:"status"`
+	Error  string                  `json:"error,omitempty"`
+	Fields []viewobject.FieldError `json:"fields,omitempty"`
+	Object *model.ViewObject       `json:"object,omitempty"`
+}
+
+type BatchViewObjectsResponse struct {
+	Committed bool                    `json:"committed"`
+	Results   []BatchViewObjectResult `json:"results"`
+}
+
+// BatchViewObjects applies a list of create, update and delete operations to
+// the objects of a view in one transaction. Every operation is checked before
+// anything is written; the response holds one result per operation, in order.
+// A rolled back atomic batch responds with 400.
+func (h Handler) BatchViewObjects(c echo.Context) error {
+	workspaceId := c.Param("workspaceId")
+	viewId := c.Param("viewId")
+
+	if workspaceId == "" || viewId == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "workspace id and view id are required")
+	}
+
+	var req BatchViewObjectsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if len(req.Operations) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "operations are required")
+	}
+	if len(req.Operations) > maxBatchOperations {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("a batch can hold at most %d operations", maxBatchOperations))
+	}
+
+	// Verify the view belongs to the workspace
+	view, err := h.db.FindView(model.View{WorkspaceID: workspaceId, ID: viewId})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "view not found")
+	}
+
+	user := c.Get("user").(model.User)
+
+	db, err := h.db.Begin(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer db.Rollback()
+
+	// Load the objects targeted by updates and deletes, scoped to the view.
+	// Timeline tasks link to each other, so timelines load every object.
+	var ids []string
+	for _, op := range req.Operations {
+		if op.ID != "" {
+			ids = append(ids, op.ID)
+		}
+	}
+	objects := map[string]model.ViewObject{}
+	if len(ids) > 0 || view.Type == "timeline" {
+		filter := model.ViewObjectFilter{ViewID: viewId, ObjectIDs: ids, PageNumber: 1, PageSize: -1}
+		if view.Type == "timeline" {
+			filter.ObjectIDs = nil
+		}
+		found, err := db.FindViewObjects(filter)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		for _, o := range found {
+			objects[o.ID] = o
+		}
+	}
+
+	checkLinks := func(o model.ViewObject) error {
+		if view.Type != "timeline" {
+			return nil
+		}
+		return timeline.CheckObject(objectList(objects), o)
+	}
+
+	// Resolve every operation against the state left by the previous ones.
+	now := time.Now().UTC().String()
+	results := make([]BatchViewObjectResult, len(req.Operations))
+	writes := make([]func() error, len(req.Operations))
+	failed := false
+	for i, op := range req.Operations {
+		res := BatchViewObjectResult{Index: i, Op: op.Op, ID: op.ID}
+
+		switch op.Op {
+		case "create":
+			o := model.ViewObject{
+				ID:        util.NewId(),
+				ViewID:    viewId,
+				Name:      op.Name,
+				Type:      op.Type,
+				Data:      op.Data,
+				CreatedAt: now,
+				CreatedBy: user.ID,
+				UpdatedAt: now,
+				UpdatedBy: user.ID,
+			}
+			if op.Name == "" {
+				res.fail(http.StatusBadRequest, errors.New("name is required"))
+			} else if !viewobject.IsCompatible(view.Type, o.Type) {
+				res.fail(http.StatusBadRequest, errors.New("object type is not compatible with view type"))
+			} else if err := viewobject.Validate(o.Type, o.Data); err != nil {
+				res.fail(http.StatusBadRequest, err)
+			} else if err := checkLinks(o); err != nil {
+				res.fail(http.StatusBadRequest, err)
+			} else {
+				o = whiteboard.PrepareObject(o)
+				res.ID = o.ID
+				res.Status = http.StatusCreated
+				res.Object = &o
+				objects[o.ID] = o
+				writes[i] = func() error { return db.CreateViewObject(o) }
+			}
+
+		case "update":
+			existing, ok := objects[op.ID]
+			if !ok {
+				res.fail(http.StatusNotFound, errors.New("view object not found"))
+				break
+			}
+			o := existing
+			if op.Name != "" {
+				o.Name = op.Name
+			}
+			if op.Type != "" {
+				o.Type = op.Type
+			}
+			if op.Data != "" {
+				o.Data = op.Data
+			}
+			o.UpdatedAt = now
+			o.UpdatedBy = user.ID
+			// Like UpdateViewObject, a rename alone does not validate the data.
+			var err error
+			if op.Type != "" || op.Data != "" {
+				if !viewobject.IsCompatible(view.Type, o.Type) {
+					err = errors.New("object type is not compatible with view type")
+				} else {
+					err = viewobject.Validate(o.Type, o.Data)
+				}
+				if err == nil {
+					err = checkLinks(o)
+				}
+			}
+			if err != nil {
+				res.fail(http.StatusBadRequest, err)
+			} else {
+				if op.Type != "" || op.Data != "" {
+					o = whiteboard.PrepareObject(o)
+				}
+				res.Status = http.StatusOK
+				res.Object = &o
+				objects[o.ID] = o
+				writes[i] = func() error { return db.UpdateViewObject(o) }
+			}
+
+		case "delete":
+			if _, ok := objects[op.ID]; !ok {
+				res.fail(http.StatusNotFound, errors.New("view object not found"))
+				break
+			}
+			res.Status = http.StatusNoContent
+			delete(objects, op.ID)
+			id := op.ID
+			writes[i] = func() error { return db.DeleteViewObject(model.ViewObject{ID: id}) }
+
+		default:
+			res.fail(http.StatusBadRequest, errors.New("op must be create, update or delete"))
+		}
+
+		if res.Error != "" {
+			failed = true
+		}
+		results[i] = res
+	}
+
+	// Deleted tasks are checked against the tasks the batch keeps, so that a
+	// batch may delete a task along with the tasks linked to it, or unlink
+	// them, in any order.
+	if view.Type == "timeline" {
+		tasks := timeline.Load(objectList(objects))
+		for i, op := range req.Operations {
+			if op.Op != "delete" || results[i].Error != "" {
+				continue
+			}
+			if err := timeline.CheckDelete(tasks, op.ID); err != nil {
+				results[i].fail(http.StatusConflict, err)
+				writes[i] = nil
+				failed = true
+			}
+		}
+	}
+
+	if failed && req.Atomic {
+		for i := range results {
+			if results[i].Error == "" {
+				results[i] = BatchViewObjectResult{Index: i, Op: results[i].Op, ID: req.Operations[i].ID}
+				results[i].fail(http.StatusFailedDependency, errors.New("not applied because another operation failed"))
+			}
+		}
+		return c.JSON(http.StatusBadRequest, BatchViewObjectsResponse{Committed: false, Results: results})
+	}
+
+	written := false
+	for _, write := range writes {
+		if write == nil {
+			continue
+		}
+		if err := write(); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		written = true
+	}
+
+	if err := db.Commit(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	// Open collaborative documents reload the view objects, rather than
+	// writing their own copy of them back.
+	if written {
+		h.events.Publish(events.ViewDataChanged{
+			ViewID:      view.ID,
+			Type:        view.Type,
+			ObjectsOnly: true,
+		})
+	}
+
+	return c.JSON(http.StatusOK, BatchViewObjectsResponse{Committed: true, Results: results})
+}
+
+func objectList(objects map[string]model.ViewObject) []model.ViewObject {
+	list := make([]model.ViewObject, 0, len(objects))
+	for _, o := range objects {
+		list = append(list, o)
+	}
+	return list
+}
+
+func (r *BatchViewObjectResult) fail(status int, err error) {
+	r.Status = status
+	r.Error = err.Error()
+	var verr *viewobject.ValidationError
+	if errors.As(err, &verr) {
+		r.Fields = verr.Fields
+	}
+}
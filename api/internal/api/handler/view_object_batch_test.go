@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/api/validate"
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/db/dbtest"
+	"github.com/collabreef/collabreef/internal/events"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// serve calls a handler the way the router does, with the path parameters
+// given as name and value pairs and the user the auth middleware sets.
+// Errors returned by the handler are written to the response.
+func serve(h echo.HandlerFunc, req *http.Request, user *model.User, params ...string) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.Validator = &validate.CustomValidator{Validator: validator.New()}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	var names, values []string
+	for i := 0; i+1 < len(params); i += 2 {
+		names, values = append(names, params[i]), append(values, params[i+1])
+	}
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+	if user != nil {
+		c.Set("user", *user)
+	}
+	if err := h(c); err != nil {
+		e.HTTPErrorHandler(err, c)
+	}
+	return rec
+}
+
+func jsonRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	return req
+}
+
+// newTimeline stores a timeline view holding tasks a, b and c, where b
+// depends on a and c is a child of b.
+func newTimeline(t *testing.T, d db.DB) model.View {
+	t.Helper()
+	view := model.View{WorkspaceID: "w1", ID: "v1", Name: "Plan", Type: "timeline"}
+	if err := d.CreateView(view); err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range []model.ViewObject{
+		{ID: "a", Name: "Design", Data: `{"start":"2024-01-01","end":"2024-01-05"}`},
+		{ID: "b", Name: "Build", Data: `{"start":"2024-01-06","end":"2024-01-10","dependencies":["a"]}`},
+		{ID: "c", Name: "Test", Data: `{"start":"2024-01-08","end":"2024-01-09","parent_id":"b"}`},
+	} {
+		o.ViewID, o.Type = view.ID, "timeline_task"
+		if err := d.CreateViewObject(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return view
+}
+
+func TestBatchDeleteTimelineTasks(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		status    int
+		committed bool
+		results   []int
+		remaining []string
+	}{
+		{
+			name:      "task others depend on",
+			body:      `{"atomic":true,"operations":[{"op":"delete","id":"a"}]}`,
+			status:    http.StatusBadRequest,
+			results:   []int{http.StatusConflict},
+			remaining: []string{"a", "b", "c"},
+		},
+		{
+			name:      "parent of a task",
+			body:      `{"atomic":true,"operations":[{"op":"delete","id":"b"}]}`,
+			status:    http.StatusBadRequest,
+			results:   []int{http.StatusConflict},
+			remaining: []string{"a", "b", "c"},
+		},
+		{
+			name:      "task with every task linked to it",
+			body:      `{"atomic":true,"operations":[{"op":"delete","id":"a"},{"op":"delete","id":"b"},{"op":"delete","id":"c"}]}`,
+			status:    http.StatusOK,
+			committed: true,
+			results:   []int{http.StatusNoContent, http.StatusNoContent, http.StatusNoContent},
+		},
+		{
+			name:      "task unlinked later in the batch",
+			body:      `{"atomic":true,"operations":[{"op":"delete","id":"a"},{"op":"update","id":"b","data":"{\"start\":\"2024-01-06\",\"end\":\"2024-01-10\"}"}]}`,
+			status:    http.StatusOK,
+			committed: true,
+			results:   []int{http.StatusNoContent, http.StatusOK},
+			remaining: []string{"b", "c"},
+		},
+		{
+			name:      "linked task skipped in a non-atomic batch",
+			body:      `{"operations":[{"op":"delete","id":"a"},{"op":"delete","id":"c"}]}`,
+			status:    http.StatusOK,
+			committed: true,
+			results:   []int{http.StatusConflict, http.StatusNoContent},
+			remaining: []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := dbtest.New(t)
+			view := newTimeline(t, d)
+			h := NewHandler(d, nil, events.NewBus())
+
+			rec := serve(h.BatchViewObjects, jsonRequest(http.MethodPost, "/", tt.body), &model.User{ID: "u1"},
+				"workspaceId", view.WorkspaceID, "viewId", view.ID)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			var res BatchViewObjectsResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+			if res.Committed != tt.committed || len(res.Results) != len(tt.results) {
+				t.Fatalf("response = %+v", res)
+			}
+			for i, r := range res.Results {
+				if r.Status != tt.results[i] {
+					t.Errorf("result %d = %d %s, want %d", i, r.Status, r.Error, tt.results[i])
+				}
+			}
+
+			objects, err := d.FindViewObjects(model.ViewObjectFilter{ViewID: view.ID, PageNumber: 1, PageSize: -1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, o := range objects {
+				ids = append(ids, o.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.remaining, ",") {
+				t.Errorf("remaining tasks = %v, want %v", ids, tt.remaining)
+			}
+		})
+	}
+}
+
+func TestBatchPublishesObjectsChange(t *testing.T) {
+	d := dbtest.New(t)
+	view := newTimeline(t, d)
+	bus := events.NewBus()
+	changes, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+	h := NewHandler(d, nil, bus)
+
+	rec := serve(h.BatchViewObjects, jsonRequest(http.MethodPost, "/", `{"operations":[{"op":"delete","id":"c"}]}`), &model.User{ID: "u1"},
+		"workspaceId", view.WorkspaceID, "viewId", view.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	select {
+	case e := <-changes:
+		if e.ViewID != view.ID || !e.ObjectsOnly || e.Data != "" {
+			t.Errorf("event = %+v, want an objects change of the view", e)
+		}
+	default:
+		t.Fatal("no event published")
+	}
+
+	// A batch that writes nothing publishes nothing.
+	serve(h.BatchViewObjects, jsonRequest(http.MethodPost, "/", `{"operations":[{"op":"delete","id":"missing"}]}`), &model.User{ID: "u1"},
+		"workspaceId", view.WorkspaceID, "viewId", view.ID)
+	select {
+	case e := <-changes:
+		t.Errorf("event %+v published for a batch without writes", e)
+	default:
+	}
+}
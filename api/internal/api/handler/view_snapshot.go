@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/collabreef/collabreef/internal/events"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/snapshot"
+
+	"github.com/labstack/echo/v4"
+)
+
+type CreateViewSnapshotRequest struct {
+	Name string `json:"name"`
+}
+
+type ViewSnapshotResponse struct {
+	ID          string `json:"id"`
+	ViewID      string `json:"view_id"`
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	ObjectCount int    `json:"object_count"`
+	CreatedAt   string `json:"created_at"`
+	CreatedBy   string `json:"created_by"`
+	// Data and Objects are only included when a single snapshot is read.
+	Data    string            `json:"data,omitempty"`
+	Objects []snapshot.Object `json:"objects,omitempty"`
+}
+
+type RestoreViewSnapshotResponse struct {
+	View model.View `json:"view"`
+	// Backup holds the state the restore replaced.
+	Backup ViewSnapshotResponse `json:"backup"`
+}
+
+// GetViewSnapshots lists the snapshots of a view, newest first.
+func (h Handler) GetViewSnapshots(c echo.Context) error {
+	view, err := h.findSnapshotView(c, false)
+	if err != nil {
+		return err
+	}
+
+	pageSize := 50
+	pageNumber := 1
+	if ps := c.QueryParam("pageSize"); ps != "" {
+		if v, err := strconv.Atoi(ps); err == nil && v > 0 {
+			pageSize = v
+		}
+	}
+	if pn := c.QueryParam("pageNumber"); pn != "" {
+		if v, err := strconv.Atoi(pn); err == nil && v > 0 {
+			pageNumber = v
+		}
+	}
+
+	snapshots, err := h.db.FindViewSnapshots(model.ViewSnapshotFilter{
+		ViewID:     view.ID,
+		Kind:       c.QueryParam("kind"),
+		PageSize:   pageSize,
+		PageNumber: pageNumber,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	res := []ViewSnapshotResponse{}
+	for _, s := range snapshots {
+		res = append(res, h.viewSnapshotResponse(s))
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetViewSnapshot returns a snapshot with its data and objects, for preview.
+func (h Handler) GetViewSnapshot(c echo.Context) error {
+	view, err := h.findSnapshotView(c, false)
+	if err != nil {
+		return err
+	}
+
+	s, err := h.db.FindViewSnapshot(model.ViewSnapshot{ID: c.Param("snapshotId"), ViewID: view.ID})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "snapshot not found")
+	}
+
+	objects, err := snapshot.Objects(s)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	res := h.viewSnapshotResponse(s)
+	res.Data = s.Data
+	res.Objects = objects
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// CreateViewSnapshot takes a manual snapshot of the current state of a view.
+func (h Handler) CreateViewSnapshot(c echo.Context) error {
+	view, err := h.findSnapshotView(c, true)
+	if err != nil {
+		return err
+	}
+
+	var req CreateViewSnapshotRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	user := c.Get("user").(model.User)
+
+	s, err := snapshot.Capture(h.db, view, model.SnapshotKindManual, req.Name, user.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if err := h.db.CreateViewSnapshot(s); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, h.viewSnapshotResponse(s))
+}
+
+// RestoreViewSnapshot brings a view back to a snapshot. The current state is
+// saved as a restore snapshot first, so the restore can itself be undone.
+func (h Handler) RestoreViewSnapshot(c echo.Context) error {
+	view, err := h.findSnapshotView(c, true)
+	if err != nil {
+		return err
+	}
+
+	s, err := h.db.FindViewSnapshot(model.ViewSnapshot{ID: c.Param("snapshotId"), ViewID: view.ID})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "snapshot not found")
+	}
+
+	user := c.Get("user").(model.User)
+
+	db, err := h.db.Begin(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer db.Rollback()
+
+	name := s.Name
+	if name == "" {
+		name = s.CreatedAt
+	}
+	backup, err := snapshot.Capture(db, view, model.SnapshotKindRestore, "Before restoring "+name, user.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if err := db.CreateViewSnapshot(backup); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	if err := snapshot.Restore(db, view, s, user.ID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	if err := db.Commit(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	restored, err := h.db.FindView(model.View{ID: view.ID})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	// Open collaborative documents reload the restored state.
+	h.events.Publish(events.ViewDataChanged{
+		ViewID: restored.ID,
+		Type:   restored.Type,
+		Data:   restored.Data,
+	})
+
+	return c.JSON(http.StatusOK, RestoreViewSnapshotResponse{
+		View:   restored,
+		Backup: h.viewSnapshotResponse(backup),
+	})
+}
+
+func (h Handler) DeleteViewSnapshot(c echo.Context) error {
+	view, err := h.findSnapshotView(c, true)
+	if err != nil {
+		return err
+	}
+
+	s, err := h.db.FindViewSnapshot(model.ViewSnapshot{ID: c.Param("snapshotId"), ViewID: view.ID})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "snapshot not found")
+	}
+
+	if err := h.db.DeleteViewSnapshot(s); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// findSnapshotView loads the view of the request. Reading snapshots follows
+// the visibility of the view; taking, restoring and deleting them requires
+// workspace membership.
+func (h Handler) findSnapshotView(c echo.Context, write bool) (model.View, error) {
+	workspaceId := c.Param("workspaceId")
+	id := c.Param("id")
+	if workspaceId == "" || id == "" {
+		return model.View{}, echo.NewHTTPError(http.StatusBadRequest, "workspace id and view id are required")
+	}
+
+	view, err := h.db.FindView(model.View{WorkspaceID: workspaceId, ID: id})
+	if err != nil || view.WorkspaceID != workspaceId || !h.canReadView(c, view) {
+		return model.View{}, echo.NewHTTPError(http.StatusNotFound, "view not found")
+	}
+
+	if write {
+		user, ok := c.Get("user").(model.User)
+		if !ok || !h.isUserWorkspaceMember(user.ID, view.WorkspaceID) {
+			return model.View{}, echo.NewHTTPError(http.StatusForbidden, "only workspace members can manage snapshots")
+		}
+	}
+
+	return view, nil
+}
+
+func (h Handler) viewSnapshotResponse(s model.ViewSnapshot) ViewSnapshotResponse {
+	return ViewSnapshotResponse{
+		ID:          s.ID,
+		ViewID:      s.ViewID,
+		Name:        s.Name,
+		Kind:        s.Kind,
+		ObjectCount: s.ObjectCount,
+		CreatedAt:   s.CreatedAt,
+		CreatedBy:   h.getUserNameByID(s.CreatedBy),
+	}
+}
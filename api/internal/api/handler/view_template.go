@@ -0,0 +1,344 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/util"
+	"github.com/collabreef/collabreef/internal/viewcopy"
+
+	"github.com/labstack/echo/v4"
+)
+
+type CreateViewTemplateRequest struct {
+	// ViewID is the view the template is made from.
+	ViewID      string `json:"view_id" validate:"required"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type UpdateViewTemplateRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	// ViewID replaces the content of the template with that of a view of
+	// the same type.
+	ViewID string `json:"view_id"`
+}
+
+type InstantiateViewTemplateRequest struct {
+	// Name defaults to the name of the template.
+	Name       string `json:"name"`
+	NoteID     string `json:"note_id"`
+	Visibility string `json:"visibility"`
+}
+
+type ViewTemplateResponse struct {
+	ID          string `json:"id"`
+	WorkspaceID string `json:"workspace_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	ObjectCount int    `json:"object_count"`
+	CreatedAt   string `json:"created_at"`
+	CreatedBy   string `json:"created_by"`
+	UpdatedAt   string `json:"updated_at"`
+	UpdatedBy   string `json:"updated_by"`
+	// Data and Objects are only included when a single template is read.
+	Data    string            `json:"data,omitempty"`
+	Objects []viewcopy.Object `json:"objects,omitempty"`
+}
+
+// GetViewTemplates lists the view templates of a workspace by name,
+// optionally of one view type.
+func (h Handler) GetViewTemplates(c echo.Context) error {
+	workspaceId := c.Param("workspaceId")
+	if workspaceId == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "workspace id is required")
+	}
+	if err := h.checkViewTemplateMember(c, workspaceId); err != nil {
+		return err
+	}
+
+	pageSize := 100
+	pageNumber := 1
+	if ps := c.QueryParam("pageSize"); ps != "" {
+		if v, err := strconv.Atoi(ps); err == nil && v > 0 {
+			pageSize = v
+		}
+	}
+	if pn := c.QueryParam("pageNumber"); pn != "" {
+		if v, err := strconv.Atoi(pn); err == nil && v > 0 {
+			pageNumber = v
+		}
+	}
+
+	templates, err := h.db.FindViewTemplates(model.ViewTemplateFilter{
+		WorkspaceID: workspaceId,
+		ViewType:    c.QueryParam("type"),
+		PageSize:    pageSize,
+		PageNumber:  pageNumber,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	res := []ViewTemplateResponse{}
+	for _, t := range templates {
+		res = append(res, h.viewTemplateResponse(t))
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetViewTemplate returns a template with its data and objects.
+func (h Handler) GetViewTemplate(c echo.Context) error {
+	t, err := h.findViewTemplate(c)
+	if err != nil {
+		return err
+	}
+
+	objects, err := viewcopy.Decode(t.Objects)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	res := h.viewTemplateResponse(t)
+	res.Data = t.Data
+	res.Objects = objects
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// CreateViewTemplate saves the data and objects of a view as a template of
+// its workspace.
+func (h Handler) CreateViewTemplate(c echo.Context) error {
+	workspaceId := c.Param("workspaceId")
+	if workspaceId == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "workspace id is required")
+	}
+
+	var req CreateViewTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	if err := h.checkViewTemplateMember(c, workspaceId); err != nil {
+		return err
+	}
+
+	view, err := h.db.FindView(model.View{WorkspaceID: workspaceId, ID: req.ViewID})
+	if err != nil || view.WorkspaceID != workspaceId || !h.canReadView(c, view) {
+		return echo.NewHTTPError(http.StatusNotFound, "view not found")
+	}
+
+	user := c.Get("user").(model.User)
+	now := time.Now().UTC().String()
+
+	t := model.ViewTemplate{
+		ID:          util.NewId(),
+		WorkspaceID: workspaceId,
+		Name:        req.Name,
+		Description: req.Description,
+		Type:        view.Type,
+		CreatedAt:   now,
+		CreatedBy:   user.ID,
+		UpdatedAt:   now,
+		UpdatedBy:   user.ID,
+	}
+	if t.Name == "" {
+		t.Name = view.Name
+	}
+	if err := h.fillViewTemplate(&t, view); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	if err := h.db.CreateViewTemplate(t); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, h.viewTemplateResponse(t))
+}
+
+// UpdateViewTemplate renames a template, or replaces its content with that
+// of a view.
+func (h Handler) UpdateViewTemplate(c echo.Context) error {
+	t, err := h.findViewTemplate(c)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateViewTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if req.Name != nil {
+		if *req.Name == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Validation failed: name must not be empty",
+			})
+		}
+		t.Name = *req.Name
+	}
+	if req.Description != nil {
+		t.Description = *req.Description
+	}
+	if req.ViewID != "" {
+		view, err := h.db.FindView(model.View{WorkspaceID: t.WorkspaceID, ID: req.ViewID})
+		if err != nil || view.WorkspaceID != t.WorkspaceID || !h.canReadView(c, view) {
+			return echo.NewHTTPError(http.StatusNotFound, "view not found")
+		}
+		if view.Type != t.Type {
+			return echo.NewHTTPError(http.StatusBadRequest, "view type does not match the template")
+		}
+		if err := h.fillViewTemplate(&t, view); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	user := c.Get("user").(model.User)
+	t.UpdatedAt = time.Now().UTC().String()
+	t.UpdatedBy = user.ID
+
+	if err := h.db.UpdateViewTemplate(t); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, h.viewTemplateResponse(t))
+}
+
+func (h Handler) DeleteViewTemplate(c echo.Context) error {
+	t, err := h.findViewTemplate(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.db.DeleteViewTemplate(t); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// InstantiateViewTemplate creates a new view from a template.
+func (h Handler) InstantiateViewTemplate(c echo.Context) error {
+	t, err := h.findViewTemplate(c)
+	if err != nil {
+		return err
+	}
+
+	var req InstantiateViewTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	objects, err := viewcopy.Decode(t.Objects)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	user := c.Get("user").(model.User)
+
+	target := model.View{
+		WorkspaceID: t.WorkspaceID,
+		NoteID:      req.NoteID,
+		Name:        req.Name,
+		Type:        t.Type,
+		Data:        t.Data,
+		Visibility:  req.Visibility,
+	}
+	if target.Name == "" {
+		target.Name = t.Name
+	}
+	if target.Visibility == "" {
+		target.Visibility = "private"
+	}
+	if err := h.checkViewCopyTarget(user, &target); err != nil {
+		return err
+	}
+
+	db, err := h.db.Begin(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer db.Rollback()
+
+	v, err := viewcopy.Create(db, target, objects)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	if err := db.Commit(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, v)
+}
+
+// findViewTemplate loads the template of the request. Templates are shared
+// by the members of their workspace.
+func (h Handler) findViewTemplate(c echo.Context) (model.ViewTemplate, error) {
+	workspaceId := c.Param("workspaceId")
+	id := c.Param("id")
+	if workspaceId == "" || id == "" {
+		return model.ViewTemplate{}, echo.NewHTTPError(http.StatusBadRequest, "workspace id and template id are required")
+	}
+
+	if err := h.checkViewTemplateMember(c, workspaceId); err != nil {
+		return model.ViewTemplate{}, err
+	}
+
+	t, err := h.db.FindViewTemplate(model.ViewTemplate{WorkspaceID: workspaceId, ID: id})
+	if err != nil {
+		return model.ViewTemplate{}, echo.NewHTTPError(http.StatusNotFound, "view template not found")
+	}
+
+	return t, nil
+}
+
+func (h Handler) checkViewTemplateMember(c echo.Context, workspaceID string) error {
+	user, ok := c.Get("user").(model.User)
+	if !ok || !h.isUserWorkspaceMember(user.ID, workspaceID) {
+		return echo.NewHTTPError(http.StatusForbidden, "only workspace members can use view templates")
+	}
+	return nil
+}
+
+// fillViewTemplate copies the data and objects of a view into a template.
+func (h Handler) fillViewTemplate(t *model.ViewTemplate, view model.View) error {
+	objects, err := viewcopy.Load(h.db, view.ID)
+	if err != nil {
+		return err
+	}
+	encoded, err := viewcopy.Encode(objects)
+	if err != nil {
+		return err
+	}
+	t.Data = view.Data
+	t.Objects = encoded
+	t.ObjectCount = len(objects)
+	return nil
+}
+
+func (h Handler) viewTemplateResponse(t model.ViewTemplate) ViewTemplateResponse {
+	return ViewTemplateResponse{
+		ID:          t.ID,
+		WorkspaceID: t.WorkspaceID,
+		Name:        t.Name,
+		Description: t.Description,
+		Type:        t.Type,
+		ObjectCount: t.ObjectCount,
+		CreatedAt:   t.CreatedAt,
+		CreatedBy:   h.getUserNameByID(t.CreatedBy),
+		UpdatedAt:   t.UpdatedAt,
+		UpdatedBy:   h.getUserNameByID(t.UpdatedBy),
+	}
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/db/dbtest"
+	"github.com/collabreef/collabreef/internal/events"
+	"github.com/collabreef/collabreef/internal/model"
+)
+
+func TestUpdateViewCanvasObjects(t *testing.T) {
+	const canvas = `{"a":{"type":"stroke","data":{"points":[{"x":0,"y":0},{"x":1,"y":1}]}}}`
+	tests := []struct {
+		name string
+		from string
+		body string
+		want []string
+	}{
+		{name: "data of a whiteboard", from: "whiteboard", body: `{"data":` + strconv.Quote(canvas) + `}`, want: []string{"a"}},
+		{name: "whiteboard without new data", from: "whiteboard", body: `{"name":"Board"}`, want: []string{"a"}},
+		{name: "whiteboard becomes a kanban", from: "whiteboard", body: `{"type":"kanban"}`, want: []string{}},
+		{name: "kanban becomes a whiteboard", from: "kanban", body: `{"type":"whiteboard"}`, want: []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := dbtest.New(t)
+			h := NewHandler(d, nil, events.NewBus())
+			view := model.View{WorkspaceID: "w1", ID: "v1", Name: "Plan", Type: tt.from, Data: canvas}
+			if err := d.CreateView(view); err != nil {
+				t.Fatal(err)
+			}
+			if tt.from == "whiteboard" {
+				if err := d.SaveCanvasObject(model.CanvasObject{ViewID: "v1", ID: "a", Data: "{}"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			user := model.User{ID: "u1"}
+			rec := serve(h.UpdateView, jsonRequest(http.MethodPut, "/", tt.body), &user, "workspaceId", "w1", "id", "v1")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+			}
+
+			objects, err := d.FindCanvasObjects(model.CanvasObjectFilter{ViewID: "v1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, o := range objects {
+				got = append(got, o.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("canvas objects = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/whiteboard"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Screen size assumed for the viewport parameter when width/height are not given.
+const (
+	defaultRenderWidth  = 1280
+	defaultRenderHeight = 720
+)
+
+// anonymousRenderDimension caps renders of public views for requests that
+// are not signed in, which the route also rate limits.
+const anonymousRenderDimension = 2048
+
+// renderSlots bounds the renders run at once, as each one holds its image
+// in memory.
+var renderSlots = make(chan struct{}, runtime.NumCPU())
+
+// RenderWhiteboardSVG renders a whiteboard view as SVG.
+func (h Handler) RenderWhiteboardSVG(c echo.Context) error {
+	return h.renderWhiteboard(c, "svg")
+}
+
+// RenderWhiteboardPNG renders a whiteboard view as PNG.
+func (h Handler) RenderWhiteboardPNG(c echo.Context) error {
+	return h.renderWhiteboard(c, "png")
+}
+
+// renderWhiteboard serves a whiteboard snapshot. Public views can be rendered
+// without signing in so that snapshots can be embedded in shared notes.
+//
+// Query parameters:
+//   - bbox=minX,minY,maxX,maxY: region in board coordinates
+//   - viewport=x,y,zoom: the web client's pan and zoom; the region is the
+//     width x height screen it shows
+//   - width, height: output size in pixels (with bbox or no region: the
+//     maximum size, keeping the aspect ratio); renders are capped at
+//     8192 pixels on either axis and 16 megapixels, and at 2048 pixels
+//     for requests that are not signed in
+//   - scale: pixels per board unit, overrides width/height
+//   - layers: comma-separated strokes, shapes, text, notes, views, edges
+//   - background: CSS color or "transparent" (default white)
+func (h Handler) renderWhiteboard(c echo.Context, format string) error {
+	workspaceId := c.Param("workspaceId")
+	id := c.Param("id")
+	if workspaceId == "" || id == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "workspace id and view id are required")
+	}
+
+	view, err := h.db.FindView(model.View{WorkspaceID: workspaceId, ID: id})
+	if err != nil || view.WorkspaceID != workspaceId {
+		return echo.NewHTTPError(http.StatusNotFound, "view not found")
+	}
+	if !h.canReadView(c, view) {
+		return echo.NewHTTPError(http.StatusNotFound, "view not found")
+	}
+	if view.Type != "whiteboard" {
+		return echo.NewHTTPError(http.StatusBadRequest, "view is not a whiteboard")
+	}
+
+	opts, err := parseRenderOptions(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if _, ok := c.Get("user").(model.User); !ok {
+		opts.MaxDimension = anonymousRenderDimension
+	}
+
+	// A page size of -1 disables the limit.
+	objects, err := h.db.FindViewObjects(model.ViewObjectFilter{ViewID: view.ID, PageNumber: 1, PageSize: -1})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	board := whiteboard.Load(view.Data, objects)
+
+	select {
+	case renderSlots <- struct{}{}:
+		defer func() { <-renderSlots }()
+	case <-c.Request().Context().Done():
+		return c.Request().Context().Err()
+	}
+
+	var buf bytes.Buffer
+	var contentType string
+	switch format {
+	case "png":
+		err = board.RenderPNG(&buf, opts)
+		contentType = "image/png"
+	default:
+		err = board.RenderSVG(&buf, opts)
+		contentType = "image/svg+xml"
+		// The SVG is served from the app origin; make sure it can never run script.
+		c.Response().Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	c.Response().Header().Set("Cache-Control", "private, max-age=60")
+	return c.Blob(http.StatusOK, contentType, buf.Bytes())
+}
+
+// canReadView reports whether the requester may see the view: public views
+// are open to everyone, the others require workspace membership, and private
+// views are limited to their creator.
+func (h Handler) canReadView(c echo.Context, view model.View) bool {
+	if view.Visibility == "public" {
+		return true
+	}
+	user, ok := c.Get("user").(model.User)
+	if !ok || !h.isUserWorkspaceMember(user.ID, view.WorkspaceID) {
+		return false
+	}
+	return view.Visibility != "private" || view.CreatedBy == user.ID
+}
+
+func parseRenderOptions(c echo.Context) (whiteboard.Options, error) {
+	var opts whiteboard.Options
+	var err error
+
+	if opts.Width, err = queryInt(c, "width"); err != nil {
+		return opts, err
+	}
+	if opts.Height, err = queryInt(c, "height"); err != nil {
+		return opts, err
+	}
+
+	if s := c.QueryParam("scale"); s != "" {
+		opts.Scale, err = strconv.ParseFloat(s, 64)
+		if err != nil || !(opts.Scale > 0) || math.IsInf(opts.Scale, 0) {
+			return opts, errors.New("scale must be a positive number")
+		}
+	}
+
+	if s := c.QueryParam("bbox"); s != "" {
+		v, err := parseFloats(s, 4)
+		if err != nil {
+			return opts, errors.New("bbox must be minX,minY,maxX,maxY")
+		}
+		opts.Region = &whiteboard.Rect{MinX: v[0], MinY: v[1], MaxX: v[2], MaxY: v[3]}
+	} else if s := c.QueryParam("viewport"); s != "" {
+		v, err := parseFloats(s, 3)
+		if err != nil || v[2] <= 0 {
+			return opts, errors.New("viewport must be x,y,zoom with a positive zoom")
+		}
+		x, y, zoom := v[0], v[1], v[2]
+		width, height := float64(defaultRenderWidth), float64(defaultRenderHeight)
+		if opts.Width > 0 {
+			width = float64(opts.Width)
+		}
+		if opts.Height > 0 {
+			height = float64(opts.Height)
+		}
+		opts.Region = &whiteboard.Rect{MinX: -x / zoom, MinY: -y / zoom, MaxX: (width - x) / zoom, MaxY: (height - y) / zoom}
+		if opts.Scale == 0 {
+			opts.Scale = zoom
+		}
+	}
+
+	if opts.Layers, err = whiteboard.ParseLayers(c.QueryParam("layers")); err != nil {
+		return opts, err
+	}
+	opts.Background = c.QueryParam("background")
+
+	return opts, nil
+}
+
+func queryInt(c echo.Context, name string) (int, error) {
+	s := c.QueryParam(name)
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v <= 0 {
+		return 0, errors.New(name + " must be a positive integer")
+	}
+	return v, nil
+}
+
+func parseFloats(s string, n int) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, strconv.ErrSyntax
+	}
+	out := make([]float64, n)
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, strconv.ErrRange
+		}
+		out[i] = v
+	}
+	return out, nil
+}
@@ -0,0 +1,25 @@
+package route
+
+import (
+	"github.com/collabreef/collabreef/internal/api/handler"
+	"github.com/collabreef/collabreef/internal/api/middlewares"
+
+	"github.com/labstack/echo/v4"
+)
+
+func RegisterRSS(api *echo.Group, h handler.Handler, authMiddleware middlewares.AuthMiddleware) {
+	g := api.Group("/rss")
+	g.Use(authMiddleware.CheckJWT())
+	g.Use(authMiddleware.ParseJWT())
+
+	// Feed subscriptions of the current user
+	g.GET("/feeds", h.GetRSSFeeds)
+	g.POST("/feeds", h.SubscribeRSSFeed)
+	g.GET("/feeds/:id", h.GetRSSFeed)
+	g.DELETE("/feeds/:id", h.UnsubscribeRSSFeed)
+	g.POST("/feeds/:id/read", h.MarkRSSFeedRead)
+
+	// Read state of single items
+	g.PUT("/items/:id/read", h.MarkRSSItemRead)
+	g.DELETE("/items/:id/read", h.MarkRSSItemUnread)
+}
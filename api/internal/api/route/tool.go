@@ -14,4 +14,5 @@ func RegisterTool(api *echo.Group, h handler.Handler, authMiddleware middlewares
 
 	g.POST("/fetchfile", h.FetchFile)
 	g.GET("/fetch-rss", h.FetchRSS)
+	g.GET("/unfurl", h.Unfurl)
 }
@@ -2,18 +2,25 @@ package route
 
 import (
 	"strings"
+	"time"
 
 	"github.com/collabreef/collabreef/internal/api/handler"
 	"github.com/collabreef/collabreef/internal/api/middlewares"
+	"github.com/collabreef/collabreef/internal/model"
 
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 )
 
 func RegisterWorkspace(api *echo.Group, h handler.Handler, authMiddleware middlewares.AuthMiddleware, workspaceMiddleware middlewares.WorkspaceMiddleware) {
 	g := api.Group("/workspaces")
 	g.Use(middlewares.Skippable(authMiddleware.CheckJWT(), func(c echo.Context) bool {
-		// Skip JWT auth for routes intended to be publicly accessible
-		return strings.HasSuffix(c.Path(), "/:workspaceId/files/:id")
+		// Skip JWT auth for routes intended to be publicly accessible.
+		// Downloads check access themselves, since public notes and signed
+		// URLs are loaded without the session cookie.
+		return strings.HasSuffix(c.Path(), "/:workspaceId/files/:id") ||
+			strings.HasSuffix(c.Path(), "/:workspaceId/views/:id/render.svg") ||
+			strings.HasSuffix(c.Path(), "/:workspaceId/views/:id/render.png")
 	}))
 	g.Use(authMiddleware.ParseJWT())
 	g.Use(workspaceMiddleware.CheckWorkspaceExists())
@@ -32,12 +39,32 @@ func RegisterWorkspace(api *echo.Group, h handler.Handler, authMiddleware middle
 	g.PATCH("/:workspaceId/notes/:id/visibility/:visibility", h.UpdateNoteVisibility)
 	// Note-scoped views: returns all views belonging to a specific note
 	g.GET("/:workspaceId/notes/:noteId/views", h.GetNoteViews)
+	// Web clipper: saves the readable content of a page or RSS item as a note
+	g.POST("/:workspaceId/clip", h.Clip)
+
+	// Imports from other applications
+	g.POST("/:workspaceId/imports/enex", h.ImportENEX)
+	g.POST("/:workspaceId/imports/notion", h.ImportNotion)
+	g.POST("/:workspaceId/imports/trello", h.ImportTrello)
 
 	g.GET("/:workspaceId/files/:id", h.Download)
 	g.GET("/:workspaceId/files", h.List)
 	g.POST("/:workspaceId/files", h.Upload)
 	g.PATCH("/:workspaceId/files/:id", h.RenameFile)
 	g.DELETE("/:workspaceId/files/:id", h.Delete)
+	g.POST("/:workspaceId/files/:id/signed-url", h.SignFileURL)
+
+	// Resumable uploads (tus protocol)
+	g.OPTIONS("/:workspaceId/uploads", h.UploadOptions)
+	g.POST("/:workspaceId/uploads", h.CreateUpload)
+	g.HEAD("/:workspaceId/uploads/:id", h.GetUploadOffset)
+	g.GET("/:workspaceId/uploads/:id", h.GetUpload)
+	g.PATCH("/:workspaceId/uploads/:id", h.PatchUpload)
+	g.DELETE("/:workspaceId/uploads/:id", h.TerminateUpload)
+
+	// Presigned uploads, sent directly to the storage
+	g.POST("/:workspaceId/uploads/presigned", h.CreatePresignedUpload)
+	g.POST("/:workspaceId/uploads/:id/finalize", h.FinalizePresignedUpload)
 
 	g.GET("/:workspaceId/views", h.GetViews)
 	g.POST("/:workspaceId/views", h.CreateView)
@@ -45,6 +72,54 @@ func RegisterWorkspace(api *echo.Group, h handler.Handler, authMiddleware middle
 	g.PUT("/:workspaceId/views/:id", h.UpdateView)
 	g.DELETE("/:workspaceId/views/:id", h.DeleteView)
 	g.PATCH("/:workspaceId/views/:id/visibility/:visibility", h.UpdateViewVisibility)
+	g.POST("/:workspaceId/views/:id/duplicate", h.DuplicateView)
+
+	// View templates: reusable view data and objects shared in a workspace
+	g.GET("/:workspaceId/view-templates", h.GetViewTemplates)
+	g.POST("/:workspaceId/view-templates", h.CreateViewTemplate)
+	g.GET("/:workspaceId/view-templates/:id", h.GetViewTemplate)
+	g.PATCH("/:workspaceId/view-templates/:id", h.UpdateViewTemplate)
+	g.DELETE("/:workspaceId/view-templates/:id", h.DeleteViewTemplate)
+	g.POST("/:workspaceId/view-templates/:id/instantiate", h.InstantiateViewTemplate)
+
+	// Spreadsheet import/export (CSV, XLSX)
+	g.POST("/:workspaceId/views/import", h.ImportSpreadsheet)
+	g.GET("/:workspaceId/views/:id/export", h.ExportSpreadsheet)
+	g.GET("/:workspaceId/views/:id/cells", h.GetSpreadsheetCells)
+	g.PUT("/:workspaceId/views/:id/cells", h.UpdateSpreadsheetCells)
+
+	// Table views: notes as rows with typed properties
+	g.POST("/:workspaceId/views/:id/query", h.QueryTable)
+	g.POST("/:workspaceId/views/:id/rows", h.AddTableRow)
+	g.PATCH("/:workspaceId/views/:id/rows/:noteId", h.UpdateTableRow)
+	g.DELETE("/:workspaceId/views/:id/rows/:noteId", h.RemoveTableRow)
+
+	// Timeline views
+	g.POST("/:workspaceId/views/:id/tasks/:taskId/reschedule", h.RescheduleTimelineTask)
+
+	// View history: point-in-time snapshots of view data and objects
+	g.GET("/:workspaceId/views/:id/snapshots", h.GetViewSnapshots)
+	g.POST("/:workspaceId/views/:id/snapshots", h.CreateViewSnapshot)
+	g.GET("/:workspaceId/views/:id/snapshots/:snapshotId", h.GetViewSnapshot)
+	g.DELETE("/:workspaceId/views/:id/snapshots/:snapshotId", h.DeleteViewSnapshot)
+	g.POST("/:workspaceId/views/:id/snapshots/:snapshotId/restore", h.RestoreViewSnapshot)
+
+	// Whiteboard snapshots
+	// Renders of public views are open to everyone; limit how often a client
+	// that is not signed in can request them.
+	renderLimit := middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+		Skipper: func(c echo.Context) bool {
+			_, ok := c.Get("user").(model.User)
+			return ok
+		},
+		Store: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+			Rate:      1,
+			Burst:     10,
+			ExpiresIn: 3 * time.Minute,
+		}),
+	})
+	g.GET("/:workspaceId/views/:id/render.svg", h.RenderWhiteboardSVG, renderLimit)
+	g.GET("/:workspaceId/views/:id/render.png", h.RenderWhiteboardPNG, renderLimit)
 
 	// View objects (internal data storage for view types: calendar slots, map markers, kanban columns, whiteboard objects)
 	g.GET("/:workspaceId/views/:viewId/objects", h.GetViewObjects)
@@ -52,6 +127,8 @@ func RegisterWorkspace(api *echo.Group, h handler.Handler, authMiddleware middle
 	g.GET("/:workspaceId/views/:viewId/objects/:id", h.GetViewObject)
 	g.PUT("/:workspaceId/views/:viewId/objects/:id", h.UpdateViewObject)
 	g.DELETE("/:workspaceId/views/:viewId/objects/:id", h.DeleteViewObject)
+	g.POST("/:workspaceId/views/:viewId/objects\\:batch", h.BatchViewObjects)
+	g.POST("/:workspaceId/views/:viewId/objects\\:import", h.ImportViewObjects)
 
 	// Widgets
 	g.GET("/:workspaceId/widgets", h.GetWidgets)
@@ -10,7 +10,12 @@ import (
 )
 
 func NewStorage() (storage.Storage, error) {
-	storageType := config.C.GetString(config.STORAGE_TYPE)
+	return NewStorageOfType(config.C.GetString(config.STORAGE_TYPE))
+}
+
+// NewStorageOfType returns the storage of a type, configured as NewStorage
+// would be for that type.
+func NewStorageOfType(storageType string) (storage.Storage, error) {
 	storageRoot := config.C.GetString(config.STORAGE_ROOT)
 
 	switch storageType {
@@ -23,6 +28,8 @@ func NewStorage() (storage.Storage, error) {
 			SecretAccessKey: config.C.GetString(config.STORAGE_S3_SECRET_KEY),
 			Bucket:          config.C.GetString(config.STORAGE_S3_BUCKET),
 			UseSSL:          config.C.GetBool(config.STORAGE_S3_USE_SSL),
+			Region:          config.C.GetString(config.STORAGE_S3_REGION),
+			PublicURL:       config.C.GetString(config.STORAGE_S3_PUBLIC_URL),
 		}
 		return s3storage.NewS3Storage(s3Config)
 	}
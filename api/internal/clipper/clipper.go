@@ -0,0 +1,265 @@
+// Package clipper turns web pages into note content. It keeps the readable
+// part of a page, the way browser reader modes do, and converts it to the
+// TipTap JSON of the editor.
+package clipper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/collabreef/collabreef/internal/urlfetcher"
+	"github.com/collabreef/collabreef/internal/util"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// MaxImages is the number of images downloaded per clip. Further images
+// keep their remote address.
+const MaxImages = 20
+
+// maxExcerptLength bounds excerpts taken from the article text.
+const maxExcerptLength = 200
+
+var ErrNoContent = errors.New("no readable content found")
+
+// SaveImageFunc stores a downloaded image and returns the address the note
+// should use for it.
+type SaveImageFunc func(name, contentType string, data []byte) (string, error)
+
+// Clip is a page reduced to its readable content.
+type Clip struct {
+	Title     string
+	SiteName  string
+	Excerpt   string
+	SourceURL string
+	// Content is the article as TipTap JSON.
+	Content string
+}
+
+// ClipURL fetches a page and clips it. Images are passed to saveImage when
+// it is not nil.
+func ClipURL(ctx context.Context, rawURL string, saveImage SaveImageFunc) (Clip, error) {
+	pageURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (pageURL.Scheme != "http" && pageURL.Scheme != "https") || pageURL.Host == "" {
+		return Clip{}, errors.New("url must be an http or https URL")
+	}
+
+	data, contentType, err := urlfetcher.SafeFetchFile(ctx, pageURL.String())
+	if err != nil {
+		return Clip{}, err
+	}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "" &&
+		mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Clip{}, errors.New("url is not an HTML page: " + mediaType)
+	}
+
+	return ClipHTML(ctx, pageURL, data, contentType, saveImage)
+}
+
+// ClipHTML clips an HTML page or fragment. Relative links and images are
+// resolved against pageURL.
+func ClipHTML(ctx context.Context, pageURL *url.URL, data []byte, contentType string, saveImage SaveImageFunc) (Clip, error) {
+	r, err := charset.NewReader(bytes.NewReader(data), contentType)
+	if err != nil {
+		return Clip{}, err
+	}
+	doc, err := html.Parse(r)
+	if err != nil {
+		return Clip{}, err
+	}
+
+	article := Extract(doc)
+	if article.Content == nil {
+		return Clip{}, ErrNoContent
+	}
+
+	resolve(article.Content, pageURL)
+	dropTitleHeading(article.Content, article.Title)
+	if saveImage != nil {
+		downloadImages(ctx, article.Content, saveImage)
+	}
+
+	content, err := jsonOf(article.Content)
+	if err != nil {
+		return Clip{}, err
+	}
+
+	clip := Clip{
+		Title:     article.Title,
+		SiteName:  article.SiteName,
+		Excerpt:   article.Excerpt,
+		SourceURL: pageURL.String(),
+		Content:   content,
+	}
+	if clip.Excerpt == "" {
+		clip.Excerpt = excerpt(article.Content)
+	}
+	if clip.Title == "" {
+		clip.Title = pageURL.Host
+	}
+	return clip, nil
+}
+
+// resolve makes the links and images of the article absolute, picking up
+// the real source of lazily loaded images.
+func resolve(root *html.Node, base *url.URL) {
+	walk(root, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		switch n.DataAtom {
+		case atom.A:
+			if href := attr(n, "href"); href != "" {
+				if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+					setAttr(n, "href", u.String())
+				}
+			}
+		case atom.Img:
+			src := imageSource(n)
+			if src == "" {
+				return false
+			}
+			if u, err := base.Parse(src); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+				setAttr(n, "src", u.String())
+			} else {
+				setAttr(n, "src", "")
+			}
+		}
+		return true
+	})
+}
+
+// imageSource returns the address of an image, preferring lazy loading
+// attributes over a placeholder src.
+func imageSource(n *html.Node) string {
+	for _, key := range []string{"data-src", "data-original", "data-lazy-src"} {
+		if v := strings.TrimSpace(attr(n, key)); v != "" {
+			return v
+		}
+	}
+	src := strings.TrimSpace(attr(n, "src"))
+	if src == "" || strings.HasPrefix(src, "data:") {
+		for _, key := range []string{"srcset", "data-srcset"} {
+			if v := firstSrcset(attr(n, key)); v != "" {
+				return v
+			}
+		}
+	}
+	if strings.HasPrefix(src, "data:") {
+		return ""
+	}
+	return src
+}
+
+func firstSrcset(srcset string) string {
+	candidate, _, _ := strings.Cut(strings.TrimSpace(srcset), ",")
+	fields := strings.Fields(candidate)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// dropTitleHeading removes a leading heading that repeats the title, which
+// becomes the note title instead.
+func dropTitleHeading(root *html.Node, title string) {
+	if title == "" {
+		return
+	}
+	h := find(root, atom.H1)
+	if h != nil && h.Parent != nil && strings.EqualFold(strings.TrimSpace(textOf(h)), title) {
+		h.Parent.RemoveChild(h)
+	}
+}
+
+// downloadImages stores the images of the article through saveImage and
+// points them at the stored copies. Images that fail to download keep their
+// remote address.
+func downloadImages(ctx context.Context, root *html.Node, saveImage SaveImageFunc) {
+	saved := map[string]string{}
+	count := 0
+	walk(root, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Img {
+			return true
+		}
+		src := attr(n, "src")
+		if src == "" {
+			return false
+		}
+		if local, ok := saved[src]; ok {
+			setAttr(n, "src", local)
+			return false
+		}
+		if count >= MaxImages {
+			return false
+		}
+		count++
+
+		data, contentType, err := urlfetcher.SafeFetchFile(ctx, src)
+		if err != nil {
+			return false
+		}
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if !strings.HasPrefix(mediaType, "image/") {
+			return false
+		}
+		local, err := saveImage(imageName(src, mediaType), mediaType, data)
+		if err != nil {
+			return false
+		}
+		saved[src] = local
+		setAttr(n, "src", local)
+		return false
+	})
+}
+
+// imageName derives a file name for an image from its address, with an
+// extension that matches its type.
+func imageName(src, mediaType string) string {
+	name := "image"
+	if u, err := url.Parse(src); err == nil {
+		if base := path.Base(u.Path); base != "." && base != "/" {
+			name = base
+		}
+	}
+	if path.Ext(name) == "" {
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			name += exts[0]
+		}
+	}
+	return name
+}
+
+func jsonOf(root *html.Node) (string, error) {
+	b, err := json.Marshal(util.HTMLNodeToTipTap(root))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// excerpt returns the start of the first paragraph of the article.
+func excerpt(root *html.Node) string {
+	var text string
+	walk(root, func(n *html.Node) bool {
+		if text != "" {
+			return false
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.P {
+			text = strings.Join(strings.Fields(textOf(n)), " ")
+			return false
+		}
+		return true
+	})
+	if r := []rune(text); len(r) > maxExcerptLength {
+		text = strings.TrimSpace(string(r[:maxExcerptLength])) + "…"
+	}
+	return text
+}
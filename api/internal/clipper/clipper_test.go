@@ -0,0 +1,83 @@
+package clipper
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestClipHTML(t *testing.T) {
+	page := `<html><head><title>Bridge plans</title></head><body><article>
+		<h1>Bridge plans</h1>
+		<p>` + prose + ` See <a href="/budget">the budget</a>.</p>
+		<img src="data:image/gif;base64,R0lGOD" data-src="img/bridge.jpg">
+		<img srcset="/small.png 1x, /large.png 2x">
+		<img src="javascript:alert(1)">
+	</article></body></html>`
+	base, _ := url.Parse("https://news.example.com/2024/bridge.html")
+
+	clip, err := ClipHTML(context.Background(), base, []byte(page), "text/html; charset=utf-8", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clip.Title != "Bridge plans" || clip.SourceURL != base.String() {
+		t.Errorf("title, source = %q, %q", clip.Title, clip.SourceURL)
+	}
+	if !strings.HasPrefix(clip.Excerpt, "The committee met on Tuesday") {
+		t.Errorf("excerpt = %q, want the first paragraph", clip.Excerpt)
+	}
+	for _, want := range []string{
+		`"href":"https://news.example.com/budget"`,
+		`"src":"https://news.example.com/2024/img/bridge.jpg"`,
+		`"src":"https://news.example.com/small.png"`,
+	} {
+		if !strings.Contains(clip.Content, want) {
+			t.Errorf("content lacks %s: %s", want, clip.Content)
+		}
+	}
+	for _, notWant := range []string{"javascript:", `"level":1`} {
+		if strings.Contains(clip.Content, notWant) {
+			t.Errorf("content holds %s: %s", notWant, clip.Content)
+		}
+	}
+}
+
+func TestClipHTMLWithoutTitle(t *testing.T) {
+	base, _ := url.Parse("https://example.com/page")
+	long := strings.Repeat("word, ", 60)
+	clip, err := ClipHTML(context.Background(), base, []byte(`<div class="content"><p>`+long+`</p></div>`), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clip.Title != "example.com" {
+		t.Errorf("title = %q, want the host", clip.Title)
+	}
+	if r := []rune(clip.Excerpt); len(r) != maxExcerptLength+1 || !strings.HasSuffix(clip.Excerpt, "…") {
+		t.Errorf("excerpt = %q, want %d characters and an ellipsis", clip.Excerpt, maxExcerptLength)
+	}
+}
+
+func TestClipHTMLNoContent(t *testing.T) {
+	base, _ := url.Parse("https://example.com/")
+	_, err := ClipHTML(context.Background(), base, []byte(`<nav><a href="/">Home</a></nav>`), "text/html", nil)
+	if !errors.Is(err, ErrNoContent) {
+		t.Errorf("error = %v, want ErrNoContent", err)
+	}
+}
+
+func TestImageName(t *testing.T) {
+	tests := []struct {
+		src, mediaType, want string
+	}{
+		{src: "https://example.com/a/photo.jpg?w=200", mediaType: "image/jpeg", want: "photo.jpg"},
+		{src: "https://example.com/a/photo", mediaType: "image/png", want: "photo.png"},
+		{src: "https://example.com/", mediaType: "image/png", want: "image.png"},
+	}
+	for _, tt := range tests {
+		if got := imageName(tt.src, tt.mediaType); got != tt.want {
+			t.Errorf("imageName(%q, %q) = %q, want %q", tt.src, tt.mediaType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package clipper
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// The extraction follows the approach of browser reader modes: boilerplate
+// is removed, paragraphs score their ancestors by the amount of prose they
+// hold, and the best scoring element is taken as the article.
+
+var (
+	unlikelyPattern = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|nav|newsletter|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|tags|tool|widget|^ad-|-ad$|\bads?\b|advert`)
+	likelyPattern   = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow|story|entry|post|text|blog`)
+	positivePattern = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativePattern = regexp.MustCompile(`(?i)hidden|banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// minParagraphLength is the shortest text that counts as prose.
+const minParagraphLength = 25
+
+// Article is the readable part of a page.
+type Article struct {
+	Title    string
+	SiteName string
+	Excerpt  string
+	Content  *html.Node
+}
+
+// Extract finds the main content of a parsed page. Content is nil when the
+// page holds no prose.
+func Extract(doc *html.Node) Article {
+	a := Article{
+		Title:    pageTitle(doc),
+		SiteName: meta(doc, "og:site_name"),
+		Excerpt:  meta(doc, "og:description"),
+	}
+	if a.Excerpt == "" {
+		a.Excerpt = meta(doc, "description")
+	}
+
+	body := find(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+	removeBoilerplate(body)
+
+	a.Content = bestCandidate(body)
+	return a
+}
+
+// pageTitle prefers the Open Graph title, which is free of the site name
+// that page titles often carry.
+func pageTitle(doc *html.Node) string {
+	if t := meta(doc, "og:title"); t != "" {
+		return t
+	}
+	if t := find(doc, atom.Title); t != nil {
+		if s := strings.TrimSpace(textOf(t)); s != "" {
+			return s
+		}
+	}
+	if h := find(doc, atom.H1); h != nil {
+		return strings.TrimSpace(textOf(h))
+	}
+	return ""
+}
+
+func meta(doc *html.Node, name string) string {
+	var value string
+	walk(doc, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Meta {
+			key := attr(n, "property")
+			if key == "" {
+				key = attr(n, "name")
+			}
+			if strings.EqualFold(key, name) {
+				value = strings.TrimSpace(attr(n, "content"))
+				return false
+			}
+		}
+		return value == ""
+	})
+	return value
+}
+
+// removeBoilerplate drops elements that never hold article content, and
+// elements whose class or id marks them as page chrome.
+func removeBoilerplate(root *html.Node) {
+	var remove []*html.Node
+	walk(root, func(n *html.Node) bool {
+		if n.Type == html.CommentNode {
+			remove = append(remove, n)
+			return false
+		}
+		if n.Type != html.ElementNode {
+			return true
+		}
+		switch n.DataAtom {
+		case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Link,
+			atom.Form, atom.Button, atom.Input, atom.Select, atom.Textarea,
+			atom.Iframe, atom.Object, atom.Embed, atom.Svg, atom.Canvas,
+			atom.Nav, atom.Footer, atom.Aside:
+			remove = append(remove, n)
+			return false
+		case atom.Body, atom.Article, atom.Main:
+			return true
+		}
+		if hasAttr(n, "hidden") || strings.EqualFold(attr(n, "aria-hidden"), "true") {
+			remove = append(remove, n)
+			return false
+		}
+		if role := attr(n, "role"); role == "navigation" || role == "complementary" || role == "banner" || role == "dialog" {
+			remove = append(remove, n)
+			return false
+		}
+		match := attr(n, "class") + " " + attr(n, "id")
+		if unlikelyPattern.MatchString(match) && !likelyPattern.MatchString(match) {
+			remove = append(remove, n)
+			return false
+		}
+		return true
+	})
+	for _, n := range remove {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+}
+
+// bestCandidate scores the ancestors of every paragraph and returns the
+// element with the highest score, adjusted for link density.
+func bestCandidate(root *html.Node) *html.Node {
+	scores := map[*html.Node]float64{}
+	var order []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			order = append(order, n)
+		}
+		scores[n] += score
+	}
+
+	walk(root, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td, atom.Blockquote, atom.Li:
+		default:
+			return true
+		}
+		text := strings.TrimSpace(textOf(n))
+		if len(text) < minParagraphLength {
+			return false
+		}
+		score := 1 + float64(strings.Count(text, ",")) + minFloat(float64(len(text))/100, 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+		}
+		return false
+	})
+
+	var best *html.Node
+	bestScore := 0.0
+	for _, n := range order {
+		score := scores[n] * (1 - linkDensity(n))
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+
+	// Articles marked up as such win over a scored guess when they hold
+	// most of the prose.
+	if article := find(root, atom.Article); article != nil {
+		if best == nil || contains(article, best) || len(textOf(article)) >= len(textOf(best)) {
+			return article
+		}
+	}
+	return best
+}
+
+func initialScore(n *html.Node) float64 {
+	var score float64
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		score = 10
+	case atom.Div, atom.Section:
+		score = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score = 3
+	case atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score = -5
+	}
+	match := attr(n, "class") + " " + attr(n, "id")
+	if negativePattern.MatchString(match) {
+		score -= 25
+	}
+	if positivePattern.MatchString(match) {
+		score += 25
+	}
+	return score
+}
+
+// linkDensity is the share of the text of n that is link text.
+func linkDensity(n *html.Node) float64 {
+	total := len(textOf(n))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A {
+			links += len(textOf(c))
+			return false
+		}
+		return true
+	})
+	return float64(links) / float64(total)
+}
+
+func contains(ancestor, n *html.Node) bool {
+	for ; n != nil; n = n.Parent {
+		if n == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+func find(root *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walk(root, func(n *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if n.Type == html.ElementNode && n.DataAtom == a {
+			found = n
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// walk visits n and its descendants in document order. Returning false
+// skips the children of a node.
+func walk(n *html.Node, visit func(*html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		walk(child, visit)
+	}
+}
+
+func textOf(n *html.Node) string {
+	var sb strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+		return true
+	})
+	return sb.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasAttr reports whether n has an attribute, which for boolean attributes
+// such as hidden is usually empty.
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,138 @@
+package clipper
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const prose = "The committee met on Tuesday, and after a long debate, it agreed to fund the new library, the park and the bridge."
+
+func parse(t *testing.T, s string) *html.Node {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		// want is text the content must hold, and not text it must not.
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "scored content beats page chrome",
+			page: `<body>
+				<div class="site-header"><p>` + prose + ` header</p></div>
+				<nav><p>` + prose + ` nav</p></nav>
+				<div id="story"><p>` + prose + ` one</p><p>` + prose + ` two</p><p>` + prose + ` three</p></div>
+				<div class="sidebar"><p>` + prose + ` sidebar</p></div>
+				<div class="comments"><p>` + prose + ` comment</p></div>
+				<footer><p>` + prose + ` footer</p></footer>
+			</body>`,
+			want:    []string{"one", "two", "three"},
+			notWant: []string{"header", "nav", "sidebar", "comment", "footer"},
+		},
+		{
+			name: "link lists lose to prose",
+			page: `<body>
+				<div><p><a href="/a">` + prose + ` linked</a></p><p><a href="/b">` + prose + ` linked</a></p><p><a href="/c">` + prose + ` linked</a></p></div>
+				<div><p>` + prose + ` plain</p><p>` + prose + ` plain</p></div>
+			</body>`,
+			want:    []string{"plain"},
+			notWant: []string{"linked"},
+		},
+		{
+			name: "article element wins",
+			page: `<body>
+				<div class="content"><p>` + prose + ` teaser</p></div>
+				<article><h2>Heading</h2><p>` + prose + ` body</p><p>` + prose + ` more</p></article>
+			</body>`,
+			want:    []string{"Heading", "body", "more"},
+			notWant: []string{"teaser"},
+		},
+		{
+			name: "scripts, hidden and aria-hidden elements are dropped",
+			page: `<body><div class="post">
+				<p>` + prose + ` kept</p>
+				<script>var tracking = "script text";</script>
+				<p hidden>` + prose + ` hidden</p>
+				<p aria-hidden="true">` + prose + ` aria</p>
+				<!-- a comment -->
+			</div></body>`,
+			want:    []string{"kept"},
+			notWant: []string{"script text", "hidden", "aria", "a comment"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Extract(parse(t, tt.page))
+			if a.Content == nil {
+				t.Fatal("no content found")
+			}
+			var sb strings.Builder
+			if err := html.Render(&sb, a.Content); err != nil {
+				t.Fatal(err)
+			}
+			got := sb.String()
+			for _, s := range tt.want {
+				if !strings.Contains(got, s) {
+					t.Errorf("content lacks %q: %s", s, got)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("content holds %q: %s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractNoProse(t *testing.T) {
+	a := Extract(parse(t, `<body><div><p>Short.</p><ul><li>Item</li></ul></div></body>`))
+	if a.Content != nil {
+		t.Errorf("content of a page without prose = %v, want none", a.Content)
+	}
+}
+
+func TestExtractMetadata(t *testing.T) {
+	tests := []struct {
+		name  string
+		head  string
+		body  string
+		title string
+		site  string
+		ex    string
+	}{
+		{
+			name:  "Open Graph",
+			head:  `<title>Story | Daily</title><meta property="og:title" content="Story"><meta property="og:site_name" content="Daily"><meta property="og:description" content="What happened."><meta name="description" content="Other.">`,
+			title: "Story", site: "Daily", ex: "What happened.",
+		},
+		{
+			name:  "title and description",
+			head:  `<title> Story | Daily </title><meta name="Description" content="Plain.">`,
+			title: "Story | Daily", ex: "Plain.",
+		},
+		{
+			name:  "first heading",
+			body:  `<h1> Heading </h1>`,
+			title: "Heading",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Extract(parse(t, `<html><head>`+tt.head+`</head><body>`+tt.body+`<p>`+prose+`</p></body></html>`))
+			if a.Title != tt.title || a.SiteName != tt.site || a.Excerpt != tt.ex {
+				t.Errorf("title, site, excerpt = %q, %q, %q, want %q, %q, %q", a.Title, a.SiteName, a.Excerpt, tt.title, tt.site, tt.ex)
+			}
+		})
+	}
+}
@@ -46,10 +46,22 @@ const (
 	STORAGE_S3_SECRET_KEY   = "storage_s3_secret_key"
 	STORAGE_S3_BUCKET       = "storage_s3_bucket"
 	STORAGE_S3_USE_SSL      = "storage_s3_use_ssl"
+	STORAGE_S3_REGION       = "storage_s3_region"
+	STORAGE_S3_PUBLIC_URL   = "storage_s3_public_url"
+	STORAGE_S3_PRESIGN      = "storage_s3_presign"
 	SERVER_API_ROOT_PATH    = "server_api_root_path"
 	APP_DISABLE_SIGNUP      = "app_disable_signup"
 	APP_SECRET              = "app_secret"
 	GRPC_PORT               = "grpc_port"
+	VIEW_SNAPSHOT_INTERVAL  = "view_snapshot_interval"
+	VIEW_SNAPSHOT_RETENTION = "view_snapshot_retention"
+	RSS_POLL_INTERVAL       = "rss_poll_interval"
+	UNFURL_CACHE_TTL        = "unfurl_cache_ttl"
+	FILE_URL_TTL            = "file_url_ttl"
+	STORAGE_MAX_FILE_SIZE   = "storage_max_file_size"
+	STORAGE_WORKSPACE_QUOTA = "storage_workspace_quota"
+	STORAGE_USER_QUOTA      = "storage_user_quota"
+	UPLOAD_EXPIRY           = "upload_expiry"
 )
 
 func Init() {
@@ -64,10 +76,22 @@ func Init() {
 	C.SetDefault(STORAGE_S3_SECRET_KEY, "")
 	C.SetDefault(STORAGE_S3_BUCKET, "collabreef")
 	C.SetDefault(STORAGE_S3_USE_SSL, false)
+	C.SetDefault(STORAGE_S3_REGION, "")
+	C.SetDefault(STORAGE_S3_PUBLIC_URL, "")
+	C.SetDefault(STORAGE_S3_PRESIGN, false)
 	C.SetDefault(SERVER_API_ROOT_PATH, "/api/v1")
 	C.SetDefault(APP_DISABLE_SIGNUP, false)
 	C.SetDefault(APP_SECRET, "default_secret")
 	C.SetDefault(GRPC_PORT, "50051")
+	C.SetDefault(VIEW_SNAPSHOT_INTERVAL, "1h")
+	C.SetDefault(VIEW_SNAPSHOT_RETENTION, 48)
+	C.SetDefault(RSS_POLL_INTERVAL, "15m")
+	C.SetDefault(UNFURL_CACHE_TTL, "24h")
+	C.SetDefault(FILE_URL_TTL, "15m")
+	C.SetDefault(STORAGE_MAX_FILE_SIZE, "0")
+	C.SetDefault(STORAGE_WORKSPACE_QUOTA, "0")
+	C.SetDefault(STORAGE_USER_QUOTA, "0")
+	C.SetDefault(UPLOAD_EXPIRY, "24h")
 
 	C.AutomaticEnv()
 }
@@ -0,0 +1,479 @@
+// Package csvimport turns the rows of a CSV file into view objects: calendar
+// slots, map markers, or kanban columns and cards.
+package csvimport
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/util"
+	"github.com/collabreef/collabreef/internal/viewobject"
+)
+
+// MaxRows caps the number of data rows in one import.
+const MaxRows = 10000
+
+// Mapping names the CSV columns, by header, that fill each field. Which
+// fields are used depends on the view type:
+//
+//	calendar: name, date, end_date, start_time, end_time, all_day, color
+//	map:      name, lat, lng, color
+//	kanban:   name (card title), status (column)
+type Mapping struct {
+	Name      string `json:"name"`
+	Date      string `json:"date"`
+	EndDate   string `json:"end_date"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	AllDay    string `json:"all_day"`
+	Lat       string `json:"lat"`
+	Lng       string `json:"lng"`
+	Status    string `json:"status"`
+	Color     string `json:"color"`
+	// DateFormat is the layout of date cells: YYYY-MM-DD (default),
+	// YYYY/MM/DD, MM/DD/YYYY, DD/MM/YYYY or DD.MM.YYYY.
+	DateFormat string `json:"date_format"`
+	// Delimiter is the field separator, a comma by default.
+	Delimiter string `json:"delimiter"`
+}
+
+// RowError describes why a row cannot be imported. Row is the line number
+// in the file, the header being line 1.
+type RowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// Result is the outcome of planning an import. Create and Update hold the
+// view objects to write; kanban imports update existing columns that gain
+// cards.
+type Result struct {
+	Rows   int
+	Valid  int
+	Errors []RowError
+	Create []model.ViewObject
+	Update []model.ViewObject
+}
+
+// Plan reads a CSV file and builds the view objects for a view of the given
+// type. Existing objects of the view are used to add kanban cards to
+// columns that already exist. Problems with the mapping or the file as a
+// whole are returned as an error; problems with single rows are listed in
+// the result.
+func Plan(viewType string, r io.Reader, m Mapping, existing []model.ViewObject) (Result, error) {
+	builder, err := newBuilder(viewType, m, existing)
+	if err != nil {
+		return Result{}, err
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	if m.Delimiter != "" {
+		d, size := utf8.DecodeRuneInString(m.Delimiter)
+		if size != len(m.Delimiter) || d == '"' || d == '\r' || d == '\n' {
+			return Result{}, errors.New("delimiter must be a single character")
+		}
+		cr.Comma = d
+	}
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return Result{}, errors.New("the file is empty")
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	columns := map[string]int{}
+	for i, h := range header {
+		if i == 0 {
+			h = strings.TrimPrefix(h, "\ufeff")
+		}
+		key := strings.ToLower(strings.TrimSpace(h))
+		if _, dup := columns[key]; !dup {
+			columns[key] = i
+		}
+	}
+	if err := builder.bind(columns); err != nil {
+		return Result{}, err
+	}
+
+	var res Result
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to parse CSV: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		if blank(record) {
+			continue
+		}
+		res.Rows++
+		if res.Rows > MaxRows {
+			return Result{}, fmt.Errorf("an import can hold at most %d rows", MaxRows)
+		}
+
+		errs := builder.add(row{line: line, record: record})
+		if len(errs) > 0 {
+			res.Errors = append(res.Errors, errs...)
+		} else {
+			res.Valid++
+		}
+	}
+
+	res.Create, res.Update = builder.objects()
+	return res, nil
+}
+
+func blank(record []string) bool {
+	for _, f := range record {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// row is a data row of the file.
+type row struct {
+	line   int
+	record []string
+}
+
+// builder collects the view objects for the rows of one view type.
+type builder interface {
+	bind(columns map[string]int) error
+	add(r row) []RowError
+	objects() (create, update []model.ViewObject)
+}
+
+func newBuilder(viewType string, m Mapping, existing []model.ViewObject) (builder, error) {
+	layout, err := dateLayout(m.DateFormat)
+	if err != nil {
+		return nil, err
+	}
+	switch viewType {
+	case "calendar":
+		return &calendarBuilder{m: m, layout: layout}, nil
+	case "map":
+		return &mapBuilder{m: m}, nil
+	case "kanban":
+		return newKanbanBuilder(m, existing), nil
+	}
+	return nil, fmt.Errorf("CSV import is not supported for %s views", viewType)
+}
+
+// fields resolves mapped column names to their index in a row. Unmapped
+// fields read as empty.
+type fields struct {
+	index map[string]int
+	names map[string]string
+}
+
+// binding maps a field to the column that fills it.
+type binding struct {
+	field    string
+	column   string
+	required bool
+}
+
+func bindFields(columns map[string]int, bindings ...binding) (fields, error) {
+	f := fields{index: map[string]int{}, names: map[string]string{}}
+	for _, b := range bindings {
+		if b.column == "" {
+			if b.required {
+				return f, fmt.Errorf("mapping for %s is required", b.field)
+			}
+			continue
+		}
+		i, ok := columns[strings.ToLower(strings.TrimSpace(b.column))]
+		if !ok {
+			return f, fmt.Errorf("column %q mapped to %s is not in the file", b.column, b.field)
+		}
+		f.index[b.field] = i
+		f.names[b.field] = strings.TrimSpace(b.column)
+	}
+	return f, nil
+}
+
+// get returns the trimmed cell of a field.
+func (f fields) get(r row, field string) string {
+	i, ok := f.index[field]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+func (f fields) fail(r row, field, message string) RowError {
+	return RowError{Row: r.line, Column: f.names[field], Message: message}
+}
+
+// validate checks generated data against the schema of the object type and
+// reports invalid fields against their columns.
+func (f fields) validate(r row, objectType string, data map[string]interface{}) (string, []RowError) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", []RowError{{Row: r.line, Message: err.Error()}}
+	}
+	if err := viewobject.Validate(objectType, string(b)); err != nil {
+		var verr *viewobject.ValidationError
+		if !errors.As(err, &verr) {
+			return "", []RowError{{Row: r.line, Message: err.Error()}}
+		}
+		var errs []RowError
+		for _, fe := range verr.Fields {
+			field := strings.TrimPrefix(fe.Field, "data.")
+			errs = append(errs, f.fail(r, field, fe.Message))
+		}
+		return "", errs
+	}
+	return string(b), nil
+}
+
+// ---------- Calendar ----------
+
+type calendarBuilder struct {
+	m      Mapping
+	layout string
+	f      fields
+	create []model.ViewObject
+}
+
+func (b *calendarBuilder) bind(columns map[string]int) (err error) {
+	b.f, err = bindFields(columns,
+		binding{"name", b.m.Name, true},
+		binding{"date", b.m.Date, true},
+		binding{"end_date", b.m.EndDate, false},
+		binding{"start_time", b.m.StartTime, false},
+		binding{"end_time", b.m.EndTime, false},
+		binding{"is_all_day", b.m.AllDay, false},
+		binding{"color", b.m.Color, false})
+	return err
+}
+
+func (b *calendarBuilder) add(r row) []RowError {
+	var errs []RowError
+	name := b.f.get(r, "name")
+	if name == "" {
+		errs = append(errs, b.f.fail(r, "name", "is required"))
+	}
+
+	data := map[string]interface{}{}
+	for _, field := range []string{"date", "end_date"} {
+		cell := b.f.get(r, field)
+		if cell == "" {
+			if field == "date" {
+				errs = append(errs, b.f.fail(r, field, "is required"))
+			}
+			continue
+		}
+		d, err := parseDate(cell, b.layout)
+		if err != nil {
+			errs = append(errs, b.f.fail(r, field, err.Error()))
+			continue
+		}
+		data[field] = d
+	}
+	for _, field := range []string{"start_time", "end_time"} {
+		if cell := b.f.get(r, field); cell != "" {
+			t, err := parseClock(cell)
+			if err != nil {
+				errs = append(errs, b.f.fail(r, field, err.Error()))
+				continue
+			}
+			data[field] = t
+		}
+	}
+	// Without an all-day column, events without a start time are all day.
+	if cell := b.f.get(r, "is_all_day"); b.f.names["is_all_day"] != "" {
+		allDay, err := parseBool(cell)
+		if err != nil {
+			errs = append(errs, b.f.fail(r, "is_all_day", err.Error()))
+		}
+		data["is_all_day"] = allDay
+	} else {
+		_, timed := data["start_time"]
+		data["is_all_day"] = !timed
+	}
+	if color := b.f.get(r, "color"); color != "" {
+		data["color"] = color
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	encoded, errs := b.f.validate(r, "calendar_slot", data)
+	if len(errs) > 0 {
+		return errs
+	}
+	b.create = append(b.create, model.ViewObject{ID: util.NewId(), Name: name, Type: "calendar_slot", Data: encoded})
+	return nil
+}
+
+func (b *calendarBuilder) objects() ([]model.ViewObject, []model.ViewObject) {
+	return b.create, nil
+}
+
+// ---------- Map ----------
+
+type mapBuilder struct {
+	m      Mapping
+	f      fields
+	create []model.ViewObject
+}
+
+func (b *mapBuilder) bind(columns map[string]int) (err error) {
+	b.f, err = bindFields(columns,
+		binding{"name", b.m.Name, true},
+		binding{"lat", b.m.Lat, true},
+		binding{"lng", b.m.Lng, true},
+		binding{"color", b.m.Color, false})
+	return err
+}
+
+func (b *mapBuilder) add(r row) []RowError {
+	var errs []RowError
+	name := b.f.get(r, "name")
+	if name == "" {
+		errs = append(errs, b.f.fail(r, "name", "is required"))
+	}
+
+	data := map[string]interface{}{}
+	for _, field := range []string{"lat", "lng"} {
+		cell := b.f.get(r, field)
+		if cell == "" {
+			errs = append(errs, b.f.fail(r, field, "is required"))
+			continue
+		}
+		v, err := parseNumber(cell)
+		if err != nil {
+			errs = append(errs, b.f.fail(r, field, err.Error()))
+			continue
+		}
+		data[field] = v
+	}
+	if color := b.f.get(r, "color"); color != "" {
+		data["color"] = color
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	encoded, errs := b.f.validate(r, "map_marker", data)
+	if len(errs) > 0 {
+		return errs
+	}
+	b.create = append(b.create, model.ViewObject{ID: util.NewId(), Name: name, Type: "map_marker", Data: encoded})
+	return nil
+}
+
+func (b *mapBuilder) objects() ([]model.ViewObject, []model.ViewObject) {
+	return b.create, nil
+}
+
+// ---------- Kanban ----------
+
+// kanbanColumn is a column that receives cards, with its data decoded.
+type kanbanColumn struct {
+	object   model.ViewObject
+	data     map[string]interface{}
+	items    []interface{}
+	existing bool
+	changed  bool
+}
+
+type kanbanBuilder struct {
+	m       Mapping
+	f       fields
+	columns []*kanbanColumn
+	byName  map[string]*kanbanColumn
+}
+
+// newKanbanBuilder indexes the existing columns by name, so that cards are
+// added to them instead of to new columns of the same name.
+func newKanbanBuilder(m Mapping, existing []model.ViewObject) *kanbanBuilder {
+	b := &kanbanBuilder{m: m, byName: map[string]*kanbanColumn{}}
+	for _, o := range existing {
+		if o.Type != "kanban_column" {
+			continue
+		}
+		data := map[string]interface{}{}
+		if o.Data != "" && json.Unmarshal([]byte(o.Data), &data) != nil {
+			continue
+		}
+		items, _ := data["items"].([]interface{})
+		key := strings.ToLower(strings.TrimSpace(o.Name))
+		if _, dup := b.byName[key]; !dup {
+			b.byName[key] = &kanbanColumn{object: o, data: data, items: items, existing: true}
+		}
+	}
+	return b
+}
+
+func (b *kanbanBuilder) bind(columns map[string]int) (err error) {
+	b.f, err = bindFields(columns,
+		binding{"name", b.m.Name, true},
+		binding{"status", b.m.Status, true},
+		binding{"color", b.m.Color, false})
+	return err
+}
+
+func (b *kanbanBuilder) add(r row) []RowError {
+	var errs []RowError
+	title := b.f.get(r, "name")
+	if title == "" {
+		errs = append(errs, b.f.fail(r, "name", "is required"))
+	}
+	status := b.f.get(r, "status")
+	if status == "" {
+		errs = append(errs, b.f.fail(r, "status", "is required"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	key := strings.ToLower(status)
+	col, ok := b.byName[key]
+	if !ok {
+		col = &kanbanColumn{
+			object: model.ViewObject{ID: util.NewId(), Name: status, Type: "kanban_column"},
+			data:   map[string]interface{}{},
+		}
+		if color := b.f.get(r, "color"); color != "" {
+			col.data["color"] = color
+		}
+		b.byName[key] = col
+	}
+	if !col.changed {
+		col.changed = true
+		b.columns = append(b.columns, col)
+	}
+	col.items = append(col.items, map[string]interface{}{"id": util.NewId(), "title": title})
+	return nil
+}
+
+func (b *kanbanBuilder) objects() (create, update []model.ViewObject) {
+	for _, col := range b.columns {
+		col.data["items"] = col.items
+		encoded, _ := json.Marshal(col.data)
+		o := col.object
+		o.Data = string(encoded)
+		if col.existing {
+			update = append(update, o)
+		} else {
+			create = append(create, o)
+		}
+	}
+	return create, update
+}
@@ -0,0 +1,177 @@
+package csvimport
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/model"
+)
+
+// objectData decodes the data of the objects, keyed by name.
+func objectData(t *testing.T, objects []model.ViewObject) map[string]map[string]interface{} {
+	t.Helper()
+	out := map[string]map[string]interface{}{}
+	for _, o := range objects {
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(o.Data), &data); err != nil {
+			t.Fatalf("data of %s: %v", o.Name, err)
+		}
+		out[o.Name] = data
+	}
+	return out
+}
+
+func TestPlanCalendar(t *testing.T) {
+	file := "\ufeffTitle,Day,Ends,From,To,All day\n" +
+		"Standup,03/07/2024,,9:30 am,09:45,no\n" +
+		"Offsite,03/08/2024,03/09/2024,,,\n" +
+		"\n" +
+		",03/10/2024,,,,\n" +
+		"Review,2024-03-11,,25:00,,maybe\n"
+	m := Mapping{Name: "title", Date: " Day ", EndDate: "Ends", StartTime: "From", EndTime: "To", AllDay: "All Day", DateFormat: "MM/DD/YYYY"}
+
+	res, err := Plan("calendar", strings.NewReader(file), m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rows != 4 || res.Valid != 2 {
+		t.Errorf("rows = %d, valid = %d, want 4 and 2", res.Rows, res.Valid)
+	}
+	wantErrors := []RowError{
+		{Row: 5, Column: "title", Message: "is required"},
+		{Row: 6, Column: "Day", Message: `"2024-03-11" is not a date in the expected format`},
+		{Row: 6, Column: "From", Message: `"25:00" is not a time of day`},
+		{Row: 6, Column: "All Day", Message: `"maybe" is not yes or no`},
+	}
+	if !reflect.DeepEqual(res.Errors, wantErrors) {
+		t.Errorf("errors = %+v, want %+v", res.Errors, wantErrors)
+	}
+
+	want := map[string]map[string]interface{}{
+		"Standup": {"date": "2024-03-07", "start_time": "09:30", "end_time": "09:45", "is_all_day": false},
+		"Offsite": {"date": "2024-03-08", "end_date": "2024-03-09", "is_all_day": false},
+	}
+	if got := objectData(t, res.Create); !reflect.DeepEqual(got, want) {
+		t.Errorf("objects = %v, want %v", got, want)
+	}
+	for _, o := range res.Create {
+		if o.Type != "calendar_slot" || o.ID == "" {
+			t.Errorf("object %s has type %q and id %q", o.Name, o.Type, o.ID)
+		}
+	}
+}
+
+func TestPlanCalendarAllDay(t *testing.T) {
+	// Without an all-day column, events without a start time are all day.
+	file := "name;date;start\nHoliday;2024-12-25;\nCall;2024-12-26;10:00\n"
+	res, err := Plan("calendar", strings.NewReader(file), Mapping{Name: "name", Date: "date", StartTime: "start", Delimiter: ";"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]interface{}{
+		"Holiday": {"date": "2024-12-25", "is_all_day": true},
+		"Call":    {"date": "2024-12-26", "start_time": "10:00", "is_all_day": false},
+	}
+	if got := objectData(t, res.Create); !reflect.DeepEqual(got, want) {
+		t.Errorf("objects = %v, want %v", got, want)
+	}
+}
+
+func TestPlanMap(t *testing.T) {
+	file := "place,latitude,longitude,colour\n" +
+		"Office,25.03,121.56,#ff0000\n" +
+		"Nowhere,north,121.56,\n" +
+		"Pole,91,0,\n"
+	m := Mapping{Name: "place", Lat: "latitude", Lng: "longitude", Color: "colour"}
+
+	res, err := Plan("map", strings.NewReader(file), m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]interface{}{
+		"Office": {"lat": 25.03, "lng": 121.56, "color": "#ff0000"},
+	}
+	if got := objectData(t, res.Create); !reflect.DeepEqual(got, want) {
+		t.Errorf("objects = %v, want %v", got, want)
+	}
+	if len(res.Errors) != 2 {
+		t.Fatalf("errors = %+v, want 2", res.Errors)
+	}
+	if e := res.Errors[0]; e.Row != 3 || e.Column != "latitude" {
+		t.Errorf("first error = %+v, want row 3 column latitude", e)
+	}
+	if e := res.Errors[1]; e.Row != 4 || e.Column != "latitude" {
+		t.Errorf("second error = %+v, want row 4 column latitude", e)
+	}
+}
+
+func TestPlanKanban(t *testing.T) {
+	existing := []model.ViewObject{
+		{ID: "todo", Name: "To do", Type: "kanban_column", Data: `{"color":"blue","items":[{"id":"c0","title":"Existing"}]}`},
+		{ID: "other", Name: "Done", Type: "kanban_column", Data: `{"items":[]}`},
+	}
+	file := "task,state\nWrite,to do\nShip,Doing\nTest,TO DO\n"
+
+	res, err := Plan("kanban", strings.NewReader(file), Mapping{Name: "task", Status: "state"}, existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	titles := func(o model.ViewObject) []string {
+		var data struct {
+			Items []struct {
+				Title string `json:"title"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(o.Data), &data); err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, item := range data.Items {
+			out = append(out, item.Title)
+		}
+		return out
+	}
+	if len(res.Update) != 1 || res.Update[0].ID != "todo" {
+		t.Fatalf("updated columns = %+v, want the To do column", res.Update)
+	}
+	if got, want := titles(res.Update[0]), []string{"Existing", "Write", "Test"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cards of To do = %v, want %v", got, want)
+	}
+	if !strings.Contains(res.Update[0].Data, `"color":"blue"`) {
+		t.Errorf("To do lost its color: %s", res.Update[0].Data)
+	}
+	if len(res.Create) != 1 || res.Create[0].Name != "Doing" {
+		t.Fatalf("created columns = %+v, want Doing", res.Create)
+	}
+	if got, want := titles(res.Create[0]), []string{"Ship"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cards of Doing = %v, want %v", got, want)
+	}
+}
+
+func TestPlanErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		viewType string
+		file     string
+		m        Mapping
+		want     string
+	}{
+		{name: "unsupported view", viewType: "whiteboard", file: "a\n", m: Mapping{}, want: "not supported"},
+		{name: "empty file", viewType: "map", file: "", m: Mapping{Name: "n", Lat: "a", Lng: "b"}, want: "empty"},
+		{name: "missing mapping", viewType: "map", file: "n,a,b\n", m: Mapping{Name: "n", Lat: "a"}, want: "mapping for lng is required"},
+		{name: "unknown column", viewType: "map", file: "n,a,b\n", m: Mapping{Name: "n", Lat: "a", Lng: "c"}, want: `column "c"`},
+		{name: "bad delimiter", viewType: "map", file: "n,a,b\n", m: Mapping{Name: "n", Lat: "a", Lng: "b", Delimiter: "::"}, want: "single character"},
+		{name: "bad date format", viewType: "calendar", file: "n,d\n", m: Mapping{Name: "n", Date: "d", DateFormat: "D/M/Y"}, want: "unsupported date format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Plan(tt.viewType, strings.NewReader(tt.file), tt.m, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Plan error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
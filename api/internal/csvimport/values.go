@@ -0,0 +1,79 @@
+package csvimport
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayouts maps the date formats a mapping may name to Go layouts.
+var dateLayouts = map[string]string{
+	"YYYY-MM-DD": "2006-01-02",
+	"YYYY/MM/DD": "2006/01/02",
+	"MM/DD/YYYY": "01/02/2006",
+	"DD/MM/YYYY": "02/01/2006",
+	"DD.MM.YYYY": "02.01.2006",
+}
+
+func dateLayout(format string) (string, error) {
+	if format == "" {
+		format = "YYYY-MM-DD"
+	}
+	layout, ok := dateLayouts[strings.ToUpper(format)]
+	if !ok {
+		return "", fmt.Errorf("unsupported date format %q", format)
+	}
+	return layout, nil
+}
+
+// parseDate reads a date in the layout of the mapping and returns it as
+// YYYY-MM-DD. Single-digit days and months are accepted, and a time of day
+// after the date is ignored.
+func parseDate(s, layout string) (string, error) {
+	if i := strings.IndexAny(s, " T"); i > 0 {
+		s = s[:i]
+	}
+	for _, l := range []string{layout, strings.NewReplacer("01", "1", "02", "2").Replace(layout)} {
+		if t, err := time.Parse(l, s); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	return "", fmt.Errorf("%q is not a date in the expected format", s)
+}
+
+var clockLayouts = []string{"15:04", "15:04:05", "3:04 PM", "3:04PM", "3:04:05 PM", "3 PM", "3PM"}
+
+// parseClock reads a time of day, in 24-hour or 12-hour form, and returns
+// it as HH:MM.
+func parseClock(s string) (string, error) {
+	upper := strings.ToUpper(s)
+	for _, l := range clockLayouts {
+		if t, err := time.Parse(l, upper); err == nil {
+			return t.Format("15:04"), nil
+		}
+	}
+	return "", fmt.Errorf("%q is not a time of day", s)
+}
+
+// parseBool reads the yes/no values spreadsheets commonly export. Empty
+// cells are false.
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "", "false", "no", "n", "0", "off":
+		return false, nil
+	case "true", "yes", "y", "1", "x", "on":
+		return true, nil
+	}
+	return false, fmt.Errorf("%q is not yes or no", s)
+}
+
+func parseNumber(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, errors.New("must be a number")
+	}
+	return f, nil
+}
@@ -0,0 +1,120 @@
+package csvimport
+
+import "testing"
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in      string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{in: "2024-03-07", want: "2024-03-07"},
+		{in: "2024-3-7", want: "2024-03-07"},
+		{in: "2024-03-07 14:30", want: "2024-03-07"},
+		{in: "2024-03-07T14:30:00Z", want: "2024-03-07"},
+		{in: "2024/03/07", format: "YYYY/MM/DD", want: "2024-03-07"},
+		{in: "03/07/2024", format: "MM/DD/YYYY", want: "2024-03-07"},
+		{in: "07/03/2024", format: "dd/mm/yyyy", want: "2024-03-07"},
+		{in: "7.3.2024", format: "DD.MM.YYYY", want: "2024-03-07"},
+		{in: "03/07/2024", wantErr: true},
+		{in: "2024-02-30", wantErr: true},
+		{in: "tomorrow", wantErr: true},
+	}
+	for _, tt := range tests {
+		layout, err := dateLayout(tt.format)
+		if err != nil {
+			t.Fatalf("dateLayout(%q): %v", tt.format, err)
+		}
+		got, err := parseDate(tt.in, layout)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDate(%q, %q) error = %v, want error %v", tt.in, tt.format, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseDate(%q, %q) = %q, want %q", tt.in, tt.format, got, tt.want)
+		}
+	}
+
+	if _, err := dateLayout("YYYYMMDD"); err == nil {
+		t.Error("dateLayout(YYYYMMDD) succeeded, want an error")
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "09:30", want: "09:30"},
+		{in: "9:30", want: "09:30"},
+		{in: "21:05:59", want: "21:05"},
+		{in: "9:30 pm", want: "21:30"},
+		{in: "12:15AM", want: "00:15"},
+		{in: "3 PM", want: "15:00"},
+		{in: "25:00", wantErr: true},
+		{in: "noon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseClock(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseClock(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseClock(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseBool(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    bool
+		wantErr bool
+	}{
+		{in: "", want: false},
+		{in: "No", want: false},
+		{in: "0", want: false},
+		{in: "TRUE", want: true},
+		{in: "y", want: true},
+		{in: "x", want: true},
+		{in: "maybe", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseBool(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseBool(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseBool(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{in: "25.03", want: 25.03},
+		{in: "-121.5", want: -121.5},
+		{in: "1e2", want: 100},
+		{in: "NaN", wantErr: true},
+		{in: "Inf", wantErr: true},
+		{in: "25,03", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseNumber(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseNumber(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseNumber(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
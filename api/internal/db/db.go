@@ -15,8 +15,15 @@ type DB interface {
 	WorkspaceUserRepository
 	ViewRepository
 	ViewObjectRepository
+	CanvasObjectRepository
 	WidgetRepository
 	APIKeyRepository
+	TableRepository
+	ViewSnapshotRepository
+	ViewTemplateRepository
+	RSSRepository
+	LinkPreviewRepository
+	UploadRepository
 }
 type Uow interface {
 	Begin(ctx context.Context) (DB, error)
@@ -44,7 +51,14 @@ type FileRepository interface {
 	FindFiles(f model.FileFilter) ([]model.File, error)
 	FindFileByID(id string) (model.File, error)
 	UpdateFile(f model.File) error
-	DeleteFile(f model.FileFilter) error
+	// DeleteFile deletes the file of a workspace with the id of the filter,
+	// and returns the number of files deleted.
+	DeleteFile(f model.FileFilter) (int, error)
+	FindFileUsage(f model.FileFilter) ([]model.FileUsage, error)
+	SaveFileVariant(v model.FileVariant) error
+	FindFileVariant(workspaceID, fileName, variant string) (model.FileVariant, error)
+	FindFileVariants(workspaceID, fileName string) ([]model.FileVariant, error)
+	DeleteFileVariants(workspaceID, fileName string) error
 }
 type WorkspaceRepository interface {
 	FindWorkspaces(f model.WorkspaceFilter) ([]model.Workspace, error)
@@ -73,6 +87,11 @@ type ViewObjectRepository interface {
 	FindViewObject(v model.ViewObject) (model.ViewObject, error)
 	FindViewObjects(f model.ViewObjectFilter) ([]model.ViewObject, error)
 }
+type CanvasObjectRepository interface {
+	SaveCanvasObject(o model.CanvasObject) error
+	DeleteCanvasObjects(viewID string, ids []string) error
+	FindCanvasObjects(f model.CanvasObjectFilter) ([]model.CanvasObject, error)
+}
 type WidgetRepository interface {
 	CreateWidget(w model.Widget) error
 	UpdateWidget(w model.Widget) error
@@ -88,3 +107,56 @@ type APIKeyRepository interface {
 	UpdateAPIKey(k model.APIKey) error
 	DeleteAPIKey(id string) error
 }
+type TableRepository interface {
+	CreateTableRow(r model.TableRow) error
+	DeleteTableRow(r model.TableRow) error
+	FindTableRows(f model.TableRowFilter) ([]model.TableRow, error)
+	SaveNoteProperty(p model.NoteProperty) error
+	DeleteNoteProperty(p model.NoteProperty) error
+	FindNoteProperties(f model.NotePropertyFilter) ([]model.NoteProperty, error)
+}
+type ViewSnapshotRepository interface {
+	CreateViewSnapshot(s model.ViewSnapshot) error
+	DeleteViewSnapshot(s model.ViewSnapshot) error
+	FindViewSnapshot(s model.ViewSnapshot) (model.ViewSnapshot, error)
+	FindViewSnapshots(f model.ViewSnapshotFilter) ([]model.ViewSnapshot, error)
+	SetViewData(v model.View) error
+	FindChangedViews(f model.ChangedViewFilter) ([]model.View, error)
+}
+type ViewTemplateRepository interface {
+	CreateViewTemplate(t model.ViewTemplate) error
+	UpdateViewTemplate(t model.ViewTemplate) error
+	DeleteViewTemplate(t model.ViewTemplate) error
+	FindViewTemplate(t model.ViewTemplate) (model.ViewTemplate, error)
+	FindViewTemplates(f model.ViewTemplateFilter) ([]model.ViewTemplate, error)
+}
+type RSSRepository interface {
+	CreateRSSFeed(f model.RSSFeed) error
+	UpdateRSSFeed(f model.RSSFeed) error
+	DeleteRSSFeed(f model.RSSFeed) error
+	FindRSSFeed(f model.RSSFeed) (model.RSSFeed, error)
+	FindRSSFeeds(f model.RSSFeedFilter) ([]model.RSSFeed, error)
+	SaveRSSItem(i model.RSSItem) error
+	FindRSSItem(i model.RSSItem) (model.RSSItem, error)
+	FindRSSItems(f model.RSSItemFilter) ([]model.RSSItem, error)
+	CountUnreadRSSItems(userID string, feedID string) (int64, error)
+	PruneRSSItems(feedID string, keep int) error
+	CreateRSSSubscription(s model.RSSSubscription) error
+	DeleteRSSSubscription(s model.RSSSubscription) error
+	FindRSSSubscriptions(f model.RSSSubscriptionFilter) ([]model.RSSSubscription, error)
+	MarkRSSItemsRead(userID string, itemIDs []string, readAt string) error
+	MarkRSSItemsUnread(userID string, itemIDs []string) error
+	FindRSSItemReads(userID string, itemIDs []string) ([]model.RSSItemRead, error)
+}
+type LinkPreviewRepository interface {
+	SaveLinkPreview(p model.LinkPreview) error
+	FindLinkPreview(url string) (model.LinkPreview, error)
+	DeleteExpiredLinkPreviews(before string) error
+}
+type UploadRepository interface {
+	CreateUpload(u model.Upload) error
+	FindUpload(id string) (model.Upload, error)
+	FindUploads(f model.UploadFilter) ([]model.Upload, error)
+	UpdateUpload(u model.Upload) error
+	DeleteUpload(id string) error
+}
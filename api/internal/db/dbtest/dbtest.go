@@ -0,0 +1,58 @@
+// Package dbtest opens databases for tests of code that reads and writes
+// through db.DB, so that they run against the queries the server runs.
+package dbtest
+
+import (
+	"database/sql"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/config"
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/db/sqlitedb"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// New returns an sqlite database in a temporary directory of the test,
+// with every migration applied.
+func New(t testing.TB) db.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "collabreef.db") + "?_busy_timeout=5000"
+
+	sqlDB, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	driver, err := sqlite3.WithInstance(sqlDB, &sqlite3.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://"+migrations(), "main", driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("applying migrations: %v", err)
+	}
+
+	if config.C == nil {
+		config.Init()
+	}
+	config.C.Set(config.DB_DSN, dsn)
+	d, err := sqlitedb.NewSqliteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// migrations returns the directory of the sqlite3 migrations, which is
+// found from this file as tests run in the directory of their package.
+func migrations() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "migrations", "sqlite3")
+}
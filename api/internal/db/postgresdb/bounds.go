@@ -0,0 +1,22 @@
+package postgresdb
+
+import (
+	"fmt"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"gorm.io/gorm"
+)
+
+// inBBox selects the rows of a table with bounds, view_objects or
+// canvas_objects, that belong to a view and intersect a box, together with
+// the rows that have no bounds. The box expression matches the GiST index of
+// the table, and the rows without bounds are selected apart so that the
+// index stays usable.
+func inBBox(db *gorm.DB, table, viewID string, b model.BBox) *gorm.DB {
+	return db.Raw(fmt.Sprintf(`SELECT * FROM %[1]s
+WHERE view_id = ? AND min_x IS NOT NULL AND box(point(min_x, min_y), point(max_x, max_y)) && box(point(?, ?), point(?, ?))
+UNION ALL
+SELECT * FROM %[1]s WHERE view_id = ? AND min_x IS NULL`, table),
+		viewID, b.MinX, b.MinY, b.MaxX, b.MaxY,
+		viewID)
+}
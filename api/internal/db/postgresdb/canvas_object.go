@@ -0,0 +1,39 @@
+package postgresdb
+
+import (
+	"context"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveCanvasObject records an entry of a whiteboard canvas, replacing an
+// earlier one with the same id, bounds included.
+func (s PostgresDB) SaveCanvasObject(o model.CanvasObject) error {
+	return gorm.G[model.CanvasObject](s.getDB(), clause.OnConflict{
+		Columns:   []clause.Column{{Name: "view_id"}, {Name: "id"}},
+		UpdateAll: true,
+	}).Create(context.Background(), &o)
+}
+
+func (s PostgresDB) DeleteCanvasObjects(viewID string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := gorm.G[model.CanvasObject](s.getDB()).
+		Where("view_id = ? AND id IN ?", viewID, ids).
+		Delete(context.Background())
+	return err
+}
+
+func (s PostgresDB) FindCanvasObjects(f model.CanvasObjectFilter) ([]model.CanvasObject, error) {
+	if f.BBox == nil {
+		return gorm.G[model.CanvasObject](s.getDB()).Where("view_id = ?", f.ViewID).Order("id ASC").Find(context.Background())
+	}
+
+	var objects []model.CanvasObject
+	err := s.getDB().Raw(`SELECT * FROM (?) AS canvas_objects ORDER BY id ASC`,
+		inBBox(s.getDB(), "canvas_objects", f.ViewID, *f.BBox)).Scan(&objects).Error
+	return objects, err
+}
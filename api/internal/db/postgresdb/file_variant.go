@@ -0,0 +1,37 @@
+package postgresdb
+
+import (
+	"context"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveFileVariant records a variant of a file, replacing an earlier one of
+// the same name.
+func (s PostgresDB) SaveFileVariant(v model.FileVariant) error {
+	return gorm.G[model.FileVariant](s.getDB(), clause.OnConflict{
+		Columns:   []clause.Column{{Name: "workspace_id"}, {Name: "file_name"}, {Name: "variant"}},
+		UpdateAll: true,
+	}).Create(context.Background(), &v)
+}
+
+func (s PostgresDB) FindFileVariant(workspaceID, fileName, variant string) (model.FileVariant, error) {
+	return gorm.G[model.FileVariant](s.getDB()).
+		Where("workspace_id = ? AND file_name = ? AND variant = ?", workspaceID, fileName, variant).
+		Take(context.Background())
+}
+
+func (s PostgresDB) FindFileVariants(workspaceID, fileName string) ([]model.FileVariant, error) {
+	return gorm.G[model.FileVariant](s.getDB()).
+		Where("workspace_id = ? AND file_name = ?", workspaceID, fileName).
+		Find(context.Background())
+}
+
+func (s PostgresDB) DeleteFileVariants(workspaceID, fileName string) error {
+	_, err := gorm.G[model.FileVariant](s.getDB()).
+		Where("workspace_id = ? AND file_name = ?", workspaceID, fileName).
+		Delete(context.Background())
+	return err
+}
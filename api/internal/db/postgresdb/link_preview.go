@@ -0,0 +1,28 @@
+package postgresdb
+
+import (
+	"context"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveLinkPreview stores the metadata of a URL, replacing any earlier lookup.
+func (s PostgresDB) SaveLinkPreview(p model.LinkPreview) error {
+	return gorm.G[model.LinkPreview](s.getDB(), clause.OnConflict{
+		Columns:   []clause.Column{{Name: "url"}},
+		UpdateAll: true,
+	}).Create(context.Background(), &p)
+}
+
+func (s PostgresDB) FindLinkPreview(url string) (model.LinkPreview, error) {
+	return gorm.G[model.LinkPreview](s.getDB()).Where("url = ?", url).Take(context.Background())
+}
+
+// DeleteExpiredLinkPreviews removes the lookups that expired before the given
+// RFC 3339 time.
+func (s PostgresDB) DeleteExpiredLinkPreviews(before string) error {
+	_, err := gorm.G[model.LinkPreview](s.getDB()).Where("expires_at < ?", before).Delete(context.Background())
+	return err
+}
@@ -0,0 +1,193 @@
+package postgresdb
+
+import (
+	"context"
+	"strings"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (s PostgresDB) CreateRSSFeed(f model.RSSFeed) error {
+	return gorm.G[model.RSSFeed](s.getDB()).Create(context.Background(), &f)
+}
+
+// UpdateRSSFeed writes the fetch state of a feed, including empty values.
+func (s PostgresDB) UpdateRSSFeed(f model.RSSFeed) error {
+	return s.getDB().Model(&model.RSSFeed{}).
+		Where("id = ?", f.ID).
+		Select("title", "description", "link", "etag", "last_modified", "fetched_at", "next_fetch_at", "failures", "last_error").
+		Updates(f).Error
+}
+
+func (s PostgresDB) DeleteRSSFeed(f model.RSSFeed) error {
+	_, err := gorm.G[model.RSSFeed](s.getDB()).Where("id = ?", f.ID).Delete(context.Background())
+	return err
+}
+
+// FindRSSFeed finds a feed by id, or by URL when no id is given.
+func (s PostgresDB) FindRSSFeed(f model.RSSFeed) (model.RSSFeed, error) {
+	if f.ID == "" {
+		return gorm.G[model.RSSFeed](s.getDB()).Where("url = ?", f.URL).Take(context.Background())
+	}
+	return gorm.G[model.RSSFeed](s.getDB()).Where("id = ?", f.ID).Take(context.Background())
+}
+
+func (s PostgresDB) FindRSSFeeds(f model.RSSFeedFilter) ([]model.RSSFeed, error) {
+	var feeds []model.RSSFeed
+
+	var conds []string
+	var args []interface{}
+
+	if f.UserID != "" {
+		conds = append(conds, "id IN (SELECT feed_id FROM rss_subscriptions WHERE user_id = ?)")
+		args = append(args, f.UserID)
+	}
+
+	if f.Subscribed {
+		conds = append(conds, "id IN (SELECT feed_id FROM rss_subscriptions)")
+	}
+
+	query := s.getDB().Model(&model.RSSFeed{})
+
+	if len(conds) > 0 {
+		query = query.Where(strings.Join(conds, " AND "), args...)
+	}
+
+	err := query.Order("title ASC").Find(&feeds).Error
+
+	return feeds, err
+}
+
+// SaveRSSItem inserts an item, or refreshes the item with the same GUID in
+// its feed. The id and first-seen time of an existing item are kept.
+func (s PostgresDB) SaveRSSItem(i model.RSSItem) error {
+	return gorm.G[model.RSSItem](s.getDB(), clause.OnConflict{
+		Columns:   []clause.Column{{Name: "feed_id"}, {Name: "guid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title", "link", "description", "pub_date", "image_url"}),
+	}).Create(context.Background(), &i)
+}
+
+func (s PostgresDB) FindRSSItem(i model.RSSItem) (model.RSSItem, error) {
+	return gorm.G[model.RSSItem](s.getDB()).Where("id = ?", i.ID).Take(context.Background())
+}
+
+// FindRSSItems lists the items of a feed, newest first.
+func (s PostgresDB) FindRSSItems(f model.RSSItemFilter) ([]model.RSSItem, error) {
+	var items []model.RSSItem
+
+	var conds []string
+	var args []interface{}
+
+	if f.FeedID != "" {
+		conds = append(conds, "feed_id = ?")
+		args = append(args, f.FeedID)
+	}
+
+	if f.Unread && f.UserID != "" {
+		conds = append(conds, "id NOT IN (SELECT item_id FROM rss_item_reads WHERE user_id = ?)")
+		args = append(args, f.UserID)
+	}
+
+	query := s.getDB().Model(&model.RSSItem{})
+
+	if len(conds) > 0 {
+		query = query.Where(strings.Join(conds, " AND "), args...)
+	}
+
+	err := query.
+		Order("published_at DESC").
+		Order("created_at DESC").
+		Offset((f.PageNumber - 1) * f.PageSize).
+		Limit(f.PageSize).
+		Find(&items).Error
+
+	return items, err
+}
+
+func (s PostgresDB) CountUnreadRSSItems(userID string, feedID string) (int64, error) {
+	var count int64
+	err := s.getDB().Model(&model.RSSItem{}).
+		Where("feed_id = ? AND id NOT IN (SELECT item_id FROM rss_item_reads WHERE user_id = ?)", feedID, userID).
+		Count(&count).Error
+	return count, err
+}
+
+// PruneRSSItems deletes the items of a feed beyond the newest keep.
+func (s PostgresDB) PruneRSSItems(feedID string, keep int) error {
+	return s.getDB().Exec(
+		"DELETE FROM rss_items WHERE feed_id = ? AND id NOT IN (SELECT id FROM rss_items WHERE feed_id = ? ORDER BY published_at DESC, created_at DESC LIMIT ?)",
+		feedID, feedID, keep,
+	).Error
+}
+
+func (s PostgresDB) CreateRSSSubscription(r model.RSSSubscription) error {
+	return gorm.G[model.RSSSubscription](s.getDB(), clause.OnConflict{DoNothing: true}).Create(context.Background(), &r)
+}
+
+func (s PostgresDB) DeleteRSSSubscription(r model.RSSSubscription) error {
+	_, err := gorm.G[model.RSSSubscription](s.getDB()).
+		Where("user_id = ? AND feed_id = ?", r.UserID, r.FeedID).
+		Delete(context.Background())
+	return err
+}
+
+func (s PostgresDB) FindRSSSubscriptions(f model.RSSSubscriptionFilter) ([]model.RSSSubscription, error) {
+	var subscriptions []model.RSSSubscription
+
+	var conds []string
+	var args []interface{}
+
+	if f.UserID != "" {
+		conds = append(conds, "user_id = ?")
+		args = append(args, f.UserID)
+	}
+
+	if f.FeedID != "" {
+		conds = append(conds, "feed_id = ?")
+		args = append(args, f.FeedID)
+	}
+
+	query := s.getDB().Model(&model.RSSSubscription{})
+
+	if len(conds) > 0 {
+		query = query.Where(strings.Join(conds, " AND "), args...)
+	}
+
+	err := query.Order("created_at ASC").Find(&subscriptions).Error
+
+	return subscriptions, err
+}
+
+func (s PostgresDB) MarkRSSItemsRead(userID string, itemIDs []string, readAt string) error {
+	if len(itemIDs) == 0 {
+		return nil
+	}
+	reads := make([]model.RSSItemRead, len(itemIDs))
+	for i, id := range itemIDs {
+		reads[i] = model.RSSItemRead{UserID: userID, ItemID: id, ReadAt: readAt}
+	}
+	return s.getDB().Clauses(clause.OnConflict{DoNothing: true}).Create(&reads).Error
+}
+
+func (s PostgresDB) MarkRSSItemsUnread(userID string, itemIDs []string) error {
+	if len(itemIDs) == 0 {
+		return nil
+	}
+	_, err := gorm.G[model.RSSItemRead](s.getDB()).
+		Where("user_id = ? AND item_id IN ?", userID, itemIDs).
+		Delete(context.Background())
+	return err
+}
+
+func (s PostgresDB) FindRSSItemReads(userID string, itemIDs []string) ([]model.RSSItemRead, error) {
+	var reads []model.RSSItemRead
+	if len(itemIDs) == 0 {
+		return reads, nil
+	}
+	err := s.getDB().Model(&model.RSSItemRead{}).
+		Where("user_id = ? AND item_id IN ?", userID, itemIDs).
+		Find(&reads).Error
+	return reads, err
+}
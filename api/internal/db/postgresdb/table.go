@@ -0,0 +1,90 @@
+package postgresdb
+
+import (
+	"context"
+	"strings"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (s PostgresDB) CreateTableRow(r model.TableRow) error {
+	return gorm.G[model.TableRow](s.getDB(), clause.OnConflict{DoNothing: true}).Create(context.Background(), &r)
+}
+
+func (s PostgresDB) DeleteTableRow(r model.TableRow) error {
+	_, err := gorm.G[model.TableRow](s.getDB()).
+		Where("view_id = ? AND note_id = ?", r.ViewID, r.NoteID).
+		Delete(context.Background())
+	return err
+}
+
+func (s PostgresDB) FindTableRows(f model.TableRowFilter) ([]model.TableRow, error) {
+	var rows []model.TableRow
+
+	var conds []string
+	var args []interface{}
+
+	if f.ViewID != "" {
+		conds = append(conds, "view_id = ?")
+		args = append(args, f.ViewID)
+	}
+
+	if f.NoteID != "" {
+		conds = append(conds, "note_id = ?")
+		args = append(args, f.NoteID)
+	}
+
+	query := s.getDB().Model(&model.TableRow{})
+
+	if len(conds) > 0 {
+		query = query.Where(strings.Join(conds, " AND "), args...)
+	}
+
+	err := query.Order("created_at ASC").Find(&rows).Error
+
+	return rows, err
+}
+
+// SaveNoteProperty inserts or replaces the value of a property for a note.
+func (s PostgresDB) SaveNoteProperty(p model.NoteProperty) error {
+	return gorm.G[model.NoteProperty](s.getDB(), clause.OnConflict{
+		Columns:   []clause.Column{{Name: "note_id"}, {Name: "property_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at", "updated_by"}),
+	}).Create(context.Background(), &p)
+}
+
+func (s PostgresDB) DeleteNoteProperty(p model.NoteProperty) error {
+	_, err := gorm.G[model.NoteProperty](s.getDB()).
+		Where("note_id = ? AND property_id = ?", p.NoteID, p.PropertyID).
+		Delete(context.Background())
+	return err
+}
+
+func (s PostgresDB) FindNoteProperties(f model.NotePropertyFilter) ([]model.NoteProperty, error) {
+	var props []model.NoteProperty
+
+	var conds []string
+	var args []interface{}
+
+	if len(f.NoteIDs) > 0 {
+		conds = append(conds, "note_id IN ?")
+		args = append(args, f.NoteIDs)
+	}
+
+	if len(f.PropertyIDs) > 0 {
+		conds = append(conds, "property_id IN ?")
+		args = append(args, f.PropertyIDs)
+	}
+
+	query := s.getDB().Model(&model.NoteProperty{})
+
+	if len(conds) > 0 {
+		query = query.Where(strings.Join(conds, " AND "), args...)
+	}
+
+	err := query.Find(&props).Error
+
+	return props, err
+}
@@ -0,0 +1,90 @@
+package postgresdb
+
+import (
+	"context"
+	"strings"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"gorm.io/gorm"
+)
+
+func (s PostgresDB) CreateViewSnapshot(v model.ViewSnapshot) error {
+	return gorm.G[model.ViewSnapshot](s.getDB()).Create(context.Background(), &v)
+}
+
+func (s PostgresDB) DeleteViewSnapshot(v model.ViewSnapshot) error {
+	_, err := gorm.G[model.ViewSnapshot](s.getDB()).Where("id = ?", v.ID).Delete(context.Background())
+	return err
+}
+
+func (s PostgresDB) FindViewSnapshot(v model.ViewSnapshot) (model.ViewSnapshot, error) {
+	return gorm.
+		G[model.ViewSnapshot](s.getDB()).
+		Where("id = ? AND view_id = ?", v.ID, v.ViewID).
+		Take(context.Background())
+}
+
+// FindViewSnapshots lists snapshots newest first, without their data and
+// objects.
+func (s PostgresDB) FindViewSnapshots(f model.ViewSnapshotFilter) ([]model.ViewSnapshot, error) {
+	var snapshots []model.ViewSnapshot
+
+	var conds []string
+	var args []interface{}
+
+	if f.ViewID != "" {
+		conds = append(conds, "view_id = ?")
+		args = append(args, f.ViewID)
+	}
+
+	if f.Kind != "" {
+		conds = append(conds, "kind = ?")
+		args = append(args, f.Kind)
+	}
+
+	query := s.getDB().Model(&model.ViewSnapshot{}).Omit("data", "objects")
+
+	if len(conds) > 0 {
+		query = query.Where(strings.Join(conds, " AND "), args...)
+	}
+
+	err := query.
+		Order("created_at DESC").
+		Offset((f.PageNumber - 1) * f.PageSize).
+		Limit(f.PageSize).
+		Find(&snapshots).Error
+
+	return snapshots, err
+}
+
+// SetViewData writes the data of a view even when it is empty, which
+// UpdateView skips.
+func (s PostgresDB) SetViewData(v model.View) error {
+	return s.getDB().Model(&model.View{}).
+		Where("id = ?", v.ID).
+		Select("data", "updated_at", "updated_by").
+		Updates(v).Error
+}
+
+// FindChangedViews compares each view with the latest of its snapshots, or
+// with f.Since when that is later. The API and the collab server write
+// timestamps in different formats, so they are compared to the second as
+// YYYY-MM-DD HH:MM:SS, and a change within the same second counts.
+func (s PostgresDB) FindChangedViews(f model.ChangedViewFilter) ([]model.View, error) {
+	var views []model.View
+	err := s.getDB().Raw(`WITH latest AS (
+	SELECT v.id AS view_id,
+		GREATEST(COALESCE((SELECT MAX(created_at) FROM view_snapshots WHERE view_id = v.id), ''), ?) AS checked_at,
+		(SELECT object_count FROM view_snapshots WHERE view_id = v.id ORDER BY created_at DESC LIMIT 1) AS object_count
+	FROM views AS v WHERE v.id > ?
+)
+SELECT views.* FROM views JOIN latest ON latest.view_id = views.id
+WHERE latest.checked_at = ''
+	OR substr(replace(views.updated_at, 'T', ' '), 1, 19) >= substr(replace(latest.checked_at, 'T', ' '), 1, 19)
+	OR EXISTS (SELECT 1 FROM view_objects WHERE view_id = views.id
+		AND substr(replace(updated_at, 'T', ' '), 1, 19) >= substr(replace(latest.checked_at, 'T', ' '), 1, 19))
+	OR latest.object_count <> (SELECT COUNT(*) FROM view_objects WHERE view_id = views.id)
+ORDER BY views.id
+LIMIT ?`, f.Since, f.AfterID, f.Limit).Scan(&views).Error
+	return views, err
+}
@@ -0,0 +1,67 @@
+package postgresdb
+
+import (
+	"context"
+	"strings"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"gorm.io/gorm"
+)
+
+func (s PostgresDB) CreateViewTemplate(t model.ViewTemplate) error {
+	return gorm.G[model.ViewTemplate](s.getDB()).Create(context.Background(), &t)
+}
+
+// UpdateViewTemplate writes the name, description and content of a
+// template, including empty values.
+func (s PostgresDB) UpdateViewTemplate(t model.ViewTemplate) error {
+	return s.getDB().Model(&model.ViewTemplate{}).
+		Where("id = ?", t.ID).
+		Select("name", "description", "data", "objects", "object_count", "updated_at", "updated_by").
+		Updates(t).Error
+}
+
+func (s PostgresDB) DeleteViewTemplate(t model.ViewTemplate) error {
+	_, err := gorm.G[model.ViewTemplate](s.getDB()).Where("id = ?", t.ID).Delete(context.Background())
+	return err
+}
+
+func (s PostgresDB) FindViewTemplate(t model.ViewTemplate) (model.ViewTemplate, error) {
+	return gorm.
+		G[model.ViewTemplate](s.getDB()).
+		Where("id = ? AND workspace_id = ?", t.ID, t.WorkspaceID).
+		Take(context.Background())
+}
+
+// FindViewTemplates lists templates by name, without their data and
+// objects.
+func (s PostgresDB) FindViewTemplates(f model.ViewTemplateFilter) ([]model.ViewTemplate, error) {
+	var templates []model.ViewTemplate
+
+	var conds []string
+	var args []interface{}
+
+	if f.WorkspaceID != "" {
+		conds = append(conds, "workspace_id = ?")
+		args = append(args, f.WorkspaceID)
+	}
+
+	if f.ViewType != "" {
+		conds = append(conds, "type = ?")
+		args = append(args, f.ViewType)
+	}
+
+	query := s.getDB().Model(&model.ViewTemplate{}).Omit("data", "objects")
+
+	if len(conds) > 0 {
+		query = query.Where(strings.Join(conds, " AND "), args...)
+	}
+
+	err := query.
+		Order("name ASC").
+		Offset((f.PageNumber - 1) * f.PageSize).
+		Limit(f.PageSize).
+		Find(&templates).Error
+
+	return templates, err
+}
@@ -0,0 +1,26 @@
+package sqlitedb
+
+import (
+	"fmt"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"gorm.io/gorm"
+)
+
+// inBBox selects the rows of a table with bounds, view_objects or
+// canvas_objects, that belong to a view and intersect a box, together with
+// the rows that have no bounds. Rows with bounds are found through the
+// R*Tree of the table: CROSS JOIN makes SQLite search it first, and the
+// unary + keeps it from looking the rows up by view instead of by bounds_id.
+// The R*Tree stores rounded coordinates, so the bounds are compared again on
+// the table.
+func inBBox(db *gorm.DB, table, viewID string, b model.BBox) *gorm.DB {
+	return db.Raw(fmt.Sprintf(`SELECT o.* FROM %[1]s_bounds AS b CROSS JOIN %[1]s AS o ON o.bounds_id = b.id
+WHERE b.min_x <= ? AND b.max_x >= ? AND b.min_y <= ? AND b.max_y >= ?
+AND +o.view_id = ? AND +o.min_x <= ? AND o.max_x >= ? AND o.min_y <= ? AND o.max_y >= ?
+UNION ALL
+SELECT * FROM %[1]s INDEXED BY idx_%[1]s_view_id WHERE view_id = ? AND min_x IS NULL`, table),
+		b.MaxX, b.MinX, b.MaxY, b.MinY,
+		viewID, b.MaxX, b.MinX, b.MaxY, b.MinY,
+		viewID)
+}
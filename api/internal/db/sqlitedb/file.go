@@ -26,6 +26,21 @@ func (s SqliteDB) FindFiles(f model.FileFilter) ([]model.File, error) {
 		args = append(args, f.ID)
 	}
 
+	if f.Name != "" {
+		conds = append(conds, "name = ?")
+		args = append(args, f.Name)
+	}
+
+	if f.CreatedBy != "" {
+		conds = append(conds, "created_by = ?")
+		args = append(args, f.CreatedBy)
+	}
+
+	if f.Hash != "" {
+		conds = append(conds, "hash = ?")
+		args = append(args, f.Hash)
+	}
+
 	if len(f.Exts) > 0 {
 		conds = append(conds, "ext IN ?")
 		args = append(args, f.Exts)
@@ -68,8 +83,28 @@ func (s SqliteDB) UpdateFile(f model.File) error {
 	return err
 }
 
-func (s SqliteDB) DeleteFile(f model.FileFilter) error {
-	_, err := gorm.G[model.File](s.getDB()).Where("workspace_id = ? AND id = ?", f.WorkspaceID, f.ID).Delete(context.Background())
+func (s SqliteDB) DeleteFile(f model.FileFilter) (int, error) {
+	return gorm.G[model.File](s.getDB()).Where("workspace_id = ? AND id = ?", f.WorkspaceID, f.ID).Delete(context.Background())
+}
 
-	return err
+func (s SqliteDB) FindFileUsage(f model.FileFilter) ([]model.FileUsage, error) {
+	query := s.getDB().
+		Model(&model.File{}).
+		Select("workspace_id, created_by, content_type, COUNT(*) AS files, COALESCE(SUM(size), 0) AS size")
+
+	if f.WorkspaceID != "" {
+		query = query.Where("workspace_id = ?", f.WorkspaceID)
+	}
+
+	if f.CreatedBy != "" {
+		query = query.Where("created_by = ?", f.CreatedBy)
+	}
+
+	var usage []model.FileUsage
+	err := query.
+		Group("workspace_id, created_by, content_type").
+		Order("size DESC").
+		Scan(&usage).Error
+
+	return usage, err
 }
@@ -46,12 +46,22 @@ func (s SqliteDB) FindNotes(f model.NoteFilter) ([]model.Note, error) {
 		args = append(args, f.WorkspaceID)
 	}
 
+	if len(f.NoteIDs) > 0 {
+		conds = append(conds, "id IN ?")
+		args = append(args, f.NoteIDs)
+	}
+
 	if f.Query != "" {
 		query := "%" + f.Query + "%"
 		conds = append(conds, "(title LIKE ? OR content LIKE ?)")
 		args = append(args, query, query)
 	}
 
+	if f.ContentContains != "" {
+		conds = append(conds, `content LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(f.ContentContains)+"%")
+	}
+
 	if f.UserID != "" {
 		permissionCond := `(
             visibility IN ('public', 'workspace')
@@ -70,6 +80,10 @@ func (s SqliteDB) FindNotes(f model.NoteFilter) ([]model.Note, error) {
 		args = append(args, f.ParentID)
 	}
 
+	if f.Clipped {
+		conds = append(conds, "(source_url IS NOT NULL AND source_url <> '')")
+	}
+
 	query := s.getDB().Model(&model.Note{})
 
 	if len(conds) > 0 {
@@ -153,3 +167,9 @@ func (s SqliteDB) GetNoteCountsByDate(workspaceID string, startDate string, time
 
 	return counts, nil
 }
+
+// escapeLike escapes the wildcards of a LIKE pattern, so that text is matched
+// literally.
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text)
+}
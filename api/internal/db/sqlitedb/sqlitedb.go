@@ -28,7 +28,7 @@ func (u *SqliteDB) Begin(c context.Context) (db.DB, error) {
 	if u.inTx {
 		return nil, errors.New("transaction already started")
 	}
-	tx := u.db.Begin()
+	tx := u.db.WithContext(c).Begin()
 	if tx.Error != nil {
 		return nil, u.tx.Error
 	}
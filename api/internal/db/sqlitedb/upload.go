@@ -0,0 +1,52 @@
+package sqlitedb
+
+import (
+	"context"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"gorm.io/gorm"
+)
+
+func (s SqliteDB) CreateUpload(u model.Upload) error {
+	return gorm.G[model.Upload](s.getDB()).Create(context.Background(), &u)
+}
+
+func (s SqliteDB) FindUpload(id string) (model.Upload, error) {
+	return gorm.G[model.Upload](s.getDB()).
+		Where("id = ?", id).
+		Take(context.Background())
+}
+
+func (s SqliteDB) FindUploads(f model.UploadFilter) ([]model.Upload, error) {
+	query := gorm.G[model.Upload](s.getDB()).Where("1 = 1")
+
+	if f.WorkspaceID != "" {
+		query = query.Where("workspace_id = ?", f.WorkspaceID)
+	}
+
+	if f.CreatedBy != "" {
+		query = query.Where("created_by = ?", f.CreatedBy)
+	}
+
+	if f.UpdatedBefore != "" {
+		query = query.Where("updated_at < ?", f.UpdatedBefore)
+	}
+
+	if f.Pending {
+		query = query.Where("(file_id IS NULL OR file_id = '')")
+	}
+
+	return query.Find(context.Background())
+}
+
+func (s SqliteDB) UpdateUpload(u model.Upload) error {
+	_, err := gorm.G[model.Upload](s.getDB()).Where("id = ?", u.ID).Updates(context.Background(), u)
+
+	return err
+}
+
+func (s SqliteDB) DeleteUpload(id string) error {
+	_, err := gorm.G[model.Upload](s.getDB()).Where("id = ?", id).Delete(context.Background())
+
+	return err
+}
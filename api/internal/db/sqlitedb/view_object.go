@@ -12,9 +12,22 @@ func (s SqliteDB) CreateViewObject(v model.ViewObject) error {
 	return gorm.G[model.ViewObject](s.getDB()).Create(context.Background(), &v)
 }
 
+// UpdateViewObject updates the fields of v that are set. The bounds are
+// derived from the data, so they are written with it, even when nil, and an
+// object that no longer has geometry keeps none.
 func (s SqliteDB) UpdateViewObject(v model.ViewObject) error {
-	_, err := gorm.G[model.ViewObject](s.getDB()).Where("id = ?", v.ID).Updates(context.Background(), v)
-	return err
+	if _, err := gorm.G[model.ViewObject](s.getDB()).Where("id = ?", v.ID).Updates(context.Background(), v); err != nil {
+		return err
+	}
+	if v.Data == "" {
+		return nil
+	}
+	return s.getDB().Model(&model.ViewObject{}).Where("id = ?", v.ID).Updates(map[string]interface{}{
+		"min_x": v.MinX,
+		"min_y": v.MinY,
+		"max_x": v.MaxX,
+		"max_y": v.MaxY,
+	}).Error
 }
 
 func (s SqliteDB) DeleteViewObject(v model.ViewObject) error {
@@ -54,6 +67,10 @@ func (s SqliteDB) FindViewObjects(f model.ViewObjectFilter) ([]model.ViewObject,
 
 	query := s.getDB().Model(&model.ViewObject{})
 
+	if f.BBox != nil {
+		query = s.getDB().Table("(?) AS view_objects", inBBox(s.getDB(), "view_objects", f.ViewID, *f.BBox))
+	}
+
 	if len(conds) > 0 {
 		query = query.Where(strings.Join(conds, " AND "), args...)
 	}
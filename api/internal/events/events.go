@@ -0,0 +1,73 @@
+package events
+
+import (
+	"sync"
+)
+
+// ViewDataChanged is published when the data of a view was changed outside
+// of a collaborative session (e.g. through the REST API), so that the collab
+// server can bring open documents up to date.
+type ViewDataChanged struct {
+	ViewID string `json:"view_id"`
+	Type   string `json:"type"`
+	Data   string `json:"data"`
+	// Ops are FortuneSheet operations describing the change, for spreadsheet
+	// views. Connected editors apply them in place instead of reloading.
+	Ops []map[string]interface{} `json:"ops,omitempty"`
+	// ObjectsOnly reports that only the view objects changed. Data is not
+	// set, and open documents keep their own copy of it.
+	ObjectsOnly bool `json:"objects_only,omitempty"`
+}
+
+// subscriberBuffer is the number of events a slow subscriber may lag behind
+// before further events are dropped for it.
+const subscriberBuffer = 64
+
+// Bus is an in-process publish/subscribe hub for view change events.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[chan ViewDataChanged]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: map[chan ViewDataChanged]struct{}{},
+	}
+}
+
+// Subscribe registers a new subscriber. The returned function must be called
+// to unsubscribe; it closes the channel.
+func (b *Bus) Subscribe() (<-chan ViewDataChanged, func()) {
+	ch := make(chan ViewDataChanged, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish delivers an event to every subscriber without blocking.
+func (b *Bus) Publish(e ViewDataChanged) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
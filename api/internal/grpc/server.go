@@ -6,13 +6,18 @@ import (
 	"log"
 	"net"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"gorm.io/gorm"
 
 	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/events"
 	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/timeline"
+	"github.com/collabreef/collabreef/internal/viewobject"
+	"github.com/collabreef/collabreef/internal/whiteboard"
 )
 
 // ---------- Request / Response types (JSON-serialized) ----------
@@ -76,6 +81,80 @@ type UpdateViewDataRequest struct {
 }
 type UpdateViewDataResponse struct{}
 
+type ViewObject struct {
+	ID        string `json:"id"`
+	ViewID    string `json:"view_id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Data      string `json:"data"`
+	CreatedAt string `json:"created_at"`
+	CreatedBy string `json:"created_by"`
+	UpdatedAt string `json:"updated_at"`
+	UpdatedBy string `json:"updated_by"`
+}
+
+type GetViewObjectsRequest struct {
+	ViewID string `json:"view_id"`
+	// BBox limits a whiteboard to the objects in the viewport.
+	BBox *BBox `json:"bbox,omitempty"`
+}
+
+type BBox struct {
+	MinX float64 `json:"min_x"`
+	MinY float64 `json:"min_y"`
+	MaxX float64 `json:"max_x"`
+	MaxY float64 `json:"max_y"`
+}
+type GetViewObjectsResponse struct {
+	Objects []ViewObject `json:"objects"`
+}
+
+// CanvasObject is an entry of the canvas-objects map of a whiteboard, with
+// Data the JSON of the entry.
+type CanvasObject struct {
+	ID   string `json:"id"`
+	Data string `json:"data"`
+}
+
+type GetCanvasObjectsRequest struct {
+	ViewID string `json:"view_id"`
+	// BBox limits the entries to those in the viewport.
+	BBox *BBox `json:"bbox,omitempty"`
+}
+type GetCanvasObjectsResponse struct {
+	Objects []CanvasObject `json:"objects"`
+}
+
+type CreateViewObjectRequest struct {
+	Object ViewObject `json:"object"`
+}
+type CreateViewObjectResponse struct{}
+
+type UpdateViewObjectRequest struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Data      string `json:"data"`
+	UpdatedBy string `json:"updated_by"`
+	UpdatedAt string `json:"updated_at"`
+}
+type UpdateViewObjectResponse struct{}
+
+type DeleteViewObjectRequest struct {
+	ID string `json:"id"`
+}
+type DeleteViewObjectResponse struct{}
+
+type WatchViewChangesRequest struct{}
+type ViewChange struct {
+	ViewID string                   `json:"view_id"`
+	Type   string                   `json:"type"`
+	Data   string                   `json:"data"`
+	Ops    []map[string]interface{} `json:"ops,omitempty"`
+	// ObjectsOnly reports that only the view objects changed.
+	ObjectsOnly bool `json:"objects_only,omitempty"`
+}
+
 // ---------- Service interface ----------
 
 type CollabServiceServer interface {
@@ -85,6 +164,12 @@ type CollabServiceServer interface {
 	GetView(ctx context.Context, req *GetViewRequest) (*GetViewResponse, error)
 	UpdateNote(ctx context.Context, req *UpdateNoteRequest) (*UpdateNoteResponse, error)
 	UpdateViewData(ctx context.Context, req *UpdateViewDataRequest) (*UpdateViewDataResponse, error)
+	GetViewObjects(ctx context.Context, req *GetViewObjectsRequest) (*GetViewObjectsResponse, error)
+	GetCanvasObjects(ctx context.Context, req *GetCanvasObjectsRequest) (*GetCanvasObjectsResponse, error)
+	CreateViewObject(ctx context.Context, req *CreateViewObjectRequest) (*CreateViewObjectResponse, error)
+	UpdateViewObject(ctx context.Context, req *UpdateViewObjectRequest) (*UpdateViewObjectResponse, error)
+	DeleteViewObject(ctx context.Context, req *DeleteViewObjectRequest) (*DeleteViewObjectResponse, error)
+	WatchViewChanges(req *WatchViewChangesRequest, stream grpc.ServerStream) error
 }
 
 // ---------- Unary handler wrappers ----------
@@ -110,6 +195,23 @@ func makeHandler[Req any](fullMethod string, impl func(context.Context, *Req) (i
 	}
 }
 
+// ---------- Server-streaming handler wrappers ----------
+
+func makeServerStreamHandler[Req any](fullMethod string, impl func(*Req, grpc.ServerStream) error) grpc.StreamDesc {
+	name := fullMethod[len("/collab.CollabService/"):]
+	return grpc.StreamDesc{
+		StreamName:    name,
+		ServerStreams: true,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			in := new(Req)
+			if err := stream.RecvMsg(in); err != nil {
+				return err
+			}
+			return impl(in, stream)
+		},
+	}
+}
+
 // ---------- Service descriptor ----------
 
 func registerCollabServiceServer(s *grpc.Server, srv CollabServiceServer) {
@@ -135,8 +237,25 @@ func registerCollabServiceServer(s *grpc.Server, srv CollabServiceServer) {
 			makeHandler("/collab.CollabService/UpdateViewData", func(ctx context.Context, req *UpdateViewDataRequest) (interface{}, error) {
 				return srv.UpdateViewData(ctx, req)
 			}),
+			makeHandler("/collab.CollabService/GetViewObjects", func(ctx context.Context, req *GetViewObjectsRequest) (interface{}, error) {
+				return srv.GetViewObjects(ctx, req)
+			}),
+			makeHandler("/collab.CollabService/GetCanvasObjects", func(ctx context.Context, req *GetCanvasObjectsRequest) (interface{}, error) {
+				return srv.GetCanvasObjects(ctx, req)
+			}),
+			makeHandler("/collab.CollabService/CreateViewObject", func(ctx context.Context, req *CreateViewObjectRequest) (interface{}, error) {
+				return srv.CreateViewObject(ctx, req)
+			}),
+			makeHandler("/collab.CollabService/UpdateViewObject", func(ctx context.Context, req *UpdateViewObjectRequest) (interface{}, error) {
+				return srv.UpdateViewObject(ctx, req)
+			}),
+			makeHandler("/collab.CollabService/DeleteViewObject", func(ctx context.Context, req *DeleteViewObjectRequest) (interface{}, error) {
+				return srv.DeleteViewObject(ctx, req)
+			}),
+		},
+		Streams: []grpc.StreamDesc{
+			makeServerStreamHandler("/collab.CollabService/WatchViewChanges", srv.WatchViewChanges),
 		},
-		Streams:  []grpc.StreamDesc{},
 		Metadata: "collab.proto",
 	}
 	s.RegisterService(&desc, srv)
@@ -145,7 +264,8 @@ func registerCollabServiceServer(s *grpc.Server, srv CollabServiceServer) {
 // ---------- Implementation ----------
 
 type collabServer struct {
-	db db.DB
+	db     db.DB
+	events *events.Bus
 }
 
 func (s *collabServer) GetUser(ctx context.Context, req *GetUserRequest) (*GetUserResponse, error) {
@@ -232,23 +352,260 @@ func (s *collabServer) UpdateNote(ctx context.Context, req *UpdateNoteRequest) (
 }
 
 func (s *collabServer) UpdateViewData(ctx context.Context, req *UpdateViewDataRequest) (*UpdateViewDataResponse, error) {
-	// UpdateView with struct uses GORM Updates which skips zero-value fields,
-	// so only Data and UpdatedAt are changed.
-	if err := s.db.UpdateView(model.View{ID: req.ID, Data: req.Data, UpdatedAt: req.UpdatedAt}); err != nil {
+	data := req.Data
+	view, err := s.db.FindView(model.View{ID: req.ID})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "view not found")
+		}
+		return nil, status.Errorf(codes.Internal, "find view: %v", err)
+	}
+	if view.Type != "whiteboard" {
+		// UpdateView with struct uses GORM Updates which skips zero-value fields,
+		// so only Data and UpdatedAt are changed.
+		if err := s.db.UpdateView(model.View{ID: req.ID, Data: data, UpdatedAt: req.UpdatedAt}); err != nil {
+			return nil, status.Errorf(codes.Internal, "update view: %v", err)
+		}
+		return &UpdateViewDataResponse{}, nil
+	}
+
+	// The canvas objects of a whiteboard are indexed with the data.
+	data = whiteboard.SimplifyCanvas(data)
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "begin: %v", err)
+	}
+	defer tx.Rollback()
+	if err := tx.UpdateView(model.View{ID: req.ID, Data: data, UpdatedAt: req.UpdatedAt}); err != nil {
 		return nil, status.Errorf(codes.Internal, "update view: %v", err)
 	}
+	if err := whiteboard.IndexCanvas(tx, req.ID, data); err != nil {
+		return nil, status.Errorf(codes.Internal, "index canvas: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, status.Errorf(codes.Internal, "commit: %v", err)
+	}
 	return &UpdateViewDataResponse{}, nil
 }
 
+func (s *collabServer) GetCanvasObjects(ctx context.Context, req *GetCanvasObjectsRequest) (*GetCanvasObjectsResponse, error) {
+	filter := model.CanvasObjectFilter{ViewID: req.ViewID}
+	if b := req.BBox; b != nil {
+		if b.MaxX < b.MinX || b.MaxY < b.MinY {
+			return nil, status.Errorf(codes.InvalidArgument, "bbox min must not be greater than max")
+		}
+		filter.BBox = &model.BBox{MinX: b.MinX, MinY: b.MinY, MaxX: b.MaxX, MaxY: b.MaxY}
+	}
+	objects, err := s.db.FindCanvasObjects(filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "find canvas objects: %v", err)
+	}
+	res := &GetCanvasObjectsResponse{Objects: make([]CanvasObject, 0, len(objects))}
+	for _, o := range objects {
+		res.Objects = append(res.Objects, CanvasObject{ID: o.ID, Data: o.Data})
+	}
+	return res, nil
+}
+
+func (s *collabServer) GetViewObjects(ctx context.Context, req *GetViewObjectsRequest) (*GetViewObjectsResponse, error) {
+	// A page size of -1 disables the limit.
+	filter := model.ViewObjectFilter{ViewID: req.ViewID, PageNumber: 1, PageSize: -1}
+	if b := req.BBox; b != nil {
+		if b.MaxX < b.MinX || b.MaxY < b.MinY {
+			return nil, status.Errorf(codes.InvalidArgument, "bbox min must not be greater than max")
+		}
+		filter.BBox = &model.BBox{MinX: b.MinX, MinY: b.MinY, MaxX: b.MaxX, MaxY: b.MaxY}
+	}
+	objects, err := s.db.FindViewObjects(filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "find view objects: %v", err)
+	}
+	res := &GetViewObjectsResponse{Objects: make([]ViewObject, 0, len(objects))}
+	for _, o := range objects {
+		res.Objects = append(res.Objects, ViewObject{
+			ID:        o.ID,
+			ViewID:    o.ViewID,
+			Name:      o.Name,
+			Type:      o.Type,
+			Data:      o.Data,
+			CreatedAt: o.CreatedAt,
+			CreatedBy: o.CreatedBy,
+			UpdatedAt: o.UpdatedAt,
+			UpdatedBy: o.UpdatedBy,
+		})
+	}
+	return res, nil
+}
+
+func (s *collabServer) CreateViewObject(ctx context.Context, req *CreateViewObjectRequest) (*CreateViewObjectResponse, error) {
+	o := req.Object
+	view, err := s.db.FindView(model.View{ID: o.ViewID})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "view not found")
+		}
+		return nil, status.Errorf(codes.Internal, "find view: %v", err)
+	}
+	if !viewobject.IsCompatible(view.Type, o.Type) {
+		return nil, status.Errorf(codes.InvalidArgument, "object type is not compatible with view type")
+	}
+	if err := viewobject.Validate(o.Type, o.Data); err != nil {
+		return nil, invalidArgument(err)
+	}
+	if err := s.checkTimelineLinks(model.ViewObject{ID: o.ID, ViewID: o.ViewID, Name: o.Name, Type: o.Type, Data: o.Data}); err != nil {
+		return nil, err
+	}
+	if err := s.db.CreateViewObject(whiteboard.PrepareObject(model.ViewObject{
+		ID:        o.ID,
+		ViewID:    o.ViewID,
+		Name:      o.Name,
+		Type:      o.Type,
+		Data:      o.Data,
+		CreatedAt: o.CreatedAt,
+		CreatedBy: o.CreatedBy,
+		UpdatedAt: o.UpdatedAt,
+		UpdatedBy: o.UpdatedBy,
+	})); err != nil {
+		return nil, status.Errorf(codes.Internal, "create view object: %v", err)
+	}
+	return &CreateViewObjectResponse{}, nil
+}
+
+func (s *collabServer) UpdateViewObject(ctx context.Context, req *UpdateViewObjectRequest) (*UpdateViewObjectResponse, error) {
+	existing, err := s.db.FindViewObject(model.ViewObject{ID: req.ID})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "view object not found")
+		}
+		return nil, status.Errorf(codes.Internal, "find view object: %v", err)
+	}
+
+	// UpdateViewObject skips zero-value fields, so empty ones keep their
+	// current values; validate what the object will look like.
+	objectType, data := req.Type, req.Data
+	if objectType == "" {
+		objectType = existing.Type
+	}
+	if data == "" {
+		data = existing.Data
+	}
+	if objectType != existing.Type {
+		view, err := s.db.FindView(model.View{ID: existing.ViewID})
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "find view: %v", err)
+		}
+		if !viewobject.IsCompatible(view.Type, objectType) {
+			return nil, status.Errorf(codes.InvalidArgument, "object type is not compatible with view type")
+		}
+	}
+	if err := viewobject.Validate(objectType, data); err != nil {
+		return nil, invalidArgument(err)
+	}
+	next := existing
+	next.Type, next.Data = objectType, data
+	if err := s.checkTimelineLinks(next); err != nil {
+		return nil, err
+	}
+
+	// Keep the recorded bounds in step with the data, which is written with
+	// them.
+	next = whiteboard.PrepareObject(next)
+	updated := model.ViewObject{
+		ID:        req.ID,
+		Name:      req.Name,
+		Type:      req.Type,
+		Data:      next.Data,
+		UpdatedAt: req.UpdatedAt,
+		UpdatedBy: req.UpdatedBy,
+		MinX:      next.MinX,
+		MinY:      next.MinY,
+		MaxX:      next.MaxX,
+		MaxY:      next.MaxY,
+	}
+	if err := s.db.UpdateViewObject(updated); err != nil {
+		return nil, status.Errorf(codes.Internal, "update view object: %v", err)
+	}
+	return &UpdateViewObjectResponse{}, nil
+}
+
+func (s *collabServer) DeleteViewObject(ctx context.Context, req *DeleteViewObjectRequest) (*DeleteViewObjectResponse, error) {
+	if err := s.db.DeleteViewObject(model.ViewObject{ID: req.ID}); err != nil {
+		return nil, status.Errorf(codes.Internal, "delete view object: %v", err)
+	}
+	return &DeleteViewObjectResponse{}, nil
+}
+
+// checkTimelineLinks checks the parent and dependencies of a timeline task
+// against the other tasks of its view.
+func (s *collabServer) checkTimelineLinks(o model.ViewObject) error {
+	if o.Type != timeline.ObjectType {
+		return nil
+	}
+	objects, err := s.db.FindViewObjects(model.ViewObjectFilter{ViewID: o.ViewID, PageNumber: 1, PageSize: -1})
+	if err != nil {
+		return status.Errorf(codes.Internal, "find view objects: %v", err)
+	}
+	if err := timeline.CheckObject(objects, o); err != nil {
+		return invalidArgument(err)
+	}
+	return nil
+}
+
+// invalidArgument converts a failed data validation into an InvalidArgument
+// status with a BadRequest detail per invalid field.
+func invalidArgument(err error) error {
+	var verr *viewobject.ValidationError
+	if !errors.As(err, &verr) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	st := status.New(codes.InvalidArgument, "validation failed: "+verr.Error())
+	br := &errdetails.BadRequest{}
+	for _, f := range verr.Fields {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       f.Field,
+			Description: f.Message,
+		})
+	}
+	if withDetails, err := st.WithDetails(br); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// WatchViewChanges streams view data changes made outside of collab (e.g.
+// through the REST API) until the client disconnects.
+func (s *collabServer) WatchViewChanges(req *WatchViewChangesRequest, stream grpc.ServerStream) error {
+	ch, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case e := <-ch:
+			if err := stream.SendMsg(&ViewChange{
+				ViewID: e.ViewID,
+				Type:   e.Type,
+				Data:   e.Data,
+				Ops:    e.Ops,
+
+				ObjectsOnly: e.ObjectsOnly,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 // ---------- Start ----------
 
-func Start(database db.DB, port string) {
+func Start(database db.DB, bus *events.Bus, port string) {
 	lis, err := net.Listen("tcp", ":"+port)
 	if err != nil {
 		log.Fatalf("[gRPC] listen on :%s failed: %v", port, err)
 	}
 	srv := grpc.NewServer()
-	registerCollabServiceServer(srv, &collabServer{db: database})
+	registerCollabServiceServer(srv, &collabServer{db: database, events: bus})
 	log.Printf("[gRPC] ColabService listening on :%s", port)
 	if err := srv.Serve(lis); err != nil {
 		log.Fatalf("[gRPC] serve failed: %v", err)
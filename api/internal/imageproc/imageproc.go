@@ -0,0 +1,194 @@
+// Package imageproc resizes uploaded images, decoding JPEG, PNG, GIF and
+// WebP in pure Go. Images are turned upright according to their EXIF
+// orientation.
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"mime"
+	"strconv"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// FitContain scales an image to fit inside the box, keeping its
+	// aspect ratio.
+	FitContain = "contain"
+	// FitCover scales an image to cover the box, keeping its aspect ratio,
+	// and crops it to the box around its center.
+	FitCover = "cover"
+	// FitFill stretches an image to the box.
+	FitFill = "fill"
+)
+
+// MaxDimension is the largest width or height an image is resized to.
+const MaxDimension = 4096
+
+// MaxPixels is the size of the largest image that is decoded, so that
+// resizing cannot exhaust memory.
+const MaxPixels = 64 << 20
+
+// ErrTooLarge reports an image with more than MaxPixels pixels.
+var ErrTooLarge = errors.New("image is too large to resize")
+
+// Options describe a resized image. A zero Width or Height is derived from
+// the other from the aspect ratio of the image.
+type Options struct {
+	Width  int
+	Height int
+	Fit    string
+}
+
+// Thumbnail describes the thumbnails made of uploaded images.
+var Thumbnail = Options{Width: 480, Fit: FitContain}
+
+// ParseOptions reads options from the w, h and fit query parameters of a
+// download. Fit defaults to contain.
+func ParseOptions(w, h, fit string) (Options, error) {
+	o := Options{Fit: fit}
+	if o.Fit == "" {
+		o.Fit = FitContain
+	}
+	switch o.Fit {
+	case FitContain, FitCover, FitFill:
+	default:
+		return Options{}, errors.New("fit must be 'contain', 'cover' or 'fill'")
+	}
+
+	var err error
+	if o.Width, err = dimension("w", w); err != nil {
+		return Options{}, err
+	}
+	if o.Height, err = dimension("h", h); err != nil {
+		return Options{}, err
+	}
+	if o.Width == 0 && o.Height == 0 {
+		return Options{}, errors.New("w or h is required")
+	}
+	if o.Fit != FitContain && (o.Width == 0 || o.Height == 0) {
+		return Options{}, errors.New("w and h are required to " + o.Fit)
+	}
+	return o, nil
+}
+
+func dimension(name, s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > MaxDimension {
+		return 0, fmt.Errorf("%s must be between 1 and %d", name, MaxDimension)
+	}
+	return n, nil
+}
+
+// Key names the variant of an image the options make, such as
+// "w480_h0_contain".
+func (o Options) Key() string {
+	return fmt.Sprintf("w%d_h%d_%s", o.Width, o.Height, o.Fit)
+}
+
+// IsSupported reports whether images of a content type can be resized.
+func IsSupported(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// Resize decodes an image and resizes it. Images are not enlarged beyond
+// their size when fit is contain. Opaque images are encoded as JPEG and
+// others as PNG; the content type of the result is returned with it. Only
+// the first frame of animated GIFs is kept.
+func Resize(data []byte, o Options) ([]byte, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, "", ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+
+	orientation := 1
+	switch format {
+	case "jpeg":
+		orientation = jpegOrientation(data)
+	case "webp":
+		orientation = webpOrientation(data)
+	}
+
+	// Sizes are worked out for the upright image, and the image is turned
+	// after it is scaled down, which is cheaper.
+	b := src.Bounds()
+	uw, uh := b.Dx(), b.Dy()
+	if orientation >= 5 {
+		uw, uh = uh, uw
+	}
+	dw, dh, crop := layout(uw, uh, o)
+	if orientation >= 5 {
+		dw, dh = dh, dw
+	}
+	crop = unorientRect(crop, b.Dx(), b.Dy(), orientation).Add(b.Min)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+	out := orient(dst, orientation)
+
+	var buf bytes.Buffer
+	if out.Opaque() {
+		err = jpeg.Encode(&buf, out, &jpeg.Options{Quality: 85})
+		return buf.Bytes(), "image/jpeg", err
+	}
+	err = png.Encode(&buf, out)
+	return buf.Bytes(), "image/png", err
+}
+
+// layout returns the size of the resized image and the part of the source
+// image it shows, both for the upright image.
+func layout(sw, sh int, o Options) (int, int, image.Rectangle) {
+	full := image.Rect(0, 0, sw, sh)
+	w, h := o.Width, o.Height
+
+	switch o.Fit {
+	case FitFill:
+		return w, h, full
+	case FitCover:
+		// Crop the source to the aspect ratio of the box.
+		cw, ch := sw, sw*h/w
+		if ch > sh {
+			cw, ch = sh*w/h, sh
+		}
+		x, y := (sw-cw)/2, (sh-ch)/2
+		return w, h, image.Rect(x, y, x+cw, y+ch)
+	}
+
+	if w == 0 || w > sw {
+		w = sw
+	}
+	if h == 0 || h > sh {
+		h = sh
+	}
+	// Keep the aspect ratio within the box.
+	if w*sh > h*sw {
+		w = max(1, sw*h/sh)
+	} else {
+		h = max(1, sh*w/sw)
+	}
+	return w, h, full
+}
@@ -0,0 +1,351 @@
+package imageproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		w, h, fit string
+		want      Options
+		wantErr   bool
+	}{
+		{w: "480", want: Options{Width: 480, Fit: FitContain}},
+		{h: "200", fit: "contain", want: Options{Height: 200, Fit: FitContain}},
+		{w: "100", h: "100", fit: "cover", want: Options{Width: 100, Height: 100, Fit: FitCover}},
+		{w: "4096", h: "1", fit: "fill", want: Options{Width: 4096, Height: 1, Fit: FitFill}},
+		{wantErr: true},
+		{w: "100", fit: "cover", wantErr: true},
+		{h: "100", fit: "fill", wantErr: true},
+		{w: "100", fit: "stretch", wantErr: true},
+		{w: "0", wantErr: true},
+		{w: "4097", wantErr: true},
+		{w: "-5", wantErr: true},
+		{w: "1e3", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseOptions(tt.w, tt.h, tt.fit)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseOptions(%q, %q, %q) error = %v, want error %v", tt.w, tt.h, tt.fit, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseOptions(%q, %q, %q) = %+v, want %+v", tt.w, tt.h, tt.fit, got, tt.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	if got := Thumbnail.Key(); got != "w480_h0_contain" {
+		t.Errorf("Thumbnail.Key() = %q", got)
+	}
+}
+
+func TestIsSupported(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"image/jpeg", true},
+		{"image/png; charset=binary", true},
+		{"image/gif", true},
+		{"image/webp", true},
+		{"image/svg+xml", false},
+		{"application/pdf", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsSupported(tt.contentType); got != tt.want {
+			t.Errorf("IsSupported(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		name   string
+		sw, sh int
+		o      Options
+		w, h   int
+		crop   image.Rectangle
+	}{
+		{"contain by width", 1000, 500, Options{Width: 200, Fit: FitContain}, 200, 100, image.Rect(0, 0, 1000, 500)},
+		{"contain by height", 1000, 500, Options{Height: 100, Fit: FitContain}, 200, 100, image.Rect(0, 0, 1000, 500)},
+		{"contain in a box", 1000, 500, Options{Width: 300, Height: 300, Fit: FitContain}, 300, 150, image.Rect(0, 0, 1000, 500)},
+		{"contain does not enlarge", 100, 50, Options{Width: 400, Fit: FitContain}, 100, 50, image.Rect(0, 0, 100, 50)},
+		{"contain keeps a pixel", 5000, 1, Options{Width: 100, Fit: FitContain}, 100, 1, image.Rect(0, 0, 5000, 1)},
+		{"cover crops the sides", 1000, 500, Options{Width: 100, Height: 100, Fit: FitCover}, 100, 100, image.Rect(250, 0, 750, 500)},
+		{"cover crops top and bottom", 500, 1000, Options{Width: 200, Height: 100, Fit: FitCover}, 200, 100, image.Rect(0, 375, 500, 625)},
+		{"cover enlarges", 10, 10, Options{Width: 100, Height: 50, Fit: FitCover}, 100, 50, image.Rect(0, 2, 10, 7)},
+		{"fill stretches", 1000, 500, Options{Width: 10, Height: 300, Fit: FitFill}, 10, 300, image.Rect(0, 0, 1000, 500)},
+	}
+	for _, tt := range tests {
+		w, h, crop := layout(tt.sw, tt.sh, tt.o)
+		if w != tt.w || h != tt.h || crop != tt.crop {
+			t.Errorf("%s: layout = %dx%d %v, want %dx%d %v", tt.name, w, h, crop, tt.w, tt.h, tt.crop)
+		}
+	}
+}
+
+// halves returns a w×h image whose left half is red and right half blue.
+func halves(w, h int, alpha uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{B: 255, A: alpha}
+			if x < w/2 {
+				c = color.NRGBA{R: 255, A: alpha}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation encodes a JPEG with an EXIF orientation.
+func withOrientation(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	exif := append([]byte("Exif\x00\x00"), tiff(binary.BigEndian, orientation)...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(exif)+2))
+	app1 = append(app1, exif...)
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+// tiff returns EXIF data with an orientation tag and another tag before it.
+func tiff(order binary.ByteOrder, orientation uint16) []byte {
+	t := make([]byte, 8+2+2*12+4)
+	if order == binary.LittleEndian {
+		copy(t, "II")
+	} else {
+		copy(t, "MM")
+	}
+	order.PutUint16(t[2:], 42)
+	order.PutUint32(t[4:], 8)
+	order.PutUint16(t[8:], 2)
+	// ImageWidth, then Orientation, both SHORT.
+	order.PutUint16(t[10:], 0x0100)
+	order.PutUint16(t[12:], 3)
+	order.PutUint32(t[14:], 1)
+	order.PutUint16(t[18:], 640)
+	order.PutUint16(t[22:], orientationTag)
+	order.PutUint16(t[24:], 3)
+	order.PutUint32(t[26:], 1)
+	order.PutUint16(t[30:], orientation)
+	return t
+}
+
+func TestResize(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+
+	tests := []struct {
+		name        string
+		data        func(*testing.T) []byte
+		o           Options
+		contentType string
+		w, h        int
+		// first and last are the colors at the top left and the bottom
+		// right of the result.
+		first, last color.RGBA
+	}{
+		{
+			name:        "opaque png becomes a jpeg",
+			data:        func(t *testing.T) []byte { return encodePNG(t, halves(200, 100, 255)) },
+			o:           Options{Width: 50, Fit: FitContain},
+			contentType: "image/jpeg",
+			w:           50, h: 25,
+			first: red, last: blue,
+		},
+		{
+			name:        "transparent png stays a png",
+			data:        func(t *testing.T) []byte { return encodePNG(t, halves(200, 100, 128)) },
+			o:           Options{Width: 100, Height: 100, Fit: FitCover},
+			contentType: "image/png",
+			w:           100, h: 100,
+			first: color.RGBA{R: 128, A: 128}, last: color.RGBA{B: 128, A: 128},
+		},
+		{
+			name:        "upright jpeg",
+			data:        func(t *testing.T) []byte { return withOrientation(t, halves(40, 20, 255), 1) },
+			o:           Options{Width: 40, Fit: FitContain},
+			contentType: "image/jpeg",
+			w:           40, h: 20,
+			first: red, last: blue,
+		},
+		{
+			name:        "jpeg turned a quarter clockwise",
+			data:        func(t *testing.T) []byte { return withOrientation(t, halves(40, 20, 255), 6) },
+			o:           Options{Width: 20, Fit: FitContain},
+			contentType: "image/jpeg",
+			w:           20, h: 40,
+			first: red, last: blue,
+		},
+		{
+			name:        "jpeg turned half around",
+			data:        func(t *testing.T) []byte { return withOrientation(t, halves(40, 20, 255), 3) },
+			o:           Options{Width: 20, Fit: FitContain},
+			contentType: "image/jpeg",
+			w:           20, h: 10,
+			first: blue, last: red,
+		},
+		{
+			name:        "turned jpeg is cropped upright",
+			data:        func(t *testing.T) []byte { return withOrientation(t, halves(40, 20, 255), 8) },
+			o:           Options{Width: 10, Height: 10, Fit: FitCover},
+			contentType: "image/jpeg",
+			w:           10, h: 10,
+			first: blue, last: red,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, contentType, err := Resize(tt.data(t), tt.o)
+			if err != nil {
+				t.Fatalf("Resize: %v", err)
+			}
+			if contentType != tt.contentType {
+				t.Errorf("content type = %s, want %s", contentType, tt.contentType)
+			}
+			img, _, err := image.Decode(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("decoding the result: %v", err)
+			}
+			b := img.Bounds()
+			if b.Dx() != tt.w || b.Dy() != tt.h {
+				t.Fatalf("size = %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.w, tt.h)
+			}
+			if c := img.At(b.Min.X, b.Min.Y); !near(c, tt.first) {
+				t.Errorf("top left = %v, want %v", c, tt.first)
+			}
+			if c := img.At(b.Max.X-1, b.Max.Y-1); !near(c, tt.last) {
+				t.Errorf("bottom right = %v, want %v", c, tt.last)
+			}
+		})
+	}
+}
+
+// near compares colors loosely, as JPEG is lossy.
+func near(c color.Color, want color.RGBA) bool {
+	r, g, b, a := c.RGBA()
+	d := func(x uint32, y uint8) bool {
+		v := int(x>>8) - int(y)
+		return v > -40 && v < 40
+	}
+	return d(r, want.R) && d(g, want.G) && d(b, want.B) && d(a, want.A)
+}
+
+func TestResizeTooLarge(t *testing.T) {
+	// A PNG that claims to be 10000×10000 pixels.
+	data := encodePNG(t, image.NewGray(image.Rect(0, 0, 1, 1)))
+	ihdr := data[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:], 10000)
+	binary.BigEndian.PutUint32(ihdr[4:], 10000)
+	binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+
+	if _, _, err := Resize(data, Thumbnail); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Resize = %v, want ErrTooLarge", err)
+	}
+	if _, _, err := Resize([]byte("not an image"), Thumbnail); err == nil {
+		t.Error("Resize accepted data that is not an image")
+	}
+}
+
+func TestOrientationTags(t *testing.T) {
+	webp := func(chunk string, payload []byte) []byte {
+		data := []byte("RIFF\x00\x00\x00\x00WEBP")
+		data = append(data, []byte("VP8X")...)
+		data = binary.LittleEndian.AppendUint32(data, 10)
+		data = append(data, make([]byte, 10)...)
+		data = append(data, []byte(chunk)...)
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(payload)))
+		return append(data, payload...)
+	}
+
+	tests := []struct {
+		name string
+		got  int
+		want int
+	}{
+		{"tiff little endian", tiffOrientation(tiff(binary.LittleEndian, 6)), 6},
+		{"tiff big endian", tiffOrientation(tiff(binary.BigEndian, 8)), 8},
+		{"tiff out of range", tiffOrientation(tiff(binary.BigEndian, 9)), 1},
+		{"tiff truncated", tiffOrientation(tiff(binary.BigEndian, 6)[:20]), 1},
+		{"tiff unknown byte order", tiffOrientation(append([]byte("XX"), tiff(binary.BigEndian, 6)[2:]...)), 1},
+		{"webp exif chunk", webpOrientation(webp("EXIF", append([]byte("Exif\x00\x00"), tiff(binary.LittleEndian, 3)...))), 3},
+		{"webp exif without header", webpOrientation(webp("EXIF", tiff(binary.BigEndian, 5))), 5},
+		{"webp without exif", webpOrientation(webp("ICCP", make([]byte, 4))), 1},
+		{"not webp", webpOrientation([]byte("RIFF\x00\x00\x00\x00WAVE")), 1},
+		{"jpeg without exif", jpegOrientation([]byte{0xFF, 0xD8, 0xFF, 0xDA, 0, 2}), 1},
+		{"jpeg with a bad segment length", jpegOrientation([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF}), 1},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: orientation = %d, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestUnorientRect(t *testing.T) {
+	// Each pixel of the stored image holds its own coordinates.
+	const w, h = 5, 3
+	stored := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			stored.SetRGBA(x, y, color.RGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+
+	for orientation := 1; orientation <= 8; orientation++ {
+		upright := orient(stored, orientation)
+		ub := upright.Bounds()
+		r := image.Rect(1, 1, ub.Dx(), ub.Dy()-1)
+
+		// The rectangle of the stored image holds the pixels of r, turned.
+		crop := stored.SubImage(unorientRect(r, w, h, orientation)).(*image.RGBA)
+		turned := orient(cropped(crop), orientation)
+		for y := 0; y < r.Dy(); y++ {
+			for x := 0; x < r.Dx(); x++ {
+				if got, want := turned.RGBAAt(x, y), upright.RGBAAt(r.Min.X+x, r.Min.Y+y); got != want {
+					t.Fatalf("orientation %d: pixel %d,%d of %v = %v, want %v", orientation, x, y, r, got, want)
+				}
+			}
+		}
+	}
+}
+
+// cropped copies a sub-image to an image at the origin.
+func cropped(src *image.RGBA) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			dst.SetRGBA(x, y, src.RGBAAt(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,342 @@
+package importer
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/util"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// enexTimeLayout is the layout of the created and updated times of ENEX
+// notes.
+const enexTimeLayout = "20060102T150405Z"
+
+type enexNote struct {
+	Title     string         `xml:"title"`
+	Content   string         `xml:"content"`
+	Created   string         `xml:"created"`
+	Updated   string         `xml:"updated"`
+	Tags      []string       `xml:"tag"`
+	SourceURL string         `xml:"note-attributes>source-url"`
+	Resources []enexResource `xml:"resource"`
+}
+
+type enexResource struct {
+	Data     string `xml:"data"`
+	Mime     string `xml:"mime"`
+	FileName string `xml:"resource-attributes>file-name"`
+}
+
+// enexFile is a stored resource, found by the MD5 hash ENML refers to it by.
+type enexFile struct {
+	URL  string
+	Name string
+	Mime string
+}
+
+// ImportENEX imports an Evernote export. ENEX files hold one notebook, which
+// becomes a note titled notebook under parentID, with the notes of the
+// notebook as its children. Notes keep their times and tags, and their
+// resources are stored as workspace files.
+func (im *Importer) ImportENEX(r io.Reader, notebook, parentID string) (model.Note, error) {
+	book, err := im.CreateNote(parentID, notebook, "", time.Time{}, time.Time{})
+	if err != nil {
+		return model.Note{}, err
+	}
+
+	var children []model.Note
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	found := false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return model.Note{}, errors.New("invalid ENEX file: " + err.Error())
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "en-export":
+			found = true
+			continue
+		case "note":
+		default:
+			continue
+		}
+
+		var en enexNote
+		if err := d.DecodeElement(&en, &start); err != nil {
+			return model.Note{}, errors.New("invalid ENEX file: " + err.Error())
+		}
+
+		n, err := im.importENEXNote(book.ID, en)
+		if err != nil {
+			return model.Note{}, err
+		}
+		children = append(children, n)
+	}
+	if !found {
+		return model.Note{}, errors.New("invalid ENEX file: en-export element not found")
+	}
+
+	content, err := subPages(children)
+	if err != nil {
+		return model.Note{}, err
+	}
+	if err := im.SetNoteContent(book, content); err != nil {
+		return model.Note{}, err
+	}
+	book.Content = content
+	return book, nil
+}
+
+func (im *Importer) importENEXNote(parentID string, en enexNote) (model.Note, error) {
+	title := strings.TrimSpace(en.Title)
+	if title == "" {
+		title = "Untitled"
+	}
+
+	files := map[string]enexFile{}
+	for _, res := range en.Resources {
+		data, err := base64.StdEncoding.DecodeString(stripSpace(res.Data))
+		if err != nil {
+			im.warn(title + ": skipped a resource that is not valid base64")
+			continue
+		}
+		name := res.FileName
+		if name == "" {
+			name = "resource"
+			if exts, _ := mime.ExtensionsByType(res.Mime); len(exts) > 0 {
+				name += exts[0]
+			}
+		}
+		url, err := im.SaveFile(name, data)
+		if err != nil {
+			return model.Note{}, err
+		}
+		sum := md5.Sum(data)
+		files[hex.EncodeToString(sum[:])] = enexFile{URL: url, Name: name, Mime: res.Mime}
+	}
+
+	doc, err := enmlToTipTap(en.Content, files)
+	if err != nil {
+		im.warn(title + ": content could not be read: " + err.Error())
+		doc = util.TipTapNode{Type: "doc", Content: []util.TipTapNode{{Type: "paragraph"}}}
+	}
+	doc = withTags(doc, en.Tags)
+	if src := strings.TrimSpace(en.SourceURL); src != "" {
+		doc.Content = append(doc.Content, sourceParagraph(src))
+	}
+
+	content, err := json.Marshal(doc)
+	if err != nil {
+		return model.Note{}, err
+	}
+
+	created, _ := time.Parse(enexTimeLayout, strings.TrimSpace(en.Created))
+	updated, _ := time.Parse(enexTimeLayout, strings.TrimSpace(en.Updated))
+	return im.CreateNote(parentID, title, string(content), created, updated)
+}
+
+// sourceParagraph links a note to the page it was clipped from.
+func sourceParagraph(src string) util.TipTapNode {
+	return util.TipTapNode{
+		Type: "paragraph",
+		Content: []util.TipTapNode{
+			{Type: "text", Text: "Source: "},
+			{
+				Type:  "text",
+				Text:  src,
+				Marks: []util.TipTapMark{{Type: "link", Attrs: map[string]interface{}{"href": src, "target": "_blank"}}},
+			},
+		},
+	}
+}
+
+// selfClosing matches the empty ENML elements, which the HTML parser would
+// otherwise treat as open.
+var selfClosing = regexp.MustCompile(`<(en-todo|en-media|en-crypt)(\s[^>]*?)?\s*/>`)
+
+// enmlToTipTap converts ENML, the XHTML dialect of Evernote notes. Media
+// elements point at the stored resources, and to-do checkboxes become task
+// lists.
+func enmlToTipTap(enml string, files map[string]enexFile) (util.TipTapNode, error) {
+	enml = selfClosing.ReplaceAllString(enml, "<$1$2></$1>")
+	doc, err := html.Parse(strings.NewReader(enml))
+	if err != nil {
+		return util.TipTapNode{}, err
+	}
+
+	var media, todos, crypts []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "en-media":
+				media = append(media, n)
+			case "en-todo":
+				todos = append(todos, n)
+			case "en-crypt":
+				crypts = append(crypts, n)
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	for _, n := range media {
+		replaceMedia(n, files)
+	}
+	for _, n := range crypts {
+		n.Parent.InsertBefore(&html.Node{Type: html.TextNode, Data: "[encrypted content]"}, n)
+		n.Parent.RemoveChild(n)
+	}
+	convertTodos(todos)
+
+	return util.HTMLNodeToTipTap(doc), nil
+}
+
+func replaceMedia(n *html.Node, files map[string]enexFile) {
+	f, ok := files[strings.ToLower(attrOf(n, "hash"))]
+	if !ok {
+		n.Parent.RemoveChild(n)
+		return
+	}
+
+	var el *html.Node
+	if strings.HasPrefix(f.Mime, "image/") {
+		el = &html.Node{Type: html.ElementNode, DataAtom: atom.Img, Data: "img", Attr: []html.Attribute{
+			{Key: "src", Val: f.URL},
+			{Key: "alt", Val: f.Name},
+		}}
+	} else {
+		el = &html.Node{Type: html.ElementNode, Data: "file-node", Attr: []html.Attribute{
+			{Key: "src", Val: f.URL},
+			{Key: "name", Val: f.Name},
+		}}
+	}
+	n.Parent.InsertBefore(el, n)
+	n.Parent.RemoveChild(n)
+}
+
+// convertTodos turns blocks that start with a checkbox into task lists in
+// the form the editor renders them to. A block holding several checkboxes
+// becomes one item per checkbox; checkboxes elsewhere become text.
+func convertTodos(todos []*html.Node) {
+	converted := map[*html.Node]bool{}
+	for _, todo := range todos {
+		if converted[todo] || todo.Parent == nil {
+			continue
+		}
+		block := todo.Parent
+		isBlock := block.DataAtom == atom.Div || block.DataAtom == atom.P || block.DataAtom == atom.Li
+		if !isBlock || !startsWith(block, todo) {
+			todo.Parent.InsertBefore(&html.Node{Type: html.TextNode, Data: checkboxText(todo)}, todo)
+			todo.Parent.RemoveChild(todo)
+			continue
+		}
+
+		list := &html.Node{Type: html.ElementNode, DataAtom: atom.Ul, Data: "ul", Attr: []html.Attribute{{Key: "data-type", Val: "taskList"}}}
+		var item *html.Node
+		for child := block.FirstChild; child != nil; {
+			next := child.NextSibling
+			block.RemoveChild(child)
+			if child.Type == html.ElementNode && child.Data == "en-todo" {
+				converted[child] = true
+				checked := "false"
+				if strings.EqualFold(attrOf(child, "checked"), "true") {
+					checked = "true"
+				}
+				item = &html.Node{Type: html.ElementNode, DataAtom: atom.Li, Data: "li", Attr: []html.Attribute{{Key: "data-checked", Val: checked}}}
+				list.AppendChild(item)
+			} else if item != nil {
+				item.AppendChild(child)
+			}
+			child = next
+		}
+
+		// List items keep their place in the list and hold the task list.
+		if block.DataAtom == atom.Li {
+			block.AppendChild(list)
+			continue
+		}
+
+		// Consecutive checkbox blocks form one list.
+		prev := block.PrevSibling
+		for prev != nil && prev.Type == html.TextNode && strings.TrimSpace(prev.Data) == "" {
+			prev = prev.PrevSibling
+		}
+		if prev != nil && prev.DataAtom == atom.Ul && attrOf(prev, "data-type") == "taskList" {
+			for item := list.FirstChild; item != nil; {
+				next := item.NextSibling
+				list.RemoveChild(item)
+				prev.AppendChild(item)
+				item = next
+			}
+			block.Parent.RemoveChild(block)
+		} else {
+			block.Parent.InsertBefore(list, block)
+			block.Parent.RemoveChild(block)
+		}
+	}
+}
+
+// startsWith reports whether child is the first content of block.
+func startsWith(block, child *html.Node) bool {
+	for c := block.FirstChild; c != nil; c = c.NextSibling {
+		if c == child {
+			return true
+		}
+		if c.Type == html.TextNode && strings.TrimSpace(c.Data) == "" {
+			continue
+		}
+		return false
+	}
+	return false
+}
+
+func checkboxText(todo *html.Node) string {
+	if strings.EqualFold(attrOf(todo, "checked"), "true") {
+		return "☑ "
+	}
+	return "☐ "
+}
+
+func attrOf(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func stripSpace(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', '\r':
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,217 @@
+package importer
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/db/dbtest"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/storage/localfile"
+	"github.com/collabreef/collabreef/internal/util"
+)
+
+// newTestImporter returns an importer writing to a migrated database and
+// to local storage in temporary directories of the test.
+func newTestImporter(t *testing.T) *Importer {
+	t.Helper()
+	return &Importer{
+		DB:          dbtest.New(t),
+		Storage:     localfile.NewLocalFileStorage(t.TempDir()),
+		WorkspaceID: "w1",
+		UserID:      "u1",
+		Visibility:  "private",
+		APIRoot:     "/api/v1",
+	}
+}
+
+// importFixture runs an import of a file of testdata and ends it as
+// committed.
+func importFixture(t *testing.T, im *Importer, name string, run func(f *os.File) error) {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := run(f); err != nil {
+		t.Fatalf("importing %s: %v", name, err)
+	}
+	im.End(true)
+}
+
+// notesByTitle returns the notes of the workspace of the importer.
+func notesByTitle(t *testing.T, im *Importer) map[string]model.Note {
+	t.Helper()
+	notes, err := im.DB.FindNotes(model.NoteFilter{WorkspaceID: im.WorkspaceID, UserID: im.UserID, PageNumber: 1, PageSize: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]model.Note{}
+	for _, n := range notes {
+		out[n.Title] = n
+	}
+	return out
+}
+
+func decodeDoc(t *testing.T, content string) util.TipTapNode {
+	t.Helper()
+	var doc util.TipTapNode
+	if err := json.Unmarshal([]byte(content), &doc); err != nil {
+		t.Fatalf("content %q: %v", content, err)
+	}
+	return doc
+}
+
+func nodeTypes(nodes []util.TipTapNode) []string {
+	types := make([]string, len(nodes))
+	for i, n := range nodes {
+		types[i] = n.Type
+	}
+	return types
+}
+
+func TestImportENEX(t *testing.T) {
+	im := newTestImporter(t)
+	var book model.Note
+	importFixture(t, im, "notebook.enex", func(f *os.File) (err error) {
+		book, err = im.ImportENEX(f, "Home", "")
+		return err
+	})
+
+	res := im.Result()
+	if res.Notes != 3 || res.Files != 2 {
+		t.Errorf("result = %+v, want 3 notes and 2 files", res)
+	}
+	if want := []string{"Untitled: skipped a resource that is not valid base64"}; !reflect.DeepEqual(res.Warnings, want) {
+		t.Errorf("warnings = %q, want %q", res.Warnings, want)
+	}
+
+	notes := notesByTitle(t, im)
+	groceries, untitled := notes["Groceries"], notes["Untitled"]
+	if groceries.ParentID != book.ID || untitled.ParentID != book.ID {
+		t.Fatalf("notes are not children of the notebook: %+v", notes)
+	}
+	if groceries.CreatedAt != "2024-01-02T03:04:05Z" || groceries.UpdatedAt != "2024-02-03T04:05:06Z" {
+		t.Errorf("times = %s, %s, want those of the export", groceries.CreatedAt, groceries.UpdatedAt)
+	}
+	if untitled.UpdatedAt != untitled.CreatedAt {
+		t.Errorf("note without an updated time updated at %s, want %s", untitled.UpdatedAt, untitled.CreatedAt)
+	}
+
+	pages := decodeDoc(t, book.Content).Content
+	if len(pages) != 2 || pages[0].Attrs["noteId"] != groceries.ID || pages[1].Attrs["noteId"] != untitled.ID {
+		t.Errorf("notebook lists %+v, want the notes in export order", pages)
+	}
+
+	doc := decodeDoc(t, groceries.Content)
+	wantTypes := []string{"tagsNode", "taskList", "paragraph", "table", "image", "paragraph"}
+	if got := nodeTypes(doc.Content); !reflect.DeepEqual(got, wantTypes) {
+		t.Fatalf("groceries holds %v, want %v", got, wantTypes)
+	}
+	if tags := doc.Content[0].Attrs["tags"]; !reflect.DeepEqual(tags, []interface{}{"home", "errands"}) {
+		t.Errorf("tags = %v, want trimmed tags", tags)
+	}
+	if src := doc.Content[5].Content[1].Text; src != "https://example.com/list" {
+		t.Errorf("source = %q, want the source URL", src)
+	}
+
+	// Resources are stored as files, and media point at them.
+	image := doc.Content[4].Attrs["src"].(string)
+	files, err := im.DB.FindFiles(model.FileFilter{WorkspaceID: "w1", PageNumber: 1, PageSize: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]string{}
+	for _, f := range files {
+		names[im.APIRoot+"/workspaces/w1/files/"+f.Name] = f.OriginalFilename
+	}
+	if names[image] != "shelf.png" {
+		t.Errorf("image %s is not the stored shelf.png, files are %v", image, names)
+	}
+	attachment := decodeDoc(t, untitled.Content).Content[0]
+	if attachment.Type != "attachment" || names[attachment.Attrs["src"].(string)] != "resource.pdf" {
+		t.Errorf("attachment = %+v, want the stored PDF named after its type", attachment)
+	}
+}
+
+func TestImportENEXInvalid(t *testing.T) {
+	im := newTestImporter(t)
+	defer im.End(false)
+	_, err := im.ImportENEX(strings.NewReader(`<notes><note><title>A</title></note></notes>`), "Home", "")
+	if err == nil || !strings.Contains(err.Error(), "en-export") {
+		t.Errorf("error = %v, want a missing en-export error", err)
+	}
+}
+
+func TestENMLToTipTap(t *testing.T) {
+	files := map[string]enexFile{
+		"0123456789abcdef0123456789abcdef": {URL: "/files/a.png", Name: "a.png", Mime: "image/png"},
+		"fedcba9876543210fedcba9876543210": {URL: "/files/b.zip", Name: "b.zip", Mime: "application/zip"},
+	}
+
+	tests := []struct {
+		name string
+		enml string
+		want string
+	}{
+		{
+			name: "consecutive checkboxes form one task list",
+			enml: `<en-note><div><en-todo checked="true"/>One</div><div><en-todo/>Two</div></en-note>`,
+			want: `{"type":"doc","content":[{"type":"taskList","content":[` +
+				`{"type":"taskItem","content":[{"type":"paragraph","content":[{"type":"text","text":"One"}]}],"attrs":{"checked":true}},` +
+				`{"type":"taskItem","content":[{"type":"paragraph","content":[{"type":"text","text":"Two"}]}],"attrs":{"checked":false}}]}]}`,
+		},
+		{
+			name: "checkboxes in a block become items",
+			enml: `<en-note><div><en-todo/>A<en-todo checked="true"/>B</div></en-note>`,
+			want: `{"type":"doc","content":[{"type":"taskList","content":[` +
+				`{"type":"taskItem","content":[{"type":"paragraph","content":[{"type":"text","text":"A"}]}],"attrs":{"checked":false}},` +
+				`{"type":"taskItem","content":[{"type":"paragraph","content":[{"type":"text","text":"B"}]}],"attrs":{"checked":true}}]}]}`,
+		},
+		{
+			name: "checkbox inside text",
+			enml: `<en-note><p>Done <en-todo checked="true"/>today</p></en-note>`,
+			want: `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"Done "},{"type":"text","text":"☑ "},{"type":"text","text":"today"}]}]}`,
+		},
+		{
+			name: "media by hash in any case",
+			enml: `<en-note><en-media type="image/png" hash="0123456789ABCDEF0123456789ABCDEF"/><en-media hash="fedcba9876543210fedcba9876543210"/></en-note>`,
+			want: `{"type":"doc","content":[{"type":"image","attrs":{"name":"a.png","src":"/files/a.png"}},{"type":"attachment","attrs":{"name":"b.zip","src":"/files/b.zip"}}]}`,
+		},
+		{
+			name: "unknown media is dropped",
+			enml: `<en-note><p>x<en-media hash="00"/></p></en-note>`,
+			want: `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"x"}]}]}`,
+		},
+		{
+			name: "encrypted text",
+			enml: `<en-note><div><en-crypt>abc</en-crypt></div></en-note>`,
+			want: `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"[encrypted content]"}]}]}`,
+		},
+		{
+			name: "links and tables",
+			enml: `<en-note><a href="https://example.com">site</a><table><tr><td>1</td></tr></table></en-note>`,
+			want: `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","marks":[{"type":"link","attrs":{"href":"https://example.com","target":"_blank"}}],"text":"site"}]},` +
+				`{"type":"table","content":[{"type":"tableRow","content":[{"type":"tableCell","content":[{"type":"paragraph","content":[{"type":"text","text":"1"}]}]}]}]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := enmlToTipTap(tt.enml, files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("enmlToTipTap =\n%s\nwant\n%s", b, tt.want)
+			}
+		})
+	}
+}
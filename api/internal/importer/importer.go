@@ -0,0 +1,292 @@
+// Package importer brings notes, files and views exported from other
+// applications into a workspace.
+package importer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/quota"
+	"github.com/collabreef/collabreef/internal/storage"
+	"github.com/collabreef/collabreef/internal/util"
+)
+
+// Importer writes imported content to a workspace on behalf of a user.
+// Notes are created with Visibility, and stored files are addressed under
+// APIRoot, the root path of the API. Call End once the import is committed
+// or rolled back.
+type Importer struct {
+	DB          db.DB
+	Storage     storage.Storage
+	WorkspaceID string
+	UserID      string
+	Visibility  string
+	APIRoot     string
+
+	result Result
+	// blobs are the blobs stored by the import, by hash, and reservation
+	// the storage of the files stored, until the import ends.
+	blobs       map[string]*heldBlob
+	reservation *quota.Reservation
+}
+
+// Result counts what an import created. Warnings describe the content that
+// could not be imported.
+type Result struct {
+	Notes    int      `json:"notes"`
+	Files    int      `json:"files"`
+	Views    int      `json:"views"`
+	Warnings []string `json:"warnings"`
+}
+
+// Result returns what the importer has created so far.
+func (im *Importer) Result() Result {
+	r := im.result
+	if r.Warnings == nil {
+		r.Warnings = []string{}
+	}
+	return r
+}
+
+func (im *Importer) warn(msg string) {
+	im.result.Warnings = append(im.result.Warnings, msg)
+}
+
+// CreateNote creates a note. Zero times are replaced by the current time.
+func (im *Importer) CreateNote(parentID, title, content string, createdAt, updatedAt time.Time) (model.Note, error) {
+	now := time.Now().UTC()
+	if createdAt.IsZero() {
+		createdAt = now
+	}
+	if updatedAt.IsZero() {
+		updatedAt = createdAt
+	}
+
+	n := model.Note{
+		WorkspaceID: im.WorkspaceID,
+		ID:          util.NewId(),
+		ParentID:    parentID,
+		Visibility:  im.Visibility,
+		Title:       title,
+		Content:     content,
+		CreatedAt:   createdAt.UTC().Format(time.RFC3339),
+		CreatedBy:   im.UserID,
+		UpdatedAt:   updatedAt.UTC().Format(time.RFC3339),
+		UpdatedBy:   im.UserID,
+	}
+	if err := im.DB.CreateNote(n); err != nil {
+		return model.Note{}, err
+	}
+	im.result.Notes++
+	return n, nil
+}
+
+// SetNoteContent replaces the content of a note created by the import,
+// keeping its timestamps.
+func (im *Importer) SetNoteContent(n model.Note, content string) error {
+	n.Content = content
+	return im.DB.UpdateNote(n)
+}
+
+// CreateView creates a view attached to a note created by the import.
+func (im *Importer) CreateView(noteID, name, viewType, data string) (model.View, error) {
+	now := time.Now().UTC().String()
+	v := model.View{
+		WorkspaceID: im.WorkspaceID,
+		NoteID:      noteID,
+		ID:          util.NewId(),
+		Name:        name,
+		Type:        viewType,
+		Data:        data,
+		Visibility:  im.Visibility,
+		CreatedAt:   now,
+		CreatedBy:   im.UserID,
+		UpdatedAt:   now,
+		UpdatedBy:   im.UserID,
+	}
+	if err := im.DB.CreateView(v); err != nil {
+		return model.View{}, err
+	}
+	im.result.Views++
+	return v, nil
+}
+
+// CreateViewObject adds an object to a view created by the import.
+func (im *Importer) CreateViewObject(viewID, name, objectType, data string) (model.ViewObject, error) {
+	now := time.Now().UTC().String()
+	o := model.ViewObject{
+		ID:        util.NewId(),
+		ViewID:    viewID,
+		Name:      name,
+		Type:      objectType,
+		Data:      data,
+		CreatedAt: now,
+		CreatedBy: im.UserID,
+		UpdatedAt: now,
+		UpdatedBy: im.UserID,
+	}
+	if err := im.DB.CreateViewObject(o); err != nil {
+		return model.ViewObject{}, err
+	}
+	return o, nil
+}
+
+// SaveFile stores a file in the workspace, named the way uploads are, and
+// returns the address notes use for it. Files over the storage quotas are
+// refused.
+func (im *Importer) SaveFile(name string, data []byte) (string, error) {
+	f, err := im.storeFile(name, data)
+	if err != nil {
+		return "", err
+	}
+	return im.addFile(f)
+}
+
+// storedFile is a file whose content is stored, to be added to the
+// workspace with addFile.
+type storedFile struct {
+	name        string
+	hash        string
+	size        int64
+	contentType string
+}
+
+// heldBlob is a blob stored by the import, locked until the import ends so
+// that it is not deleted before the files that refer to it are committed.
+type heldBlob struct {
+	unlock  func()
+	created bool
+}
+
+// storeFile stores the content of a file as a blob, without adding the
+// file. Stored files count towards the quotas until the import ends, as the
+// files added are not visible to other checks before they are committed.
+func (im *Importer) storeFile(name string, data []byte) (storedFile, error) {
+	size := int64(len(data))
+	if im.reservation == nil {
+		r, err := quota.Reserve(im.DB, im.WorkspaceID, im.UserID, size)
+		if err != nil {
+			return storedFile{}, err
+		}
+		im.reservation = r
+	} else if err := im.reservation.Add(im.DB, size); err != nil {
+		return storedFile{}, err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	b, ok := im.blobs[hash]
+	if !ok {
+		if im.blobs == nil {
+			im.blobs = map[string]*heldBlob{}
+		}
+		b = &heldBlob{unlock: storage.LockBlob(hash)}
+		im.blobs[hash] = b
+	}
+	created, err := storage.SaveBlob(im.Storage, hash, bytes.NewReader(data))
+	if err != nil {
+		return storedFile{}, err
+	}
+	b.created = b.created || created
+
+	return storedFile{name: name, hash: hash, size: size, contentType: util.DetectContentType(name, data)}, nil
+}
+
+// addFile adds a stored file to the workspace, and returns the address
+// notes use for it.
+func (im *Importer) addFile(f storedFile) (string, error) {
+	ext := filepath.Ext(f.name)
+	fileName := time.Now().Format("20060102150405") + "_" + randomString(4) + ext
+
+	now := time.Now().Format(time.RFC3339)
+	if err := im.DB.CreateFile(model.File{
+		WorkspaceID:      im.WorkspaceID,
+		ID:               util.NewId(),
+		Name:             fileName,
+		Ext:              ext,
+		Size:             f.size,
+		OriginalFilename: f.name,
+		ContentType:      f.contentType,
+		Hash:             f.hash,
+		CreatedAt:        now,
+		CreatedBy:        im.UserID,
+		UpdatedAt:        now,
+		UpdatedBy:        im.UserID,
+	}); err != nil {
+		return "", err
+	}
+	im.reservation.Record(f.size)
+	im.result.Files++
+
+	return im.APIRoot + "/workspaces/" + im.WorkspaceID + "/files/" + fileName, nil
+}
+
+// End ends an import once its transaction is committed or rolled back, and
+// unlocks the blobs it stored. When the import was not committed, the
+// blobs it created are deleted: no other file could refer to them while
+// they were locked.
+func (im *Importer) End(committed bool) {
+	for hash, b := range im.blobs {
+		if !committed && b.created {
+			if err := im.Storage.Delete(storage.BlobSegments(hash)); err != nil {
+				log.Printf("importer: deleting blob %s: %v", hash, err)
+			}
+		}
+		b.unlock()
+	}
+	im.blobs = nil
+	im.reservation.Release()
+	im.reservation = nil
+}
+
+// subPages builds the content of a note that lists its child notes, as the
+// editor does for pages created inside a note.
+func subPages(children []model.Note) (string, error) {
+	doc := util.TipTapNode{Type: "doc"}
+	for _, child := range children {
+		doc.Content = append(doc.Content, util.TipTapNode{
+			Type:  "subPage",
+			Attrs: map[string]interface{}{"noteId": child.ID, "title": child.Title},
+		})
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []util.TipTapNode{{Type: "paragraph"}}
+	}
+	b, err := json.Marshal(doc)
+	return string(b), err
+}
+
+// withTags adds the tags node of the editor to the top of a document.
+func withTags(doc util.TipTapNode, tags []string) util.TipTapNode {
+	var clean []string
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			clean = append(clean, tag)
+		}
+	}
+	if len(clean) == 0 {
+		return doc
+	}
+	node := util.TipTapNode{Type: "tagsNode", Attrs: map[string]interface{}{"tags": clean}}
+	doc.Content = append([]util.TipTapNode{node}, doc.Content...)
+	return doc
+}
+
+var letterRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+
+func randomString(n int) string {
+	b := make([]rune, n)
+	for i := range b {
+		b[i] = letterRunes[rand.Intn(len(letterRunes))]
+	}
+	return string(b)
+}
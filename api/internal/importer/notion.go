@@ -0,0 +1,653 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/quota"
+	"github.com/collabreef/collabreef/internal/spreadsheet"
+	"github.com/collabreef/collabreef/internal/util"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxNotionEntrySize caps the size of a single file read from a Notion
+// export.
+const maxNotionEntrySize = 100 << 20
+
+// maxNotionParts and maxNotionPartsSize cap the number of parts of an
+// export and their total size. Parts are extracted to temporary files, not
+// read into memory.
+const (
+	maxNotionParts     = 100
+	maxNotionPartsSize = 2 << 30
+)
+
+// notionName matches the names Notion gives exported pages and databases:
+// the title followed by the 32 hex digit id of the page.
+var notionName = regexp.MustCompile(`^(.*?)\s*([0-9a-f]{32})$`)
+
+// notionMarkdown renders Notion markdown, which uses GitHub tables and task
+// lists, and raw HTML for callouts.
+var notionMarkdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(gmhtml.WithUnsafe()),
+)
+
+type notionPage struct {
+	path  string
+	title string
+	// markdown is the content of the page, without its title heading.
+	markdown string
+	parent   *notionPage
+	note     model.Note
+	// children are the pages nested under the page, in export order.
+	children []*notionPage
+}
+
+type notionDatabase struct {
+	path   string
+	name   string
+	parent *notionPage
+	view   model.View
+	shown  bool
+}
+
+type notionExport struct {
+	files     map[string]*zip.File
+	pages     map[string]*notionPage
+	databases map[string]*notionDatabase
+	assets    map[string]string
+}
+
+// ImportNotion imports a Notion "Markdown & CSV" export. Pages are nested
+// the way their folders are, under parentID, and links between pages point
+// at the imported notes. Each database becomes a spreadsheet view attached
+// to the note of its parent page, and the files pages refer to are stored
+// as workspace files. It returns the notes created at the top level.
+func (im *Importer) ImportNotion(r io.ReaderAt, size int64, parentID string) ([]model.Note, error) {
+	files, closeFiles, err := notionFiles(r, size)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFiles()
+
+	ex := &notionExport{
+		files:     files,
+		pages:     map[string]*notionPage{},
+		databases: map[string]*notionDatabase{},
+		assets:    map[string]string{},
+	}
+	if err := ex.index(); err != nil {
+		return nil, err
+	}
+
+	// Parents are created first, so that children can refer to them.
+	var pages []*notionPage
+	for _, p := range ex.pages {
+		pages = append(pages, p)
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		di, dj := pages[i].depth(), pages[j].depth()
+		if di != dj {
+			return di < dj
+		}
+		return pages[i].path < pages[j].path
+	})
+
+	for _, p := range pages {
+		pid := parentID
+		if p.parent != nil {
+			pid = p.parent.note.ID
+			p.parent.children = append(p.parent.children, p)
+		}
+		n, err := im.CreateNote(pid, p.title, "", time.Time{}, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		p.note = n
+	}
+
+	for _, d := range ex.sortedDatabases() {
+		if err := im.importNotionDatabase(ex, d); err != nil {
+			return nil, err
+		}
+	}
+
+	var roots []model.Note
+	for _, p := range pages {
+		if err := im.importNotionPage(ex, p); err != nil {
+			return nil, err
+		}
+		if p.parent == nil {
+			roots = append(roots, p.note)
+		}
+	}
+
+	return roots, nil
+}
+
+// notionFiles lists the files of an export. Large workspaces are exported
+// as a zip holding one zip per part; the parts are extracted to temporary
+// files, which closeFiles removes, and read as one export.
+func notionFiles(r io.ReaderAt, size int64) (files map[string]*zip.File, closeFiles func(), err error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, errors.New("invalid Notion export: " + err.Error())
+	}
+
+	files = map[string]*zip.File{}
+	var parts []*zip.File
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if strings.EqualFold(path.Ext(f.Name), ".zip") {
+			parts = append(parts, f)
+			continue
+		}
+		files[f.Name] = f
+	}
+
+	var temps []*os.File
+	closeFiles = func() {
+		for _, t := range temps {
+			t.Close()
+			os.Remove(t.Name())
+		}
+	}
+
+	if len(files) == 0 {
+		if len(parts) > maxNotionParts {
+			return nil, nil, fmt.Errorf("invalid Notion export: more than %d parts", maxNotionParts)
+		}
+		var left int64 = maxNotionPartsSize
+		for _, part := range parts {
+			t, n, err := extractZipFile(part, left)
+			if t != nil {
+				temps = append(temps, t)
+			}
+			if err != nil {
+				closeFiles()
+				return nil, nil, err
+			}
+			left -= n
+			pr, err := zip.NewReader(t, n)
+			if err != nil {
+				closeFiles()
+				return nil, nil, errors.New("invalid Notion export: " + part.Name + ": " + err.Error())
+			}
+			for _, f := range pr.File {
+				if !f.FileInfo().IsDir() {
+					files[f.Name] = f
+				}
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		closeFiles()
+		return nil, nil, errors.New("invalid Notion export: no pages found")
+	}
+	return files, closeFiles, nil
+}
+
+// extractZipFile copies a file of a zip to a temporary file, and returns it
+// with its size. Files over limit bytes are refused.
+func extractZipFile(f *zip.File, limit int64) (*os.File, int64, error) {
+	tooLarge := errors.New("invalid Notion export: parts are larger than " + quota.FormatSize(maxNotionPartsSize) + " in all")
+	if f.UncompressedSize64 > uint64(limit) {
+		return nil, 0, tooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rc.Close()
+
+	t, err := os.CreateTemp("", "collabreef-notion-*.zip")
+	if err != nil {
+		return nil, 0, err
+	}
+	// The recorded size may be wrong; read one more byte to tell.
+	n, err := io.Copy(t, io.LimitReader(rc, limit+1))
+	if err != nil {
+		return t, 0, err
+	}
+	if n > limit {
+		return t, 0, tooLarge
+	}
+	return t, n, nil
+}
+
+// index finds the pages and databases of the export and their parents.
+func (ex *notionExport) index() error {
+	for name := range ex.files {
+		switch strings.ToLower(path.Ext(name)) {
+		case ".md":
+			ex.pages[name] = &notionPage{path: name, title: notionTitle(name)}
+		case ".csv":
+			// Databases are exported as the rows of the current view, and
+			// all rows in a file ending in _all, which is preferred.
+			key := strings.TrimSuffix(strings.TrimSuffix(name, path.Ext(name)), "_all")
+			d, ok := ex.databases[key]
+			if !ok {
+				d = &notionDatabase{name: notionTitle(key)}
+				ex.databases[key] = d
+			}
+			if d.path == "" || strings.HasSuffix(strings.TrimSuffix(name, path.Ext(name)), "_all") {
+				d.path = name
+			}
+		}
+	}
+	if len(ex.pages) == 0 && len(ex.databases) == 0 {
+		return errors.New("invalid Notion export: no pages found")
+	}
+
+	// Databases are resolved first, as the rows of a database are nested
+	// under its parent.
+	for key, d := range ex.databases {
+		ex.databaseParent(key, d)
+	}
+	for _, p := range ex.pages {
+		if p.parent == nil {
+			p.parent = ex.parentPage(p.path)
+		}
+		if err := ex.read(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// read reads the markdown of a page. Notion starts pages with their full
+// title as a heading, which becomes the title of the note, as file names
+// may be shortened.
+func (ex *notionExport) read(p *notionPage) error {
+	f, ok := ex.files[p.path]
+	if !ok {
+		return nil
+	}
+	data, err := readZipFile(f)
+	if err != nil {
+		return err
+	}
+	md := strings.TrimPrefix(string(data), "\ufeff")
+	if first, rest, _ := strings.Cut(md, "\n"); strings.HasPrefix(first, "# ") {
+		if title := strings.TrimSpace(first[2:]); title != "" {
+			p.title = title
+		}
+		md = rest
+	}
+	p.markdown = md
+	return nil
+}
+
+// databaseParent finds the page a database is attached to. Databases
+// outside of any page get a page of their own.
+func (ex *notionExport) databaseParent(key string, d *notionDatabase) *notionPage {
+	if d.parent != nil {
+		return d.parent
+	}
+	d.parent = ex.parentPage(d.path)
+	if d.parent == nil {
+		d.parent = &notionPage{path: key + ".md", title: d.name}
+		ex.pages[d.parent.path] = d.parent
+	}
+	return d.parent
+}
+
+// parentPage finds the page a file is nested under: the page exported next
+// to the folder holding the file. The rows of a database are nested under
+// the parent of the database.
+func (ex *notionExport) parentPage(name string) *notionPage {
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if p, ok := ex.pages[dir+".md"]; ok {
+			return p
+		}
+		if d, ok := ex.databases[dir]; ok {
+			return ex.databaseParent(dir, d)
+		}
+	}
+	return nil
+}
+
+func (p *notionPage) depth() int {
+	depth := 0
+	for parent := p.parent; parent != nil; parent = parent.parent {
+		depth++
+	}
+	return depth
+}
+
+func (im *Importer) importNotionDatabase(ex *notionExport, d *notionDatabase) error {
+	data, err := readZipFile(ex.files[d.path])
+	if err != nil {
+		return err
+	}
+	sheet, err := spreadsheet.ReadCSV(bytes.NewReader(data), d.name)
+	if err != nil {
+		im.warn(d.name + ": database could not be read: " + err.Error())
+		return nil
+	}
+	sheets, err := spreadsheet.MarshalSheets([]spreadsheet.Sheet{sheet})
+	if err != nil {
+		return err
+	}
+	d.view, err = im.CreateView(d.parent.note.ID, d.name, "spreadsheet", sheets)
+	return err
+}
+
+func (im *Importer) importNotionPage(ex *notionExport, p *notionPage) error {
+	doc, err := im.notionDocument(ex, p)
+	if err != nil {
+		return err
+	}
+
+	// Sub-pages and databases the page does not link to are listed at the
+	// end, so that every imported note can be reached from its parent.
+	linked := map[string]bool{}
+	walkTipTap(doc, func(n util.TipTapNode) {
+		if n.Type == "subPage" {
+			id, _ := n.Attrs["noteId"].(string)
+			linked[id] = true
+		}
+	})
+	for _, child := range p.children {
+		if !linked[child.note.ID] {
+			doc.Content = append(doc.Content, util.TipTapNode{
+				Type:  "subPage",
+				Attrs: map[string]interface{}{"noteId": child.note.ID, "title": child.note.Title},
+			})
+		}
+	}
+	for _, d := range ex.sortedDatabases() {
+		if d.parent == p && d.view.ID != "" && !d.shown {
+			doc.Content = append(doc.Content, viewNode(d.view))
+		}
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []util.TipTapNode{{Type: "paragraph"}}
+	}
+
+	content, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	p.note.Content = string(content)
+	return im.SetNoteContent(p.note, p.note.Content)
+}
+
+// notionDocument converts the markdown of a page.
+func (im *Importer) notionDocument(ex *notionExport, p *notionPage) (util.TipTapNode, error) {
+	if strings.TrimSpace(p.markdown) == "" {
+		return util.TipTapNode{Type: "doc"}, nil
+	}
+
+	var buf bytes.Buffer
+	if err := notionMarkdown.Convert([]byte(p.markdown), &buf); err != nil {
+		return util.TipTapNode{}, err
+	}
+	root, err := html.Parse(&buf)
+	if err != nil {
+		return util.TipTapNode{}, err
+	}
+
+	if err := im.resolveNotionLinks(ex, p, root); err != nil {
+		return util.TipTapNode{}, err
+	}
+	convertCheckboxLists(root)
+
+	return util.HTMLNodeToTipTap(root), nil
+}
+
+// resolveNotionLinks points the links and images of a page at the imported
+// notes, views and files. A paragraph holding only a link to a sub-page or
+// database shows it the way the editor does.
+func (im *Importer) resolveNotionLinks(ex *notionExport, p *notionPage, root *html.Node) error {
+	var links, images []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.A:
+				links = append(links, n)
+			case atom.Img:
+				images = append(images, n)
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	for _, img := range images {
+		target, ok := notionTarget(p.path, attrOf(img, "src"))
+		if !ok {
+			continue
+		}
+		src, err := im.notionAsset(ex, p, target)
+		if err != nil {
+			return err
+		}
+		setAttrOf(img, "src", src)
+	}
+
+	for _, a := range links {
+		target, ok := notionTarget(p.path, attrOf(a, "href"))
+		if !ok {
+			continue
+		}
+
+		if linked, ok := ex.pages[target]; ok {
+			if linked.parent == p && onlyChild(a) {
+				replaceBlock(a, &html.Node{Type: html.ElementNode, Data: "sub-page-node", Attr: []html.Attribute{
+					{Key: "noteid", Val: linked.note.ID},
+					{Key: "title", Val: linked.note.Title},
+				}})
+				continue
+			}
+			setAttrOf(a, "href", "/workspaces/"+im.WorkspaceID+"/notes/"+linked.note.ID)
+			continue
+		}
+
+		key := strings.TrimSuffix(strings.TrimSuffix(target, path.Ext(target)), "_all")
+		if d, ok := ex.databases[key]; ok {
+			if d.view.ID == "" {
+				continue
+			}
+			if d.parent == p && !d.shown && onlyChild(a) {
+				d.shown = true
+				replaceBlock(a, &html.Node{Type: html.ElementNode, Data: "view-node", Attr: []html.Attribute{
+					{Key: "viewid", Val: d.view.ID},
+					{Key: "viewtype", Val: d.view.Type},
+					{Key: "name", Val: d.view.Name},
+				}})
+				continue
+			}
+			setAttrOf(a, "href", "/workspaces/"+im.WorkspaceID+"/spreadsheet/"+d.view.ID)
+			continue
+		}
+
+		href, err := im.notionAsset(ex, p, target)
+		if err != nil {
+			return err
+		}
+		setAttrOf(a, "href", href)
+	}
+	return nil
+}
+
+// notionAsset stores a file of the export once and returns its address.
+// Files missing from the export keep their relative address.
+func (im *Importer) notionAsset(ex *notionExport, p *notionPage, target string) (string, error) {
+	if src, ok := ex.assets[target]; ok {
+		return src, nil
+	}
+	f, ok := ex.files[target]
+	if !ok {
+		im.warn(p.title + ": file not found in export: " + target)
+		ex.assets[target] = target
+		return target, nil
+	}
+	data, err := readZipFile(f)
+	if err != nil {
+		return "", err
+	}
+	src, err := im.SaveFile(path.Base(target), data)
+	if err != nil {
+		return "", err
+	}
+	ex.assets[target] = src
+	return src, nil
+}
+
+// notionTarget resolves a relative link of a page to the path of the file
+// in the export.
+func notionTarget(pagePath, ref string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil || ref == "" || u.Scheme != "" || u.Host != "" || u.Path == "" || strings.HasPrefix(u.Path, "/") {
+		return "", false
+	}
+	return path.Join(path.Dir(pagePath), u.Path), true
+}
+
+// notionTitle is the title of a page or database named by a file of the
+// export, without its extension and id.
+func notionTitle(name string) string {
+	base := path.Base(name)
+	base = strings.TrimSuffix(base, path.Ext(base))
+	base = strings.TrimSuffix(base, "_all")
+	if m := notionName.FindStringSubmatch(base); m != nil && m[1] != "" {
+		return m[1]
+	}
+	return base
+}
+
+// convertCheckboxLists turns lists of checkbox items into task lists in the
+// form the editor renders them to. Checkboxes elsewhere become text.
+func convertCheckboxLists(root *html.Node) {
+	var inputs []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Input && strings.EqualFold(attrOf(n, "type"), "checkbox") {
+			inputs = append(inputs, n)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	for _, input := range inputs {
+		checked := hasAttr(input, "checked")
+		li := input.Parent
+		if li != nil && li.DataAtom == atom.Li && startsWith(li, input) && li.Parent != nil && li.Parent.DataAtom == atom.Ul {
+			setAttrOf(li.Parent, "data-type", "taskList")
+			if checked {
+				setAttrOf(li, "data-checked", "true")
+			} else {
+				setAttrOf(li, "data-checked", "false")
+			}
+			li.RemoveChild(input)
+			continue
+		}
+		text := "☐ "
+		if checked {
+			text = "☑ "
+		}
+		input.Parent.InsertBefore(&html.Node{Type: html.TextNode, Data: text}, input)
+		input.Parent.RemoveChild(input)
+	}
+}
+
+// onlyChild reports whether a link is the only content of its paragraph.
+func onlyChild(a *html.Node) bool {
+	p := a.Parent
+	if p == nil || p.DataAtom != atom.P {
+		return false
+	}
+	for c := p.FirstChild; c != nil; c = c.NextSibling {
+		if c != a && !(c.Type == html.TextNode && strings.TrimSpace(c.Data) == "") {
+			return false
+		}
+	}
+	return true
+}
+
+// replaceBlock replaces the paragraph holding a link with el.
+func replaceBlock(a, el *html.Node) {
+	p := a.Parent
+	p.Parent.InsertBefore(el, p)
+	p.Parent.RemoveChild(p)
+}
+
+func viewNode(v model.View) util.TipTapNode {
+	return util.TipTapNode{
+		Type:  "viewNode",
+		Attrs: map[string]interface{}{"viewId": v.ID, "viewType": v.Type, "name": v.Name},
+	}
+}
+
+func (ex *notionExport) sortedDatabases() []*notionDatabase {
+	var databases []*notionDatabase
+	for _, d := range ex.databases {
+		databases = append(databases, d)
+	}
+	sort.Slice(databases, func(i, j int) bool { return databases[i].path < databases[j].path })
+	return databases
+}
+
+func walkTipTap(n util.TipTapNode, visit func(util.TipTapNode)) {
+	visit(n)
+	for _, child := range n.Content {
+		walkTipTap(child, visit)
+	}
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > maxNotionEntrySize {
+		return nil, errors.New(f.Name + " is too large")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, maxNotionEntrySize))
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func setAttrOf(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
@@ -0,0 +1,233 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/util"
+)
+
+const (
+	projectsID = "0123456789abcdef0123456789abcdef"
+	planID     = "11111111111111111111111111111111"
+	notesID    = "22222222222222222222222222222222"
+	tasksID    = "33333333333333333333333333333333"
+	writeID    = "44444444444444444444444444444444"
+)
+
+// notionSample is a small "Markdown & CSV" export: a page with a sub-page,
+// a database and its rows, and a second top level page. Names carry the
+// ids Notion appends, and links are relative and escaped as Notion writes
+// them.
+var notionSample = map[string]string{
+	"Projects " + projectsID + ".md": "# Projects\n\n" +
+		"Overview, see the [notes](Notes%20" + notesID + ".md).\n\n" +
+		"[Plan](Projects%20" + projectsID + "/Plan%20" + planID + ".md)\n\n" +
+		"[Tasks](Projects%20" + projectsID + "/Tasks%20" + tasksID + ".csv)\n",
+	"Projects " + projectsID + "/Plan " + planID + ".md": "# Plan: Q3\n\n" +
+		"- [x] Draft\n- [ ] Review\n\n" +
+		"Back to [Projects](../Projects%20" + projectsID + ".md).\n\n" +
+		"![chart](Plan%20" + planID + "/chart.png)\n",
+	"Projects " + projectsID + "/Plan " + planID + "/chart.png":                  "\x89PNG\r\n\x1a\nchart",
+	"Projects " + projectsID + "/Tasks " + tasksID + ".csv":                      "Name,Status\nWrite,Done\n",
+	"Projects " + projectsID + "/Tasks " + tasksID + "_all.csv":                  "Name,Status\nWrite,Done\nLater,Open\n",
+	"Projects " + projectsID + "/Tasks " + tasksID + "/Write " + writeID + ".md": "# Write\n\nThe row page.\n",
+	"Notes " + notesID + ".md":                                                   "# Notes\n\nSee [the report](report.pdf).\n",
+}
+
+// zipExport writes files to a zip, in name order.
+func zipExport(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestImportNotion(t *testing.T) {
+	im := newTestImporter(t)
+	defer im.End(true)
+	r := zipExport(t, notionSample)
+	roots, err := im.ImportNotion(r, r.Size(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rootTitles []string
+	for _, n := range roots {
+		rootTitles = append(rootTitles, n.Title)
+	}
+	if want := []string{"Notes", "Projects"}; !reflect.DeepEqual(rootTitles, want) {
+		t.Errorf("roots = %q, want %q", rootTitles, want)
+	}
+	if want := []string{"Notes: file not found in export: report.pdf"}; !reflect.DeepEqual(im.Result().Warnings, want) {
+		t.Errorf("warnings = %q, want %q", im.Result().Warnings, want)
+	}
+
+	// Titles drop the ids of the file names, and the heading of a page
+	// wins over its file name.
+	notes := notesByTitle(t, im)
+	projects, plan, write, notesPage := notes["Projects"], notes["Plan: Q3"], notes["Write"], notes["Notes"]
+	if projects.ID == "" || plan.ID == "" || write.ID == "" || notesPage.ID == "" || len(notes) != 4 {
+		t.Fatalf("notes = %v, want Projects, Plan: Q3, Write and Notes", notes)
+	}
+	if plan.ParentID != projects.ID {
+		t.Errorf("Plan is under %q, want Projects", plan.ParentID)
+	}
+	if write.ParentID != projects.ID {
+		t.Errorf("database row is under %q, want the parent of the database", write.ParentID)
+	}
+
+	// The page shows its linked sub-page and database in place, links to
+	// other pages point at their notes, and sub-pages it does not link to
+	// are listed at the end.
+	doc := decodeDoc(t, projects.Content)
+	wantTypes := []string{"paragraph", "subPage", "viewNode", "subPage"}
+	if got := nodeTypes(doc.Content); !reflect.DeepEqual(got, wantTypes) {
+		t.Fatalf("Projects holds %v, want %v", got, wantTypes)
+	}
+	if href := linkHref(doc.Content[0]); href != "/workspaces/w1/notes/"+notesPage.ID {
+		t.Errorf("link to Notes = %q, want the Notes note", href)
+	}
+	if id := doc.Content[1].Attrs["noteId"]; id != plan.ID {
+		t.Errorf("sub-page = %v, want Plan", id)
+	}
+	if id := doc.Content[3].Attrs["noteId"]; id != write.ID {
+		t.Errorf("listed sub-page = %v, want the row page", id)
+	}
+
+	view, err := im.DB.FindView(model.View{ID: doc.Content[2].Attrs["viewId"].(string)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Name != "Tasks" || view.Type != "spreadsheet" || view.NoteID != projects.ID {
+		t.Errorf("view = %s %s on %s, want the Tasks spreadsheet on Projects", view.Name, view.Type, view.NoteID)
+	}
+	if !strings.Contains(view.Data, "Later") {
+		t.Error("database was not read from the file of all rows")
+	}
+
+	doc = decodeDoc(t, plan.Content)
+	wantTypes = []string{"taskList", "paragraph", "image"}
+	if got := nodeTypes(doc.Content); !reflect.DeepEqual(got, wantTypes) {
+		t.Fatalf("Plan holds %v, want %v", got, wantTypes)
+	}
+	items := doc.Content[0].Content
+	if len(items) != 2 || items[0].Attrs["checked"] != true || items[1].Attrs["checked"] != false {
+		t.Errorf("task items = %+v, want Draft done and Review open", items)
+	}
+	if href := linkHref(doc.Content[1]); href != "/workspaces/w1/notes/"+projects.ID {
+		t.Errorf("link to the parent = %q, want the Projects note", href)
+	}
+	if src, _ := doc.Content[2].Attrs["src"].(string); !strings.HasPrefix(src, "/api/v1/workspaces/w1/files/") {
+		t.Errorf("image src = %q, want the stored chart", src)
+	}
+}
+
+// linkHref returns the address of the first link of a paragraph.
+func linkHref(p util.TipTapNode) string {
+	for _, n := range p.Content {
+		for _, m := range n.Marks {
+			if m.Type == "link" {
+				href, _ := m.Attrs["href"].(string)
+				return href
+			}
+		}
+	}
+	return ""
+}
+
+func TestNotionTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Projects " + projectsID + ".md", "Projects"},
+		{"a/b/Tasks " + tasksID + "_all.csv", "Tasks"},
+		{"Tasks " + tasksID, "Tasks"},
+		{projectsID + ".md", projectsID},
+		{"Plain.md", "Plain"},
+		{"Short abc123.md", "Short abc123"},
+	}
+	for _, tt := range tests {
+		if got := notionTitle(tt.name); got != tt.want {
+			t.Errorf("notionTitle(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNotionTarget(t *testing.T) {
+	tests := []struct {
+		page, ref string
+		want      string
+		ok        bool
+	}{
+		{"A/B.md", "C%20" + planID + ".md", "A/C " + planID + ".md", true},
+		{"A/B.md", "../D.md#heading", "D.md", true},
+		{"A/B.md", "https://example.com/x.md", "", false},
+		{"A/B.md", "/root.md", "", false},
+		{"A/B.md", "#top", "", false},
+		{"A/B.md", "", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := notionTarget(tt.page, tt.ref)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("notionTarget(%q, %q) = %q, %v, want %q, %v", tt.page, tt.ref, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestImportNotionParts(t *testing.T) {
+	// Large workspaces are exported as a zip of zipped parts.
+	var outer bytes.Buffer
+	zw := zip.NewWriter(&outer)
+	for i, files := range []map[string]string{
+		{"A " + projectsID + ".md": "# A\n"},
+		{"A " + projectsID + "/B " + planID + ".md": "# B\n"},
+	} {
+		part, err := zw.Create("Export-part-" + string(rune('1'+i)) + ".zip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := zipExport(t, files).WriteTo(part); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	im := newTestImporter(t)
+	defer im.End(true)
+	r := bytes.NewReader(outer.Bytes())
+	roots, err := im.ImportNotion(r, r.Size(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := notesByTitle(t, im)
+	if len(roots) != 1 || notes["B"].ParentID != notes["A"].ID {
+		t.Errorf("parts were not imported as one export: roots %v, notes %v", roots, notes)
+	}
+}
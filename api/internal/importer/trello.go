@@ -0,0 +1,366 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/urlfetcher"
+	"github.com/collabreef/collabreef/internal/util"
+)
+
+// fetchFile downloads attachments. urlfetcher.SafeFetchFile refuses private
+// and loopback addresses, so tests replace it to serve attachments locally.
+var fetchFile = urlfetcher.SafeFetchFile
+
+type trelloBoard struct {
+	Name       string            `json:"name"`
+	Desc       string            `json:"desc"`
+	Lists      []trelloList      `json:"lists"`
+	Cards      []trelloCard      `json:"cards"`
+	Checklists []trelloChecklist `json:"checklists"`
+}
+
+type trelloList struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Closed bool    `json:"closed"`
+	Pos    float64 `json:"pos"`
+}
+
+type trelloCard struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Desc        string             `json:"desc"`
+	Closed      bool               `json:"closed"`
+	IDList      string             `json:"idList"`
+	Pos         float64            `json:"pos"`
+	Due         string             `json:"due"`
+	Labels      []trelloLabel      `json:"labels"`
+	Attachments []trelloAttachment `json:"attachments"`
+}
+
+type trelloLabel struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type trelloAttachment struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	MimeType string `json:"mimeType"`
+	IsUpload bool   `json:"isUpload"`
+}
+
+type trelloChecklist struct {
+	IDCard     string            `json:"idCard"`
+	Name       string            `json:"name"`
+	Pos        float64           `json:"pos"`
+	CheckItems []trelloCheckItem `json:"checkItems"`
+}
+
+type trelloCheckItem struct {
+	Name  string  `json:"name"`
+	State string  `json:"state"`
+	Pos   float64 `json:"pos"`
+}
+
+// TrelloBoard is the export of a Trello board read by ReadTrello, with the
+// attachments it could download stored.
+type TrelloBoard struct {
+	board trelloBoard
+	// attachments are the stored attachments, by card id and URL.
+	attachments map[string]storedFile
+}
+
+// ReadTrello reads the JSON export of a Trello board, and downloads and
+// stores the attachments of its open cards. Downloads are slow, so they
+// are done before the transaction of the import, which ImportTrello runs
+// in. Attachments that cannot be downloaded, such as uploads that need a
+// Trello login, are linked to.
+func (im *Importer) ReadTrello(ctx context.Context, r io.Reader) (*TrelloBoard, error) {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, errors.New("invalid Trello export: " + err.Error())
+	}
+	if board.Lists == nil && board.Cards == nil {
+		return nil, errors.New("invalid Trello export: lists not found")
+	}
+
+	open := map[string]bool{}
+	for _, list := range board.Lists {
+		open[list.ID] = !list.Closed
+	}
+	b := &TrelloBoard{board: board, attachments: map[string]storedFile{}}
+	for _, card := range board.Cards {
+		if card.Closed || !open[card.IDList] {
+			continue
+		}
+		for _, a := range card.Attachments {
+			if !a.IsUpload {
+				continue
+			}
+			if f, ok := im.fetchTrelloAttachment(ctx, trelloCardTitle(card), a); ok {
+				b.attachments[card.ID+" "+a.URL] = f
+			}
+		}
+	}
+	return b, nil
+}
+
+// ImportTrello imports a board read by ReadTrello. The board becomes a note
+// under parentID holding a kanban view, with a column per open list and the
+// open cards of the list in their order. Cards with a description,
+// checklists or attachments get a note of their own under the board note,
+// which the card links to.
+func (im *Importer) ImportTrello(b *TrelloBoard, parentID string) (model.Note, error) {
+	board := b.board
+
+	name := strings.TrimSpace(board.Name)
+	if name == "" {
+		name = "Trello board"
+	}
+	note, err := im.CreateNote(parentID, name, "", time.Time{}, time.Time{})
+	if err != nil {
+		return model.Note{}, err
+	}
+	view, err := im.CreateView(note.ID, name, "kanban", "")
+	if err != nil {
+		return model.Note{}, err
+	}
+
+	checklists := map[string][]trelloChecklist{}
+	for _, cl := range board.Checklists {
+		checklists[cl.IDCard] = append(checklists[cl.IDCard], cl)
+	}
+
+	sort.SliceStable(board.Lists, func(i, j int) bool { return board.Lists[i].Pos < board.Lists[j].Pos })
+	sort.SliceStable(board.Cards, func(i, j int) bool { return board.Cards[i].Pos < board.Cards[j].Pos })
+
+	columns := []interface{}{}
+	archived := 0
+	for _, list := range board.Lists {
+		if list.Closed {
+			continue
+		}
+		items := []interface{}{}
+		for _, card := range board.Cards {
+			if card.IDList != list.ID {
+				continue
+			}
+			if card.Closed {
+				archived++
+				continue
+			}
+			item, err := im.importTrelloCard(b, note.ID, card, checklists[card.ID])
+			if err != nil {
+				return model.Note{}, err
+			}
+			items = append(items, item)
+		}
+
+		data, err := json.Marshal(map[string]interface{}{"items": items})
+		if err != nil {
+			return model.Note{}, err
+		}
+		col, err := im.CreateViewObject(view.ID, list.Name, "kanban_column", string(data))
+		if err != nil {
+			return model.Note{}, err
+		}
+		columns = append(columns, col.ID)
+	}
+	if archived > 0 {
+		im.warn(fmt.Sprintf("%s: skipped %d archived cards", name, archived))
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"columns": columns})
+	if err != nil {
+		return model.Note{}, err
+	}
+	view.Data = string(data)
+	if err := im.DB.SetViewData(view); err != nil {
+		return model.Note{}, err
+	}
+
+	doc, err := markdownDocument(board.Desc)
+	if err != nil {
+		return model.Note{}, err
+	}
+	doc.Content = append(doc.Content, viewNode(view))
+	content, err := json.Marshal(doc)
+	if err != nil {
+		return model.Note{}, err
+	}
+	note.Content = string(content)
+	if err := im.SetNoteContent(note, note.Content); err != nil {
+		return model.Note{}, err
+	}
+	return note, nil
+}
+
+// importTrelloCard returns the kanban item of a card, creating the note of
+// the card if it has content beyond its title.
+func (im *Importer) importTrelloCard(b *TrelloBoard, boardID string, card trelloCard, checklists []trelloChecklist) (map[string]interface{}, error) {
+	title := trelloCardTitle(card)
+	item := map[string]interface{}{"id": util.NewId(), "title": title}
+	if due, err := time.Parse(time.RFC3339, card.Due); err == nil {
+		item["due_date"] = due.UTC().Format("2006-01-02")
+	}
+
+	if strings.TrimSpace(card.Desc) == "" && len(checklists) == 0 && len(card.Attachments) == 0 {
+		return item, nil
+	}
+
+	doc, err := markdownDocument(card.Desc)
+	if err != nil {
+		return nil, err
+	}
+	var labels []string
+	for _, l := range card.Labels {
+		labels = append(labels, l.Name)
+	}
+	doc = withTags(doc, labels)
+
+	sort.SliceStable(checklists, func(i, j int) bool { return checklists[i].Pos < checklists[j].Pos })
+	for _, cl := range checklists {
+		doc.Content = append(doc.Content, trelloChecklistNodes(cl)...)
+	}
+	for _, a := range card.Attachments {
+		node, err := im.trelloAttachment(b, card.ID, a)
+		if err != nil {
+			return nil, err
+		}
+		doc.Content = append(doc.Content, node)
+	}
+
+	content, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	n, err := im.CreateNote(boardID, title, string(content), time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	item["note_id"] = n.ID
+	return item, nil
+}
+
+func trelloChecklistNodes(cl trelloChecklist) []util.TipTapNode {
+	sort.SliceStable(cl.CheckItems, func(i, j int) bool { return cl.CheckItems[i].Pos < cl.CheckItems[j].Pos })
+
+	list := util.TipTapNode{Type: "taskList"}
+	for _, ci := range cl.CheckItems {
+		list.Content = append(list.Content, util.TipTapNode{
+			Type:  "taskItem",
+			Attrs: map[string]interface{}{"checked": ci.State == "complete"},
+			Content: []util.TipTapNode{{
+				Type:    "paragraph",
+				Content: textNodes(ci.Name),
+			}},
+		})
+	}
+
+	var nodes []util.TipTapNode
+	if name := strings.TrimSpace(cl.Name); name != "" {
+		nodes = append(nodes, util.TipTapNode{
+			Type:    "heading",
+			Attrs:   map[string]interface{}{"level": 3},
+			Content: textNodes(name),
+		})
+	}
+	if len(list.Content) > 0 {
+		nodes = append(nodes, list)
+	}
+	return nodes
+}
+
+func trelloCardTitle(card trelloCard) string {
+	if title := strings.TrimSpace(card.Name); title != "" {
+		return title
+	}
+	return "Untitled"
+}
+
+// fetchTrelloAttachment downloads and stores an uploaded attachment of a
+// card. It reports false, with a warning, when the attachment could not be.
+func (im *Importer) fetchTrelloAttachment(ctx context.Context, card string, a trelloAttachment) (storedFile, bool) {
+	name := trelloAttachmentName(a)
+	data, contentType, err := fetchFile(ctx, a.URL)
+	if err != nil {
+		im.warn(card + ": attachment " + name + " could not be downloaded: " + err.Error())
+		return storedFile{}, false
+	}
+	if path.Ext(name) == "" {
+		if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+			name += exts[0]
+		}
+	}
+	f, err := im.storeFile(name, data)
+	if err != nil {
+		im.warn(card + ": attachment " + name + " could not be stored: " + err.Error())
+		return storedFile{}, false
+	}
+	return f, true
+}
+
+// trelloAttachment returns the node of an attachment of a card: the stored
+// file, or a link to the attachment when it was not stored.
+func (im *Importer) trelloAttachment(b *TrelloBoard, cardID string, a trelloAttachment) (util.TipTapNode, error) {
+	f, ok := b.attachments[cardID+" "+a.URL]
+	if !ok {
+		return util.TipTapNode{
+			Type: "paragraph",
+			Content: []util.TipTapNode{{
+				Type:  "text",
+				Text:  trelloAttachmentName(a),
+				Marks: []util.TipTapMark{{Type: "link", Attrs: map[string]interface{}{"href": a.URL, "target": "_blank"}}},
+			}},
+		}, nil
+	}
+
+	src, err := im.addFile(f)
+	if err != nil {
+		return util.TipTapNode{}, err
+	}
+	if mediaType, _, _ := mime.ParseMediaType(f.contentType); strings.HasPrefix(mediaType, "image/") {
+		return util.TipTapNode{Type: "image", Attrs: map[string]interface{}{"src": src, "name": f.name}}, nil
+	}
+	return util.TipTapNode{Type: "attachment", Attrs: map[string]interface{}{"src": src, "name": f.name}}, nil
+}
+
+func trelloAttachmentName(a trelloAttachment) string {
+	if name := strings.TrimSpace(a.Name); name != "" {
+		return name
+	}
+	return a.URL
+}
+
+// markdownDocument converts markdown to a document, without the empty
+// paragraph that stands in for no content.
+func markdownDocument(md string) (util.TipTapNode, error) {
+	doc := util.TipTapNode{Type: "doc"}
+	if strings.TrimSpace(md) == "" {
+		return doc, nil
+	}
+	content, err := util.MarkdownToTipTap(md)
+	if err != nil {
+		return doc, err
+	}
+	err = json.Unmarshal([]byte(content), &doc)
+	return doc, err
+}
+
+func textNodes(s string) []util.TipTapNode {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil
+	}
+	return []util.TipTapNode{{Type: "text", Text: s}}
+}
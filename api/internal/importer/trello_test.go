@@ -0,0 +1,223 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/storage"
+)
+
+// stubFetchFile serves the attachments of testdata/board.json, failing for
+// those missing from files.
+func stubFetchFile(t *testing.T, files map[string]string) {
+	t.Helper()
+	saved := fetchFile
+	t.Cleanup(func() { fetchFile = saved })
+	fetchFile = func(ctx context.Context, rawURL string) ([]byte, string, error) {
+		data, ok := files[rawURL]
+		if !ok {
+			return nil, "", errors.New("401 Unauthorized")
+		}
+		return []byte(data), "", nil
+	}
+}
+
+// kanbanItem is an item of a kanban column as the import writes it.
+type kanbanItem struct {
+	Title   string `json:"title"`
+	DueDate string `json:"due_date"`
+	NoteID  string `json:"note_id"`
+}
+
+func TestImportTrello(t *testing.T) {
+	stubFetchFile(t, map[string]string{"https://trello.example/logo.png": "\x89PNG\r\n\x1a\nlogo"})
+	im := newTestImporter(t)
+	var note model.Note
+	importFixture(t, im, "board.json", func(f *os.File) error {
+		b, err := im.ReadTrello(context.Background(), f)
+		if err != nil {
+			return err
+		}
+		note, err = im.ImportTrello(b, "")
+		return err
+	})
+
+	res := im.Result()
+	wantWarnings := []string{
+		"Untitled: attachment private.pdf could not be downloaded: 401 Unauthorized",
+		"Launch: skipped 1 archived cards",
+	}
+	if !reflect.DeepEqual(res.Warnings, wantWarnings) {
+		t.Errorf("warnings = %q, want %q", res.Warnings, wantWarnings)
+	}
+	if res.Views != 1 || res.Files != 1 {
+		t.Errorf("result = %+v, want 1 view and 1 file", res)
+	}
+
+	doc := decodeDoc(t, note.Content)
+	if got := nodeTypes(doc.Content); !reflect.DeepEqual(got, []string{"paragraph", "viewNode"}) {
+		t.Fatalf("board note holds %v, want the description and the view", got)
+	}
+	view, err := im.DB.FindView(model.View{ID: doc.Content[1].Attrs["viewId"].(string)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Open lists become columns in list order, each holding its open cards
+	// in card order.
+	var data struct {
+		Columns []string `json:"columns"`
+	}
+	if err := json.Unmarshal([]byte(view.Data), &data); err != nil {
+		t.Fatal(err)
+	}
+	columns := map[string][]kanbanItem{}
+	var names []string
+	for _, id := range data.Columns {
+		o, err := im.DB.FindViewObject(model.ViewObject{ID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var col struct {
+			Items []kanbanItem `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(o.Data), &col); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, o.Name)
+		columns[o.Name] = col.Items
+	}
+	if want := []string{"To do", "Doing", "Done"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("columns = %q, want %q", names, want)
+	}
+	var titles []string
+	for _, name := range names {
+		for _, item := range columns[name] {
+			titles = append(titles, name+"/"+item.Title)
+		}
+	}
+	if want := []string{"To do/Copy", "To do/Website", "Doing/Untitled", "Done/QA"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("cards = %q, want %q", titles, want)
+	}
+
+	copyCard, site, press := columns["To do"][0], columns["To do"][1], columns["Doing"][0]
+	if copyCard.NoteID != "" {
+		t.Errorf("card without content has note %s", copyCard.NoteID)
+	}
+	if site.DueDate != "2024-05-01" {
+		t.Errorf("due date = %q, want 2024-05-01", site.DueDate)
+	}
+
+	// The note of a card holds its labels, description, checklists in
+	// order and attachments.
+	siteNote, err := im.DB.FindNote(model.Note{ID: site.NoteID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if siteNote.ParentID != note.ID {
+		t.Errorf("card note is under %q, want the board note", siteNote.ParentID)
+	}
+	doc = decodeDoc(t, siteNote.Content)
+	wantTypes := []string{"tagsNode", "paragraph", "taskList", "heading", "taskList"}
+	if got := nodeTypes(doc.Content); !reflect.DeepEqual(got, wantTypes) {
+		t.Fatalf("card note holds %v, want %v", got, wantTypes)
+	}
+	var items []string
+	for _, list := range []int{2, 4} {
+		for _, item := range doc.Content[list].Content {
+			items = append(items, item.Content[0].Content[0].Text)
+		}
+	}
+	if want := []string{"Domain", "Home", "About"}; !reflect.DeepEqual(items, want) {
+		t.Errorf("checklist items = %q, want %q", items, want)
+	}
+
+	pressNote, err := im.DB.FindNote(model.Note{ID: press.NoteID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = decodeDoc(t, pressNote.Content)
+	if got := nodeTypes(doc.Content); !reflect.DeepEqual(got, []string{"image", "paragraph", "paragraph"}) {
+		t.Fatalf("attachments = %v, want the stored image and two links", got)
+	}
+	if src, _ := doc.Content[0].Attrs["src"].(string); !strings.HasPrefix(src, "/api/v1/workspaces/w1/files/") {
+		t.Errorf("image src = %q, want a stored file", src)
+	}
+	if href := linkHref(doc.Content[1]); href != "https://docs.example/brief" {
+		t.Errorf("link = %q, want the attachment URL", href)
+	}
+	if href := linkHref(doc.Content[2]); href != "https://trello.example/private.pdf" {
+		t.Errorf("link = %q, want the URL of the failed download", href)
+	}
+}
+
+func TestReadTrelloInvalid(t *testing.T) {
+	im := newTestImporter(t)
+	defer im.End(false)
+	for _, export := range []string{`{"name": "x"}`, `[`} {
+		if _, err := im.ReadTrello(context.Background(), strings.NewReader(export)); err == nil {
+			t.Errorf("ReadTrello(%s) succeeded, want an error", export)
+		}
+	}
+}
+
+func TestEnd(t *testing.T) {
+	hashOf := func(data string) string {
+		sum := sha256.Sum256([]byte(data))
+		return hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		name      string
+		committed bool
+		// existing is stored before the import.
+		existing bool
+		wantKept bool
+	}{
+		{name: "committed import keeps its blobs", committed: true, wantKept: true},
+		{name: "rolled back import deletes its blobs", committed: false, wantKept: false},
+		{name: "rolled back import keeps blobs it did not create", committed: false, existing: true, wantKept: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			im := newTestImporter(t)
+			const data = "attachment"
+			hash := hashOf(data)
+			if tt.existing {
+				if _, err := storage.SaveBlob(im.Storage, hash, strings.NewReader(data)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// The same content stored twice is one blob.
+			for i := 0; i < 2; i++ {
+				if _, err := im.SaveFile("a.txt", []byte(data)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			im.End(tt.committed)
+
+			_, err := im.Storage.Stat(storage.BlobSegments(hash))
+			if kept := err == nil; kept != tt.wantKept {
+				t.Errorf("blob kept = %v, want %v (stat error %v)", kept, tt.wantKept, err)
+			}
+
+			// The blob is unlocked, so a later import can store it.
+			next := newTestImporter(t)
+			next.Storage = im.Storage
+			if _, err := next.SaveFile("b.txt", []byte(data)); err != nil {
+				t.Fatal(err)
+			}
+			next.End(true)
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package model
+
+// CanvasObject is an entry of the canvas-objects map kept in the data of a
+// whiteboard view, such as a stroke, shape or text. The entries are indexed
+// by their bounds so that a viewport can be loaded before the whole board.
+type CanvasObject struct {
+	ViewID string `json:"view_id"`
+	ID     string `json:"id"`
+	// Data is the JSON of the entry, as in the view data.
+	Data string   `json:"data"`
+	MinX *float64 `json:"-"`
+	MinY *float64 `json:"-"`
+	MaxX *float64 `json:"-"`
+	MaxY *float64 `json:"-"`
+}
+
+type CanvasObjectFilter struct {
+	ViewID string
+	// BBox selects objects whose bounds intersect it. Objects without
+	// recorded bounds always match.
+	BBox *BBox
+}
@@ -3,8 +3,11 @@ package model
 type FileFilter struct {
 	WorkspaceID string
 	ID          string
+	Name        string
 	Exts        []string
 	Query       string
+	CreatedBy   string
+	Hash        string
 	PageSize    int
 	PageNumber  int
 }
@@ -16,9 +19,19 @@ type File struct {
 	Size             int64
 	Ext              string
 	OriginalFilename string `json:"original_filename"`
+	ContentType      string `json:"content_type"`
+	Hash             string `json:"hash"`
 	Visibility       string
 	CreatedAt        string
 	CreatedBy        string
 	UpdatedAt        string
 	UpdatedBy        string
 }
+
+type FileUsage struct {
+	WorkspaceID string `json:"workspace_id"`
+	CreatedBy   string `json:"created_by"`
+	ContentType string `json:"content_type"`
+	Files       int64  `json:"files"`
+	Size        int64  `json:"size"`
+}
@@ -0,0 +1,13 @@
+package model
+
+// FileVariant is a derived copy of a file, such as a resized image, kept in
+// storage under Name. Variant names the derivation.
+type FileVariant struct {
+	WorkspaceID string `json:"workspace_id"`
+	FileName    string `json:"file_name"`
+	Variant     string `json:"variant"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	CreatedAt   string `json:"created_at"`
+}
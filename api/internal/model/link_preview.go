@@ -0,0 +1,17 @@
+package model
+
+// LinkPreview is the cached metadata of a URL. Oembed holds the oEmbed
+// response as JSON, and Error the reason a lookup failed; failed lookups are
+// cached too, for a shorter time.
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+	Favicon     string `json:"favicon"`
+	SiteName    string `json:"site_name"`
+	Oembed      string `json:"oembed"`
+	Error       string `json:"error"`
+	FetchedAt   string `json:"fetched_at"`
+	ExpiresAt   string `json:"expires_at"`
+}
@@ -2,13 +2,17 @@ package model
 
 type NoteFilter struct {
 	WorkspaceID string
-	NoteIDs     string
+	NoteIDs     []string
 	PageSize    int
 	PageNumber  int
 	UserID      string
 	Query       string
 	SortBy      string // "updated_at" or "created_at" (default)
 	ParentID    string // filter by parent note id; use "null" to get root notes
+	Clipped     bool   // only notes clipped from a web page
+	// ContentContains matches notes whose content holds the text literally,
+	// without the wildcards of Query.
+	ContentContains string
 }
 
 type Note struct {
@@ -18,6 +22,7 @@ type Note struct {
 	Title       string `json:"title"`
 	Content     string `json:"content"`
 	Visibility  string `json:"visibility"`
+	SourceURL   string `json:"source_url"`
 	CreatedAt   string `json:"created_at"`
 	CreatedBy   string `json:"created_by"`
 	UpdatedAt   string `json:"updated_at"`
@@ -0,0 +1,70 @@
+package model
+
+type RSSFeedFilter struct {
+	// UserID limits the feeds to those the user subscribes to.
+	UserID string
+	// Subscribed limits the feeds to those with at least one subscriber.
+	Subscribed bool
+}
+
+// RSSFeed is a feed fetched by the server and cached for its subscribers.
+// Etag and LastModified are the validators of the last response, sent back
+// on the next fetch.
+type RSSFeed struct {
+	ID           string `json:"id"`
+	URL          string `json:"url"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	Link         string `json:"link"`
+	Etag         string `json:"-"`
+	LastModified string `json:"-"`
+	FetchedAt    string `json:"fetched_at"`
+	NextFetchAt  string `json:"-"`
+	Failures     int    `json:"-"`
+	LastError    string `json:"last_error"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type RSSItemFilter struct {
+	FeedID string
+	// UserID together with Unread limits the items to those the user has
+	// not read.
+	UserID     string
+	Unread     bool
+	PageSize   int
+	PageNumber int
+}
+
+// RSSItem is an entry of a cached feed, unique by GUID within its feed.
+// PublishedAt orders items; it is the publication date when the feed gives
+// one and the time the item was first seen otherwise.
+type RSSItem struct {
+	ID          string `json:"id"`
+	FeedID      string `json:"feed_id"`
+	GUID        string `json:"guid"`
+	Title       string `json:"title"`
+	Link        string `json:"link"`
+	Description string `json:"description"`
+	PubDate     string `json:"pub_date"`
+	ImageURL    string `json:"image_url"`
+	PublishedAt string `json:"published_at"`
+	CreatedAt   string `json:"created_at"`
+}
+
+type RSSSubscriptionFilter struct {
+	UserID string
+	FeedID string
+}
+
+type RSSSubscription struct {
+	UserID    string `json:"user_id"`
+	FeedID    string `json:"feed_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+// RSSItemRead records that a user has read an item.
+type RSSItemRead struct {
+	UserID string `json:"user_id"`
+	ItemID string `json:"item_id"`
+	ReadAt string `json:"read_at"`
+}
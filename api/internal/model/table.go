@@ -0,0 +1,29 @@
+package model
+
+type TableRowFilter struct {
+	ViewID string
+	NoteID string
+}
+
+// TableRow adds a note as a row of a table view.
+type TableRow struct {
+	ViewID    string `json:"view_id"`
+	NoteID    string `json:"note_id"`
+	CreatedAt string `json:"created_at"`
+	CreatedBy string `json:"created_by"`
+}
+
+type NotePropertyFilter struct {
+	NoteIDs     []string
+	PropertyIDs []string
+}
+
+// NoteProperty is the value of a table property for a note. The property is
+// a table_property view object; Value is JSON.
+type NoteProperty struct {
+	NoteID     string `json:"note_id"`
+	PropertyID string `json:"property_id"`
+	Value      string `json:"value"`
+	UpdatedAt  string `json:"updated_at"`
+	UpdatedBy  string `json:"updated_by"`
+}
@@ -0,0 +1,39 @@
+package model
+
+// Uploads are written in parts through the API, with the tus protocol, or
+// by the client directly to the storage, with a presigned URL.
+const (
+	UploadMethodTus       = "tus"
+	UploadMethodPresigned = "presigned"
+)
+
+type UploadFilter struct {
+	WorkspaceID   string
+	CreatedBy     string
+	UpdatedBefore string
+	Pending       bool // only uploads that are not complete
+}
+
+// Upload is a file being uploaded in parts, over several requests. Received
+// counts the bytes received, of which Stored are in the parts of the
+// chunked file StorageID. Parts lists the tags of the parts, and HashState
+// the SHA-256 state of their content. Once complete, FileID and FileName
+// name the uploaded file; a presigned upload is given its FileName when it
+// starts, as the client writes the file there.
+type Upload struct {
+	ID          string `json:"id"`
+	WorkspaceID string `json:"workspace_id"`
+	Filename    string `json:"filename"`
+	Method      string `json:"method"`
+	Length      int64  `json:"length"`
+	Received    int64  `json:"received"`
+	Stored      int64  `json:"-"`
+	StorageID   string `json:"-"`
+	Parts       string `json:"-"`
+	HashState   string `json:"-"`
+	FileID      string `json:"file_id"`
+	FileName    string `json:"file_name"`
+	CreatedAt   string `json:"created_at"`
+	CreatedBy   string `json:"created_by"`
+	UpdatedAt   string `json:"updated_at"`
+}
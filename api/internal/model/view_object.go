@@ -4,10 +4,18 @@ type ViewObjectFilter struct {
 	ViewID     string
 	ObjectIDs  []string
 	ObjectType string
+	// BBox selects objects whose bounds intersect it. Objects without
+	// recorded bounds always match. It requires ViewID.
+	BBox       *BBox
 	PageSize   int
 	PageNumber int
 }
 
+// BBox is an axis-aligned rectangle in whiteboard coordinates.
+type BBox struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
 type ViewObject struct {
 	ID        string `json:"id"`
 	ViewID    string `json:"view_id"`
@@ -18,6 +26,11 @@ type ViewObject struct {
 	CreatedBy string `json:"created_by"`
 	UpdatedAt string `json:"updated_at"`
 	UpdatedBy string `json:"updated_by"`
+	// Bounds of whiteboard objects, kept for viewport queries.
+	MinX *float64 `json:"-"`
+	MinY *float64 `json:"-"`
+	MaxX *float64 `json:"-"`
+	MaxY *float64 `json:"-"`
 }
 
 // CalendarSlotData represents the data structure for calendar slots stored in the Data field
@@ -0,0 +1,42 @@
+package model
+
+// Snapshot kinds. Automatic snapshots are taken on a schedule and pruned;
+// restore snapshots keep the state a restore replaced.
+const (
+	SnapshotKindManual  = "manual"
+	SnapshotKindAuto    = "auto"
+	SnapshotKindRestore = "restore"
+)
+
+type ViewSnapshotFilter struct {
+	ViewID     string
+	Kind       string
+	PageSize   int
+	PageNumber int
+}
+
+// ChangedViewFilter selects the views that changed after their latest
+// snapshot and after Since: the view itself, one of its objects, or the
+// number of its objects. With an empty Since, views without snapshots are
+// selected too. Views come in id order after AfterID, so that they can be
+// paged through while snapshots are taken.
+type ChangedViewFilter struct {
+	Since   string
+	AfterID string
+	Limit   int
+}
+
+// ViewSnapshot is the data of a view together with all of its view objects
+// at one point in time. Objects holds the objects as a JSON array.
+type ViewSnapshot struct {
+	ID          string `json:"id"`
+	ViewID      string `json:"view_id"`
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	Hash        string `json:"-"`
+	Data        string `json:"data,omitempty"`
+	Objects     string `json:"objects,omitempty"`
+	ObjectCount int    `json:"object_count"`
+	CreatedAt   string `json:"created_at"`
+	CreatedBy   string `json:"created_by"`
+}
@@ -0,0 +1,26 @@
+package model
+
+type ViewTemplateFilter struct {
+	WorkspaceID string
+	ViewType    string
+	PageSize    int
+	PageNumber  int
+}
+
+// ViewTemplate is a reusable copy of the data and view objects of a view.
+// Objects holds the objects as a JSON array; their ids are replaced each
+// time the template is used.
+type ViewTemplate struct {
+	ID          string `json:"id"`
+	WorkspaceID string `json:"workspace_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Data        string `json:"data,omitempty"`
+	Objects     string `json:"objects,omitempty"`
+	ObjectCount int    `json:"object_count"`
+	CreatedAt   string `json:"created_at"`
+	CreatedBy   string `json:"created_by"`
+	UpdatedAt   string `json:"updated_at"`
+	UpdatedBy   string `json:"updated_by"`
+}
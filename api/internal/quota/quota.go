@@ -0,0 +1,161 @@
+// Package quota limits the storage uploaded files take: the size of a file,
+// and the total size of the files of a workspace and of a user. Limits are
+// configured as sizes such as "50MB", and a limit of zero is not enforced.
+// Derived files, such as image thumbnails, are not counted.
+package quota
+
+import (
+	"fmt"
+
+	"github.com/collabreef/collabreef/internal/config"
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/model"
+)
+
+const (
+	ScopeFile      = "file"
+	ScopeWorkspace = "workspace"
+	ScopeUser      = "user"
+)
+
+// Limits are the configured quotas, in bytes.
+type Limits struct {
+	MaxFileSize int64 `json:"max_file_size"`
+	Workspace   int64 `json:"workspace"`
+	User        int64 `json:"user"`
+}
+
+// Current returns the configured quotas.
+func Current() Limits {
+	return Limits{
+		MaxFileSize: int64(config.C.GetSizeInBytes(config.STORAGE_MAX_FILE_SIZE)),
+		Workspace:   int64(config.C.GetSizeInBytes(config.STORAGE_WORKSPACE_QUOTA)),
+		User:        int64(config.C.GetSizeInBytes(config.STORAGE_USER_QUOTA)),
+	}
+}
+
+// Error reports a file that would exceed a quota.
+type Error struct {
+	Scope string
+	Limit int64
+	Used  int64
+}
+
+func (e *Error) Error() string {
+	switch e.Scope {
+	case ScopeFile:
+		return fmt.Sprintf("file exceeds the maximum file size of %s", FormatSize(e.Limit))
+	case ScopeWorkspace:
+		return fmt.Sprintf("workspace storage quota exceeded: %s of %s used", FormatSize(e.Used), FormatSize(e.Limit))
+	}
+	return fmt.Sprintf("user storage quota exceeded: %s of %s used", FormatSize(e.Used), FormatSize(e.Limit))
+}
+
+// Check returns an *Error when a file of size bytes, added to a workspace
+// by a user, would exceed a quota. The storage of a user counts the files
+// they uploaded to any workspace. Uploads that are not complete count with
+// their length, and reservations with their size.
+func Check(d db.DB, workspaceID, userID string, size int64) error {
+	mu.Lock()
+	defer mu.Unlock()
+	return check(d, workspaceID, userID, size, nil, "")
+}
+
+// CheckUpload checks, as Check does, that an upload that is not complete
+// fits the quotas. The upload is not counted twice.
+func CheckUpload(d db.DB, up model.Upload) error {
+	mu.Lock()
+	defer mu.Unlock()
+	return check(d, up.WorkspaceID, up.CreatedBy, up.Length, nil, up.ID)
+}
+
+// check runs Check on behalf of a reservation or an upload, if any, while
+// mu is held.
+func check(d db.DB, workspaceID, userID string, size int64, own *Reservation, uploadID string) error {
+	limits := Current()
+
+	if limits.MaxFileSize > 0 && size > limits.MaxFileSize {
+		return &Error{Scope: ScopeFile, Limit: limits.MaxFileSize}
+	}
+
+	if limits.Workspace > 0 {
+		used, err := inUse(d, workspaceID, "", own, uploadID)
+		if err != nil {
+			return err
+		}
+		if used+size > limits.Workspace {
+			return &Error{Scope: ScopeWorkspace, Limit: limits.Workspace, Used: used}
+		}
+	}
+
+	if limits.User > 0 && userID != "" {
+		used, err := inUse(d, "", userID, own, uploadID)
+		if err != nil {
+			return err
+		}
+		if used+size > limits.User {
+			return &Error{Scope: ScopeUser, Limit: limits.User, Used: used}
+		}
+	}
+
+	return nil
+}
+
+// inUse returns the storage taken in a workspace or by a user: the size of
+// the files, the length of the uploads that are not complete, other than
+// uploadID, and the size of the reservations. The part of own that is
+// recorded as files is counted once.
+func inUse(d db.DB, workspaceID, userID string, own *Reservation, uploadID string) (int64, error) {
+	used, err := Used(d, model.FileFilter{WorkspaceID: workspaceID, CreatedBy: userID})
+	if err != nil {
+		return 0, err
+	}
+
+	uploads, err := d.FindUploads(model.UploadFilter{WorkspaceID: workspaceID, CreatedBy: userID, Pending: true})
+	if err != nil {
+		return 0, err
+	}
+	for _, up := range uploads {
+		if up.ID != uploadID {
+			used += up.Length
+		}
+	}
+
+	for r := range reservations {
+		switch {
+		case r == own:
+			used += r.size - r.recorded
+		case workspaceID != "" && r.workspaceID == workspaceID, userID != "" && r.userID == userID:
+			used += r.size
+		}
+	}
+	return used, nil
+}
+
+// Used returns the total size of the files that match a filter.
+func Used(d db.DB, f model.FileFilter) (int64, error) {
+	usage, err := d.FindFileUsage(f)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, u := range usage {
+		total += u.Size
+	}
+	return total, nil
+}
+
+// FormatSize writes a size in bytes the way sizes are configured, such as
+// "1.5 GB".
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 3; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
+}
@@ -0,0 +1,204 @@
+package quota
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/config"
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/db/dbtest"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/util"
+)
+
+// newUsageDB returns a database holding files and uploads.
+func newUsageDB(t *testing.T, files []model.File, uploads []model.Upload) db.DB {
+	t.Helper()
+	d := dbtest.New(t)
+	for _, f := range files {
+		addFile(t, d, f.WorkspaceID, f.CreatedBy, f.Size)
+	}
+	for _, up := range uploads {
+		if err := d.CreateUpload(up); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return d
+}
+
+func addFile(t *testing.T, d db.DB, workspaceID, userID string, size int64) {
+	t.Helper()
+	f := model.File{WorkspaceID: workspaceID, ID: util.NewId(), CreatedBy: userID, Size: size}
+	f.Name = f.ID
+	if err := d.CreateFile(f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setLimits(t *testing.T, l Limits) {
+	t.Helper()
+	config.Init()
+	config.C.Set(config.STORAGE_MAX_FILE_SIZE, l.MaxFileSize)
+	config.C.Set(config.STORAGE_WORKSPACE_QUOTA, l.Workspace)
+	config.C.Set(config.STORAGE_USER_QUOTA, l.User)
+}
+
+func TestCheck(t *testing.T) {
+	stored := newUsageDB(t,
+		[]model.File{
+			{WorkspaceID: "w1", CreatedBy: "u1", Size: 400},
+			{WorkspaceID: "w1", CreatedBy: "u2", Size: 200},
+			{WorkspaceID: "w2", CreatedBy: "u1", Size: 300},
+		},
+		[]model.Upload{
+			{ID: "pending", WorkspaceID: "w2", CreatedBy: "u2", Length: 250},
+			{ID: "complete", WorkspaceID: "w2", CreatedBy: "u2", Length: 900, FileID: "f"},
+		},
+	)
+
+	tests := []struct {
+		name        string
+		limits      Limits
+		workspaceID string
+		userID      string
+		size        int64
+		want        *Error
+	}{
+		{name: "no limits", workspaceID: "w1", userID: "u1", size: 1 << 40},
+		{name: "file within the limit", limits: Limits{MaxFileSize: 100}, workspaceID: "w1", size: 100},
+		{name: "file too large", limits: Limits{MaxFileSize: 100}, workspaceID: "w1", size: 101, want: &Error{Scope: ScopeFile, Limit: 100}},
+		{name: "workspace fits exactly", limits: Limits{Workspace: 1000}, workspaceID: "w1", size: 400},
+		{name: "workspace full", limits: Limits{Workspace: 1000}, workspaceID: "w1", size: 401, want: &Error{Scope: ScopeWorkspace, Limit: 1000, Used: 600}},
+		{name: "pending uploads count", limits: Limits{Workspace: 1000}, workspaceID: "w2", size: 451, want: &Error{Scope: ScopeWorkspace, Limit: 1000, Used: 550}},
+		{name: "complete uploads count as files", limits: Limits{Workspace: 1000}, workspaceID: "w2", size: 450},
+		{name: "user counts every workspace", limits: Limits{User: 1000}, workspaceID: "w1", userID: "u1", size: 301, want: &Error{Scope: ScopeUser, Limit: 1000, Used: 700}},
+		{name: "user with pending uploads", limits: Limits{User: 500}, workspaceID: "w1", userID: "u2", size: 51, want: &Error{Scope: ScopeUser, Limit: 500, Used: 450}},
+		{name: "user quota without a user", limits: Limits{User: 1}, workspaceID: "w1", size: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setLimits(t, tt.limits)
+			err := Check(stored, tt.workspaceID, tt.userID, tt.size)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Check = %v, want no error", err)
+				}
+				return
+			}
+			var qerr *Error
+			if !errors.As(err, &qerr) {
+				t.Fatalf("Check = %v, want a *Error", err)
+			}
+			if *qerr != *tt.want {
+				t.Errorf("Check = %+v, want %+v", *qerr, *tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckUpload(t *testing.T) {
+	setLimits(t, Limits{Workspace: 1000})
+	up := model.Upload{ID: "u", WorkspaceID: "w1", CreatedBy: "u1", Length: 600}
+	d := newUsageDB(t, []model.File{{WorkspaceID: "w1", Size: 300}}, []model.Upload{up})
+
+	// The upload is not counted against itself.
+	if err := CheckUpload(d, up); err != nil {
+		t.Fatalf("CheckUpload = %v, want no error", err)
+	}
+
+	if err := d.CreateUpload(model.Upload{ID: "other", WorkspaceID: "w1", Length: 101}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckUpload(d, up); err == nil {
+		t.Fatal("CheckUpload passed with another upload taking the space")
+	}
+}
+
+func TestReservation(t *testing.T) {
+	setLimits(t, Limits{Workspace: 1000})
+	d := dbtest.New(t)
+
+	r, err := Reserve(d, "w1", "u1", 600)
+	if err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if err := Check(d, "w1", "u2", 401); err == nil {
+		t.Error("Check passed over a reservation")
+	}
+	if err := Check(d, "w2", "u2", 1000); err != nil {
+		t.Errorf("reservation counted in another workspace: %v", err)
+	}
+
+	// Files recorded against the reservation are counted once by it.
+	if err := r.Add(d, 300); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	addFile(t, d, "w1", "u1", 600)
+	r.Record(600)
+	if err := r.Add(d, 100); err != nil {
+		t.Errorf("Add counted recorded files twice: %v", err)
+	}
+	if err := r.Add(d, 1); err == nil {
+		t.Error("Add passed over the quota")
+	}
+
+	r.Release()
+	if err := Check(d, "w1", "u2", 400); err != nil {
+		t.Errorf("released reservation still counted: %v", err)
+	}
+	r.Release()
+
+	var none *Reservation
+	none.Release()
+}
+
+func TestReserveConcurrently(t *testing.T) {
+	setLimits(t, Limits{Workspace: 1000})
+	d := dbtest.New(t)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var held []*Reservation
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if r, err := Reserve(d, "w1", "u1", 100); err == nil {
+				mu.Lock()
+				held = append(held, r)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(held) != 10 {
+		t.Errorf("%d reservations of 100 bytes fit a quota of 1000, want 10", len(held))
+	}
+	for _, r := range held {
+		r.Release()
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{50 << 20, "50.0 MB"},
+		{3 << 30, "3.0 GB"},
+		{5 << 40, "5.0 TB"},
+		{2048 << 40, "2048.0 TB"},
+	}
+	for _, tt := range tests {
+		if got := FormatSize(tt.n); got != tt.want {
+			t.Errorf("FormatSize(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package quota
+
+import (
+	"sync"
+
+	"github.com/collabreef/collabreef/internal/db"
+)
+
+var (
+	// mu serializes the checks with the reservations they make, so that
+	// concurrent uploads cannot both fit the last of a quota.
+	mu           sync.Mutex
+	reservations = map[*Reservation]struct{}{}
+)
+
+// A Reservation holds storage for files that are being added, so that the
+// checks made meanwhile count them before they are recorded. Release it
+// once the files are recorded and visible to other checks, or discarded.
+type Reservation struct {
+	workspaceID string
+	userID      string
+	size        int64
+	recorded    int64
+}
+
+// Reserve checks, as Check does, that a file of size bytes fits the quotas
+// and reserves the storage for it.
+func Reserve(d db.DB, workspaceID, userID string, size int64) (*Reservation, error) {
+	r := &Reservation{workspaceID: workspaceID, userID: userID}
+	if err := r.Add(d, size); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Add reserves storage for another file of size bytes, when it fits the
+// quotas with the files reserved before.
+func (r *Reservation) Add(d db.DB, size int64) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := check(d, r.workspaceID, r.userID, size, r, ""); err != nil {
+		return err
+	}
+	r.size += size
+	reservations[r] = struct{}{}
+	return nil
+}
+
+// Record notes that size bytes of the reservation are recorded as files in
+// the database its checks read, which may be a transaction that is not
+// committed. Its own checks count them once; other checks count them until
+// the reservation is released.
+func (r *Reservation) Record(size int64) {
+	mu.Lock()
+	defer mu.Unlock()
+	r.recorded += size
+}
+
+// Release gives the reserved storage back. A nil reservation holds none.
+func (r *Reservation) Release() {
+	if r == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	delete(reservations, r)
+	r.size, r.recorded = 0, 0
+}
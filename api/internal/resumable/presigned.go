@@ -0,0 +1,155 @@
+package resumable
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/quota"
+	"github.com/collabreef/collabreef/internal/storage"
+	"github.com/collabreef/collabreef/internal/util"
+)
+
+var (
+	ErrPresignNotSupported = errors.New("storage does not support presigned uploads")
+	ErrPresignedTooLarge   = errors.New("presigned uploads are limited to " + quota.FormatSize(storage.MaxPresignedSize))
+	ErrNotUploaded         = errors.New("file has not been uploaded")
+	ErrLengthMismatch      = errors.New("uploaded file does not have the length of the upload")
+)
+
+// PresignExpiry is how long the URL of a presigned upload is valid. The
+// client starts the PUT request as soon as it has the URL, and storages
+// check the expiry when a request starts, not while its body is read.
+const PresignExpiry = 5 * time.Minute
+
+// A presigned upload is written by the client to an object of its own,
+// which is moved to the file in its workspace once it is checked, so that
+// writing to the URL again cannot change an accepted file. The content is
+// not read to store it as a blob, which the dedupe-files command does later.
+func presignedSegments(up model.Upload) []string { return []string{"uploads", up.ID} }
+func fileSegments(up model.Upload) []string      { return []string{up.WorkspaceID, up.FileName} }
+
+// CreatePresigned starts an upload of a file of length bytes that the
+// client writes directly to the storage, with a PUT request to the
+// returned URL, which is valid for PresignExpiry. The upload is finished
+// with Finish.
+func (u Uploader) CreatePresigned(workspaceID, userID, filename string, length int64) (model.Upload, string, error) {
+	p, ok := u.Storage.(storage.Presigner)
+	if !ok {
+		return model.Upload{}, "", ErrPresignNotSupported
+	}
+	if length < 0 {
+		return model.Upload{}, "", errors.New("upload length must not be negative")
+	}
+	if length > storage.MaxPresignedSize {
+		return model.Upload{}, "", ErrPresignedTooLarge
+	}
+	// The upload counts towards the quotas once it is recorded.
+	reservation, err := quota.Reserve(u.DB, workspaceID, userID, length)
+	if err != nil {
+		return model.Upload{}, "", err
+	}
+	defer reservation.Release()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	up := model.Upload{
+		ID:          util.NewId(),
+		WorkspaceID: workspaceID,
+		Filename:    filename,
+		Method:      model.UploadMethodPresigned,
+		Length:      length,
+		FileName:    newFileName(filename),
+		CreatedAt:   now,
+		CreatedBy:   userID,
+		UpdatedAt:   now,
+	}
+	url, err := p.PresignPut(presignedSegments(up), PresignExpiry)
+	if err != nil {
+		return model.Upload{}, "", err
+	}
+	if err := u.DB.CreateUpload(up); err != nil {
+		return model.Upload{}, "", err
+	}
+	reservation.Release()
+	return up, url, nil
+}
+
+// Finish adds the file the client wrote for a presigned upload to its
+// workspace, once it is checked to have the length of the upload and to fit
+// the storage quotas. Otherwise the upload is terminated.
+func (u Uploader) Finish(up model.Upload) (model.Upload, error) {
+	if _, busy := locks.LoadOrStore(up.ID, struct{}{}); busy {
+		return up, ErrLocked
+	}
+	defer locks.Delete(up.ID)
+
+	up, err := u.DB.FindUpload(up.ID)
+	if err != nil {
+		return up, err
+	}
+	if up.FileID != "" {
+		return up, ErrComplete
+	}
+	if up.Method != model.UploadMethodPresigned {
+		return up, ErrMethod
+	}
+
+	info, err := u.Storage.Stat(presignedSegments(up))
+	if errors.Is(err, fs.ErrNotExist) {
+		return up, ErrNotUploaded
+	} else if err != nil {
+		return up, err
+	}
+	if info.Size != up.Length {
+		return up, u.reject(up, ErrLengthMismatch)
+	}
+
+	// The object is checked again once moved, as the client may have
+	// written it again in between.
+	segments := fileSegments(up)
+	if err := u.Storage.Move(presignedSegments(up), segments); err != nil {
+		return up, err
+	}
+	if info, err = u.Storage.Stat(segments); err != nil || info.Size != up.Length {
+		u.deleteFile(up)
+		if err != nil {
+			return up, err
+		}
+		return up, u.reject(up, ErrLengthMismatch)
+	}
+	// Other files may have been added since the upload started.
+	if err := quota.CheckUpload(u.DB, up); err != nil {
+		u.deleteFile(up)
+		return up, u.reject(up, err)
+	}
+
+	rc, err := u.Storage.LoadRange(segments, 0, 512)
+	if err != nil {
+		return up, err
+	}
+	head, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return up, err
+	}
+
+	return u.addFile(up, up.FileName, "", head)
+}
+
+// reject terminates a presigned upload that cannot be finished, and returns
+// the reason.
+func (u Uploader) reject(up model.Upload, reason error) error {
+	if err := u.terminate(up); err != nil {
+		log.Printf("resumable uploads: %s: %v", up.ID, err)
+	}
+	return reason
+}
+
+func (u Uploader) deleteFile(up model.Upload) {
+	if err := u.Storage.Delete(fileSegments(up)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("resumable uploads: %s: %v", up.ID, err)
+	}
+}
@@ -0,0 +1,451 @@
+// Package resumable receives files uploaded in parts over several requests,
+// so that an interrupted upload continues where it stopped. Received bytes
+// are written through a storage.ChunkedStorage as they arrive, and the file
+// is added to its workspace once the last byte is received. Presigned
+// uploads are instead written by the client directly to a
+// storage.Presigner, and are tracked and expired the same way.
+package resumable
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"log"
+	"math/rand"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/quota"
+	"github.com/collabreef/collabreef/internal/storage"
+	"github.com/collabreef/collabreef/internal/util"
+)
+
+// MaxLength is the size of the largest file that can be uploaded.
+const MaxLength = storage.MaxParts * storage.MinPartSize
+
+var (
+	ErrNotSupported   = errors.New("storage does not support resumable uploads")
+	ErrTooLarge       = fmt.Errorf("uploads are limited to %s", quota.FormatSize(MaxLength))
+	ErrOffsetMismatch = errors.New("offset does not match the received size of the upload")
+	ErrComplete       = errors.New("upload is complete")
+	ErrLocked         = errors.New("upload is being written by another request")
+	ErrMethod         = errors.New("upload is written by another method")
+)
+
+// locks holds the ids of the uploads being written, so that concurrent
+// requests for an upload do not interleave its parts.
+var locks sync.Map
+
+// Uploader writes resumable uploads to a storage.
+type Uploader struct {
+	DB      db.DB
+	Storage storage.ChunkedStorage
+}
+
+// New returns an Uploader, or ErrNotSupported for storages that cannot
+// write files in parts.
+func New(d db.DB, s storage.Storage) (Uploader, error) {
+	cs, ok := s.(storage.ChunkedStorage)
+	if !ok {
+		return Uploader{}, ErrNotSupported
+	}
+	return Uploader{DB: d, Storage: cs}, nil
+}
+
+// The parts of an upload are written to a chunked file that is moved to its
+// blob once complete. The bytes received after the last part are kept in
+// the tail until they fill a part.
+func chunkedSegments(id string) []string { return []string{"uploads", id} }
+func tailSegments(id string) []string    { return []string{"uploads", id + ".tail"} }
+
+// Create starts an upload of a file of length bytes to a workspace, on
+// behalf of a user. Uploads that would exceed a storage quota are refused
+// with a *quota.Error. An empty file is complete at once.
+func (u Uploader) Create(workspaceID, userID, filename string, length int64) (model.Upload, error) {
+	if length < 0 {
+		return model.Upload{}, errors.New("upload length must not be negative")
+	}
+	if length > MaxLength {
+		return model.Upload{}, ErrTooLarge
+	}
+	// The upload counts towards the quotas once it is recorded.
+	reservation, err := quota.Reserve(u.DB, workspaceID, userID, length)
+	if err != nil {
+		return model.Upload{}, err
+	}
+	defer reservation.Release()
+
+	id := util.NewId()
+	storageID, err := u.Storage.CreateChunked(chunkedSegments(id))
+	if err != nil {
+		return model.Upload{}, err
+	}
+	state, err := marshalHash(sha256.New())
+	if err != nil {
+		return model.Upload{}, err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	up := model.Upload{
+		ID:          id,
+		WorkspaceID: workspaceID,
+		Filename:    filename,
+		Method:      model.UploadMethodTus,
+		Length:      length,
+		StorageID:   storageID,
+		HashState:   state,
+		CreatedAt:   now,
+		CreatedBy:   userID,
+		UpdatedAt:   now,
+	}
+	if err := u.DB.CreateUpload(up); err != nil {
+		u.Storage.AbortChunked(chunkedSegments(id), storageID)
+		return model.Upload{}, err
+	}
+	reservation.Release()
+
+	if length == 0 {
+		if _, err := u.Write(up, 0, bytes.NewReader(nil)); err != nil {
+			return model.Upload{}, err
+		}
+		return u.DB.FindUpload(id)
+	}
+	return up, nil
+}
+
+// Write appends the bytes read from r to an upload, from offset, which
+// must be the number of bytes received so far. Bytes past the length of
+// the upload are not read. The upload is returned with the bytes received,
+// and with the uploaded file once the last byte is received. When reading
+// r fails, the bytes read until then are kept and the error is returned.
+func (u Uploader) Write(up model.Upload, offset int64, r io.Reader) (model.Upload, error) {
+	if _, busy := locks.LoadOrStore(up.ID, struct{}{}); busy {
+		return up, ErrLocked
+	}
+	defer locks.Delete(up.ID)
+
+	// Read the upload again, now that no other request writes it.
+	up, err := u.DB.FindUpload(up.ID)
+	if err != nil {
+		return up, err
+	}
+	if up.FileID != "" {
+		return up, ErrComplete
+	}
+	if up.Method != model.UploadMethodTus {
+		return up, ErrMethod
+	}
+	if offset != up.Received {
+		return up, ErrOffsetMismatch
+	}
+
+	h, err := unmarshalHash(up.HashState)
+	if err != nil {
+		return up, err
+	}
+	var tags []string
+	if up.Parts != "" {
+		if err := json.Unmarshal([]byte(up.Parts), &tags); err != nil {
+			return up, err
+		}
+	}
+
+	part := make([]byte, 0, storage.MinPartSize)
+	if up.Received > up.Stored {
+		tail, err := u.loadTail(up)
+		if err != nil {
+			return up, err
+		}
+		part = append(part, tail...)
+	}
+
+	var readErr error
+	r = io.LimitReader(r, up.Length-up.Received)
+	for up.Received < up.Length {
+		n, err := io.ReadFull(r, part[len(part):cap(part)])
+		part = part[:len(part)+n]
+		up.Received += int64(n)
+
+		// Store full parts as they are read, so that an interrupted
+		// request keeps them. The last part is stored on completion.
+		if len(part) == cap(part) && up.Received < up.Length {
+			if err := u.savePart(&up, &tags, h, part); err != nil {
+				return up, err
+			}
+			part = part[:0]
+			up.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+			if err := u.DB.UpdateUpload(up); err != nil {
+				return up, err
+			}
+		}
+
+		if err != nil {
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				readErr = err
+			}
+			break
+		}
+	}
+
+	if up.Received == up.Length {
+		return u.complete(up, tags, h, part)
+	}
+
+	if len(part) > 0 {
+		if err := u.Storage.Save(tailSegments(up.ID), bytes.NewReader(part)); err != nil {
+			return up, err
+		}
+	}
+	up.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := u.DB.UpdateUpload(up); err != nil {
+		return up, err
+	}
+	return up, readErr
+}
+
+// loadTail reads the bytes received after the last part of an upload.
+func (u Uploader) loadTail(up model.Upload) ([]byte, error) {
+	rc, err := u.Storage.Load(tailSegments(up.ID))
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// A tail saved by a request that failed to record it can be longer.
+	tail := make([]byte, up.Received-up.Stored)
+	if _, err := io.ReadFull(rc, tail); err != nil {
+		return nil, err
+	}
+	return tail, nil
+}
+
+func (u Uploader) savePart(up *model.Upload, tags *[]string, h hash.Hash, data []byte) error {
+	tag, err := u.Storage.SavePart(chunkedSegments(up.ID), up.StorageID, len(*tags)+1, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	h.Write(data)
+	*tags = append(*tags, tag)
+
+	parts, err := json.Marshal(*tags)
+	if err != nil {
+		return err
+	}
+	state, err := marshalHash(h)
+	if err != nil {
+		return err
+	}
+	up.Stored += int64(len(data))
+	up.Parts = string(parts)
+	up.HashState = state
+	return nil
+}
+
+// complete stores the last part of an upload, moves the file to the blob
+// of its content and adds it to the workspace.
+func (u Uploader) complete(up model.Upload, tags []string, h hash.Hash, last []byte) (model.Upload, error) {
+	// Other files may have been added since the upload started.
+	if err := quota.CheckUpload(u.DB, up); err != nil {
+		if termErr := u.terminate(up); termErr != nil {
+			log.Printf("resumable uploads: %s: %v", up.ID, termErr)
+		}
+		return up, err
+	}
+
+	segments := chunkedSegments(up.ID)
+	var sum string
+	var head []byte
+	if up.Length == 0 {
+		// Chunked files hold at least one part; empty files are saved whole.
+		if err := u.Storage.AbortChunked(segments, up.StorageID); err != nil {
+			return up, err
+		}
+		blob, unlock, err := storage.PutBlob(u.Storage, bytes.NewReader(nil))
+		if err != nil {
+			return up, err
+		}
+		defer unlock()
+		sum = blob
+	} else {
+		if err := u.savePart(&up, &tags, h, last); err != nil {
+			return up, err
+		}
+		if err := u.Storage.CompleteChunked(segments, up.StorageID, tags); err != nil {
+			return up, err
+		}
+		sum = hex.EncodeToString(h.Sum(nil))
+
+		rc, err := u.Storage.LoadRange(segments, 0, 512)
+		if err != nil {
+			return up, err
+		}
+		head, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return up, err
+		}
+
+		// Content that is already stored is not kept twice. The blob stays
+		// locked until the file is added, so it is not deleted meanwhile.
+		unlock := storage.LockBlob(sum)
+		defer unlock()
+		if _, err := u.Storage.Stat(storage.BlobSegments(sum)); err == nil {
+			if err := u.Storage.Delete(segments); err != nil {
+				return up, err
+			}
+		} else if errors.Is(err, fs.ErrNotExist) {
+			if err := u.Storage.Move(segments, storage.BlobSegments(sum)); err != nil {
+				return up, err
+			}
+		} else {
+			return up, err
+		}
+	}
+	u.deleteTail(up)
+
+	return u.addFile(up, newFileName(up.Filename), sum, head)
+}
+
+// addFile adds the file of a complete upload to its workspace, and records
+// it with the upload.
+func (u Uploader) addFile(up model.Upload, name, sum string, head []byte) (model.Upload, error) {
+	now := time.Now().UTC()
+	f := model.File{
+		WorkspaceID:      up.WorkspaceID,
+		ID:               util.NewId(),
+		Name:             name,
+		Size:             up.Length,
+		Ext:              filepath.Ext(up.Filename),
+		OriginalFilename: up.Filename,
+		ContentType:      util.DetectContentType(up.Filename, head),
+		Hash:             sum,
+		CreatedAt:        now.Format(time.RFC3339),
+		CreatedBy:        up.CreatedBy,
+		UpdatedAt:        now.Format(time.RFC3339),
+		UpdatedBy:        up.CreatedBy,
+	}
+	if err := u.DB.CreateFile(f); err != nil {
+		return up, err
+	}
+
+	up.Received = up.Length
+	up.FileID = f.ID
+	up.FileName = f.Name
+	up.UpdatedAt = now.Format(time.RFC3339)
+	if err := u.DB.UpdateUpload(up); err != nil {
+		return up, err
+	}
+	return up, nil
+}
+
+// Terminate discards an upload and the bytes received. The file of a
+// complete upload is kept.
+func (u Uploader) Terminate(up model.Upload) error {
+	if _, busy := locks.LoadOrStore(up.ID, struct{}{}); busy {
+		return ErrLocked
+	}
+	defer locks.Delete(up.ID)
+
+	return u.terminate(up)
+}
+
+func (u Uploader) terminate(up model.Upload) error {
+	if up.Method == model.UploadMethodPresigned {
+		// The URL of a finished upload can still be written to, until the
+		// upload expires.
+		if err := u.Storage.Delete(presignedSegments(up)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	} else if up.FileID == "" {
+		if err := u.Storage.AbortChunked(chunkedSegments(up.ID), up.StorageID); err != nil {
+			return err
+		}
+		u.deleteTail(up)
+	}
+	return u.DB.DeleteUpload(up.ID)
+}
+
+func (u Uploader) deleteTail(up model.Upload) {
+	if err := u.Storage.Delete(tailSegments(up.ID)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("resumable uploads: %s: %v", up.ID, err)
+	}
+}
+
+// Expire terminates the uploads that were not written since before.
+func (u Uploader) Expire(before time.Time) error {
+	uploads, err := u.DB.FindUploads(model.UploadFilter{UpdatedBefore: before.UTC().Format(time.RFC3339)})
+	if err != nil {
+		return err
+	}
+	for _, up := range uploads {
+		if err := u.Terminate(up); err != nil {
+			log.Printf("resumable uploads: %s: %v", up.ID, err)
+		}
+	}
+	return nil
+}
+
+// Schedule terminates uploads that were not written for the expiry
+// duration. It blocks; run it in a goroutine. It returns at once for
+// storages without resumable uploads, or a non-positive expiry.
+func Schedule(d db.DB, s storage.Storage, expiry time.Duration) {
+	u, err := New(d, s)
+	if err != nil || expiry <= 0 {
+		return
+	}
+	ticker := time.NewTicker(min(expiry, time.Hour))
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := u.Expire(time.Now().Add(-expiry)); err != nil {
+			log.Printf("resumable uploads: %v", err)
+		}
+	}
+}
+
+// The SHA-256 state of the parts is kept with the upload, so that the hash
+// of a file is known once it is complete, without reading it again.
+func marshalHash(h hash.Hash) (string, error) {
+	state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(state), nil
+}
+
+func unmarshalHash(s string) (hash.Hash, error) {
+	state, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// newFileName names a file in its workspace as Upload does.
+func newFileName(filename string) string {
+	return time.Now().UTC().Format("20060102150405") + "_" + randomString(4) + filepath.Ext(filename)
+}
+
+var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+
+func randomString(n int) string {
+	b := make([]rune, n)
+	for i := range b {
+		b[i] = letters[rand.Intn(len(letters))]
+	}
+	return string(b)
+}
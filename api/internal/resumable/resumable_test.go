@@ -0,0 +1,277 @@
+package resumable
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/config"
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/db/dbtest"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/quota"
+	"github.com/collabreef/collabreef/internal/storage"
+	"github.com/collabreef/collabreef/internal/storage/localfile"
+)
+
+func newUploader(t *testing.T) (Uploader, db.DB) {
+	t.Helper()
+	config.Init()
+	d := dbtest.New(t)
+	u, err := New(d, localfile.NewLocalFileStorage(t.TempDir()+"/"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return u, d
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// failingReader returns the bytes of r, then fails.
+type failingReader struct {
+	r io.Reader
+}
+
+func (f failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+// checkStored checks that an upload is complete with the content data.
+func checkStored(t *testing.T, u Uploader, d db.DB, up model.Upload, data []byte) {
+	t.Helper()
+	if up.FileID == "" || up.Received != int64(len(data)) {
+		t.Fatalf("upload not complete: received %d of %d, file %q", up.Received, len(data), up.FileID)
+	}
+
+	file, err := d.FindFileByID(up.FileID)
+	if err != nil {
+		t.Fatalf("finding file: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	if want := hex.EncodeToString(sum[:]); file.Hash != want {
+		t.Errorf("file hash = %s, want %s", file.Hash, want)
+	}
+	if file.Size != int64(len(data)) || file.Name != up.FileName {
+		t.Errorf("file = %+v, want size %d and name %s", file, len(data), up.FileName)
+	}
+
+	rc, err := u.Storage.Load(storage.BlobSegments(file.Hash))
+	if err != nil {
+		t.Fatalf("loading blob: %v", err)
+	}
+	defer rc.Close()
+	stored, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stored, data) {
+		t.Errorf("blob holds %d bytes that differ from the %d uploaded", len(stored), len(data))
+	}
+}
+
+func TestWrite(t *testing.T) {
+	const part = storage.MinPartSize
+
+	tests := []struct {
+		name   string
+		length int
+		// writes are the sizes of the requests; a negative size is a
+		// request that fails after that many bytes.
+		writes []int
+	}{
+		{name: "one request", length: 1000, writes: []int{1000}},
+		{name: "small requests", length: 1000, writes: []int{1, 499, 500}},
+		{name: "requests across parts", length: 2*part + 1234, writes: []int{part - 10, part + 20, part}},
+		{name: "exactly one part", length: part, writes: []int{part}},
+		{name: "interrupted requests resume", length: part + 100, writes: []int{-(part / 2), -(part/2 + 50), 150}},
+		{name: "bytes past the length are not read", length: 100, writes: []int{60, 80}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, d := newUploader(t)
+			data := randomBytes(t, tt.length)
+
+			up, err := u.Create("w1", "u1", "file.bin", int64(tt.length))
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			var offset int64
+			for _, n := range tt.writes {
+				fails := n < 0
+				if fails {
+					n = -n
+				}
+				// Requests may send more than the upload is missing.
+				end := min(offset+int64(n), int64(tt.length))
+				sent := append(bytes.Clone(data[offset:end]), make([]byte, offset+int64(n)-end)...)
+				body := io.Reader(bytes.NewReader(sent))
+				if fails {
+					body = failingReader{body}
+				}
+
+				up, err = u.Write(up, offset, body)
+				if fails {
+					if err == nil {
+						t.Fatalf("Write of a failing request succeeded")
+					}
+				} else if err != nil {
+					t.Fatalf("Write at %d: %v", offset, err)
+				}
+				if up.Received != end {
+					t.Fatalf("received %d after writing to %d", up.Received, end)
+				}
+				offset = up.Received
+			}
+
+			checkStored(t, u, d, up, data)
+		})
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	u, _ := newUploader(t)
+
+	up, err := u.Create("w1", "u1", "file.bin", 10)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := u.Write(up, 5, bytes.NewReader(make([]byte, 5))); !errors.Is(err, ErrOffsetMismatch) {
+		t.Errorf("Write at a wrong offset = %v, want ErrOffsetMismatch", err)
+	}
+	if up, err = u.Write(up, 0, bytes.NewReader(make([]byte, 10))); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := u.Write(up, 10, bytes.NewReader(nil)); !errors.Is(err, ErrComplete) {
+		t.Errorf("Write to a complete upload = %v, want ErrComplete", err)
+	}
+
+	locks.Store(up.ID, struct{}{})
+	defer locks.Delete(up.ID)
+	if _, err := u.Write(up, 10, bytes.NewReader(nil)); !errors.Is(err, ErrLocked) {
+		t.Errorf("Write to a locked upload = %v, want ErrLocked", err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		length  int64
+		want    error
+		wantErr bool
+	}{
+		{name: "negative length", length: -1, wantErr: true},
+		{name: "too large", length: MaxLength + 1, want: ErrTooLarge},
+		{name: "largest file", length: MaxLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, _ := newUploader(t)
+			_, err := u.Create("w1", "u1", "file.bin", tt.length)
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Create = %v, want %v", err, tt.want)
+			}
+			if (err != nil) != (tt.wantErr || tt.want != nil) {
+				t.Errorf("Create error = %v, want error %v", err, tt.wantErr || tt.want != nil)
+			}
+		})
+	}
+
+	t.Run("empty file is complete at once", func(t *testing.T) {
+		u, d := newUploader(t)
+		up, err := u.Create("w1", "u1", "empty.txt", 0)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		checkStored(t, u, d, up, nil)
+	})
+}
+
+func TestDuplicateContent(t *testing.T) {
+	u, d := newUploader(t)
+	data := randomBytes(t, 2048)
+
+	for i := 0; i < 2; i++ {
+		up, err := u.Create("w1", "u1", "copy.bin", int64(len(data)))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if up, err = u.Write(up, 0, bytes.NewReader(data)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		checkStored(t, u, d, up, data)
+	}
+	files, err := d.FindFiles(model.FileFilter{WorkspaceID: "w1", PageNumber: 1, PageSize: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Hash != files[1].Hash || files[0].Name == files[1].Name {
+		t.Errorf("files = %+v, want two files of one blob", files)
+	}
+}
+
+func TestQuota(t *testing.T) {
+	u, d := newUploader(t)
+	config.C.Set(config.STORAGE_WORKSPACE_QUOTA, "1000B")
+
+	up, err := u.Create("w1", "u1", "file.bin", 600)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// The length of the upload is reserved while it is written.
+	var qerr *quota.Error
+	if _, err := u.Create("w1", "u2", "other.bin", 401); !errors.As(err, &qerr) {
+		t.Errorf("Create over a pending upload = %v, want a quota error", err)
+	}
+
+	// Files added meanwhile are counted when the upload completes.
+	if err := d.CreateFile(model.File{WorkspaceID: "w1", ID: "other", Name: "other.bin", Size: 500}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.Write(up, 0, bytes.NewReader(make([]byte, 600))); !errors.As(err, &qerr) {
+		t.Fatalf("Write over the quota = %v, want a quota error", err)
+	}
+	if _, err := d.FindUpload(up.ID); err == nil {
+		t.Error("upload over the quota was not terminated")
+	}
+}
+
+func TestHashState(t *testing.T) {
+	data := randomBytes(t, 1000)
+
+	h := sha256.New()
+	h.Write(data[:300])
+	state, err := marshalHash(h)
+	if err != nil {
+		t.Fatalf("marshalHash: %v", err)
+	}
+	resumed, err := unmarshalHash(state)
+	if err != nil {
+		t.Fatalf("unmarshalHash: %v", err)
+	}
+	resumed.Write(data[300:])
+
+	if want := sha256.Sum256(data); !bytes.Equal(resumed.Sum(nil), want[:]) {
+		t.Error("resumed hash does not match the hash of the whole content")
+	}
+	if _, err := unmarshalHash("not base64!"); err == nil {
+		t.Error("unmarshalHash accepted an invalid state")
+	}
+}
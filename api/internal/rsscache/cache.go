@@ -0,0 +1,205 @@
+// Package rsscache keeps a server-side copy of the RSS feeds users subscribe
+// to. Feeds are fetched once for all subscribers and refreshed in the
+// background with conditional requests.
+package rsscache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/rssfetcher"
+	"github.com/collabreef/collabreef/internal/urlfetcher"
+	"github.com/collabreef/collabreef/internal/util"
+)
+
+// MaxItems is the number of items kept per feed; older items are dropped.
+const MaxItems = 200
+
+// minInterval is the shortest delay between fetches of a feed, also used
+// when background polling is disabled.
+const minInterval = time.Minute
+
+// maxBackoff caps the delay between fetches of a failing feed.
+const maxBackoff = 24 * time.Hour
+
+// timeLayout is the layout of the time strings the repo stores, as written
+// by time.Time.String.
+const timeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// fetchURL fetches feeds. urlfetcher.SafeFetch refuses private and
+// loopback addresses, so tests replace it to serve feeds locally.
+var fetchURL = urlfetcher.SafeFetch
+
+// NormalizeURL checks that a feed URL is an absolute http or https URL and
+// returns it in a canonical form, so that one feed is stored once.
+func NormalizeURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errors.New("feed URL must be an http or https URL")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// Subscribe subscribes a user to the feed at rawURL, storing the feed if it
+// is new. A feed that was never fetched is fetched right away so that the
+// subscriber has items to read; a failing fetch is recorded on the feed and
+// returned along with it, and is not retried before its next fetch time.
+func Subscribe(ctx context.Context, d db.DB, userID, rawURL string, interval time.Duration) (model.RSSFeed, error) {
+	feedURL, err := NormalizeURL(rawURL)
+	if err != nil {
+		return model.RSSFeed{}, err
+	}
+
+	feed, err := d.FindRSSFeed(model.RSSFeed{URL: feedURL})
+	if err != nil {
+		feed = model.RSSFeed{
+			ID:        util.NewId(),
+			URL:       feedURL,
+			CreatedAt: time.Now().UTC().String(),
+		}
+		if err := d.CreateRSSFeed(feed); err != nil {
+			// Another request may have stored the feed first.
+			if feed, err = d.FindRSSFeed(model.RSSFeed{URL: feedURL}); err != nil {
+				return model.RSSFeed{}, err
+			}
+		}
+	}
+
+	if err := d.CreateRSSSubscription(model.RSSSubscription{
+		UserID:    userID,
+		FeedID:    feed.ID,
+		CreatedAt: time.Now().UTC().String(),
+	}); err != nil {
+		return model.RSSFeed{}, err
+	}
+
+	if feed.FetchedAt == "" && due(feed, time.Now()) {
+		return Refresh(ctx, d, feed, interval)
+	}
+	return feed, nil
+}
+
+// Refresh fetches a feed, sending the validators of the last response so an
+// unchanged feed costs the publisher a 304. New items are stored, items
+// already known by GUID are updated, and the next fetch is scheduled one
+// interval later, or later still after repeated failures.
+func Refresh(ctx context.Context, d db.DB, feed model.RSSFeed, interval time.Duration) (model.RSSFeed, error) {
+	if interval < minInterval {
+		interval = minInterval
+	}
+	now := time.Now().UTC()
+
+	fetchErr := fetch(ctx, d, &feed, now)
+	if fetchErr != nil {
+		feed.Failures++
+		feed.LastError = fetchErr.Error()
+		feed.NextFetchAt = now.Add(backoff(interval, feed.Failures)).String()
+	} else {
+		feed.Failures = 0
+		feed.LastError = ""
+		feed.FetchedAt = now.String()
+		feed.NextFetchAt = now.Add(interval).String()
+	}
+
+	if err := d.UpdateRSSFeed(feed); err != nil {
+		return feed, err
+	}
+	return feed, fetchErr
+}
+
+func fetch(ctx context.Context, d db.DB, feed *model.RSSFeed, now time.Time) error {
+	header := http.Header{}
+	if feed.Etag != "" {
+		header.Set("If-None-Match", feed.Etag)
+	}
+	if feed.LastModified != "" {
+		header.Set("If-Modified-Since", feed.LastModified)
+	}
+
+	res, err := fetchURL(ctx, feed.URL, header)
+	if err != nil {
+		return err
+	}
+	if res.NotModified {
+		return nil
+	}
+
+	parsed, err := rssfetcher.Parse(res.Data)
+	if err != nil {
+		return err
+	}
+
+	feed.Title = parsed.Title
+	feed.Description = parsed.Description
+	feed.Link = parsed.Link
+	feed.Etag = res.ETag
+	feed.LastModified = res.LastModified
+
+	for _, item := range parsed.Items {
+		if err := d.SaveRSSItem(newItem(feed.ID, item, now)); err != nil {
+			return err
+		}
+	}
+
+	return d.PruneRSSItems(feed.ID, MaxItems)
+}
+
+// newItem converts a parsed item for storage. Items without a GUID or link
+// are identified by their title and date.
+func newItem(feedID string, item rssfetcher.RSSItem, now time.Time) model.RSSItem {
+	guid := item.GUID
+	if guid == "" {
+		sum := sha256.Sum256([]byte(item.Title + "\x00" + item.PubDate))
+		guid = hex.EncodeToString(sum[:])
+	}
+
+	published := now
+	if t, err := time.Parse(time.RFC3339, item.PubDate); err == nil {
+		published = t
+	}
+
+	return model.RSSItem{
+		ID:          util.NewId(),
+		FeedID:      feedID,
+		GUID:        guid,
+		Title:       item.Title,
+		Link:        item.Link,
+		Description: item.Description,
+		PubDate:     item.PubDate,
+		ImageURL:    item.ImageURL,
+		PublishedAt: published.UTC().Format(time.RFC3339),
+		CreatedAt:   now.String(),
+	}
+}
+
+// backoff doubles the fetch interval for every failure in a row.
+func backoff(interval time.Duration, failures int) time.Duration {
+	d := interval
+	for i := 1; i < failures && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// due reports whether a feed should be fetched at t.
+func due(feed model.RSSFeed, t time.Time) bool {
+	if feed.NextFetchAt == "" {
+		return true
+	}
+	next, err := time.Parse(timeLayout, feed.NextFetchAt)
+	return err != nil || !next.After(t)
+}
@@ -0,0 +1,169 @@
+package rsscache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/db/dbtest"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/rssfetcher"
+	"github.com/collabreef/collabreef/internal/urlfetcher"
+)
+
+const sampleFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel>
+<title>Example</title><link>https://example.com/</link><description>News</description>
+<item><title>First</title><link>https://example.com/1</link><guid>1</guid><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate></item>
+<item><title>Second</title><link>https://example.com/2</link><guid>2</guid><pubDate>Tue, 02 Jan 2024 10:00:00 GMT</pubDate></item>
+</channel></rss>`
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{interval: time.Minute, failures: 1, want: time.Minute},
+		{interval: time.Minute, failures: 2, want: 2 * time.Minute},
+		{interval: time.Minute, failures: 4, want: 8 * time.Minute},
+		{interval: time.Minute, failures: 100, want: maxBackoff},
+		{interval: 10 * time.Hour, failures: 2, want: 20 * time.Hour},
+		{interval: 10 * time.Hour, failures: 3, want: maxBackoff},
+		{interval: 48 * time.Hour, failures: 1, want: maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.interval, tt.failures); got != tt.want {
+			t.Errorf("backoff(%v, %d) = %v, want %v", tt.interval, tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestDue(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		next string
+		want bool
+	}{
+		{next: "", want: true},
+		{next: now.Add(-time.Minute).String(), want: true},
+		{next: now.String(), want: true},
+		{next: now.Add(time.Minute).String(), want: false},
+		{next: "not a time", want: true},
+	}
+	for _, tt := range tests {
+		if got := due(model.RSSFeed{NextFetchAt: tt.next}, now); got != tt.want {
+			t.Errorf("due(%q) = %v, want %v", tt.next, got, tt.want)
+		}
+	}
+}
+
+func TestNewItemWithoutGUID(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	a := newItem("f1", rssfetcher.RSSItem{Title: "Hello", PubDate: "2024-04-01T08:00:00Z"}, now)
+	b := newItem("f1", rssfetcher.RSSItem{Title: "Hello", PubDate: "2024-04-01T08:00:00Z"}, now)
+	if a.GUID == "" || a.GUID != b.GUID {
+		t.Errorf("GUIDs of the same item = %q and %q, want equal and set", a.GUID, b.GUID)
+	}
+	if a.PublishedAt != "2024-04-01T08:00:00Z" {
+		t.Errorf("PublishedAt = %q, want the pubDate", a.PublishedAt)
+	}
+	if c := newItem("f1", rssfetcher.RSSItem{Title: "Hello", PubDate: "yesterday"}, now); c.PublishedAt != "2024-05-01T12:00:00Z" {
+		t.Errorf("PublishedAt of an unparsed date = %q, want the fetch time", c.PublishedAt)
+	}
+}
+
+// stubFetch serves responses in turn and records the request headers.
+type stubFetch struct {
+	responses []func() (urlfetcher.Response, error)
+	headers   []http.Header
+}
+
+func (s *stubFetch) fetch(ctx context.Context, rawURL string, header http.Header) (urlfetcher.Response, error) {
+	s.headers = append(s.headers, header)
+	next := s.responses[0]
+	s.responses = s.responses[1:]
+	return next()
+}
+
+func TestRefresh(t *testing.T) {
+	stub := &stubFetch{responses: []func() (urlfetcher.Response, error){
+		func() (urlfetcher.Response, error) {
+			return urlfetcher.Response{Data: []byte(sampleFeed), ETag: `"v1"`, LastModified: "Mon, 01 Jan 2024 10:00:00 GMT"}, nil
+		},
+		func() (urlfetcher.Response, error) { return urlfetcher.Response{NotModified: true}, nil },
+		func() (urlfetcher.Response, error) { return urlfetcher.Response{}, errors.New("bad status: 503") },
+		func() (urlfetcher.Response, error) { return urlfetcher.Response{}, errors.New("bad status: 503") },
+		func() (urlfetcher.Response, error) {
+			return urlfetcher.Response{Data: []byte(sampleFeed), ETag: `"v2"`}, nil
+		},
+	}}
+	defer func(f func(context.Context, string, http.Header) (urlfetcher.Response, error)) { fetchURL = f }(fetchURL)
+	fetchURL = stub.fetch
+
+	d := dbtest.New(t)
+	feed, err := Subscribe(context.Background(), d, "u1", "HTTPS://Example.com/feed#top", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.URL != "https://example.com/feed" || feed.Title != "Example" || feed.Etag != `"v1"` {
+		t.Fatalf("subscribed feed = %+v", feed)
+	}
+	items, err := d.FindRSSItems(model.RSSItemFilter{FeedID: feed.ID, PageNumber: 1, PageSize: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("stored %d items, want 2", len(items))
+	}
+
+	// The validators of the first response make the next request conditional.
+	stored, err := d.FindRSSFeed(model.RSSFeed{ID: feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().UTC()
+	if feed, err = Refresh(context.Background(), d, stored, time.Hour); err != nil {
+		t.Fatalf("Refresh of an unchanged feed: %v", err)
+	}
+	if h := stub.headers[1]; h.Get("If-None-Match") != `"v1"` || h.Get("If-Modified-Since") != "Mon, 01 Jan 2024 10:00:00 GMT" {
+		t.Errorf("conditional request headers = %v", h)
+	}
+	if feed.Etag != `"v1"` || feed.Title != "Example" {
+		t.Errorf("a 304 changed the feed: %+v", feed)
+	}
+
+	// Failures back off, doubling the interval each time.
+	for i, wantDelay := range []time.Duration{time.Hour, 2 * time.Hour} {
+		feed, err = Refresh(context.Background(), d, feed, time.Hour)
+		if err == nil {
+			t.Fatalf("failure %d: Refresh succeeded", i+1)
+		}
+		next, perr := time.Parse(timeLayout, feed.NextFetchAt)
+		if perr != nil {
+			t.Fatal(perr)
+		}
+		if delay := next.Sub(start); feed.Failures != i+1 || delay < wantDelay || delay > wantDelay+time.Minute {
+			t.Errorf("failure %d: failures = %d, next fetch in %v, want %d and %v", i+1, feed.Failures, delay, i+1, wantDelay)
+		}
+		if due(feed, time.Now()) {
+			t.Errorf("failure %d: feed is due again at once", i+1)
+		}
+	}
+	if stored, err = d.FindRSSFeed(model.RSSFeed{ID: feed.ID}); err != nil || stored.Failures != 2 || stored.LastError == "" {
+		t.Errorf("stored failing feed = %+v, %v", stored, err)
+	}
+
+	// A success resets the failures.
+	if feed, err = Refresh(context.Background(), d, feed, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if stored, err = d.FindRSSFeed(model.RSSFeed{ID: feed.ID}); err != nil || stored.Failures != 0 || stored.LastError != "" || stored.Etag != `"v2"` {
+		t.Errorf("stored recovered feed = %+v, %v", stored, err)
+	}
+	if len(stub.responses) != 0 {
+		t.Errorf("%d responses were not fetched", len(stub.responses))
+	}
+}
@@ -0,0 +1,47 @@
+package rsscache
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/model"
+)
+
+// Poll refreshes the feeds that have subscribers once per interval. It
+// blocks; run it in a goroutine. A non-positive interval disables polling;
+// feeds are then only fetched when first subscribed to.
+func Poll(d db.DB, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := RunOnce(d, interval); err != nil {
+			log.Printf("rss poller: %v", err)
+		}
+	}
+}
+
+// RunOnce refreshes every subscribed feed that is due. Feeds are fetched one
+// at a time; a failing feed is logged and backs off on its own schedule.
+func RunOnce(d db.DB, interval time.Duration) error {
+	feeds, err := d.FindRSSFeeds(model.RSSFeedFilter{Subscribed: true})
+	if err != nil {
+		return err
+	}
+	// Feeds fetched in the previous round are due about now; allow for the
+	// time that round took so they are not put off a whole interval.
+	cutoff := time.Now().Add(interval / 2)
+	for _, feed := range feeds {
+		if !due(feed, cutoff) {
+			continue
+		}
+		if _, err := Refresh(context.Background(), d, feed, interval); err != nil {
+			log.Printf("rss poller: feed %s: %v", feed.URL, err)
+		}
+	}
+	return nil
+}
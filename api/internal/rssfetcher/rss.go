@@ -34,6 +34,11 @@ func FetchAndParseFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 		return nil, err
 	}
 
+	return Parse(data)
+}
+
+// Parse parses the body of an RSS/Atom feed
+func Parse(data []byte) (*RSSFeed, error) {
 	// Parse the feed using gofeed
 	fp := gofeed.NewParser()
 	feed, err := fp.ParseString(string(data))
@@ -10,10 +10,11 @@ import (
 	"github.com/collabreef/collabreef/internal/api/validate"
 	"github.com/collabreef/collabreef/internal/config"
 	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/events"
 	"github.com/collabreef/collabreef/internal/storage"
 )
 
-func New(db db.DB, storage storage.Storage) (*echo.Echo, error) {
+func New(db db.DB, storage storage.Storage, bus *events.Bus) (*echo.Echo, error) {
 	e := echo.New()
 
 	// Middleware
@@ -23,7 +24,7 @@ func New(db db.DB, storage storage.Storage) (*echo.Echo, error) {
 
 	apiRoot := config.C.GetString(config.SERVER_API_ROOT_PATH)
 
-	handler := handler.NewHandler(db, storage)
+	handler := handler.NewHandler(db, storage, bus)
 	auth := middlewares.NewAuthMiddleware(db)
 	workspace := middlewares.NewWorkspaceMiddleware(db)
 
@@ -34,6 +35,7 @@ func New(db db.DB, storage storage.Storage) (*echo.Echo, error) {
 	route.RegisterUser(api, *handler, *auth)
 	route.RegisterWorkspace(api, *handler, *auth, *workspace)
 	route.RegisterTool(api, *handler, *auth)
+	route.RegisterRSS(api, *handler, *auth)
 
 	return e, nil
 }
@@ -0,0 +1,108 @@
+package snapshot
+
+import (
+	"log"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/model"
+)
+
+// pageSize is the number of views RunOnce loads at a time.
+const pageSize = 100
+
+// Schedule takes an automatic snapshot of every view that changed since its
+// last snapshot, once per interval, and keeps the newest retain automatic
+// snapshots of each view. It blocks; run it in a goroutine. A non-positive
+// interval disables automatic snapshots.
+func Schedule(d db.DB, interval time.Duration, retain int) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	since := ""
+	for range ticker.C {
+		next, err := RunOnce(d, since, retain)
+		if err != nil {
+			log.Printf("view snapshots: %v", err)
+		}
+		since = next
+	}
+}
+
+// RunOnce takes one round of automatic snapshots of the views that changed
+// after their latest snapshot and after since. It returns the since of the
+// next round: the start of this one, or since again when a view failed, so
+// that the view is retried. A failing view is logged and skipped so that it
+// does not hold back the others.
+//
+// Views whose content is unchanged, such as renamed views, match the hash of
+// their latest snapshot and get none; since keeps them from being hashed
+// again in every round.
+func RunOnce(d db.DB, since string, retain int) (string, error) {
+	// Snapshots are dated at the start of the round, before the views are
+	// read, so that changes made while it runs are seen by the next one.
+	start := time.Now().UTC().String()
+	next := start
+	after := ""
+	for {
+		views, err := d.FindChangedViews(model.ChangedViewFilter{Since: since, AfterID: after, Limit: pageSize})
+		if err != nil {
+			return since, err
+		}
+		for _, view := range views {
+			if err := autoSnapshot(d, view, start, retain); err != nil {
+				log.Printf("view snapshots: view %s: %v", view.ID, err)
+				next = since
+			}
+		}
+		if len(views) < pageSize {
+			return next, nil
+		}
+		after = views[len(views)-1].ID
+	}
+}
+
+func autoSnapshot(d db.DB, view model.View, createdAt string, retain int) error {
+	latest, err := d.FindViewSnapshots(model.ViewSnapshotFilter{ViewID: view.ID, PageNumber: 1, PageSize: 1})
+	if err != nil {
+		return err
+	}
+
+	s, err := Capture(d, view, model.SnapshotKindAuto, "", "")
+	if err != nil {
+		return err
+	}
+	s.CreatedAt = createdAt
+	// Skip views without content and views that did not change.
+	if len(latest) == 0 && view.Data == "" && s.ObjectCount == 0 {
+		return nil
+	}
+	if len(latest) > 0 && latest[0].Hash == s.Hash {
+		return nil
+	}
+	if err := d.CreateViewSnapshot(s); err != nil {
+		return err
+	}
+
+	return Prune(d, view.ID, retain)
+}
+
+// Prune deletes the automatic snapshots of a view beyond the newest retain.
+// Manual and restore snapshots are kept until deleted by a user.
+func Prune(d db.DB, viewID string, retain int) error {
+	if retain <= 0 {
+		return nil
+	}
+	auto, err := d.FindViewSnapshots(model.ViewSnapshotFilter{ViewID: viewID, Kind: model.SnapshotKindAuto, PageNumber: 1, PageSize: -1})
+	if err != nil {
+		return err
+	}
+	for i := retain; i < len(auto); i++ {
+		if err := d.DeleteViewSnapshot(auto[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
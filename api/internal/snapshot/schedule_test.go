@@ -0,0 +1,178 @@
+package snapshot
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/db/dbtest"
+	"github.com/collabreef/collabreef/internal/model"
+)
+
+// newViews stores view a with objects o1 and o2, view b with data only and
+// an empty view, all last changed an hour ago.
+func newViews(t *testing.T, d db.DB) {
+	t.Helper()
+	earlier := time.Now().UTC().Add(-time.Hour).String()
+	for _, v := range []model.View{
+		{ID: "a", Type: "kanban", Data: `{"columns":[]}`},
+		{ID: "b", Type: "whiteboard", Data: `{}`},
+		{ID: "empty", Type: "map"},
+	} {
+		v.WorkspaceID, v.UpdatedAt = "w1", earlier
+		if err := d.CreateView(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"o1", "o2"} {
+		o := model.ViewObject{ID: id, ViewID: "a", Name: id, Type: "card", Data: `{}`, UpdatedAt: earlier}
+		if err := d.CreateViewObject(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func changedViews(t *testing.T, d db.DB, since string) []string {
+	t.Helper()
+	views, err := d.FindChangedViews(model.ChangedViewFilter{Since: since, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, v := range views {
+		ids = append(ids, v.ID)
+	}
+	return ids
+}
+
+func snapshotCount(t *testing.T, d db.DB, viewID string) int {
+	t.Helper()
+	list, err := d.FindViewSnapshots(model.ViewSnapshotFilter{ViewID: viewID, PageNumber: 1, PageSize: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(list)
+}
+
+func TestFindChangedViews(t *testing.T) {
+	now := time.Now().UTC()
+	later := now.Add(time.Hour).String()
+	tests := []struct {
+		name   string
+		change func(d db.DB) error
+		since  string
+		want   []string
+	}{
+		{name: "nothing changed", since: later, want: []string{}},
+		{name: "first round includes views without snapshots", since: "", want: []string{"empty"}},
+		{
+			name: "view renamed",
+			change: func(d db.DB) error {
+				return d.UpdateView(model.View{ID: "a", Name: "Renamed", UpdatedAt: now.Add(2 * time.Hour).String()})
+			},
+			since: later,
+			want:  []string{"a"},
+		},
+		{
+			name: "view changed before since",
+			change: func(d db.DB) error {
+				return d.UpdateView(model.View{ID: "a", Name: "Renamed", UpdatedAt: now.Add(30 * time.Minute).String()})
+			},
+			since: later,
+			want:  []string{},
+		},
+		{
+			name: "object updated by the collab server",
+			change: func(d db.DB) error {
+				return d.UpdateViewObject(model.ViewObject{ID: "o1", Data: `{"x":1}`, UpdatedAt: now.Add(2 * time.Hour).Format(time.RFC3339Nano)})
+			},
+			since: later,
+			want:  []string{"a"},
+		},
+		{
+			name:   "object deleted",
+			change: func(d db.DB) error { return d.DeleteViewObject(model.ViewObject{ID: "o2"}) },
+			since:  later,
+			want:   []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := dbtest.New(t)
+			newViews(t, d)
+			if _, err := RunOnce(d, "", 10); err != nil {
+				t.Fatal(err)
+			}
+			if tt.change != nil {
+				if err := tt.change(d); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := changedViews(t, d, tt.since); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changed views = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	d := dbtest.New(t)
+	newViews(t, d)
+
+	since, err := RunOnce(d, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if since == "" {
+		t.Fatal("RunOnce returned no since")
+	}
+	counts := func() map[string]int {
+		return map[string]int{"a": snapshotCount(t, d, "a"), "b": snapshotCount(t, d, "b"), "empty": snapshotCount(t, d, "empty")}
+	}
+	if got, want := counts(), map[string]int{"a": 1, "b": 1, "empty": 0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("snapshots after the first round = %v, want %v", got, want)
+	}
+
+	// A rename leaves the content as it was.
+	if err := d.UpdateView(model.View{ID: "a", Name: "Renamed", UpdatedAt: time.Now().UTC().String()}); err != nil {
+		t.Fatal(err)
+	}
+	if since, err = RunOnce(d, since, 10); err != nil {
+		t.Fatal(err)
+	}
+	if got := snapshotCount(t, d, "a"); got != 1 {
+		t.Errorf("snapshots of a after a rename = %d, want 1", got)
+	}
+
+	if err := d.DeleteViewObject(model.ViewObject{ID: "o2"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = RunOnce(d, since, 10); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := counts(), map[string]int{"a": 2, "b": 1, "empty": 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshots after deleting an object = %v, want %v", got, want)
+	}
+}
+
+func TestRunOncePages(t *testing.T) {
+	d := dbtest.New(t)
+	n := pageSize + 5
+	for i := range n {
+		v := model.View{ID: fmt.Sprintf("v%03d", i), WorkspaceID: "w1", Type: "kanban", Data: `{}`}
+		if err := d.CreateView(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := RunOnce(d, "", 10); err != nil {
+		t.Fatal(err)
+	}
+	for i := range n {
+		if got := snapshotCount(t, d, fmt.Sprintf("v%03d", i)); got != 1 {
+			t.Errorf("snapshots of v%03d = %d, want 1", i, got)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package snapshot keeps point-in-time copies of views: the view data and
+// all of its view objects, so that destructive edits can be undone.
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/util"
+	"github.com/collabreef/collabreef/internal/whiteboard"
+)
+
+// Object is a view object as stored in a snapshot.
+type Object struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Data      string `json:"data"`
+	CreatedAt string `json:"created_at"`
+	CreatedBy string `json:"created_by"`
+	UpdatedAt string `json:"updated_at"`
+	UpdatedBy string `json:"updated_by"`
+}
+
+// Capture builds a snapshot of the current state of a view. It is not saved.
+func Capture(d db.DB, view model.View, kind, name, userID string) (model.ViewSnapshot, error) {
+	// A page size of -1 disables the limit.
+	objects, err := d.FindViewObjects(model.ViewObjectFilter{ViewID: view.ID, PageNumber: 1, PageSize: -1})
+	if err != nil {
+		return model.ViewSnapshot{}, err
+	}
+
+	list := make([]Object, len(objects))
+	for i, o := range objects {
+		list[i] = Object{
+			ID:        o.ID,
+			Name:      o.Name,
+			Type:      o.Type,
+			Data:      o.Data,
+			CreatedAt: o.CreatedAt,
+			CreatedBy: o.CreatedBy,
+			UpdatedAt: o.UpdatedAt,
+			UpdatedBy: o.UpdatedBy,
+		}
+	}
+	encoded, err := json.Marshal(list)
+	if err != nil {
+		return model.ViewSnapshot{}, err
+	}
+
+	return model.ViewSnapshot{
+		ID:          util.NewId(),
+		ViewID:      view.ID,
+		Name:        name,
+		Kind:        kind,
+		Hash:        hash(view.Data, list),
+		Data:        view.Data,
+		Objects:     string(encoded),
+		ObjectCount: len(list),
+		CreatedAt:   time.Now().UTC().String(),
+		CreatedBy:   userID,
+	}, nil
+}
+
+// hash fingerprints the content of a view, ignoring object order and
+// timestamps, so that unchanged views are not snapshotted again.
+func hash(data string, objects []Object) string {
+	sorted := make([]Object, len(objects))
+	copy(sorted, objects)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	h := sha256.New()
+	h.Write([]byte(data))
+	for _, o := range sorted {
+		for _, s := range []string{o.ID, o.Name, o.Type, o.Data} {
+			h.Write([]byte{0})
+			h.Write([]byte(s))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Objects decodes the view objects of a snapshot.
+func Objects(s model.ViewSnapshot) ([]Object, error) {
+	var list []Object
+	if s.Objects == "" {
+		return list, nil
+	}
+	err := json.Unmarshal([]byte(s.Objects), &list)
+	return list, err
+}
+
+// Restore brings a view back to the state of a snapshot. Objects that still
+// exist are updated in place rather than recreated, so values that refer to
+// them, such as table property values, are kept. Run it in a transaction.
+func Restore(d db.DB, view model.View, s model.ViewSnapshot, userID string) error {
+	saved, err := Objects(s)
+	if err != nil {
+		return err
+	}
+	current, err := d.FindViewObjects(model.ViewObjectFilter{ViewID: view.ID, PageNumber: 1, PageSize: -1})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().String()
+	keep := make(map[string]bool, len(saved))
+	for _, o := range saved {
+		keep[o.ID] = true
+	}
+	existing := make(map[string]bool, len(current))
+	for _, o := range current {
+		existing[o.ID] = true
+		if !keep[o.ID] {
+			if err := d.DeleteViewObject(model.ViewObject{ID: o.ID}); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, o := range saved {
+		vo := model.ViewObject{
+			ID:        o.ID,
+			ViewID:    view.ID,
+			Name:      o.Name,
+			Type:      o.Type,
+			Data:      o.Data,
+			CreatedAt: o.CreatedAt,
+			CreatedBy: o.CreatedBy,
+			UpdatedAt: now,
+			UpdatedBy: userID,
+		}
+		vo = whiteboard.PrepareObject(vo)
+		if existing[o.ID] {
+			err = d.UpdateViewObject(vo)
+		} else {
+			err = d.CreateViewObject(vo)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := d.SetViewData(model.View{ID: view.ID, Data: s.Data, UpdatedAt: now, UpdatedBy: userID}); err != nil {
+		return err
+	}
+	if view.Type == "whiteboard" {
+		return whiteboard.IndexCanvas(d, view.ID, s.Data)
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+package spreadsheet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// maxRangeCells bounds the number of cells a single range request may touch.
+const maxRangeCells = 100000
+
+// Range is a rectangular block of cells in A1 notation, e.g. Sheet1!A1:D20.
+// Rows and columns are zero-based and inclusive.
+type Range struct {
+	Sheet    string
+	StartRow int
+	StartCol int
+	EndRow   int
+	EndCol   int
+}
+
+// ParseRange parses an A1 range such as "A1", "B2:D20", "Sheet1!A1:D20" or
+// "'My Sheet'!A1:B2". Without a sheet prefix the range refers to the first
+// sheet.
+func ParseRange(s string) (Range, error) {
+	var rng Range
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return rng, errors.New("range is required")
+	}
+
+	ref := s
+	if i := strings.LastIndex(s, "!"); i >= 0 {
+		rng.Sheet = s[:i]
+		ref = s[i+1:]
+		if strings.HasPrefix(rng.Sheet, "'") && strings.HasSuffix(rng.Sheet, "'") && len(rng.Sheet) >= 2 {
+			rng.Sheet = strings.ReplaceAll(rng.Sheet[1:len(rng.Sheet)-1], "''", "'")
+		}
+		if rng.Sheet == "" {
+			return rng, fmt.Errorf("invalid range %q: empty sheet name", s)
+		}
+	}
+
+	start, end, found := strings.Cut(ref, ":")
+	if !found {
+		end = start
+	}
+
+	c1, r1, err := excelize.CellNameToCoordinates(strings.ReplaceAll(start, "$", ""))
+	if err != nil {
+		return rng, fmt.Errorf("invalid range %q", s)
+	}
+	c2, r2, err := excelize.CellNameToCoordinates(strings.ReplaceAll(end, "$", ""))
+	if err != nil {
+		return rng, fmt.Errorf("invalid range %q", s)
+	}
+
+	rng.StartRow, rng.EndRow = min(r1, r2)-1, max(r1, r2)-1
+	rng.StartCol, rng.EndCol = min(c1, c2)-1, max(c1, c2)-1
+
+	if rng.Rows()*rng.Cols() > maxRangeCells {
+		return rng, fmt.Errorf("range %q exceeds %d cells", s, maxRangeCells)
+	}
+
+	return rng, nil
+}
+
+func (r Range) Rows() int { return r.EndRow - r.StartRow + 1 }
+func (r Range) Cols() int { return r.EndCol - r.StartCol + 1 }
+
+// IsSingleCell reports whether the range addresses exactly one cell.
+func (r Range) IsSingleCell() bool { return r.Rows() == 1 && r.Cols() == 1 }
+
+// String formats the range in A1 notation with the given sheet name.
+func (r Range) String() string {
+	start, _ := excelize.CoordinatesToCellName(r.StartCol+1, r.StartRow+1)
+	ref := start
+	if !r.IsSingleCell() {
+		end, _ := excelize.CoordinatesToCellName(r.EndCol+1, r.EndRow+1)
+		ref += ":" + end
+	}
+	if r.Sheet == "" {
+		return ref
+	}
+	name := r.Sheet
+	if strings.ContainsAny(name, " !'") {
+		name = "'" + strings.ReplaceAll(name, "'", "''") + "'"
+	}
+	return name + "!" + ref
+}
+
+// RangeValues is the content of a range. Each matrix has one row per range
+// row and one entry per range column.
+type RangeValues struct {
+	Range    string          `json:"range"`
+	SheetID  string          `json:"sheet_id"`
+	Formulas [][]string      `json:"formulas"`
+	Values   [][]interface{} `json:"values"`
+	Display  [][]string      `json:"display"`
+}
+
+// ReadRange returns the stored formulas of a range together with their
+// computed values. Formulas are evaluated against the whole workbook, so
+// cross-sheet references work.
+func ReadRange(sheets []Sheet, rng Range) (*RangeValues, error) {
+	sheet, err := FindSheet(sheets, rng.Sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	ev, err := newEvaluator(sheets)
+	if err != nil {
+		return nil, err
+	}
+	defer ev.Close()
+
+	index := sheet.cellIndex()
+
+	rng.Sheet = sheet.Name
+	out := &RangeValues{
+		Range:    rng.String(),
+		SheetID:  sheet.ID,
+		Formulas: make([][]string, rng.Rows()),
+		Values:   make([][]interface{}, rng.Rows()),
+		Display:  make([][]string, rng.Rows()),
+	}
+	for i := range rng.Rows() {
+		out.Formulas[i] = make([]string, rng.Cols())
+		out.Values[i] = make([]interface{}, rng.Cols())
+		out.Display[i] = make([]string, rng.Cols())
+		for j := range rng.Cols() {
+			cell := index[[2]int{rng.StartRow + i, rng.StartCol + j}]
+			if cell == nil {
+				continue
+			}
+			if formula := cell.Formula(); formula != "" {
+				v := ev.Calc(sheet.ID, rng.StartRow+i, rng.StartCol+j)
+				out.Formulas[i][j] = formula
+				out.Values[i][j] = v
+				out.Display[i][j] = FormatValue(v)
+				continue
+			}
+			out.Values[i][j] = cell.Value()
+			out.Display[i][j] = cell.Text()
+		}
+	}
+
+	return out, nil
+}
+
+// CellUpdate is a cell changed by WriteRange.
+type CellUpdate struct {
+	SheetID string
+	Row     int
+	Col     int
+	Cell    *Cell
+}
+
+// Op returns the FortuneSheet operation that applies the update in a
+// connected editor.
+func (u CellUpdate) Op() map[string]interface{} {
+	var value interface{}
+	if u.Cell != nil {
+		value = u.Cell
+	}
+	return map[string]interface{}{
+		"op":    "replace",
+		"id":    u.SheetID,
+		"path":  []interface{}{"data", u.Row, u.Col},
+		"value": value,
+	}
+}
+
+// WriteRange stores values into a range of the sheet it names. Each value is
+// a string, number, boolean or null; strings starting with "=" are formulas
+// and null clears the cell. A single-cell range is an anchor that values may
+// extend from; a larger range must contain all values.
+//
+// Formula results in all sheets are recomputed afterwards. The returned
+// updates list every cell whose content changed, including dependent formulas.
+func WriteRange(sheets []Sheet, rng Range, values [][]interface{}) (*Sheet, []CellUpdate, error) {
+	sheet, err := FindSheet(sheets, rng.Sheet)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, cols := len(values), 0
+	for _, row := range values {
+		cols = max(cols, len(row))
+	}
+	if rows == 0 || cols == 0 {
+		return nil, nil, errors.New("values are required")
+	}
+	if rows*cols > maxRangeCells {
+		return nil, nil, fmt.Errorf("values exceed %d cells", maxRangeCells)
+	}
+	if !rng.IsSingleCell() && (rows > rng.Rows() || cols > rng.Cols()) {
+		return nil, nil, fmt.Errorf("values (%dx%d) do not fit range %s", rows, cols, rng.String())
+	}
+
+	index := sheet.cellIndex()
+	var updates []CellUpdate
+	written := map[cellKey]bool{}
+	for i, row := range values {
+		for j, v := range row {
+			r, c := rng.StartRow+i, rng.StartCol+j
+			cell, err := inputCell(index[[2]int{r, c}], v)
+			if err != nil {
+				addr, _ := excelize.CoordinatesToCellName(c+1, r+1)
+				return nil, nil, fmt.Errorf("%s: %w", addr, err)
+			}
+			sheet.SetCell(r, c, cell)
+			index[[2]int{r, c}] = cell
+			written[cellKey{sheet.ID, r, c}] = true
+			if cell == nil || cell.Formula() == "" {
+				updates = append(updates, CellUpdate{SheetID: sheet.ID, Row: r, Col: c, Cell: cell})
+			}
+		}
+	}
+
+	recalculated, err := recalculate(sheets, written)
+	if err != nil {
+		return nil, nil, err
+	}
+	updates = append(updates, recalculated...)
+	sortUpdates(updates)
+
+	return sheet, updates, nil
+}
+
+type cellKey struct {
+	sheetID string
+	row     int
+	col     int
+}
+
+// inputCell merges an API value into the existing cell, keeping its style and
+// merge information.
+func inputCell(existing *Cell, v interface{}) (*Cell, error) {
+	var cell Cell
+	if existing != nil {
+		cell = *existing
+	}
+	cell.V, cell.M, cell.F = nil, nil, ""
+
+	switch t := v.(type) {
+	case nil:
+		if existing == nil || (existing.Mc == nil && len(existing.extra) == 0) {
+			return nil, nil
+		}
+		cell.Ct = nil
+	case string:
+		if strings.HasPrefix(t, "=") && len(t) > 1 {
+			cell.F = t
+			cell.Ct = &CellType{Fa: "General", T: "g"}
+			break
+		}
+		if t == "" {
+			return inputCell(existing, nil)
+		}
+		nc := NewValueCell(t)
+		cell.V, cell.M, cell.Ct = nc.V, nc.M, nc.Ct
+	case float64:
+		cell.V, cell.M = t, FormatValue(t)
+		cell.Ct = &CellType{Fa: "General", T: "n"}
+	case bool:
+		cell.V = FormatValue(t)
+		cell.M = cell.V
+		cell.Ct = &CellType{Fa: "General", T: "b"}
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+
+	return &cell, nil
+}
+
+// recalculate refreshes the cached value of every formula cell in the
+// workbook and returns the formula cells that were written or whose result
+// changed.
+func recalculate(sheets []Sheet, written map[cellKey]bool) ([]CellUpdate, error) {
+	ev, err := newEvaluator(sheets)
+	if err != nil {
+		return nil, err
+	}
+	defer ev.Close()
+
+	var updates []CellUpdate
+	for i := range sheets {
+		s := &sheets[i]
+		for _, cd := range s.Celldata {
+			if cd.V == nil || cd.V.Formula() == "" {
+				continue
+			}
+			v := ev.Calc(s.ID, cd.R, cd.C)
+			m := FormatValue(v)
+			if !written[cellKey{s.ID, cd.R, cd.C}] && cd.V.V == v && cd.V.M == m {
+				continue
+			}
+			cd.V.V, cd.V.M = v, m
+			if _, ok := v.(float64); ok {
+				cd.V.Ct = &CellType{Fa: "General", T: "n"}
+			}
+			updates = append(updates, CellUpdate{SheetID: s.ID, Row: cd.R, Col: cd.C, Cell: cd.V})
+		}
+	}
+
+	return updates, nil
+}
+
+// evaluator computes formula results with excelize's calculation engine.
+type evaluator struct {
+	f     *excelize.File
+	names map[string]string
+}
+
+func newEvaluator(sheets []Sheet) (*evaluator, error) {
+	f, names, err := buildWorkbook(sheets)
+	if err != nil {
+		return nil, err
+	}
+	return &evaluator{f: f, names: names}, nil
+}
+
+// Calc returns the result of the formula at r, c: a number, a string, or an
+// Excel error value such as "#DIV/0!".
+func (e *evaluator) Calc(sheetID string, r, c int) interface{} {
+	axis, err := excelize.CoordinatesToCellName(c+1, r+1)
+	if err != nil {
+		return "#REF!"
+	}
+	result, err := e.f.CalcCellValue(e.names[sheetID], axis, excelize.Options{RawCellValue: true})
+	if err != nil && result == "" {
+		// Formula errors such as #DIV/0! are reported through err.
+		if msg := err.Error(); strings.HasPrefix(msg, "#") {
+			return msg
+		}
+		return "#VALUE!"
+	}
+	if n, err := strconv.ParseFloat(result, 64); err == nil {
+		return n
+	}
+	return result
+}
+
+func (e *evaluator) Close() error {
+	return e.f.Close()
+}
+
+func (s *Sheet) cellIndex() map[[2]int]*Cell {
+	index := make(map[[2]int]*Cell, len(s.Celldata))
+	for _, cd := range s.Celldata {
+		if cd.V != nil {
+			index[[2]int{cd.R, cd.C}] = cd.V
+		}
+	}
+	return index
+}
+
+func sortUpdates(updates []CellUpdate) {
+	sort.SliceStable(updates, func(i, j int) bool {
+		if updates[i].SheetID != updates[j].SheetID {
+			return updates[i].SheetID < updates[j].SheetID
+		}
+		if updates[i].Row != updates[j].Row {
+			return updates[i].Row < updates[j].Row
+		}
+		return updates[i].Col < updates[j].Col
+	})
+}
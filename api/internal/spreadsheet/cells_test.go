@@ -0,0 +1,262 @@
+package spreadsheet
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Range
+		str     string
+		wantErr bool
+	}{
+		{in: "A1", want: Range{}, str: "A1"},
+		{in: " B2:D20 ", want: Range{StartRow: 1, StartCol: 1, EndRow: 19, EndCol: 3}, str: "B2:D20"},
+		{in: "D20:B2", want: Range{StartRow: 1, StartCol: 1, EndRow: 19, EndCol: 3}, str: "B2:D20"},
+		{in: "$A$1:$B$2", want: Range{EndRow: 1, EndCol: 1}, str: "A1:B2"},
+		{in: "Sheet1!C3", want: Range{Sheet: "Sheet1", StartRow: 2, StartCol: 2, EndRow: 2, EndCol: 2}, str: "Sheet1!C3"},
+		{in: "'My Sheet'!A1:B2", want: Range{Sheet: "My Sheet", EndRow: 1, EndCol: 1}, str: "'My Sheet'!A1:B2"},
+		{in: "'Bob''s'!AA10", want: Range{Sheet: "Bob's", StartRow: 9, StartCol: 26, EndRow: 9, EndCol: 26}, str: "'Bob''s'!AA10"},
+		{in: "A1:J10000", want: Range{EndRow: 9999, EndCol: 9}, str: "A1:J10000"},
+		{in: "A1:J10001", wantErr: true},
+		{in: "A:B", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "!A1", wantErr: true},
+		{in: "Sheet1!", wantErr: true},
+		{in: "1A", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRange(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRange(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRange(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if s := got.String(); s != tt.str {
+			t.Errorf("ParseRange(%q).String() = %q, want %q", tt.in, s, tt.str)
+		}
+	}
+}
+
+// workbook returns two sheets where the second refers to the first.
+func workbook(t *testing.T) []Sheet {
+	t.Helper()
+	sheets, err := ParseSheets(`[
+		{"id":"s1","name":"Data","order":0,"celldata":[
+			{"r":0,"c":0,"v":{"v":2,"m":"2"}},
+			{"r":1,"c":0,"v":{"v":3,"m":"3"}},
+			{"r":2,"c":0,"v":{"f":"=SUM(A1:A2)","v":5,"m":"5"}},
+			{"r":0,"c":1,"v":{"f":"=A1/0","v":"#DIV/0!","m":"#DIV/0!"}},
+			{"r":1,"c":1,"v":{"v":"note","m":"note","bl":1}}
+		]},
+		{"id":"s2","name":"Summary","order":1,"celldata":[
+			{"r":0,"c":0,"v":{"f":"=Data!A3*10","v":50,"m":"50"}}
+		]}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sheets
+}
+
+func TestReadRange(t *testing.T) {
+	tests := []struct {
+		rng       string
+		wantRange string
+		sheetID   string
+		formulas  [][]string
+		values    [][]interface{}
+		display   [][]string
+	}{
+		{
+			rng:       "A1:B3",
+			wantRange: "Data!A1:B3",
+			sheetID:   "s1",
+			formulas:  [][]string{{"", "=A1/0"}, {"", ""}, {"=SUM(A1:A2)", ""}},
+			values:    [][]interface{}{{2.0, "#DIV/0!"}, {3.0, "note"}, {5.0, nil}},
+			display:   [][]string{{"2", "#DIV/0!"}, {"3", "note"}, {"5", ""}},
+		},
+		{
+			rng:       "Summary!A1",
+			wantRange: "Summary!A1",
+			sheetID:   "s2",
+			formulas:  [][]string{{"=Data!A3*10"}},
+			values:    [][]interface{}{{50.0}},
+			display:   [][]string{{"50"}},
+		},
+		{
+			rng:       "s2!B2:C2",
+			wantRange: "Summary!B2:C2",
+			sheetID:   "s2",
+			formulas:  [][]string{{"", ""}},
+			values:    [][]interface{}{{nil, nil}},
+			display:   [][]string{{"", ""}},
+		},
+	}
+	for _, tt := range tests {
+		rng, err := ParseRange(tt.rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadRange(workbook(t), rng)
+		if err != nil {
+			t.Fatalf("ReadRange(%s): %v", tt.rng, err)
+		}
+		if got.Range != tt.wantRange || got.SheetID != tt.sheetID {
+			t.Errorf("ReadRange(%s) = %s of %s, want %s of %s", tt.rng, got.Range, got.SheetID, tt.wantRange, tt.sheetID)
+		}
+		if !reflect.DeepEqual(got.Formulas, tt.formulas) {
+			t.Errorf("ReadRange(%s) formulas = %v, want %v", tt.rng, got.Formulas, tt.formulas)
+		}
+		if !reflect.DeepEqual(got.Values, tt.values) {
+			t.Errorf("ReadRange(%s) values = %#v, want %#v", tt.rng, got.Values, tt.values)
+		}
+		if !reflect.DeepEqual(got.Display, tt.display) {
+			t.Errorf("ReadRange(%s) display = %v, want %v", tt.rng, got.Display, tt.display)
+		}
+	}
+
+	if _, err := ReadRange(workbook(t), Range{Sheet: "Missing"}); err == nil {
+		t.Error("ReadRange read a missing sheet")
+	}
+}
+
+// describe lists updates as "sheet!row,col=value", or "=nil" for cleared
+// cells.
+func describe(updates []CellUpdate) []string {
+	var out []string
+	for _, u := range updates {
+		v := "nil"
+		if u.Cell != nil {
+			v = FormatValue(u.Cell.V)
+			if f := u.Cell.Formula(); f != "" {
+				v = f + " " + v
+			}
+		}
+		out = append(out, fmt.Sprintf("%s!%d,%d=%s", u.SheetID, u.Row, u.Col, v))
+	}
+	return out
+}
+
+func TestWriteRange(t *testing.T) {
+	tests := []struct {
+		name   string
+		rng    string
+		values [][]interface{}
+		want   []string
+	}{
+		{
+			name:   "dependent formulas in every sheet are recomputed",
+			rng:    "A1",
+			values: [][]interface{}{{10.0}},
+			want:   []string{"s1!0,0=10", "s1!2,0==SUM(A1:A2) 13", "s2!0,0==Data!A3*10 130"},
+		},
+		{
+			name:   "a single cell anchors values extending from it",
+			rng:    "C1",
+			values: [][]interface{}{{"x", true}, {"=A1+A2", nil}},
+			want:   []string{"s1!0,2=x", "s1!0,3=TRUE", "s1!1,2==A1+A2 5", "s1!1,3=nil"},
+		},
+		{
+			name:   "numeric text becomes a number",
+			rng:    "Data!A2",
+			values: [][]interface{}{{"4.5"}},
+			want:   []string{"s1!1,0=4.5", "s1!2,0==SUM(A1:A2) 6.5", "s2!0,0==Data!A3*10 65"},
+		},
+		{
+			name:   "null and empty strings clear cells",
+			rng:    "A1:A2",
+			values: [][]interface{}{{nil}, {""}},
+			want:   []string{"s1!0,0=nil", "s1!1,0=nil", "s1!2,0==SUM(A1:A2) 0", "s2!0,0==Data!A3*10 0"},
+		},
+		{
+			name:   "the formula of a cell is replaced by a value",
+			rng:    "A3",
+			values: [][]interface{}{{7.0}},
+			want:   []string{"s1!2,0=7", "s2!0,0==Data!A3*10 70"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng, err := ParseRange(tt.rng)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sheets := workbook(t)
+			if _, updates, err := WriteRange(sheets, rng, tt.values); err != nil {
+				t.Fatalf("WriteRange: %v", err)
+			} else if got := describe(updates); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("updates = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteRangeKeepsStyle(t *testing.T) {
+	sheets := workbook(t)
+	if _, _, err := WriteRange(sheets, Range{StartRow: 1, StartCol: 1, EndRow: 1, EndCol: 1}, [][]interface{}{{"changed"}}); err != nil {
+		t.Fatalf("WriteRange: %v", err)
+	}
+	cell := sheets[0].Cell(1, 1)
+	if cell.V != "changed" || cell.extra["bl"] == nil {
+		t.Errorf("cell = %+v, want the new value with its bold flag", cell)
+	}
+
+	// A styled cell is kept empty rather than removed.
+	if _, _, err := WriteRange(sheets, Range{StartRow: 1, StartCol: 1, EndRow: 1, EndCol: 1}, [][]interface{}{{nil}}); err != nil {
+		t.Fatalf("WriteRange: %v", err)
+	}
+	if cell := sheets[0].Cell(1, 1); cell == nil || cell.V != nil || cell.extra["bl"] == nil {
+		t.Errorf("cleared styled cell = %+v", cell)
+	}
+}
+
+func TestWriteRangeErrors(t *testing.T) {
+	tooMany := make([][]interface{}, maxRangeCells+1)
+	for i := range tooMany {
+		tooMany[i] = []interface{}{1.0}
+	}
+
+	tests := []struct {
+		name   string
+		rng    Range
+		values [][]interface{}
+	}{
+		{"no values", Range{}, nil},
+		{"empty rows", Range{}, [][]interface{}{{}}},
+		{"values larger than the range", Range{EndRow: 1, EndCol: 1}, [][]interface{}{{1.0, 2.0, 3.0}}},
+		{"unsupported value", Range{}, [][]interface{}{{map[string]interface{}{}}}},
+		{"missing sheet", Range{Sheet: "Missing"}, [][]interface{}{{1.0}}},
+		{"too many values", Range{}, tooMany},
+	}
+	for _, tt := range tests {
+		if _, _, err := WriteRange(workbook(t), tt.rng, tt.values); err == nil {
+			t.Errorf("%s: WriteRange succeeded", tt.name)
+		}
+	}
+}
+
+func TestCellUpdateOp(t *testing.T) {
+	cell := &Cell{V: 1.0}
+	tests := []struct {
+		u    CellUpdate
+		want interface{}
+	}{
+		{CellUpdate{SheetID: "s1", Row: 2, Col: 3, Cell: cell}, cell},
+		{CellUpdate{SheetID: "s1", Row: 2, Col: 3}, nil},
+	}
+	for _, tt := range tests {
+		op := tt.u.Op()
+		if op["op"] != "replace" || op["id"] != "s1" || !reflect.DeepEqual(op["path"], []interface{}{"data", 2, 3}) || op["value"] != tt.want {
+			t.Errorf("Op() = %v", op)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/collabreef/collabreef/internal/util"
+)
+
+// WriteCSV writes the displayed values of a sheet as CSV. Formulas are
+// exported as their last computed value.
+func WriteCSV(s *Sheet, w io.Writer) error {
+	rows, cols := s.Bounds()
+
+	grid := make([][]string, rows)
+	for r := range grid {
+		grid[r] = make([]string, cols)
+	}
+	for _, cd := range s.Celldata {
+		if cd.V == nil {
+			continue
+		}
+		grid[cd.R][cd.C] = cd.V.Text()
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(grid); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// ReadCSV builds a single sheet from CSV content. Numeric fields become
+// number cells; everything else is kept as text.
+func ReadCSV(r io.Reader, name string) (Sheet, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return Sheet{}, err
+	}
+
+	s := Sheet{
+		ID:   util.NewId(),
+		Name: name,
+	}
+	for ri, record := range records {
+		for ci, field := range record {
+			if ri == 0 && ci == 0 {
+				field = strings.TrimPrefix(field, "\ufeff")
+			}
+			if cell := NewValueCell(field); cell != nil {
+				s.Celldata = append(s.Celldata, CellData{R: ri, C: ci, V: cell})
+			}
+		}
+	}
+	s.fitSize()
+
+	return s, nil
+}
@@ -0,0 +1,70 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	s, err := ReadCSV(strings.NewReader("\ufeffName,Qty\n\"Widget, large\",3\nbolt,\n\"say \"\"hi\"\"\",0.5,extra\n"), "Stock")
+	if err != nil {
+		t.Fatalf("ReadCSV: %v", err)
+	}
+	if s.Name != "Stock" || s.ID == "" {
+		t.Errorf("sheet = %q %q", s.ID, s.Name)
+	}
+
+	tests := []struct {
+		r, c int
+		v    interface{}
+	}{
+		{0, 0, "Name"},
+		{0, 1, "Qty"},
+		{1, 0, "Widget, large"},
+		{1, 1, 3.0},
+		{2, 0, "bolt"},
+		{2, 1, nil},
+		{3, 0, `say "hi"`},
+		{3, 1, 0.5},
+		{3, 2, "extra"},
+	}
+	for _, tt := range tests {
+		if got := s.Cell(tt.r, tt.c).Value(); got != tt.v {
+			t.Errorf("cell %d,%d = %#v, want %#v", tt.r, tt.c, got, tt.v)
+		}
+	}
+	if s.Row != defaultRows || s.Column != defaultColumns {
+		t.Errorf("grid = %dx%d, want the default size", s.Row, s.Column)
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	in := "a,b,c\n1,,\"x, y\"\n,=SUM(A2),\"line\nbreak\"\n"
+	s, err := ReadCSV(strings.NewReader(in), "Sheet1")
+	if err != nil {
+		t.Fatalf("ReadCSV: %v", err)
+	}
+	var out bytes.Buffer
+	if err := WriteCSV(&s, &out); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	if out.String() != in {
+		t.Errorf("WriteCSV = %q, want %q", out.String(), in)
+	}
+}
+
+func TestWriteCSVUsesDisplayedValues(t *testing.T) {
+	s := Sheet{Celldata: []CellData{
+		{R: 0, C: 0, V: &Cell{V: 0.5, M: "50%"}},
+		{R: 0, C: 2, V: &Cell{F: "=A1*2", V: 1.0}},
+		{R: 1, C: 1, V: nil},
+	}}
+	var out bytes.Buffer
+	if err := WriteCSV(&s, &out); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	if want := "50%,,1\n"; out.String() != want {
+		t.Errorf("WriteCSV = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,453 @@
+package spreadsheet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sheet mirrors a FortuneSheet worksheet as persisted in views.data.
+// Fields this package does not understand are kept in extra so that a
+// parse/modify/marshal round-trip does not drop client-side state.
+type Sheet struct {
+	ID       string       `json:"id"`
+	Name     string       `json:"name"`
+	Order    int          `json:"order"`
+	Row      int          `json:"row,omitempty"`
+	Column   int          `json:"column,omitempty"`
+	Celldata []CellData   `json:"celldata,omitempty"`
+	Data     [][]*Cell    `json:"data,omitempty"`
+	Config   *SheetConfig `json:"config,omitempty"`
+	Frozen   *Frozen      `json:"frozen,omitempty"`
+	extra    map[string]json.RawMessage
+}
+
+// CellData is one entry of the sparse celldata array.
+type CellData struct {
+	R int   `json:"r"`
+	C int   `json:"c"`
+	V *Cell `json:"v"`
+}
+
+// Cell is a FortuneSheet cell value.
+type Cell struct {
+	V     interface{} `json:"v,omitempty"`
+	M     interface{} `json:"m,omitempty"`
+	F     string      `json:"f,omitempty"`
+	Ct    *CellType   `json:"ct,omitempty"`
+	Mc    *Merge      `json:"mc,omitempty"`
+	extra map[string]json.RawMessage
+}
+
+// CellType holds the number format and value type of a cell.
+type CellType struct {
+	Fa string                   `json:"fa,omitempty"`
+	T  string                   `json:"t,omitempty"`
+	S  []map[string]interface{} `json:"s,omitempty"`
+}
+
+// Merge describes a merged range. Only the top-left cell of a merge carries
+// Rs and Cs; the other cells point back to it with R and C.
+type Merge struct {
+	R  int `json:"r"`
+	C  int `json:"c"`
+	Rs int `json:"rs,omitempty"`
+	Cs int `json:"cs,omitempty"`
+}
+
+type SheetConfig struct {
+	Merge map[string]Merge `json:"merge,omitempty"`
+	extra map[string]json.RawMessage
+}
+
+// Frozen describes frozen panes. Type is one of row, column, both,
+// rangeRow, rangeColumn or rangeBoth; the range variants use Range.
+type Frozen struct {
+	Type  string       `json:"type,omitempty"`
+	Range *FrozenRange `json:"range,omitempty"`
+}
+
+type FrozenRange struct {
+	RowFocus    int `json:"row_focus"`
+	ColumnFocus int `json:"column_focus"`
+}
+
+var (
+	sheetKeys  = []string{"id", "name", "order", "row", "column", "celldata", "data", "config", "frozen"}
+	cellKeys   = []string{"v", "m", "f", "ct", "mc"}
+	configKeys = []string{"merge"}
+)
+
+func (s *Sheet) UnmarshalJSON(b []byte) error {
+	type alias Sheet
+	var a alias
+	if err := json.Unmarshal(b, &a); err != nil {
+		return err
+	}
+	extra, err := extraFields(b, sheetKeys)
+	if err != nil {
+		return err
+	}
+	*s = Sheet(a)
+	s.extra = extra
+	return nil
+}
+
+func (s Sheet) MarshalJSON() ([]byte, error) {
+	type alias Sheet
+	return marshalWithExtra(alias(s), s.extra)
+}
+
+func (c *Cell) UnmarshalJSON(b []byte) error {
+	type alias Cell
+	var a alias
+	if err := json.Unmarshal(b, &a); err != nil {
+		return err
+	}
+	extra, err := extraFields(b, cellKeys)
+	if err != nil {
+		return err
+	}
+	*c = Cell(a)
+	c.extra = extra
+	return nil
+}
+
+func (c Cell) MarshalJSON() ([]byte, error) {
+	type alias Cell
+	return marshalWithExtra(alias(c), c.extra)
+}
+
+func (c *SheetConfig) UnmarshalJSON(b []byte) error {
+	type alias SheetConfig
+	var a alias
+	if err := json.Unmarshal(b, &a); err != nil {
+		return err
+	}
+	extra, err := extraFields(b, configKeys)
+	if err != nil {
+		return err
+	}
+	*c = SheetConfig(a)
+	c.extra = extra
+	return nil
+}
+
+func (c SheetConfig) MarshalJSON() ([]byte, error) {
+	type alias SheetConfig
+	return marshalWithExtra(alias(c), c.extra)
+}
+
+func extraFields(b []byte, known []string) (map[string]json.RawMessage, error) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	for _, k := range known {
+		delete(m, k)
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+func marshalWithExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return b, err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	for k, raw := range extra {
+		if _, ok := m[k]; !ok {
+			m[k] = raw
+		}
+	}
+	return json.Marshal(m)
+}
+
+// ParseSheets decodes views.data of a spreadsheet view. The collab service
+// stores an array of sheets; older documents may use an object keyed by
+// sheet id. Sheets are returned sorted by order with cells in celldata form.
+func ParseSheets(data string) ([]Sheet, error) {
+	data = strings.TrimSpace(data)
+	if data == "" || data == "null" {
+		return []Sheet{}, nil
+	}
+
+	var sheets []Sheet
+	if strings.HasPrefix(data, "[") {
+		if err := json.Unmarshal([]byte(data), &sheets); err != nil {
+			return nil, fmt.Errorf("invalid spreadsheet data: %w", err)
+		}
+	} else {
+		var keyed map[string]Sheet
+		if err := json.Unmarshal([]byte(data), &keyed); err != nil {
+			return nil, fmt.Errorf("invalid spreadsheet data: %w", err)
+		}
+		for key, s := range keyed {
+			if strings.HasPrefix(key, "_") {
+				continue
+			}
+			if s.ID == "" {
+				s.ID = key
+			}
+			sheets = append(sheets, s)
+		}
+	}
+
+	for i := range sheets {
+		sheets[i].normalize()
+	}
+	sort.SliceStable(sheets, func(i, j int) bool { return sheets[i].Order < sheets[j].Order })
+
+	return sheets, nil
+}
+
+// MarshalSheets encodes sheets in the array form written by the collab service.
+func MarshalSheets(sheets []Sheet) (string, error) {
+	b, err := json.Marshal(sheets)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// FindSheet looks a sheet up by id or, failing that, by name. An empty key
+// returns the first sheet.
+func FindSheet(sheets []Sheet, key string) (*Sheet, error) {
+	if len(sheets) == 0 {
+		return nil, errors.New("spreadsheet has no sheets")
+	}
+	if key == "" {
+		return &sheets[0], nil
+	}
+	for i := range sheets {
+		if sheets[i].ID == key {
+			return &sheets[i], nil
+		}
+	}
+	for i := range sheets {
+		if sheets[i].Name == key {
+			return &sheets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("sheet %q not found", key)
+}
+
+// normalize folds a dense data matrix into celldata, which is the form the
+// frontend loads from.
+func (s *Sheet) normalize() {
+	if len(s.Celldata) > 0 || len(s.Data) == 0 {
+		s.Data = nil
+		return
+	}
+	for r, row := range s.Data {
+		for c, cell := range row {
+			if cell == nil || cell.isEmpty() {
+				continue
+			}
+			s.Celldata = append(s.Celldata, CellData{R: r, C: c, V: cell})
+		}
+	}
+	s.Data = nil
+}
+
+// Cell returns the cell at r, c or nil if it is empty.
+func (s *Sheet) Cell(r, c int) *Cell {
+	for i := range s.Celldata {
+		if s.Celldata[i].R == r && s.Celldata[i].C == c {
+			return s.Celldata[i].V
+		}
+	}
+	return nil
+}
+
+// SetCell replaces the cell at r, c. A nil cell clears it.
+func (s *Sheet) SetCell(r, c int, cell *Cell) {
+	for i := range s.Celldata {
+		if s.Celldata[i].R == r && s.Celldata[i].C == c {
+			if cell == nil {
+				s.Celldata = append(s.Celldata[:i], s.Celldata[i+1:]...)
+			} else {
+				s.Celldata[i].V = cell
+			}
+			return
+		}
+	}
+	if cell == nil {
+		return
+	}
+	s.Celldata = append(s.Celldata, CellData{R: r, C: c, V: cell})
+	if s.Row > 0 && r+1 > s.Row {
+		s.Row = r + 1
+	}
+	if s.Column > 0 && c+1 > s.Column {
+		s.Column = c + 1
+	}
+}
+
+// Grid sizes FortuneSheet uses for a new sheet.
+const (
+	defaultRows    = 84
+	defaultColumns = 60
+)
+
+// fitSize grows the sheet grid so that every imported cell is reachable.
+func (s *Sheet) fitSize() {
+	rows, cols := s.Bounds()
+	s.Row = max(rows, defaultRows)
+	s.Column = max(cols, defaultColumns)
+}
+
+// Bounds returns the number of rows and columns that contain cells.
+func (s *Sheet) Bounds() (rows, cols int) {
+	for _, cd := range s.Celldata {
+		if cd.V == nil {
+			continue
+		}
+		if cd.R+1 > rows {
+			rows = cd.R + 1
+		}
+		if cd.C+1 > cols {
+			cols = cd.C + 1
+		}
+	}
+	return rows, cols
+}
+
+// Merges returns the merged ranges of the sheet.
+func (s *Sheet) Merges() []Merge {
+	var merges []Merge
+	if s.Config != nil {
+		for _, m := range s.Config.Merge {
+			if m.Rs > 0 && m.Cs > 0 {
+				merges = append(merges, m)
+			}
+		}
+	}
+	sort.Slice(merges, func(i, j int) bool {
+		if merges[i].R != merges[j].R {
+			return merges[i].R < merges[j].R
+		}
+		return merges[i].C < merges[j].C
+	})
+	return merges
+}
+
+// FrozenSplit returns the number of frozen rows and columns.
+func (s *Sheet) FrozenSplit() (rows, cols int) {
+	if s.Frozen == nil {
+		return 0, 0
+	}
+	focusRow, focusCol := 0, 0
+	if s.Frozen.Range != nil {
+		focusRow, focusCol = s.Frozen.Range.RowFocus, s.Frozen.Range.ColumnFocus
+	}
+	switch s.Frozen.Type {
+	case "row":
+		return 1, 0
+	case "column":
+		return 0, 1
+	case "both":
+		return 1, 1
+	case "rangeRow":
+		return focusRow + 1, 0
+	case "rangeColumn":
+		return 0, focusCol + 1
+	case "rangeBoth":
+		return focusRow + 1, focusCol + 1
+	}
+	return 0, 0
+}
+
+// SetFrozenSplit freezes the given number of leading rows and columns.
+func (s *Sheet) SetFrozenSplit(rows, cols int) {
+	switch {
+	case rows > 0 && cols > 0:
+		s.Frozen = &Frozen{Type: "rangeBoth", Range: &FrozenRange{RowFocus: rows - 1, ColumnFocus: cols - 1}}
+	case rows > 0:
+		s.Frozen = &Frozen{Type: "rangeRow", Range: &FrozenRange{RowFocus: rows - 1}}
+	case cols > 0:
+		s.Frozen = &Frozen{Type: "rangeColumn", Range: &FrozenRange{ColumnFocus: cols - 1}}
+	default:
+		s.Frozen = nil
+	}
+}
+
+func (c *Cell) isEmpty() bool {
+	return c.V == nil && c.M == nil && c.F == "" && c.Mc == nil && c.Ct == nil && len(c.extra) == 0
+}
+
+// Formula returns the cell formula including the leading "=", if any.
+func (c *Cell) Formula() string {
+	if c == nil {
+		return ""
+	}
+	return c.F
+}
+
+// Value returns the raw value of the cell. Inline rich-text strings are
+// flattened into plain text.
+func (c *Cell) Value() interface{} {
+	if c == nil {
+		return nil
+	}
+	if c.V == nil && c.Ct != nil && c.Ct.T == "inlineStr" {
+		var sb strings.Builder
+		for _, part := range c.Ct.S {
+			if v, ok := part["v"].(string); ok {
+				sb.WriteString(v)
+			}
+		}
+		return sb.String()
+	}
+	return c.V
+}
+
+// Text returns what the cell displays, falling back to the raw value.
+func (c *Cell) Text() string {
+	if c == nil {
+		return ""
+	}
+	if m, ok := c.M.(string); ok && m != "" {
+		return m
+	}
+	return FormatValue(c.Value())
+}
+
+// FormatValue renders a raw cell value as text.
+func FormatValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		if t {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// NewValueCell builds a cell from plain text, detecting numbers.
+func NewValueCell(text string) *Cell {
+	if text == "" {
+		return nil
+	}
+	if n, err := strconv.ParseFloat(strings.TrimSpace(text), 64); err == nil {
+		return &Cell{V: n, M: text, Ct: &CellType{Fa: "General", T: "n"}}
+	}
+	return &Cell{V: text, M: text, Ct: &CellType{Fa: "General", T: "g"}}
+}
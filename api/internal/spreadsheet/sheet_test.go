@@ -0,0 +1,201 @@
+package spreadsheet
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseSheets(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		// ids are the sheets in order, and want the cells of each sheet
+		// as "r,c=text".
+		ids  []string
+		want map[string][]string
+	}{
+		{name: "empty", data: "", ids: []string{}},
+		{name: "null", data: " null ", ids: []string{}},
+		{
+			name: "array sorted by order",
+			data: `[{"id":"b","name":"B","order":1},{"id":"a","name":"A","order":0,"celldata":[{"r":1,"c":2,"v":{"v":3,"m":"3"}}]}]`,
+			ids:  []string{"a", "b"},
+			want: map[string][]string{"a": {"1,2=3"}},
+		},
+		{
+			name: "keyed by id",
+			data: `{"s1":{"name":"One","order":0,"data":[[{"v":"x"},null],[null,{"v":1}]]},"_meta":{"x":1}}`,
+			ids:  []string{"s1"},
+			want: map[string][]string{"s1": {"0,0=x", "1,1=1"}},
+		},
+		{
+			name: "dense data without values is dropped",
+			data: `[{"id":"a","order":0,"data":[[{},null,{"v":"kept"}]]}]`,
+			ids:  []string{"a"},
+			want: map[string][]string{"a": {"0,2=kept"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheets, err := ParseSheets(tt.data)
+			if err != nil {
+				t.Fatalf("ParseSheets: %v", err)
+			}
+			ids := []string{}
+			for _, s := range sheets {
+				ids = append(ids, s.ID)
+				if s.Data != nil {
+					t.Errorf("sheet %s keeps dense data", s.ID)
+				}
+				var cells []string
+				for _, cd := range s.Celldata {
+					cells = append(cells, fmt.Sprintf("%d,%d=%s", cd.R, cd.C, cd.V.Text()))
+				}
+				if !reflect.DeepEqual(cells, tt.want[s.ID]) {
+					t.Errorf("sheet %s cells = %v, want %v", s.ID, cells, tt.want[s.ID])
+				}
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("sheets = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+
+	if _, err := ParseSheets(`[{"id":`); err == nil {
+		t.Error("ParseSheets accepted invalid JSON")
+	}
+}
+
+func TestMarshalSheetsKeepsUnknownFields(t *testing.T) {
+	data := `[{"id":"a","name":"A","order":0,"color":"red","config":{"rowlen":{"0":30},"merge":{"0_0":{"r":0,"c":0,"rs":1,"cs":2}}},"celldata":[{"r":0,"c":0,"v":{"v":"x","bl":1,"mc":{"r":0,"c":0,"rs":1,"cs":2}}}]}]`
+	sheets, err := ParseSheets(data)
+	if err != nil {
+		t.Fatalf("ParseSheets: %v", err)
+	}
+	sheets[0].SetCell(0, 0, &Cell{V: "y", extra: sheets[0].Cell(0, 0).extra})
+
+	out, err := MarshalSheets(sheets)
+	if err != nil {
+		t.Fatalf("MarshalSheets: %v", err)
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got[0]["color"] != "red" {
+		t.Errorf("sheet field lost: %s", out)
+	}
+	config := got[0]["config"].(map[string]interface{})
+	if config["rowlen"] == nil || config["merge"] == nil {
+		t.Errorf("config fields lost: %s", out)
+	}
+	cell := got[0]["celldata"].([]interface{})[0].(map[string]interface{})["v"].(map[string]interface{})
+	if cell["v"] != "y" || cell["bl"] != float64(1) {
+		t.Errorf("cell = %v, want the new value with its bold flag", cell)
+	}
+}
+
+func TestFindSheet(t *testing.T) {
+	sheets := []Sheet{{ID: "a", Name: "First"}, {ID: "b", Name: "a"}}
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "", want: "a"},
+		{key: "b", want: "b"},
+		{key: "First", want: "a"},
+		{key: "a", want: "a"},
+		{key: "missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		s, err := FindSheet(sheets, tt.key)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("FindSheet(%q) error = %v", tt.key, err)
+			continue
+		}
+		if err == nil && s.ID != tt.want {
+			t.Errorf("FindSheet(%q) = %s, want %s", tt.key, s.ID, tt.want)
+		}
+	}
+	if _, err := FindSheet(nil, ""); err == nil {
+		t.Error("FindSheet found a sheet in no sheets")
+	}
+}
+
+func TestFrozenSplit(t *testing.T) {
+	tests := []struct {
+		rows, cols int
+		typ        string
+	}{
+		{0, 0, ""},
+		{2, 0, "rangeRow"},
+		{0, 3, "rangeColumn"},
+		{1, 4, "rangeBoth"},
+	}
+	for _, tt := range tests {
+		var s Sheet
+		s.SetFrozenSplit(tt.rows, tt.cols)
+		typ := ""
+		if s.Frozen != nil {
+			typ = s.Frozen.Type
+		}
+		rows, cols := s.FrozenSplit()
+		if typ != tt.typ || rows != tt.rows || cols != tt.cols {
+			t.Errorf("SetFrozenSplit(%d, %d) = %q, split %d, %d", tt.rows, tt.cols, typ, rows, cols)
+		}
+	}
+
+	s := Sheet{Frozen: &Frozen{Type: "both"}}
+	if rows, cols := s.FrozenSplit(); rows != 1 || cols != 1 {
+		t.Errorf("both frozen = %d, %d, want 1, 1", rows, cols)
+	}
+}
+
+func TestNewValueCell(t *testing.T) {
+	tests := []struct {
+		text string
+		v    interface{}
+		typ  string
+	}{
+		{"", nil, ""},
+		{"12.5", 12.5, "n"},
+		{" 7 ", 7.0, "n"},
+		{"1e3", 1000.0, "n"},
+		{"12 apples", "12 apples", "g"},
+	}
+	for _, tt := range tests {
+		c := NewValueCell(tt.text)
+		if tt.typ == "" {
+			if c != nil {
+				t.Errorf("NewValueCell(%q) = %+v, want nil", tt.text, c)
+			}
+			continue
+		}
+		if c.V != tt.v || c.Ct.T != tt.typ || c.Text() != tt.text {
+			t.Errorf("NewValueCell(%q) = %v (%s) showing %q", tt.text, c.V, c.Ct.T, c.Text())
+		}
+	}
+}
+
+func TestCellValue(t *testing.T) {
+	rich := &Cell{Ct: &CellType{T: "inlineStr", S: []map[string]interface{}{{"v": "Hello, "}, {"v": "world", "bl": 1}}}}
+	tests := []struct {
+		cell *Cell
+		text string
+	}{
+		{nil, ""},
+		{rich, "Hello, world"},
+		{&Cell{V: 3.25}, "3.25"},
+		{&Cell{V: 3.0, M: "3.00"}, "3.00"},
+		{&Cell{V: true}, "TRUE"},
+	}
+	for _, tt := range tests {
+		if got := tt.cell.Text(); got != tt.text {
+			t.Errorf("Text() = %q, want %q", got, tt.text)
+		}
+	}
+}
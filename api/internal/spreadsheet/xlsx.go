@@ -0,0 +1,316 @@
+package spreadsheet
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/collabreef/collabreef/internal/util"
+	"github.com/xuri/excelize/v2"
+)
+
+const maxSheetNameLength = 31
+
+// WriteXLSX writes sheets as an Excel workbook, keeping formulas, merged
+// cells and frozen panes.
+func WriteXLSX(sheets []Sheet, w io.Writer) error {
+	f, _, err := buildWorkbook(sheets)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return f.Write(w)
+}
+
+// ReadXLSX converts every worksheet of an Excel workbook into a sheet.
+func ReadXLSX(r io.Reader) ([]Sheet, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sheets []Sheet
+	for order, name := range f.GetSheetList() {
+		s, err := readWorksheet(f, name)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", name, err)
+		}
+		s.Order = order
+		sheets = append(sheets, s)
+	}
+
+	return sheets, nil
+}
+
+// buildWorkbook creates an in-memory workbook from sheets. The returned map
+// gives the worksheet name used for each sheet id, since Excel restricts
+// names that FortuneSheet allows.
+func buildWorkbook(sheets []Sheet) (*excelize.File, map[string]string, error) {
+	f := excelize.NewFile()
+	names := make(map[string]string, len(sheets))
+	used := map[string]bool{}
+
+	defaultSheet := f.GetSheetName(0)
+	for i, s := range sheets {
+		name := uniqueSheetName(s.Name, i, used)
+		names[s.ID] = name
+
+		if i == 0 {
+			if err := f.SetSheetName(defaultSheet, name); err != nil {
+				f.Close()
+				return nil, nil, err
+			}
+		} else if _, err := f.NewSheet(name); err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+
+		if err := writeWorksheet(f, name, &sheets[i]); err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("sheet %q: %w", s.Name, err)
+		}
+	}
+
+	return f, names, nil
+}
+
+func writeWorksheet(f *excelize.File, name string, s *Sheet) error {
+	for _, cd := range s.Celldata {
+		if cd.V == nil {
+			continue
+		}
+		axis, err := excelize.CoordinatesToCellName(cd.C+1, cd.R+1)
+		if err != nil {
+			return err
+		}
+		if formula := cd.V.Formula(); formula != "" {
+			if err := f.SetCellFormula(name, axis, strings.TrimPrefix(formula, "=")); err != nil {
+				return err
+			}
+			continue
+		}
+		if v := cd.V.Value(); v != nil {
+			if err := f.SetCellValue(name, axis, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, m := range s.Merges() {
+		start, err := excelize.CoordinatesToCellName(m.C+1, m.R+1)
+		if err != nil {
+			return err
+		}
+		end, err := excelize.CoordinatesToCellName(m.C+m.Cs, m.R+m.Rs)
+		if err != nil {
+			return err
+		}
+		if err := f.MergeCell(name, start, end); err != nil {
+			return err
+		}
+	}
+
+	if rows, cols := s.FrozenSplit(); rows > 0 || cols > 0 {
+		topLeft, err := excelize.CoordinatesToCellName(cols+1, rows+1)
+		if err != nil {
+			return err
+		}
+		pane := "bottomRight"
+		switch {
+		case cols == 0:
+			pane = "bottomLeft"
+		case rows == 0:
+			pane = "topRight"
+		}
+		if err := f.SetPanes(name, &excelize.Panes{
+			Freeze:      true,
+			XSplit:      cols,
+			YSplit:      rows,
+			TopLeftCell: topLeft,
+			ActivePane:  pane,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readWorksheet(f *excelize.File, name string) (Sheet, error) {
+	s := Sheet{
+		ID:   util.NewId(),
+		Name: name,
+	}
+
+	raw, err := f.GetRows(name, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return s, err
+	}
+	formatted, err := f.GetRows(name)
+	if err != nil {
+		return s, err
+	}
+
+	index := map[[2]int]*Cell{}
+	for r, row := range raw {
+		for c := range row {
+			axis, err := excelize.CoordinatesToCellName(c+1, r+1)
+			if err != nil {
+				return s, err
+			}
+			formula, err := f.GetCellFormula(name, axis)
+			if err != nil {
+				return s, err
+			}
+			display := ""
+			if r < len(formatted) && c < len(formatted[r]) {
+				display = formatted[r][c]
+			}
+			cell, err := readCell(f, name, axis, row[c], display, formula)
+			if err != nil {
+				return s, err
+			}
+			if cell == nil {
+				continue
+			}
+			index[[2]int{r, c}] = cell
+			s.Celldata = append(s.Celldata, CellData{R: r, C: c, V: cell})
+		}
+	}
+
+	merges, err := f.GetMergeCells(name, true)
+	if err != nil {
+		return s, err
+	}
+	for _, mc := range merges {
+		c1, r1, err := excelize.CellNameToCoordinates(mc.GetStartAxis())
+		if err != nil {
+			return s, err
+		}
+		c2, r2, err := excelize.CellNameToCoordinates(mc.GetEndAxis())
+		if err != nil {
+			return s, err
+		}
+		m := Merge{R: r1 - 1, C: c1 - 1, Rs: r2 - r1 + 1, Cs: c2 - c1 + 1}
+		for r := m.R; r < m.R+m.Rs; r++ {
+			for c := m.C; c < m.C+m.Cs; c++ {
+				if _, ok := index[[2]int{r, c}]; !ok {
+					cell := &Cell{}
+					index[[2]int{r, c}] = cell
+					s.Celldata = append(s.Celldata, CellData{R: r, C: c, V: cell})
+				}
+			}
+		}
+		addMerge(&s, m, index)
+	}
+
+	panes, err := f.GetPanes(name)
+	if err != nil {
+		return s, err
+	}
+	if panes.Freeze {
+		s.SetFrozenSplit(panes.YSplit, panes.XSplit)
+	}
+
+	s.fitSize()
+	return s, nil
+}
+
+func readCell(f *excelize.File, sheet, axis, raw, display, formula string) (*Cell, error) {
+	if formula != "" {
+		cell := &Cell{F: "=" + formula, Ct: &CellType{Fa: "General", T: "g"}}
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			cell.V = n
+			cell.Ct.T = "n"
+		} else if raw != "" {
+			cell.V = raw
+		}
+		if display != "" {
+			cell.M = display
+		}
+		return cell, nil
+	}
+	if raw == "" {
+		return nil, nil
+	}
+
+	typ, err := f.GetCellType(sheet, axis)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case excelize.CellTypeBool:
+		v := "FALSE"
+		if raw == "1" || strings.EqualFold(raw, "true") {
+			v = "TRUE"
+		}
+		return &Cell{V: v, M: v, Ct: &CellType{Fa: "General", T: "b"}}, nil
+	case excelize.CellTypeNumber, excelize.CellTypeUnset:
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			if display == "" {
+				display = raw
+			}
+			return &Cell{V: n, M: display, Ct: &CellType{Fa: "General", T: "n"}}, nil
+		}
+	}
+	if display == "" {
+		display = raw
+	}
+	return &Cell{V: display, M: display, Ct: &CellType{Fa: "General", T: "g"}}, nil
+}
+
+func addMerge(s *Sheet, m Merge, index map[[2]int]*Cell) {
+	if s.Config == nil {
+		s.Config = &SheetConfig{}
+	}
+	if s.Config.Merge == nil {
+		s.Config.Merge = map[string]Merge{}
+	}
+	s.Config.Merge[strconv.Itoa(m.R)+"_"+strconv.Itoa(m.C)] = m
+
+	for r := m.R; r < m.R+m.Rs; r++ {
+		for c := m.C; c < m.C+m.Cs; c++ {
+			cell := index[[2]int{r, c}]
+			if r == m.R && c == m.C {
+				cell.Mc = &Merge{R: m.R, C: m.C, Rs: m.Rs, Cs: m.Cs}
+			} else {
+				cell.Mc = &Merge{R: m.R, C: m.C}
+			}
+		}
+	}
+}
+
+// uniqueSheetName makes a FortuneSheet name acceptable to Excel: no
+// reserved characters, at most 31 characters and unique in the workbook.
+func uniqueSheetName(name string, index int, used map[string]bool) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '\\', '/', '?', '*', '[', ']':
+			return '_'
+		}
+		return r
+	}, strings.Trim(strings.TrimSpace(name), "'"))
+	if name == "" {
+		name = "Sheet" + strconv.Itoa(index+1)
+	}
+	name = truncateRunes(name, maxSheetNameLength)
+
+	candidate := name
+	for n := 2; used[strings.ToLower(candidate)]; n++ {
+		suffix := " (" + strconv.Itoa(n) + ")"
+		candidate = truncateRunes(name, maxSheetNameLength-len(suffix)) + suffix
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
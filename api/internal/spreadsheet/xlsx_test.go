@@ -0,0 +1,101 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestXLSXRoundTrip(t *testing.T) {
+	sheets, err := ParseSheets(`[
+		{"id":"s1","name":"Budget","order":0,
+		 "config":{"merge":{"0_0":{"r":0,"c":0,"rs":1,"cs":2}}},
+		 "frozen":{"type":"rangeBoth","range":{"row_focus":0,"column_focus":0}},
+		 "celldata":[
+			{"r":0,"c":0,"v":{"v":"Title","mc":{"r":0,"c":0,"rs":1,"cs":2}}},
+			{"r":0,"c":1,"v":{"mc":{"r":0,"c":0}}},
+			{"r":1,"c":0,"v":{"v":2}},
+			{"r":1,"c":1,"v":{"v":3.5}},
+			{"r":2,"c":0,"v":{"v":true}},
+			{"r":2,"c":1,"v":{"f":"=SUM(A2:B2)","v":5.5}}
+		 ]},
+		{"id":"s2","name":"Notes","order":1,"celldata":[{"r":3,"c":4,"v":{"v":"later"}}]}
+	]`)
+	if err != nil {
+		t.Fatalf("ParseSheets: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteXLSX(sheets, &buf); err != nil {
+		t.Fatalf("WriteXLSX: %v", err)
+	}
+	got, err := ReadXLSX(&buf)
+	if err != nil {
+		t.Fatalf("ReadXLSX: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "Budget" || got[1].Name != "Notes" || got[1].Order != 1 {
+		t.Fatalf("sheets = %+v", got)
+	}
+
+	budget := &got[0]
+	tests := []struct {
+		r, c    int
+		v       interface{}
+		formula string
+	}{
+		{0, 0, "Title", ""},
+		{1, 0, 2.0, ""},
+		{1, 1, 3.5, ""},
+		{2, 0, "TRUE", ""},
+		{2, 1, nil, "=SUM(A2:B2)"},
+	}
+	for _, tt := range tests {
+		cell := budget.Cell(tt.r, tt.c)
+		if cell.Value() != tt.v || cell.Formula() != tt.formula {
+			t.Errorf("cell %d,%d = %#v %q, want %#v %q", tt.r, tt.c, cell.Value(), cell.Formula(), tt.v, tt.formula)
+		}
+	}
+
+	merges := budget.Merges()
+	if len(merges) != 1 || merges[0] != (Merge{R: 0, C: 0, Rs: 1, Cs: 2}) {
+		t.Errorf("merges = %+v", merges)
+	}
+	if mc := budget.Cell(0, 1).Mc; mc == nil || mc.R != 0 || mc.C != 0 || mc.Rs != 0 {
+		t.Errorf("merged cell = %+v, want a pointer to 0,0", mc)
+	}
+	if rows, cols := budget.FrozenSplit(); rows != 1 || cols != 1 {
+		t.Errorf("frozen = %d, %d, want 1, 1", rows, cols)
+	}
+	if got[1].Cell(3, 4).Value() != "later" {
+		t.Errorf("second sheet lost its cell")
+	}
+}
+
+func TestReadXLSXInvalid(t *testing.T) {
+	if _, err := ReadXLSX(strings.NewReader("not a workbook")); err == nil {
+		t.Error("ReadXLSX accepted a file that is not a workbook")
+	}
+}
+
+func TestUniqueSheetName(t *testing.T) {
+	used := map[string]bool{}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Sales", "Sales"},
+		{"sales", "sales (2)"},
+		{"SALES", "SALES (3)"},
+		{"Q1/Q2: [draft]?", "Q1_Q2_ _draft__"},
+		{"  'quoted'  ", "quoted"},
+		{"", "Sheet6"},
+		{strings.Repeat("x", 40), strings.Repeat("x", 31)},
+		{strings.Repeat("x", 35), strings.Repeat("x", 27) + " (2)"},
+		{strings.Repeat("表", 40), strings.Repeat("表", 31)},
+	}
+	for i, tt := range tests {
+		if got := uniqueSheetName(tt.name, i, used); got != tt.want {
+			t.Errorf("uniqueSheetName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+// BlobSegments addresses the blob holding the content with a SHA-256 hash.
+// A blob is shared by every file with the same content, in any workspace.
+func BlobSegments(hash string) []string {
+	return []string{"blobs", hash[:2], hash}
+}
+
+// PutBlob stores content by its SHA-256 hash, and returns the hex encoded
+// hash. Content that is already stored is not written again. The blob is
+// returned locked, as by LockBlob: call unlock once the file that refers to
+// it is recorded, or has failed to be.
+func PutBlob(s Storage, r io.ReadSeeker) (hash string, unlock func(), err error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", nil, err
+	}
+	hash = hex.EncodeToString(h.Sum(nil))
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", nil, err
+	}
+	unlock = LockBlob(hash)
+	if _, err := SaveBlob(s, hash, r); err != nil {
+		unlock()
+		return "", nil, err
+	}
+	return hash, unlock, nil
+}
+
+// SaveBlob stores content with the SHA-256 hash unless it is already
+// stored, and reports whether it stored it. The caller holds the blob
+// locked.
+func SaveBlob(s Storage, hash string, r io.Reader) (bool, error) {
+	if _, err := s.Stat(BlobSegments(hash)); err == nil {
+		return false, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	if err := s.Save(BlobSegments(hash), r); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// blobLock is held while a blob is stored and the file that refers to it
+// is recorded, or while a blob is checked for files and deleted, so that a
+// blob is not deleted between being found stored and being referred to.
+type blobLock struct {
+	sync.Mutex
+	waiting int
+}
+
+var (
+	blobLocksMu sync.Mutex
+	blobLocks   = map[string]*blobLock{}
+)
+
+// LockBlob locks the blob with the hash, and returns the function that
+// unlocks it.
+func LockBlob(hash string) (unlock func()) {
+	blobLocksMu.Lock()
+	l := blobLocks[hash]
+	if l == nil {
+		l = &blobLock{}
+		blobLocks[hash] = l
+	}
+	l.waiting++
+	blobLocksMu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		blobLocksMu.Lock()
+		if l.waiting--; l.waiting == 0 {
+			delete(blobLocks, hash)
+		}
+		blobLocksMu.Unlock()
+	}
+}
@@ -0,0 +1,30 @@
+package storage
+
+import "io"
+
+// MinPartSize is the size of the smallest part of a chunked file, other
+// than its last part. It is the smallest part S3 multipart uploads accept.
+const MinPartSize = 5 << 20
+
+// MaxParts is the largest number of parts of a chunked file.
+const MaxParts = 10000
+
+// ChunkedStorage is a Storage that writes a file in parts, for uploads that
+// arrive over several requests. Parts are numbered from 1, and all parts
+// but the last must hold at least MinPartSize bytes.
+type ChunkedStorage interface {
+	Storage
+	// CreateChunked starts writing a file in parts, and returns the id the
+	// parts are written under.
+	CreateChunked(segments []string) (string, error)
+	// SavePart stores part n of a chunked file, of size bytes, and returns
+	// the tag that completes it.
+	SavePart(segments []string, id string, n int, r io.Reader, size int64) (string, error)
+	// CompleteChunked joins the parts with the tags, in order, into the
+	// file.
+	CompleteChunked(segments []string, id string, tags []string) error
+	// AbortChunked discards the parts of a chunked file.
+	AbortChunked(segments []string, id string) error
+	// Move renames a stored file.
+	Move(from, to []string) error
+}
@@ -1,9 +1,26 @@
 package storage
 
-import "io"
+import (
+	"io"
+	"time"
+)
 
 type Storage interface {
 	Save(segments []string, reader io.Reader) error
 	Load(segments []string) (io.ReadCloser, error)
+	// LoadRange reads length bytes of a file from offset, or the rest of
+	// the file when length is negative.
+	LoadRange(segments []string, offset, length int64) (io.ReadCloser, error)
+	// Stat describes a file. Missing files are reported with an error
+	// matching fs.ErrNotExist.
+	Stat(segments []string) (FileInfo, error)
 	Delete(segments []string) error
 }
+
+// FileInfo describes a stored file. ETag is the entity tag of the content,
+// quoted as in HTTP headers.
+type FileInfo struct {
+	Size    int64
+	ModTime time.Time
+	ETag    string
+}
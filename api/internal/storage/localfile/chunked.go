@@ -0,0 +1,80 @@
+package localfile
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// partsDir is the directory the parts of a chunked file are kept in until
+// the file is complete.
+func (l *LocalFile) partsDir(segments []string, id string) string {
+	return l.root + strings.Join(segments, "/") + "." + id + ".parts"
+}
+
+func (l *LocalFile) CreateChunked(segments []string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+	if err := os.MkdirAll(l.partsDir(segments, id), os.ModePerm); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (l *LocalFile) SavePart(segments []string, id string, n int, r io.Reader, size int64) (string, error) {
+	f, err := os.Create(filepath.Join(l.partsDir(segments, id), fmt.Sprintf("%05d", n)))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.CopyN(f, r, size); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d", n), nil
+}
+
+func (l *LocalFile) CompleteChunked(segments []string, id string, tags []string) error {
+	dir := l.partsDir(segments, id)
+	uploadPath := l.root + strings.Join(segments, "/")
+
+	f, err := os.Create(uploadPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for n := 1; n <= len(tags); n++ {
+		if err := appendFile(f, filepath.Join(dir, fmt.Sprintf("%05d", n))); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(dir)
+}
+
+func appendFile(w io.Writer, name string) error {
+	part, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer part.Close()
+	_, err = io.Copy(w, part)
+	return err
+}
+
+func (l *LocalFile) AbortChunked(segments []string, id string) error {
+	return os.RemoveAll(l.partsDir(segments, id))
+}
+
+func (l *LocalFile) Move(from, to []string) error {
+	toPath := l.root + strings.Join(to, "/")
+	if err := os.MkdirAll(filepath.Dir(toPath), os.ModePerm); err != nil {
+		return err
+	}
+	return os.Rename(l.root+strings.Join(from, "/"), toPath)
+}
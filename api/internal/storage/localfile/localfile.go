@@ -2,6 +2,7 @@ package localfile
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -45,7 +46,43 @@ func (l *LocalFile) Load(segments []string) (io.ReadCloser, error) {
 	return f, nil
 }
 
+func (l *LocalFile) LoadRange(segments []string, offset, length int64) (io.ReadCloser, error) {
+	uploadPath := l.root + strings.Join(segments, "/")
+	f, err := os.Open(uploadPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if length < 0 {
+		return f, nil
+	}
+	return limitedFile{Reader: io.LimitReader(f, length), Closer: f}, nil
+}
+
+// Stat describes a file. Files are not changed once saved, so their size
+// and modification time identify their content.
+func (l *LocalFile) Stat(segments []string) (storage.FileInfo, error) {
+	uploadPath := l.root + strings.Join(segments, "/")
+	fi, err := os.Stat(uploadPath)
+	if err != nil {
+		return storage.FileInfo{}, err
+	}
+	return storage.FileInfo{
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		ETag:    fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size()),
+	}, nil
+}
+
 func (l *LocalFile) Delete(segments []string) error {
 	uploadPath := l.root + strings.Join(segments, "/")
 	return os.Remove(uploadPath)
 }
+
+type limitedFile struct {
+	io.Reader
+	io.Closer
+}
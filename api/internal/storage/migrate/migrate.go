@@ -0,0 +1,193 @@
+// Package migrate copies the stored content of files from one storage to
+// another, so that a deployment can change its storage backend. Every copy
+// is verified by its SHA-256 checksum. Objects already in the destination
+// are skipped, so a migration can be run again after an interruption.
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"strings"
+
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/storage"
+)
+
+// Migration copies the files recorded in DB, and their variants, from one
+// storage to another. A dry run only reports what would be copied. With
+// Verify, objects already in the destination are checked by checksum,
+// rather than by size only.
+type Migration struct {
+	DB     db.DB
+	From   storage.Storage
+	To     storage.Storage
+	DryRun bool
+	Verify bool
+}
+
+// Problem is an object that could not be copied as recorded.
+type Problem struct {
+	File   string
+	Object string
+	Reason string
+}
+
+// Report counts the objects of a migration, and lists the objects that are
+// missing from the source storage or whose content does not match.
+type Report struct {
+	Copied     int
+	Bytes      int64
+	Present    int
+	Missing    []Problem
+	Mismatched []Problem
+}
+
+// object is a stored object, with the SHA-256 hash of its content when it
+// is known from its name.
+type object struct {
+	file     string
+	segments []string
+	hash     string
+}
+
+// Run copies the objects of every file. It stops at the first error other
+// than a missing or mismatched object.
+func (m Migration) Run() (Report, error) {
+	var r Report
+
+	files, err := m.DB.FindFiles(model.FileFilter{})
+	if err != nil {
+		return r, err
+	}
+
+	// Blobs are shared by files with the same content, and copied once.
+	seen := map[string]bool{}
+	for _, f := range files {
+		objects := []object{{file: f.WorkspaceID + "/" + f.Name, segments: []string{f.WorkspaceID, f.Name}}}
+		if f.Hash != "" {
+			objects[0].segments, objects[0].hash = storage.BlobSegments(f.Hash), f.Hash
+		}
+
+		variants, err := m.DB.FindFileVariants(f.WorkspaceID, f.Name)
+		if err != nil {
+			return r, err
+		}
+		for _, v := range variants {
+			objects = append(objects, object{file: objects[0].file, segments: []string{f.WorkspaceID, "variants", v.Name}})
+		}
+
+		for _, o := range objects {
+			key := strings.Join(o.segments, "/")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if err := m.copy(&r, o); err != nil {
+				return r, err
+			}
+		}
+	}
+
+	return r, nil
+}
+
+func (m Migration) copy(r *Report, o object) error {
+	key := strings.Join(o.segments, "/")
+
+	// Stat reports missing files the same way for every storage.
+	src, err := m.From.Stat(o.segments)
+	if errors.Is(err, fs.ErrNotExist) {
+		r.Missing = append(r.Missing, Problem{File: o.file, Object: key, Reason: "missing from the source storage"})
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// A copy is complete when it has the size of the source, as an
+	// interrupted copy is shorter or not stored at all.
+	if dst, err := m.To.Stat(o.segments); err == nil && dst.Size == src.Size {
+		if !m.Verify || m.DryRun {
+			r.Present++
+			return nil
+		}
+		expected := o.hash
+		if expected == "" {
+			if expected, err = checksum(m.From, o.segments); err != nil {
+				return err
+			}
+		}
+		sum, err := checksum(m.To, o.segments)
+		if err != nil {
+			return err
+		}
+		if sum == expected {
+			r.Present++
+			return nil
+		}
+		log.Printf("Copying %s again: checksum does not match", key)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if m.DryRun {
+		r.Copied++
+		r.Bytes += src.Size
+		return nil
+	}
+
+	sum, err := m.save(o.segments)
+	if err != nil {
+		return err
+	}
+	if o.hash != "" && sum != o.hash {
+		r.Mismatched = append(r.Mismatched, Problem{File: o.file, Object: key, Reason: "source content does not match its hash"})
+	}
+
+	copied, err := checksum(m.To, o.segments)
+	if err != nil {
+		return err
+	}
+	if copied != sum {
+		r.Mismatched = append(r.Mismatched, Problem{File: o.file, Object: key, Reason: "copy does not match the source"})
+		return nil
+	}
+
+	r.Copied++
+	r.Bytes += src.Size
+	return nil
+}
+
+// save streams an object from the source to the destination, and returns
+// the checksum of the content read.
+func (m Migration) save(segments []string) (string, error) {
+	rc, err := m.From.Load(segments)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if err := m.To.Save(segments, io.TeeReader(rc, h)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func checksum(s storage.Storage, segments []string) (string, error) {
+	rc, err := s.Load(segments)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
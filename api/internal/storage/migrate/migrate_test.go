@@ -0,0 +1,191 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/db/dbtest"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/storage"
+	"github.com/collabreef/collabreef/internal/storage/localfile"
+)
+
+func hashOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func save(t *testing.T, s storage.Storage, segments []string, content string) {
+	t.Helper()
+	if err := s.Save(segments, strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func load(t *testing.T, s storage.Storage, segments []string) (string, bool) {
+	t.Helper()
+	rc, err := s.Load(segments)
+	if err != nil {
+		return "", false
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b), true
+}
+
+// newMigration records files in a database and stores their content in
+// the source storage:
+//   - a.txt and b.txt share the blob of "alpha"
+//   - old.png is stored by workspace and name, with a thumbnail variant
+//   - gone.txt has no stored content
+//   - bad.txt is stored under the hash of other content
+func newMigration(t *testing.T) Migration {
+	t.Helper()
+	m := Migration{
+		DB:   dbtest.New(t),
+		From: localfile.NewLocalFileStorage(t.TempDir()),
+		To:   localfile.NewLocalFileStorage(t.TempDir()),
+	}
+	for _, f := range []model.File{
+		{ID: "1", Name: "a.txt", Hash: hashOf("alpha")},
+		{ID: "2", Name: "b.txt", Hash: hashOf("alpha")},
+		{ID: "3", Name: "old.png"},
+		{ID: "4", Name: "gone.txt", Hash: hashOf("gone")},
+		{ID: "5", Name: "bad.txt", Hash: hashOf("expected")},
+	} {
+		f.WorkspaceID = "w1"
+		if err := m.DB.CreateFile(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.DB.SaveFileVariant(model.FileVariant{WorkspaceID: "w1", FileName: "old.png", Variant: "w480_h0_contain", Name: "old_w480.png"}); err != nil {
+		t.Fatal(err)
+	}
+
+	save(t, m.From, storage.BlobSegments(hashOf("alpha")), "alpha")
+	save(t, m.From, []string{"w1", "old.png"}, "old image")
+	save(t, m.From, []string{"w1", "variants", "old_w480.png"}, "thumb")
+	save(t, m.From, storage.BlobSegments(hashOf("expected")), "corrupted")
+	return m
+}
+
+// objects are the keys of the objects of newMigration and their content.
+var objects = map[string]struct {
+	segments []string
+	content  string
+}{
+	"alpha":   {storage.BlobSegments(hashOf("alpha")), "alpha"},
+	"old.png": {[]string{"w1", "old.png"}, "old image"},
+	"variant": {[]string{"w1", "variants", "old_w480.png"}, "thumb"},
+	"bad.txt": {storage.BlobSegments(hashOf("expected")), "corrupted"},
+}
+
+func TestRun(t *testing.T) {
+	m := newMigration(t)
+	r, err := m.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Shared blobs are copied once; a source that does not match its hash
+	// is copied as it is and reported.
+	if r.Copied != 4 || r.Present != 0 || r.Bytes != 28 {
+		t.Errorf("report = %+v, want 4 objects of 28 bytes copied", r)
+	}
+	wantMissing := []Problem{{File: "w1/gone.txt", Object: strings.Join(storage.BlobSegments(hashOf("gone")), "/"), Reason: "missing from the source storage"}}
+	if !reflect.DeepEqual(r.Missing, wantMissing) {
+		t.Errorf("missing = %+v, want %+v", r.Missing, wantMissing)
+	}
+	wantMismatched := []Problem{{File: "w1/bad.txt", Object: strings.Join(storage.BlobSegments(hashOf("expected")), "/"), Reason: "source content does not match its hash"}}
+	if !reflect.DeepEqual(r.Mismatched, wantMismatched) {
+		t.Errorf("mismatched = %+v, want %+v", r.Mismatched, wantMismatched)
+	}
+	for name, o := range objects {
+		if got, ok := load(t, m.To, o.segments); !ok || got != o.content {
+			t.Errorf("%s copied as %q, %v, want %q", name, got, ok, o.content)
+		}
+	}
+
+	// Running again finds every object in place.
+	r, err = m.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Copied != 0 || r.Present != 4 || len(r.Missing) != 1 {
+		t.Errorf("second run = %+v, want 4 objects present", r)
+	}
+}
+
+func TestRunDry(t *testing.T) {
+	m := newMigration(t)
+	m.DryRun = true
+	r, err := m.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Copied != 4 || len(r.Missing) != 1 || len(r.Mismatched) != 0 {
+		t.Errorf("report = %+v, want 4 objects to copy and 1 missing", r)
+	}
+	for name, o := range objects {
+		if _, ok := load(t, m.To, o.segments); ok {
+			t.Errorf("dry run copied %s", name)
+		}
+	}
+}
+
+func TestRunResume(t *testing.T) {
+	tests := []struct {
+		name   string
+		verify bool
+		// dest is stored in the destination before the run, for the
+		// object of the variant.
+		dest        string
+		wantCopied  int
+		wantPresent int
+		wantContent string
+	}{
+		{name: "interrupted copy", dest: "th", wantCopied: 1, wantPresent: 3, wantContent: "thumb"},
+		{name: "complete copy", dest: "thumb", wantPresent: 4, wantContent: "thumb"},
+		// Without Verify, copies are checked by size only.
+		{name: "changed copy", dest: "THUMB", wantPresent: 4, wantContent: "THUMB"},
+		// Verified copies are checked against the hash of a blob, which
+		// bad.txt does not match, so it is copied again.
+		{name: "changed copy verified", verify: true, dest: "THUMB", wantCopied: 2, wantPresent: 2, wantContent: "thumb"},
+		{name: "complete copy verified", verify: true, dest: "thumb", wantCopied: 1, wantPresent: 3, wantContent: "thumb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMigration(t)
+			// The other objects were copied by an earlier run.
+			for name, o := range objects {
+				if name != "variant" {
+					save(t, m.To, o.segments, o.content)
+				}
+			}
+			save(t, m.To, objects["variant"].segments, tt.dest)
+
+			m.Verify = tt.verify
+			r, err := m.Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Copied != tt.wantCopied || r.Present != tt.wantPresent {
+				t.Errorf("report = %+v, want %d copied and %d present", r, tt.wantCopied, tt.wantPresent)
+			}
+			if len(r.Missing) != 1 || (len(r.Mismatched) == 1) != tt.verify {
+				t.Errorf("report = %+v, want gone.txt missing, and bad.txt mismatched when verified", r)
+			}
+			if got, _ := load(t, m.To, objects["variant"].segments); got != tt.wantContent {
+				t.Errorf("variant = %q, want %q", got, tt.wantContent)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package storage
+
+import "time"
+
+// MaxPresignedSize is the size of the largest file a presigned PUT request
+// stores. It is the largest object a single S3 PUT request accepts.
+const MaxPresignedSize = 5 << 30
+
+// Presigner is a Storage that signs URLs for requests that clients send to
+// it directly, so that file content does not pass through the API. A URL is
+// valid for the expiry duration.
+type Presigner interface {
+	Storage
+	// PresignPut returns a URL that stores the body of a PUT request as the
+	// file.
+	PresignPut(segments []string, expiry time.Duration) (string, error)
+	// PresignGet returns a URL that reads the file, served with the content
+	// type and content disposition.
+	PresignGet(segments []string, expiry time.Duration, contentType, disposition string) (string, error)
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+// ReadSeeker reads a stored file from any offset, loading the range that
+// is read next only when it is read. It lets files be served with
+// http.ServeContent without being read whole.
+type ReadSeeker struct {
+	storage  Storage
+	segments []string
+	size     int64
+	offset   int64
+	r        io.ReadCloser
+}
+
+// NewReadSeeker returns a ReadSeeker for a file of the given size.
+func NewReadSeeker(s Storage, segments []string, size int64) *ReadSeeker {
+	return &ReadSeeker{storage: s, segments: segments, size: size}
+}
+
+func (rs *ReadSeeker) Read(p []byte) (int, error) {
+	if rs.offset >= rs.size {
+		return 0, io.EOF
+	}
+	if rs.r == nil {
+		r, err := rs.storage.LoadRange(rs.segments, rs.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		rs.r = r
+	}
+	n, err := rs.r.Read(p)
+	rs.offset += int64(n)
+	return n, err
+}
+
+func (rs *ReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += rs.offset
+	case io.SeekEnd:
+		offset += rs.size
+	default:
+		return 0, errors.New("storage: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("storage: negative position")
+	}
+	if offset != rs.offset {
+		rs.closeReader()
+		rs.offset = offset
+	}
+	return offset, nil
+}
+
+// Close releases the range being read, if any.
+func (rs *ReadSeeker) Close() error {
+	return rs.closeReader()
+}
+
+func (rs *ReadSeeker) closeReader() error {
+	if rs.r == nil {
+		return nil
+	}
+	err := rs.r.Close()
+	rs.r = nil
+	return err
+}
@@ -0,0 +1,89 @@
+package s3storage
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// Chunked files are written with S3 multipart uploads.
+
+func (s *S3Storage) CreateChunked(segments []string) (string, error) {
+	core := minio.Core{Client: s.client}
+	return core.NewMultipartUpload(
+		context.Background(),
+		s.bucket,
+		strings.Join(segments, "/"),
+		minio.PutObjectOptions{},
+	)
+}
+
+func (s *S3Storage) SavePart(segments []string, id string, n int, r io.Reader, size int64) (string, error) {
+	core := minio.Core{Client: s.client}
+	part, err := core.PutObjectPart(
+		context.Background(),
+		s.bucket,
+		strings.Join(segments, "/"),
+		id,
+		n,
+		r,
+		size,
+		minio.PutObjectPartOptions{},
+	)
+	if err != nil {
+		return "", err
+	}
+	return part.ETag, nil
+}
+
+func (s *S3Storage) CompleteChunked(segments []string, id string, tags []string) error {
+	parts := make([]minio.CompletePart, len(tags))
+	for i, tag := range tags {
+		parts[i] = minio.CompletePart{PartNumber: i + 1, ETag: tag}
+	}
+
+	core := minio.Core{Client: s.client}
+	_, err := core.CompleteMultipartUpload(
+		context.Background(),
+		s.bucket,
+		strings.Join(segments, "/"),
+		id,
+		parts,
+		minio.PutObjectOptions{},
+	)
+	return err
+}
+
+func (s *S3Storage) AbortChunked(segments []string, id string) error {
+	core := minio.Core{Client: s.client}
+	return core.AbortMultipartUpload(
+		context.Background(),
+		s.bucket,
+		strings.Join(segments, "/"),
+		id,
+	)
+}
+
+// Move copies an object on the server, in parts for large objects, and
+// removes the original.
+func (s *S3Storage) Move(from, to []string) error {
+	src := strings.Join(from, "/")
+
+	_, err := s.client.ComposeObject(
+		context.Background(),
+		minio.CopyDestOptions{Bucket: s.bucket, Object: strings.Join(to, "/")},
+		minio.CopySrcOptions{Bucket: s.bucket, Object: src},
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.client.RemoveObject(
+		context.Background(),
+		s.bucket,
+		src,
+		minio.RemoveObjectOptions{},
+	)
+}
@@ -0,0 +1,44 @@
+package s3storage
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func (s *S3Storage) PresignPut(segments []string, expiry time.Duration) (string, error) {
+	u, err := s.presignClient.PresignedPutObject(
+		context.Background(),
+		s.bucket,
+		strings.Join(segments, "/"),
+		expiry,
+	)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// PresignGet sets the headers of the response with the response-*
+// parameters of the URL, which are signed with it.
+func (s *S3Storage) PresignGet(segments []string, expiry time.Duration, contentType, disposition string) (string, error) {
+	params := url.Values{}
+	params.Set("response-content-type", contentType)
+	if disposition != "" {
+		params.Set("response-content-disposition", disposition)
+	}
+	params.Set("response-cache-control", "private, no-cache")
+
+	u, err := s.presignClient.PresignedGetObject(
+		context.Background(),
+		s.bucket,
+		strings.Join(segments, "/"),
+		expiry,
+		params,
+	)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
@@ -2,7 +2,10 @@ package s3storage
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"io/fs"
+	"net/url"
 	"strings"
 
 	"github.com/minio/minio-go/v7"
@@ -12,7 +15,9 @@ import (
 
 type S3Storage struct {
 	client *minio.Client
-	bucket string
+	// presignClient signs the URLs clients send to the storage directly.
+	presignClient *minio.Client
+	bucket        string
 }
 
 type S3Config struct {
@@ -21,20 +26,48 @@ type S3Config struct {
 	SecretAccessKey string
 	Bucket          string
 	UseSSL          bool
+	Region          string
+	// PublicURL is the URL clients reach the storage at, when it is not
+	// the endpoint the API uses.
+	PublicURL string
 }
 
 func NewS3Storage(cfg S3Config) (storage.Storage, error) {
 	minioClient, err := minio.New(cfg.Endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
 		Secure: cfg.UseSSL,
+		Region: cfg.Region,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	presignClient := minioClient
+	if cfg.PublicURL != "" {
+		endpoint, secure := cfg.PublicURL, cfg.UseSSL
+		if u, err := url.Parse(cfg.PublicURL); err == nil && u.Host != "" {
+			endpoint, secure = u.Host, u.Scheme == "https"
+		}
+		// The region is set so that URLs are signed without asking the
+		// public endpoint, which the API may not reach, for it.
+		region := cfg.Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		presignClient, err = minio.New(endpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+			Secure: secure,
+			Region: region,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &S3Storage{
-		client: minioClient,
-		bucket: cfg.Bucket,
+		client:        minioClient,
+		presignClient: presignClient,
+		bucket:        cfg.Bucket,
 	}, nil
 }
 
@@ -70,6 +103,61 @@ func (s *S3Storage) Load(segments []string) (io.ReadCloser, error) {
 	return object, nil
 }
 
+func (s *S3Storage) LoadRange(segments []string, offset, length int64) (io.ReadCloser, error) {
+	key := strings.Join(segments, "/")
+
+	// SetRange(0, 0) asks for the first byte, so reads of the whole object
+	// set no range.
+	opts := minio.GetObjectOptions{}
+	switch {
+	case length == 0:
+		return io.NopCloser(strings.NewReader("")), nil
+	case length > 0:
+		if err := opts.SetRange(offset, offset+length-1); err != nil {
+			return nil, err
+		}
+	case offset > 0:
+		if err := opts.SetRange(offset, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	object, err := s.client.GetObject(
+		context.Background(),
+		s.bucket,
+		key,
+		opts,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
+
+func (s *S3Storage) Stat(segments []string) (storage.FileInfo, error) {
+	key := strings.Join(segments, "/")
+
+	info, err := s.client.StatObject(
+		context.Background(),
+		s.bucket,
+		key,
+		minio.StatObjectOptions{},
+	)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return storage.FileInfo{}, fmt.Errorf("%s: %w", key, fs.ErrNotExist)
+		}
+		return storage.FileInfo{}, err
+	}
+
+	return storage.FileInfo{
+		Size:    info.Size,
+		ModTime: info.LastModified,
+		ETag:    `"` + info.ETag + `"`,
+	}, nil
+}
+
 func (s *S3Storage) Delete(segments []string) error {
 	key := strings.Join(segments, "/")
 
@@ -0,0 +1,84 @@
+// Package table implements table views: notes as rows, with typed property
+// columns whose values are stored per note.
+package table
+
+import (
+	"encoding/json"
+
+	"github.com/collabreef/collabreef/internal/model"
+)
+
+// Kind is the type of a property.
+type Kind string
+
+const (
+	KindText        Kind = "text"
+	KindNumber      Kind = "number"
+	KindSelect      Kind = "select"
+	KindMultiSelect Kind = "multi_select"
+	KindDate        Kind = "date"
+	KindPerson      Kind = "person"
+	KindCheckbox    Kind = "checkbox"
+	KindRelation    Kind = "relation"
+)
+
+// ObjectType is the view object type that defines a property.
+const ObjectType = "table_property"
+
+type Option struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+// Property is a column of a table view.
+type Property struct {
+	ID      string
+	Name    string
+	Kind    Kind
+	Options []Option
+	// ViewID restricts relation values to the rows of another table.
+	ViewID string
+}
+
+// LoadProperties returns the properties defined by the view objects of a
+// table, in order. Other objects and unreadable data are skipped.
+func LoadProperties(objects []model.ViewObject) []Property {
+	var props []Property
+	for _, o := range objects {
+		if o.Type != ObjectType {
+			continue
+		}
+		var data struct {
+			Kind    Kind     `json:"kind"`
+			Options []Option `json:"options"`
+			ViewID  string   `json:"view_id"`
+		}
+		if err := json.Unmarshal([]byte(o.Data), &data); err != nil {
+			continue
+		}
+		props = append(props, Property{
+			ID:      o.ID,
+			Name:    o.Name,
+			Kind:    data.Kind,
+			Options: data.Options,
+			ViewID:  data.ViewID,
+		})
+	}
+	return props
+}
+
+// option returns the position of an option, or -1.
+func (p Property) option(id string) int {
+	for i, o := range p.Options {
+		if o.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// multi reports whether values of the property are lists of ids.
+func (p Property) multi() bool {
+	return p.Kind == KindMultiSelect || p.Kind == KindPerson || p.Kind == KindRelation
+}
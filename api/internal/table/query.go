@@ -0,0 +1,410 @@
+package table
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/collabreef/collabreef/internal/model"
+)
+
+// builtins are note fields that can be filtered, sorted and grouped like
+// text properties.
+var builtins = map[string]func(model.Note) string{
+	"title":      func(n model.Note) string { return n.Title },
+	"created_at": func(n model.Note) string { return n.CreatedAt },
+	"created_by": func(n model.Note) string { return n.CreatedBy },
+	"updated_at": func(n model.Note) string { return n.UpdatedAt },
+	"updated_by": func(n model.Note) string { return n.UpdatedBy },
+}
+
+// Row is a note of a table with its property values in normal form, keyed
+// by property id.
+type Row struct {
+	Note   model.Note
+	Values map[string]interface{}
+}
+
+func (r Row) value(p Property) interface{} {
+	if get, ok := builtins[p.ID]; ok {
+		if s := get(r.Note); s != "" {
+			return s
+		}
+		return nil
+	}
+	return r.Values[p.ID]
+}
+
+type Filter struct {
+	Property string      `json:"property"`
+	Operator string      `json:"operator"`
+	Value    interface{} `json:"value"`
+}
+
+type Sort struct {
+	Property  string `json:"property"`
+	Direction string `json:"direction"` // asc (default) or desc
+}
+
+// Query selects, orders and groups the rows of a table. Properties are
+// referenced by id or by built-in field name.
+type Query struct {
+	Filters []Filter `json:"filters"`
+	Match   string   `json:"match"` // all (default) or any of the filters
+	Sorts   []Sort   `json:"sorts"`
+	GroupBy string   `json:"group_by"`
+}
+
+// Group holds the rows sharing a value of the group-by property. Rows
+// without a value are in the group with an empty key. Rows with several
+// values (multi-select, person, relation) appear in one group per value.
+type Group struct {
+	Key   string
+	Value interface{}
+	Rows  []Row
+}
+
+type Result struct {
+	Rows   []Row
+	Groups []Group
+}
+
+// Run applies a query to the rows of a table. Rows keep their order where
+// the sorts do not decide it.
+func Run(props []Property, rows []Row, q Query) (Result, error) {
+	byID := make(map[string]Property, len(props)+len(builtins))
+	for name := range builtins {
+		byID[name] = Property{ID: name, Name: name, Kind: KindText}
+	}
+	for _, p := range props {
+		byID[p.ID] = p
+	}
+	lookup := func(id string) (Property, error) {
+		p, ok := byID[id]
+		if !ok {
+			return Property{}, fmt.Errorf("unknown property %q", id)
+		}
+		return p, nil
+	}
+
+	var matchers []func(Row) bool
+	for _, f := range q.Filters {
+		p, err := lookup(f.Property)
+		if err != nil {
+			return Result{}, err
+		}
+		m, err := compileFilter(p, f)
+		if err != nil {
+			return Result{}, err
+		}
+		matchers = append(matchers, m)
+	}
+	matchAny := false
+	switch q.Match {
+	case "", "all":
+	case "any":
+		matchAny = true
+	default:
+		return Result{}, fmt.Errorf("match must be all or any")
+	}
+
+	var out []Row
+	for _, r := range rows {
+		if matches(r, matchers, matchAny) {
+			out = append(out, r)
+		}
+	}
+
+	type sortKey struct {
+		p    Property
+		desc bool
+	}
+	var keys []sortKey
+	for _, s := range q.Sorts {
+		p, err := lookup(s.Property)
+		if err != nil {
+			return Result{}, err
+		}
+		switch s.Direction {
+		case "", "asc", "desc":
+		default:
+			return Result{}, fmt.Errorf("sort direction must be asc or desc")
+		}
+		keys = append(keys, sortKey{p: p, desc: s.Direction == "desc"})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		for _, k := range keys {
+			a, b := out[i].value(k.p), out[j].value(k.p)
+			// Empty values sort last in both directions.
+			if a == nil || b == nil {
+				if (a == nil) != (b == nil) {
+					return b == nil
+				}
+				continue
+			}
+			c := compare(k.p, a, b)
+			if c == 0 {
+				continue
+			}
+			if k.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+
+	res := Result{Rows: out}
+	if q.GroupBy != "" {
+		p, err := lookup(q.GroupBy)
+		if err != nil {
+			return Result{}, err
+		}
+		res.Groups = group(p, out)
+	}
+	return res, nil
+}
+
+func matches(r Row, matchers []func(Row) bool, matchAny bool) bool {
+	if len(matchers) == 0 {
+		return true
+	}
+	for _, m := range matchers {
+		if m(r) == matchAny {
+			return matchAny
+		}
+	}
+	return !matchAny
+}
+
+// compileFilter returns the predicate of a filter, or an error if the
+// operator or value does not fit the kind of the property.
+func compileFilter(p Property, f Filter) (func(Row) bool, error) {
+	invalid := func() (func(Row) bool, error) {
+		return nil, fmt.Errorf("operator %q with value %v is not supported for %s property %q", f.Operator, f.Value, p.Kind, p.Name)
+	}
+
+	switch f.Operator {
+	case "is_empty":
+		return func(r Row) bool { return isEmpty(r.value(p)) }, nil
+	case "is_not_empty":
+		return func(r Row) bool { return !isEmpty(r.value(p)) }, nil
+	}
+
+	switch p.Kind {
+	case KindNumber:
+		want, ok := f.Value.(float64)
+		if !ok {
+			return invalid()
+		}
+		cmp, ok := orderOps[f.Operator]
+		if !ok {
+			return invalid()
+		}
+		return func(r Row) bool {
+			v, ok := r.value(p).(float64)
+			if !ok {
+				return f.Operator == "neq"
+			}
+			return cmp(compareFloat(v, want))
+		}, nil
+
+	case KindCheckbox:
+		want, ok := f.Value.(bool)
+		if !ok || (f.Operator != "eq" && f.Operator != "neq") {
+			return invalid()
+		}
+		return func(r Row) bool {
+			v, _ := r.value(p).(bool)
+			return (v == want) == (f.Operator == "eq")
+		}, nil
+
+	case KindMultiSelect, KindPerson, KindRelation:
+		want, ok := f.Value.(string)
+		if !ok || (f.Operator != "contains" && f.Operator != "not_contains") {
+			return invalid()
+		}
+		return func(r Row) bool {
+			ids, _ := r.value(p).([]string)
+			for _, id := range ids {
+				if id == want {
+					return f.Operator == "contains"
+				}
+			}
+			return f.Operator == "not_contains"
+		}, nil
+	}
+
+	// Text, select, date and built-in fields hold strings.
+	want, ok := f.Value.(string)
+	if !ok {
+		return invalid()
+	}
+	if cmp, ok := orderOps[f.Operator]; ok {
+		if p.Kind == KindSelect && f.Operator != "eq" && f.Operator != "neq" {
+			return invalid()
+		}
+		return func(r Row) bool {
+			v, ok := r.value(p).(string)
+			if !ok {
+				return f.Operator == "neq"
+			}
+			return cmp(compareStrings(p, v, want))
+		}, nil
+	}
+	if p.Kind != KindText {
+		return invalid()
+	}
+	lower := strings.ToLower(want)
+	var test func(string) bool
+	switch f.Operator {
+	case "contains":
+		test = func(v string) bool { return strings.Contains(v, lower) }
+	case "not_contains":
+		test = func(v string) bool { return !strings.Contains(v, lower) }
+	case "starts_with":
+		test = func(v string) bool { return strings.HasPrefix(v, lower) }
+	case "ends_with":
+		test = func(v string) bool { return strings.HasSuffix(v, lower) }
+	default:
+		return invalid()
+	}
+	return func(r Row) bool {
+		v, _ := r.value(p).(string)
+		return test(strings.ToLower(v))
+	}, nil
+}
+
+// orderOps maps comparison operators to a test of a compare result.
+var orderOps = map[string]func(int) bool{
+	"eq":  func(c int) bool { return c == 0 },
+	"neq": func(c int) bool { return c != 0 },
+	"gt":  func(c int) bool { return c > 0 },
+	"gte": func(c int) bool { return c >= 0 },
+	"lt":  func(c int) bool { return c < 0 },
+	"lte": func(c int) bool { return c <= 0 },
+}
+
+func isEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	}
+	return false
+}
+
+// compare orders two non-empty values of a property.
+func compare(p Property, a, b interface{}) int {
+	switch p.Kind {
+	case KindNumber:
+		return compareFloat(a.(float64), b.(float64))
+	case KindCheckbox:
+		x, y := a.(bool), b.(bool)
+		if x == y {
+			return 0
+		}
+		if y {
+			return -1
+		}
+		return 1
+	case KindMultiSelect, KindPerson, KindRelation:
+		x, y := a.([]string), b.([]string)
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := compareStrings(p, x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return len(x) - len(y)
+	}
+	return compareStrings(p, a.(string), b.(string))
+}
+
+// compareStrings orders option ids by option position, text case-insensitively
+// and everything else, such as dates, as is.
+func compareStrings(p Property, a, b string) int {
+	switch p.Kind {
+	case KindSelect, KindMultiSelect:
+		return p.option(a) - p.option(b)
+	case KindText:
+		// Timestamps and user ids compare exactly; titles and text do not.
+		if _, builtin := builtins[p.ID]; p.ID == "title" || !builtin {
+			return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// group splits sorted rows by the values of a property. Select options and
+// checkbox states get a group even when empty, in their defined order; other
+// values appear in the order they are first met.
+func group(p Property, rows []Row) []Group {
+	var groups []Group
+	index := map[string]int{}
+	add := func(key string, value interface{}) {
+		if _, ok := index[key]; !ok {
+			index[key] = len(groups)
+			groups = append(groups, Group{Key: key, Value: value})
+		}
+	}
+
+	switch p.Kind {
+	case KindSelect, KindMultiSelect:
+		for _, o := range p.Options {
+			add(o.ID, o.ID)
+		}
+	case KindCheckbox:
+		add("true", true)
+		add("false", false)
+	}
+
+	var empty []Row
+	for _, r := range rows {
+		v := r.value(p)
+		switch v := v.(type) {
+		case nil:
+			if p.Kind == KindCheckbox {
+				groups[index["false"]].Rows = append(groups[index["false"]].Rows, r)
+			} else {
+				empty = append(empty, r)
+			}
+		case []string:
+			for _, id := range v {
+				add(id, id)
+				groups[index[id]].Rows = append(groups[index[id]].Rows, r)
+			}
+		default:
+			key := groupKey(v)
+			add(key, v)
+			groups[index[key]].Rows = append(groups[index[key]].Rows, r)
+		}
+	}
+	if len(empty) > 0 {
+		groups = append(groups, Group{Key: "", Rows: empty})
+	}
+	return groups
+}
+
+func groupKey(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,150 @@
+package table
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/model"
+)
+
+var (
+	points = Property{ID: "points", Name: "Points", Kind: KindNumber}
+	done   = Property{ID: "done", Name: "Done", Kind: KindCheckbox}
+	due    = Property{ID: "due", Name: "Due", Kind: KindDate}
+	notes  = Property{ID: "notes", Name: "Notes", Kind: KindText}
+	props  = []Property{status, tags, points, done, due, notes}
+)
+
+// rows returns the rows of a small table, in the order they were added.
+func rows() []Row {
+	row := func(id, title, createdBy string, values map[string]interface{}) Row {
+		return Row{Note: model.Note{ID: id, Title: title, CreatedBy: createdBy}, Values: values}
+	}
+	return []Row{
+		row("n1", "Write spec", "alice", map[string]interface{}{"status": "done", "tags": []string{"red"}, "points": 3.0, "done": true, "due": "2024-01-10", "notes": "Draft in Docs"}),
+		row("n2", "build API", "bob", map[string]interface{}{"status": "doing", "tags": []string{"red", "blue"}, "points": 8.0, "due": "2024-01-20"}),
+		row("n3", "Test", "alice", map[string]interface{}{"status": "todo", "points": 5.0, "notes": "needs docs"}),
+		row("n4", "Deploy", "carol", map[string]interface{}{"tags": []string{"blue"}, "done": false}),
+	}
+}
+
+func ids(rs []Row) string {
+	var out []string
+	for _, r := range rs {
+		out = append(out, r.Note.ID)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestRunFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		q       Query
+		want    string
+		wantErr bool
+	}{
+		{name: "no filters", want: "n1,n2,n3,n4"},
+		{name: "number greater", q: Query{Filters: []Filter{{Property: "points", Operator: "gt", Value: 4.0}}}, want: "n2,n3"},
+		{name: "number not equal includes empty", q: Query{Filters: []Filter{{Property: "points", Operator: "neq", Value: 3.0}}}, want: "n2,n3,n4"},
+		{name: "number lte", q: Query{Filters: []Filter{{Property: "points", Operator: "lte", Value: 5.0}}}, want: "n1,n3"},
+		{name: "checkbox unchecked includes empty", q: Query{Filters: []Filter{{Property: "done", Operator: "eq", Value: false}}}, want: "n2,n3,n4"},
+		{name: "checkbox is empty", q: Query{Filters: []Filter{{Property: "done", Operator: "is_empty"}}}, want: "n2,n3,n4"},
+		{name: "select equals", q: Query{Filters: []Filter{{Property: "status", Operator: "eq", Value: "doing"}}}, want: "n2"},
+		{name: "select is empty", q: Query{Filters: []Filter{{Property: "status", Operator: "is_empty"}}}, want: "n4"},
+		{name: "multi-select contains", q: Query{Filters: []Filter{{Property: "tags", Operator: "contains", Value: "blue"}}}, want: "n2,n4"},
+		{name: "multi-select not contains", q: Query{Filters: []Filter{{Property: "tags", Operator: "not_contains", Value: "red"}}}, want: "n3,n4"},
+		{name: "date before", q: Query{Filters: []Filter{{Property: "due", Operator: "lt", Value: "2024-01-15"}}}, want: "n1"},
+		{name: "text contains ignores case", q: Query{Filters: []Filter{{Property: "notes", Operator: "contains", Value: "DOCS"}}}, want: "n1,n3"},
+		{name: "text starts with", q: Query{Filters: []Filter{{Property: "notes", Operator: "starts_with", Value: "draft"}}}, want: "n1"},
+		{name: "title ends with", q: Query{Filters: []Filter{{Property: "title", Operator: "ends_with", Value: "api"}}}, want: "n2"},
+		{name: "built-in field equals", q: Query{Filters: []Filter{{Property: "created_by", Operator: "eq", Value: "alice"}}}, want: "n1,n3"},
+		{
+			name: "all filters",
+			q:    Query{Filters: []Filter{{Property: "created_by", Operator: "eq", Value: "alice"}, {Property: "points", Operator: "gt", Value: 4.0}}},
+			want: "n3",
+		},
+		{
+			name: "any filter",
+			q:    Query{Match: "any", Filters: []Filter{{Property: "status", Operator: "eq", Value: "done"}, {Property: "tags", Operator: "contains", Value: "blue"}}},
+			want: "n1,n2,n4",
+		},
+		{name: "unknown property", q: Query{Filters: []Filter{{Property: "owner", Operator: "eq", Value: "x"}}}, wantErr: true},
+		{name: "number with text", q: Query{Filters: []Filter{{Property: "points", Operator: "eq", Value: "3"}}}, wantErr: true},
+		{name: "ordering options", q: Query{Filters: []Filter{{Property: "status", Operator: "gt", Value: "todo"}}}, wantErr: true},
+		{name: "contains on a date", q: Query{Filters: []Filter{{Property: "due", Operator: "contains", Value: "2024"}}}, wantErr: true},
+		{name: "checkbox ordering", q: Query{Filters: []Filter{{Property: "done", Operator: "gt", Value: true}}}, wantErr: true},
+		{name: "unknown match", q: Query{Match: "some"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		res, err := Run(props, rows(), tt.q)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Run error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got := ids(res.Rows); !tt.wantErr && got != tt.want {
+			t.Errorf("%s: rows = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRunSorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		sorts   []Sort
+		want    string
+		wantErr bool
+	}{
+		{name: "number ascending, empty last", sorts: []Sort{{Property: "points"}}, want: "n1,n3,n2,n4"},
+		{name: "number descending, empty last", sorts: []Sort{{Property: "points", Direction: "desc"}}, want: "n2,n3,n1,n4"},
+		{name: "select by option order", sorts: []Sort{{Property: "status"}}, want: "n3,n2,n1,n4"},
+		{name: "title ignores case", sorts: []Sort{{Property: "title"}}, want: "n2,n4,n3,n1"},
+		{name: "checkbox unchecked first", sorts: []Sort{{Property: "done"}}, want: "n4,n1,n2,n3"},
+		{name: "multi-select by options then length", sorts: []Sort{{Property: "tags"}}, want: "n1,n2,n4,n3"},
+		{name: "ties keep their order", sorts: []Sort{{Property: "created_by"}}, want: "n1,n3,n2,n4"},
+		{name: "second sort breaks ties", sorts: []Sort{{Property: "created_by", Direction: "desc"}, {Property: "points", Direction: "desc"}}, want: "n4,n2,n3,n1"},
+		{name: "bad direction", sorts: []Sort{{Property: "points", Direction: "up"}}, wantErr: true},
+		{name: "unknown property", sorts: []Sort{{Property: "size"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		res, err := Run(props, rows(), Query{Sorts: tt.sorts})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Run error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got := ids(res.Rows); !tt.wantErr && got != tt.want {
+			t.Errorf("%s: rows = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRunGroups(t *testing.T) {
+	tests := []struct {
+		groupBy string
+		// want lists the groups as key=row ids.
+		want []string
+	}{
+		{groupBy: "status", want: []string{"todo=n3", "doing=n2", "done=n1", "=n4"}},
+		{groupBy: "tags", want: []string{"red=n1,n2", "blue=n2,n4", "=n3"}},
+		{groupBy: "done", want: []string{"true=n1", "false=n2,n3,n4"}},
+		{groupBy: "points", want: []string{"3=n1", "8=n2", "5=n3", "=n4"}},
+		{groupBy: "created_by", want: []string{"alice=n1,n3", "bob=n2", "carol=n4"}},
+	}
+	for _, tt := range tests {
+		res, err := Run(props, rows(), Query{GroupBy: tt.groupBy})
+		if err != nil {
+			t.Fatalf("group by %s: %v", tt.groupBy, err)
+		}
+		var got []string
+		for _, g := range res.Groups {
+			got = append(got, g.Key+"="+ids(g.Rows))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("group by %s = %v, want %v", tt.groupBy, got, tt.want)
+		}
+	}
+
+	if _, err := Run(props, rows(), Query{GroupBy: "size"}); err == nil {
+		t.Error("Run grouped by an unknown property")
+	}
+}
@@ -0,0 +1,134 @@
+package table
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// ParseValue checks a JSON value against the kind of the property and returns
+// it in normal form: a string for text, select and date; a float64 for
+// number; a bool for checkbox; and a []string of ids for multi_select,
+// person and relation. Null and empty values return nil, which clears the
+// value.
+func (p Property) ParseValue(raw json.RawMessage) (interface{}, error) {
+	var v interface{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, errors.New("must be valid JSON")
+		}
+	}
+	if v == nil {
+		return nil, nil
+	}
+
+	switch p.Kind {
+	case KindText:
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("must be a string")
+		}
+		if s == "" {
+			return nil, nil
+		}
+		return s, nil
+
+	case KindNumber:
+		f, ok := v.(float64)
+		if !ok || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, errors.New("must be a finite number")
+		}
+		return f, nil
+
+	case KindSelect:
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("must be an option id")
+		}
+		if s == "" {
+			return nil, nil
+		}
+		if p.option(s) < 0 {
+			return nil, fmt.Errorf("unknown option %q", s)
+		}
+		return s, nil
+
+	case KindDate:
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("must be a date in YYYY-MM-DD format")
+		}
+		if s == "" {
+			return nil, nil
+		}
+		if _, err := time.Parse(dateLayout, s); err != nil {
+			return nil, errors.New("must be a date in YYYY-MM-DD format")
+		}
+		return s, nil
+
+	case KindCheckbox:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, errors.New("must be a boolean")
+		}
+		return b, nil
+
+	case KindMultiSelect, KindPerson, KindRelation:
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, errors.New("must be an array of ids")
+		}
+		ids := make([]string, 0, len(list))
+		seen := map[string]bool{}
+		for _, e := range list {
+			id, ok := e.(string)
+			if !ok || id == "" {
+				return nil, errors.New("must be an array of ids")
+			}
+			if p.Kind == KindMultiSelect && p.option(id) < 0 {
+				return nil, fmt.Errorf("unknown option %q", id)
+			}
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			return nil, nil
+		}
+		return ids, nil
+	}
+
+	return nil, fmt.Errorf("unknown property kind %q", p.Kind)
+}
+
+// DecodeValue reads a stored value. Values that no longer fit the property,
+// for example after its kind or options changed, read as empty.
+func (p Property) DecodeValue(stored string) interface{} {
+	if p.Kind == KindMultiSelect {
+		// Keep the options that still exist.
+		var ids []string
+		if json.Unmarshal([]byte(stored), &ids) != nil {
+			return nil
+		}
+		var kept []string
+		for _, id := range ids {
+			if p.option(id) >= 0 {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) == 0 {
+			return nil
+		}
+		return kept
+	}
+	v, err := p.ParseValue(json.RawMessage(stored))
+	if err != nil {
+		return nil
+	}
+	return v
+}
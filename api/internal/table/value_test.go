@@ -0,0 +1,93 @@
+package table
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/model"
+)
+
+var status = Property{ID: "status", Name: "Status", Kind: KindSelect, Options: []Option{{ID: "todo"}, {ID: "doing"}, {ID: "done"}}}
+var tags = Property{ID: "tags", Name: "Tags", Kind: KindMultiSelect, Options: []Option{{ID: "red"}, {ID: "blue"}}}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		p       Property
+		raw     string
+		want    interface{}
+		wantErr bool
+	}{
+		{p: Property{Kind: KindText}, raw: `"hello"`, want: "hello"},
+		{p: Property{Kind: KindText}, raw: `""`, want: nil},
+		{p: Property{Kind: KindText}, raw: `null`, want: nil},
+		{p: Property{Kind: KindText}, raw: ``, want: nil},
+		{p: Property{Kind: KindText}, raw: `3`, wantErr: true},
+		{p: Property{Kind: KindText}, raw: `{`, wantErr: true},
+		{p: Property{Kind: KindNumber}, raw: `-2.5`, want: -2.5},
+		{p: Property{Kind: KindNumber}, raw: `"2"`, wantErr: true},
+		{p: status, raw: `"doing"`, want: "doing"},
+		{p: status, raw: `""`, want: nil},
+		{p: status, raw: `"blocked"`, wantErr: true},
+		{p: Property{Kind: KindDate}, raw: `"2024-02-29"`, want: "2024-02-29"},
+		{p: Property{Kind: KindDate}, raw: `"2023-02-29"`, wantErr: true},
+		{p: Property{Kind: KindDate}, raw: `"29/02/2024"`, wantErr: true},
+		{p: Property{Kind: KindCheckbox}, raw: `false`, want: false},
+		{p: Property{Kind: KindCheckbox}, raw: `"true"`, wantErr: true},
+		{p: tags, raw: `["red","blue","red"]`, want: []string{"red", "blue"}},
+		{p: tags, raw: `[]`, want: nil},
+		{p: tags, raw: `["green"]`, wantErr: true},
+		{p: tags, raw: `"red"`, wantErr: true},
+		{p: Property{Kind: KindPerson}, raw: `["u1","u2"]`, want: []string{"u1", "u2"}},
+		{p: Property{Kind: KindRelation}, raw: `["n1",""]`, wantErr: true},
+		{p: Property{Kind: KindRelation}, raw: `[1]`, wantErr: true},
+		{p: Property{Kind: "rating"}, raw: `5`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.p.ParseValue(json.RawMessage(tt.raw))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s ParseValue(%s) error = %v, want error %v", tt.p.Kind, tt.raw, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s ParseValue(%s) = %#v, want %#v", tt.p.Kind, tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeValue(t *testing.T) {
+	tests := []struct {
+		p      Property
+		stored string
+		want   interface{}
+	}{
+		{p: Property{Kind: KindNumber}, stored: `4`, want: 4.0},
+		{p: Property{Kind: KindNumber}, stored: `"4"`, want: nil},
+		{p: status, stored: `"removed"`, want: nil},
+		{p: tags, stored: `["red","removed","blue"]`, want: []string{"red", "blue"}},
+		{p: tags, stored: `["removed"]`, want: nil},
+		{p: tags, stored: `"red"`, want: nil},
+		{p: Property{Kind: KindCheckbox}, stored: `true`, want: true},
+	}
+	for _, tt := range tests {
+		if got := tt.p.DecodeValue(tt.stored); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s DecodeValue(%s) = %#v, want %#v", tt.p.Kind, tt.stored, got, tt.want)
+		}
+	}
+}
+
+func TestLoadProperties(t *testing.T) {
+	objects := []model.ViewObject{
+		{ID: "p1", Name: "Status", Type: ObjectType, Data: `{"kind":"select","options":[{"id":"a","name":"A","color":"red"}]}`},
+		{ID: "x", Name: "Slot", Type: "calendar_slot", Data: `{"date":"2024-01-01"}`},
+		{ID: "p2", Name: "Broken", Type: ObjectType, Data: `{`},
+		{ID: "p3", Name: "Links", Type: ObjectType, Data: `{"kind":"relation","view_id":"v2"}`},
+	}
+	want := []Property{
+		{ID: "p1", Name: "Status", Kind: KindSelect, Options: []Option{{ID: "a", Name: "A", Color: "red"}}},
+		{ID: "p3", Name: "Links", Kind: KindRelation, ViewID: "v2"},
+	}
+	if got := LoadProperties(objects); !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadProperties = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,388 @@
+// Package timeline implements timeline (Gantt) views: tasks with a date
+// range, progress, a parent task and finish-to-start dependencies.
+package timeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/viewobject"
+)
+
+// ObjectType is the view object type of a task.
+const ObjectType = "timeline_task"
+
+const dateLayout = "2006-01-02"
+
+// Task is a timeline task. Start and End are inclusive days.
+type Task struct {
+	ID           string
+	Name         string
+	Start        time.Time
+	End          time.Time
+	ParentID     string
+	Dependencies []string
+
+	object model.ViewObject
+	data   map[string]interface{}
+}
+
+// Load reads the tasks among the objects of a timeline view. Objects of
+// other types and tasks with unreadable data are skipped.
+func Load(objects []model.ViewObject) []*Task {
+	var tasks []*Task
+	for _, o := range objects {
+		if o.Type != ObjectType {
+			continue
+		}
+		t, err := parse(o)
+		if err != nil {
+			continue
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+func parse(o model.ViewObject) (*Task, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(o.Data), &data); err != nil {
+		return nil, err
+	}
+	t := &Task{ID: o.ID, Name: o.Name, object: o, data: data}
+
+	start, _ := data["start"].(string)
+	end, _ := data["end"].(string)
+	var err error
+	if t.Start, err = time.Parse(dateLayout, start); err != nil {
+		return nil, err
+	}
+	if t.End, err = time.Parse(dateLayout, end); err != nil {
+		return nil, err
+	}
+	t.ParentID, _ = data["parent_id"].(string)
+	if deps, ok := data["dependencies"].([]interface{}); ok {
+		for _, d := range deps {
+			if id, ok := d.(string); ok {
+				t.Dependencies = append(t.Dependencies, id)
+			}
+		}
+	}
+	return t, nil
+}
+
+// Object returns the view object of the task with its current dates.
+func (t *Task) Object() model.ViewObject {
+	o := t.object
+	data := make(map[string]interface{}, len(t.data))
+	for k, v := range t.data {
+		data[k] = v
+	}
+	data["start"] = t.Start.Format(dateLayout)
+	data["end"] = t.End.Format(dateLayout)
+	b, _ := json.Marshal(data)
+	o.Data = string(b)
+	return o
+}
+
+// Check validates the links between the tasks of a view: the parent and
+// dependencies of the changed tasks must exist, and neither dependencies nor
+// parents may form a cycle. Links to tasks that no longer exist are ignored
+// for tasks that did not change.
+func Check(objects []model.ViewObject, changed ...string) error {
+	tasks := Load(objects)
+	byID := make(map[string]*Task, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+
+	var errs []viewobject.FieldError
+	for _, id := range changed {
+		t, ok := byID[id]
+		if !ok {
+			continue
+		}
+		if t.ParentID != "" {
+			if t.ParentID == t.ID {
+				errs = append(errs, viewobject.FieldError{Field: "data.parent_id", Message: "must not be the task itself"})
+			} else if byID[t.ParentID] == nil {
+				errs = append(errs, viewobject.FieldError{Field: "data.parent_id", Message: fmt.Sprintf("unknown task %q", t.ParentID)})
+			}
+		}
+		for i, dep := range t.Dependencies {
+			field := fmt.Sprintf("data.dependencies[%d]", i)
+			if dep == t.ID {
+				errs = append(errs, viewobject.FieldError{Field: field, Message: "must not be the task itself"})
+			} else if byID[dep] == nil {
+				errs = append(errs, viewobject.FieldError{Field: field, Message: fmt.Sprintf("unknown task %q", dep)})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return &viewobject.ValidationError{Fields: errs}
+	}
+
+	if cycle := findCycle(tasks, byID, func(t *Task) []string { return t.Dependencies }); cycle != nil {
+		return &viewobject.ValidationError{Fields: []viewobject.FieldError{{
+			Field:   "data.dependencies",
+			Message: "dependencies form a cycle: " + describeCycle(cycle, byID),
+		}}}
+	}
+	if cycle := findCycle(tasks, byID, func(t *Task) []string {
+		if t.ParentID == "" {
+			return nil
+		}
+		return []string{t.ParentID}
+	}); cycle != nil {
+		return &viewobject.ValidationError{Fields: []viewobject.FieldError{{
+			Field:   "data.parent_id",
+			Message: "parents form a cycle: " + describeCycle(cycle, byID),
+		}}}
+	}
+	return nil
+}
+
+// CheckObject validates the links of a task that is about to be created or
+// updated among the current objects of its view. Other object types pass.
+func CheckObject(objects []model.ViewObject, o model.ViewObject) error {
+	if o.Type != ObjectType {
+		return nil
+	}
+	next := make([]model.ViewObject, 0, len(objects)+1)
+	for _, x := range objects {
+		if x.ID != o.ID {
+			next = append(next, x)
+		}
+	}
+	return Check(append(next, o), o.ID)
+}
+
+// CheckDelete reports whether a task can be deleted from the tasks of its
+// view, which it cannot while other tasks have it as their parent or depend
+// on it.
+func CheckDelete(tasks []*Task, id string) error {
+	var names []string
+	for _, t := range tasks {
+		if t.ID == id {
+			continue
+		}
+		linked := t.ParentID == id
+		for _, dep := range t.Dependencies {
+			linked = linked || dep == id
+		}
+		if !linked {
+			continue
+		}
+		if t.Name != "" {
+			names = append(names, t.Name)
+		} else {
+			names = append(names, t.ID)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return fmt.Errorf("task is linked from other tasks: %s", strings.Join(names, ", "))
+}
+
+// findCycle returns the ids of a cycle in the graph given by edges, or nil.
+func findCycle(tasks []*Task, byID map[string]*Task, edges func(*Task) []string) []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(tasks))
+	var stack []string
+
+	var visit func(t *Task) []string
+	visit = func(t *Task) []string {
+		state[t.ID] = visiting
+		stack = append(stack, t.ID)
+		for _, next := range edges(t) {
+			n := byID[next]
+			if n == nil {
+				continue
+			}
+			switch state[n.ID] {
+			case visiting:
+				for i, id := range stack {
+					if id == n.ID {
+						return append(append([]string{}, stack[i:]...), n.ID)
+					}
+				}
+			case unvisited:
+				if cycle := visit(n); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[t.ID] = done
+		return nil
+	}
+
+	for _, t := range tasks {
+		if state[t.ID] == unvisited {
+			if cycle := visit(t); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+func describeCycle(ids []string, byID map[string]*Task) string {
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = id
+		if t := byID[id]; t != nil && t.Name != "" {
+			names[i] = t.Name
+		}
+	}
+	return strings.Join(names, " -> ")
+}
+
+// Mode selects how Reschedule treats the dependents of a moved task.
+type Mode string
+
+const (
+	// ModePush moves dependents only as far as needed to start after their
+	// predecessors end.
+	ModePush Mode = "push"
+	// ModeShift moves all dependents by the same number of days as the task,
+	// keeping the gaps between them, then applies ModePush.
+	ModeShift Mode = "shift"
+)
+
+const day = 24 * time.Hour
+
+// Reschedule moves a task to new dates and shifts its direct and indirect
+// dependents so that every task starts after the tasks it depends on end.
+// It returns the tasks whose dates changed, the moved task first.
+func Reschedule(tasks []*Task, id string, start, end time.Time, mode Mode) ([]*Task, error) {
+	if end.Before(start) {
+		return nil, fmt.Errorf("end must not be before start")
+	}
+	byID := make(map[string]*Task, len(tasks))
+	dependents := map[string][]*Task{}
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+	for _, t := range tasks {
+		for _, dep := range t.Dependencies {
+			if byID[dep] != nil {
+				dependents[dep] = append(dependents[dep], t)
+			}
+		}
+	}
+
+	moved, ok := byID[id]
+	if !ok {
+		return nil, fmt.Errorf("task not found")
+	}
+
+	type dates struct{ start, end time.Time }
+	original := make(map[string]dates, len(tasks))
+	for _, t := range tasks {
+		original[t.ID] = dates{t.Start, t.End}
+	}
+	delta := start.Sub(moved.Start)
+	moved.Start, moved.End = start, end
+
+	// Tasks downstream of the moved one, in dependency order.
+	order, err := downstream(moved, dependents)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range order {
+		if mode == ModeShift {
+			t.Start, t.End = t.Start.Add(delta), t.End.Add(delta)
+		}
+		var earliest time.Time
+		for _, dep := range t.Dependencies {
+			if p := byID[dep]; p != nil && p.End.Add(day).After(earliest) {
+				earliest = p.End.Add(day)
+			}
+		}
+		if t.Start.Before(earliest) {
+			shift := earliest.Sub(t.Start)
+			t.Start, t.End = t.Start.Add(shift), t.End.Add(shift)
+		}
+	}
+
+	changed := []*Task{moved}
+	for _, t := range order {
+		if o := original[t.ID]; !o.start.Equal(t.Start) || !o.end.Equal(t.End) {
+			changed = append(changed, t)
+		}
+	}
+	return changed, nil
+}
+
+// downstream returns the tasks that depend on t directly or indirectly, in
+// an order where every task comes after the tasks it depends on.
+func downstream(t *Task, dependents map[string][]*Task) ([]*Task, error) {
+	reached := map[string]*Task{}
+	queue := []*Task{t}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, d := range dependents[cur.ID] {
+			if d.ID == t.ID {
+				return nil, fmt.Errorf("dependencies form a cycle")
+			}
+			if reached[d.ID] == nil {
+				reached[d.ID] = d
+				queue = append(queue, d)
+			}
+		}
+	}
+
+	// Kahn's algorithm over the reached tasks.
+	indegree := make(map[string]int, len(reached))
+	for _, d := range reached {
+		for _, dep := range d.Dependencies {
+			if reached[dep] != nil {
+				indegree[d.ID]++
+			}
+		}
+	}
+	var ready []*Task
+	for _, d := range reached {
+		if indegree[d.ID] == 0 {
+			ready = append(ready, d)
+		}
+	}
+	var order []*Task
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool { return ready[i].ID < ready[j].ID })
+		cur := ready[0]
+		ready = ready[1:]
+		order = append(order, cur)
+		for _, d := range dependents[cur.ID] {
+			if reached[d.ID] == nil {
+				continue
+			}
+			indegree[d.ID]--
+			if indegree[d.ID] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	if len(order) != len(reached) {
+		return nil, fmt.Errorf("dependencies form a cycle")
+	}
+	return order, nil
+}
+
+// ParseDate parses a day in YYYY-MM-DD format.
+func ParseDate(s string) (time.Time, error) {
+	return time.Parse(dateLayout, s)
+}
@@ -0,0 +1,265 @@
+package timeline
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/viewobject"
+)
+
+// task returns the view object of a task. Links are given as "parent:id"
+// and "dep:id".
+func task(id, start, end string, links ...string) model.ViewObject {
+	data := fmt.Sprintf(`"start":%q,"end":%q`, start, end)
+	var deps []string
+	for _, l := range links {
+		kind, target, _ := strings.Cut(l, ":")
+		if kind == "parent" {
+			data += fmt.Sprintf(`,"parent_id":%q`, target)
+		} else {
+			deps = append(deps, fmt.Sprintf("%q", target))
+		}
+	}
+	if deps != nil {
+		data += `,"dependencies":[` + strings.Join(deps, ",") + `]`
+	}
+	return model.ViewObject{ID: id, Name: strings.ToUpper(id), Type: ObjectType, Data: "{" + data + "}"}
+}
+
+func TestLoad(t *testing.T) {
+	objects := []model.ViewObject{
+		task("a", "2024-01-01", "2024-01-03"),
+		task("b", "2024-01-04", "2024-01-05", "dep:a", "parent:a"),
+		{ID: "x", Type: "calendar_slot", Data: `{"date":"2024-01-01"}`},
+		{ID: "bad", Type: ObjectType, Data: `{"start":"soon","end":"2024-01-01"}`},
+	}
+	tasks := Load(objects)
+	if len(tasks) != 2 {
+		t.Fatalf("Load = %d tasks, want 2", len(tasks))
+	}
+	b := tasks[1]
+	if b.ParentID != "a" || !reflect.DeepEqual(b.Dependencies, []string{"a"}) || !b.Start.Equal(date(t, "2024-01-04")) {
+		t.Errorf("task b = %+v", b)
+	}
+
+	// Object keeps the other data fields and writes the current dates.
+	b.Start, b.End = date(t, "2024-02-01"), date(t, "2024-02-02")
+	if got := b.Object().Data; got != `{"dependencies":["a"],"end":"2024-02-02","parent_id":"a","start":"2024-02-01"}` {
+		t.Errorf("Object().Data = %s", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		objects []model.ViewObject
+		changed []string
+		want    []viewobject.FieldError
+	}{
+		{
+			name:    "valid links",
+			objects: []model.ViewObject{task("a", "2024-01-01", "2024-01-02"), task("b", "2024-01-03", "2024-01-04", "dep:a", "parent:a")},
+			changed: []string{"b"},
+		},
+		{
+			name:    "unknown links of a changed task",
+			objects: []model.ViewObject{task("a", "2024-01-01", "2024-01-02", "parent:gone", "dep:a", "dep:missing")},
+			changed: []string{"a"},
+			want: []viewobject.FieldError{
+				{Field: "data.parent_id", Message: `unknown task "gone"`},
+				{Field: "data.dependencies[0]", Message: "must not be the task itself"},
+				{Field: "data.dependencies[1]", Message: `unknown task "missing"`},
+			},
+		},
+		{
+			name:    "unknown links of unchanged tasks are ignored",
+			objects: []model.ViewObject{task("a", "2024-01-01", "2024-01-02", "dep:gone"), task("b", "2024-01-01", "2024-01-02")},
+			changed: []string{"b"},
+		},
+		{
+			name:    "own parent",
+			objects: []model.ViewObject{task("a", "2024-01-01", "2024-01-02", "parent:a")},
+			changed: []string{"a"},
+			want:    []viewobject.FieldError{{Field: "data.parent_id", Message: "must not be the task itself"}},
+		},
+		{
+			name: "dependency cycle",
+			objects: []model.ViewObject{
+				task("a", "2024-01-01", "2024-01-02", "dep:c"),
+				task("b", "2024-01-01", "2024-01-02", "dep:a"),
+				task("c", "2024-01-01", "2024-01-02", "dep:b"),
+			},
+			changed: []string{"a"},
+			want:    []viewobject.FieldError{{Field: "data.dependencies", Message: "dependencies form a cycle: A -> C -> B -> A"}},
+		},
+		{
+			name: "parent cycle",
+			objects: []model.ViewObject{
+				task("a", "2024-01-01", "2024-01-02", "parent:b"),
+				task("b", "2024-01-01", "2024-01-02", "parent:a"),
+			},
+			changed: []string{"b"},
+			want:    []viewobject.FieldError{{Field: "data.parent_id", Message: "parents form a cycle: A -> B -> A"}},
+		},
+		{
+			name: "diamond is not a cycle",
+			objects: []model.ViewObject{
+				task("a", "2024-01-01", "2024-01-02"),
+				task("b", "2024-01-03", "2024-01-04", "dep:a"),
+				task("c", "2024-01-03", "2024-01-04", "dep:a"),
+				task("d", "2024-01-05", "2024-01-06", "dep:b", "dep:c"),
+			},
+			changed: []string{"d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(tt.objects, tt.changed...)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Check = %v, want no error", err)
+				}
+				return
+			}
+			var verr *viewobject.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Check = %v, want a *viewobject.ValidationError", err)
+			}
+			if !reflect.DeepEqual(verr.Fields, tt.want) {
+				t.Errorf("Check fields = %v, want %v", verr.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckObject(t *testing.T) {
+	objects := []model.ViewObject{task("a", "2024-01-01", "2024-01-02"), task("b", "2024-01-03", "2024-01-04", "dep:a")}
+
+	// Updating a to depend on b closes a cycle.
+	if err := CheckObject(objects, task("a", "2024-01-01", "2024-01-02", "dep:b")); err == nil {
+		t.Error("CheckObject accepted a cycle")
+	}
+	if err := CheckObject(objects, task("c", "2024-01-05", "2024-01-06", "dep:b")); err != nil {
+		t.Errorf("CheckObject = %v for a new task", err)
+	}
+	if err := CheckObject(objects, model.ViewObject{ID: "n", Type: "kanban_column", Data: `{"dependencies":["x"]}`}); err != nil {
+		t.Errorf("CheckObject = %v for another object type", err)
+	}
+}
+
+func TestCheckDelete(t *testing.T) {
+	tasks := Load([]model.ViewObject{
+		task("a", "2024-01-01", "2024-01-02"),
+		task("b", "2024-01-03", "2024-01-04", "dep:a"),
+		task("c", "2024-01-03", "2024-01-04", "parent:a"),
+		task("d", "2024-01-05", "2024-01-06"),
+	})
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"a", "task is linked from other tasks: B, C"},
+		{"b", ""},
+		{"d", ""},
+		{"missing", ""},
+	}
+	for _, tt := range tests {
+		err := CheckDelete(tasks, tt.id)
+		if got := fmt.Sprint(err); (err == nil && tt.want != "") || (err != nil && got != tt.want) {
+			t.Errorf("CheckDelete(%s) = %v, want %q", tt.id, err, tt.want)
+		}
+	}
+}
+
+func date(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := ParseDate(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestReschedule(t *testing.T) {
+	// a -> b -> d and a -> c -> d, with a gap of two days before c and an
+	// unrelated task e.
+	objects := []model.ViewObject{
+		task("a", "2024-01-01", "2024-01-03"),
+		task("b", "2024-01-04", "2024-01-05", "dep:a"),
+		task("c", "2024-01-06", "2024-01-06", "dep:a"),
+		task("d", "2024-01-07", "2024-01-08", "dep:b", "dep:c"),
+		task("e", "2024-01-01", "2024-01-02"),
+	}
+	tests := []struct {
+		name       string
+		id         string
+		start, end string
+		mode       Mode
+		// want lists the changed tasks as id=start/end, the moved task first.
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "push moves dependents as far as needed",
+			id:   "a", start: "2024-01-03", end: "2024-01-05", mode: ModePush,
+			want: []string{"a=01-03/01-05", "b=01-06/01-07", "d=01-08/01-09"},
+		},
+		{
+			name: "shift keeps the gaps",
+			id:   "a", start: "2024-01-03", end: "2024-01-05", mode: ModeShift,
+			want: []string{"a=01-03/01-05", "b=01-06/01-07", "c=01-08/01-08", "d=01-09/01-10"},
+		},
+		{
+			name: "push earlier leaves dependents",
+			id:   "a", start: "2023-12-30", end: "2023-12-31", mode: ModePush,
+			want: []string{"a=12-30/12-31"},
+		},
+		{
+			name: "shift earlier moves dependents back",
+			id:   "a", start: "2023-12-30", end: "2024-01-01", mode: ModeShift,
+			want: []string{"a=12-30/01-01", "b=01-02/01-03", "c=01-04/01-04", "d=01-05/01-06"},
+		},
+		{
+			name: "shortening a task leaves its dependents",
+			id:   "b", start: "2024-01-04", end: "2024-01-04", mode: ModeShift,
+			want: []string{"b=01-04/01-04"},
+		},
+		{
+			name: "shift later moves every dependent",
+			id:   "c", start: "2024-01-08", end: "2024-01-08", mode: ModeShift,
+			want: []string{"c=01-08/01-08", "d=01-09/01-10"},
+		},
+		{name: "end before start", id: "a", start: "2024-01-03", end: "2024-01-02", mode: ModePush, wantErr: true},
+		{name: "unknown task", id: "z", start: "2024-01-03", end: "2024-01-04", mode: ModePush, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed, err := Reschedule(Load(objects), tt.id, date(t, tt.start), date(t, tt.end), tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Reschedule error = %v, want error %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, task := range changed {
+				got = append(got, task.ID+"="+task.Start.Format("01-02")+"/"+task.End.Format("01-02"))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRescheduleCycle(t *testing.T) {
+	tasks := Load([]model.ViewObject{
+		task("a", "2024-01-01", "2024-01-02", "dep:b"),
+		task("b", "2024-01-03", "2024-01-04", "dep:a"),
+	})
+	if _, err := Reschedule(tasks, "a", date(t, "2024-01-05"), date(t, "2024-01-06"), ModePush); err == nil {
+		t.Error("Reschedule followed a cycle")
+	}
+}
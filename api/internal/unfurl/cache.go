@@ -0,0 +1,91 @@
+package unfurl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/model"
+)
+
+// errorTTL caps how long a failed lookup is cached, so that a page that
+// was briefly down is retried soon.
+const errorTTL = 10 * time.Minute
+
+// Lookup returns the metadata of a link, from the cache while it is fresh.
+// Lookups are cached for ttl, failed lookups for at most errorTTL; a ttl of
+// zero or less disables the cache.
+func (u *Unfurler) Lookup(ctx context.Context, d db.DB, rawURL string, ttl time.Duration) (Metadata, error) {
+	key, err := NormalizeURL(rawURL)
+	if err != nil {
+		return Metadata{}, err
+	}
+	if ttl <= 0 {
+		return u.Unfurl(ctx, key)
+	}
+
+	now := time.Now().UTC()
+	if p, err := d.FindLinkPreview(key); err == nil {
+		if expires, err := time.Parse(time.RFC3339, p.ExpiresAt); err == nil && now.Before(expires) {
+			return fromPreview(p)
+		}
+	}
+
+	m, fetchErr := u.Unfurl(ctx, key)
+
+	p := model.LinkPreview{
+		URL:       key,
+		FetchedAt: now.Format(time.RFC3339),
+	}
+	if fetchErr != nil {
+		p.Error = fetchErr.Error()
+		p.ExpiresAt = now.Add(min(ttl, errorTTL)).Format(time.RFC3339)
+	} else {
+		toPreview(&p, m)
+		p.ExpiresAt = now.Add(ttl).Format(time.RFC3339)
+	}
+
+	if err := d.DeleteExpiredLinkPreviews(now.Format(time.RFC3339)); err != nil {
+		return m, err
+	}
+	if err := d.SaveLinkPreview(p); err != nil {
+		return m, err
+	}
+	return m, fetchErr
+}
+
+func toPreview(p *model.LinkPreview, m Metadata) {
+	p.Title = m.Title
+	p.Description = m.Description
+	p.Image = m.Image
+	p.Favicon = m.Favicon
+	p.SiteName = m.SiteName
+	if m.OEmbed != nil {
+		if b, err := json.Marshal(m.OEmbed); err == nil {
+			p.Oembed = string(b)
+		}
+	}
+}
+
+func fromPreview(p model.LinkPreview) (Metadata, error) {
+	if p.Error != "" {
+		return Metadata{}, errors.New(p.Error)
+	}
+	m := Metadata{
+		URL:         p.URL,
+		Title:       p.Title,
+		Description: p.Description,
+		Image:       p.Image,
+		Favicon:     p.Favicon,
+		SiteName:    p.SiteName,
+	}
+	if p.Oembed != "" {
+		var e OEmbed
+		if err := json.Unmarshal([]byte(p.Oembed), &e); err == nil {
+			m.OEmbed = &e
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,159 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/collabreef/collabreef/internal/db/dbtest"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/urlfetcher"
+)
+
+// countingFetch serves a page and counts the fetches, failing while fail
+// is set.
+type countingFetch struct {
+	calls int
+	fail  bool
+}
+
+func (f *countingFetch) fetch(ctx context.Context, rawURL string, header http.Header) (urlfetcher.Response, error) {
+	f.calls++
+	if f.fail {
+		return urlfetcher.Response{}, errors.New("unreachable")
+	}
+	return htmlPage(`<head><title>Cached</title><meta property="og:image" content="/i.png"></head>`), nil
+}
+
+func TestLookup(t *testing.T) {
+	const pageURL = "https://example.com/page"
+	past := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name      string
+		stored    *model.LinkPreview
+		fail      bool
+		ttl       time.Duration
+		wantCalls int
+		wantTitle string
+		wantErr   bool
+		// wantTTL is the lifetime of the stored preview, when one is stored.
+		wantTTL time.Duration
+	}{
+		{
+			name:      "miss fetches and stores",
+			ttl:       time.Hour,
+			wantCalls: 1,
+			wantTitle: "Cached",
+			wantTTL:   time.Hour,
+		},
+		{
+			name:      "fresh preview is served",
+			stored:    &model.LinkPreview{URL: pageURL, Title: "Stored", ExpiresAt: future},
+			ttl:       time.Hour,
+			wantTitle: "Stored",
+		},
+		{
+			name:      "expired preview is fetched again",
+			stored:    &model.LinkPreview{URL: pageURL, Title: "Stored", ExpiresAt: past},
+			ttl:       time.Hour,
+			wantCalls: 1,
+			wantTitle: "Cached",
+			wantTTL:   time.Hour,
+		},
+		{
+			name:      "fresh error is served",
+			stored:    &model.LinkPreview{URL: pageURL, Error: "unreachable", ExpiresAt: future},
+			ttl:       time.Hour,
+			wantErr:   true,
+			wantCalls: 0,
+		},
+		{
+			name:      "errors are cached briefly",
+			fail:      true,
+			ttl:       24 * time.Hour,
+			wantCalls: 1,
+			wantErr:   true,
+			wantTTL:   errorTTL,
+		},
+		{
+			name:      "zero ttl disables the cache",
+			stored:    &model.LinkPreview{URL: pageURL, Title: "Stored", ExpiresAt: future},
+			ttl:       0,
+			wantCalls: 1,
+			wantTitle: "Cached",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := dbtest.New(t)
+			if tt.stored != nil {
+				if err := d.SaveLinkPreview(*tt.stored); err != nil {
+					t.Fatal(err)
+				}
+			}
+			f := &countingFetch{fail: tt.fail}
+			u := &Unfurler{Fetch: f.fetch}
+
+			start := time.Now().UTC().Truncate(time.Second)
+			m, err := u.Lookup(context.Background(), d, pageURL+"#fragment", tt.ttl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Lookup error = %v, want error %v", err, tt.wantErr)
+			}
+			if f.calls != tt.wantCalls {
+				t.Errorf("fetches = %d, want %d", f.calls, tt.wantCalls)
+			}
+			if m.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", m.Title, tt.wantTitle)
+			}
+
+			if tt.wantTTL == 0 {
+				return
+			}
+			p, err := d.FindLinkPreview(pageURL)
+			if err != nil {
+				t.Fatalf("preview was not stored under the normalized URL: %v", err)
+			}
+			expires, err := time.Parse(time.RFC3339, p.ExpiresAt)
+			if err != nil {
+				t.Fatalf("ExpiresAt %q: %v", p.ExpiresAt, err)
+			}
+			if got := expires.Sub(start); got < tt.wantTTL || got > tt.wantTTL+2*time.Second {
+				t.Errorf("preview expires after %v, want %v", got, tt.wantTTL)
+			}
+			if tt.fail && p.Error == "" {
+				t.Error("failed lookup stored without its error")
+			}
+		})
+	}
+}
+
+func TestPreviewRoundTrip(t *testing.T) {
+	m := Metadata{
+		URL:         "https://example.com/v",
+		Title:       "Title",
+		Description: "Description",
+		Image:       "https://example.com/i.png",
+		Favicon:     "https://example.com/favicon.ico",
+		SiteName:    "Example",
+		OEmbed:      &OEmbed{Type: "video", HTML: "<iframe></iframe>", Width: 640, Height: 360},
+	}
+
+	p := model.LinkPreview{URL: m.URL}
+	toPreview(&p, m)
+	got, err := fromPreview(p)
+	if err != nil {
+		t.Fatalf("fromPreview: %v", err)
+	}
+	if got.OEmbed == nil || *got.OEmbed != *m.OEmbed {
+		t.Errorf("OEmbed = %+v, want %+v", got.OEmbed, m.OEmbed)
+	}
+	got.OEmbed, m.OEmbed = nil, nil
+	if got != m {
+		t.Errorf("fromPreview = %+v, want %+v", got, m)
+	}
+}
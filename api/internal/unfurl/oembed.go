@@ -0,0 +1,124 @@
+package unfurl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// OEmbed is an oEmbed response. HTML is set for video and rich embeds.
+type OEmbed struct {
+	Type            string `json:"type"`
+	Title           string `json:"title,omitempty"`
+	HTML            string `json:"html,omitempty"`
+	Width           size   `json:"width,omitempty"`
+	Height          size   `json:"height,omitempty"`
+	AuthorName      string `json:"author_name,omitempty"`
+	AuthorURL       string `json:"author_url,omitempty"`
+	ProviderName    string `json:"provider_name,omitempty"`
+	ProviderURL     string `json:"provider_url,omitempty"`
+	ThumbnailURL    string `json:"thumbnail_url,omitempty"`
+	ThumbnailWidth  size   `json:"thumbnail_width,omitempty"`
+	ThumbnailHeight size   `json:"thumbnail_height,omitempty"`
+}
+
+// size is a dimension of an embed. Providers send numbers, strings or null.
+type size int
+
+func (s *size) UnmarshalJSON(data []byte) error {
+	v := strings.Trim(string(data), `"`)
+	if v == "" || v == "null" {
+		*s = 0
+		return nil
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		// Sizes such as "100%" cannot be expressed; leave them unset.
+		*s = 0
+		return nil
+	}
+	*s = size(n)
+	return nil
+}
+
+// Provider is an oEmbed provider, with the URL patterns it embeds. Patterns
+// match the host and path of a link; "*" matches within a path segment, or
+// a subdomain.
+type Provider struct {
+	Name     string
+	Schemes  []string
+	Endpoint string
+}
+
+// DefaultProviders are the providers of the embeds the editor supports.
+// Other sites are embedded when their pages link to an oEmbed endpoint.
+var DefaultProviders = []Provider{
+	{
+		Name:     "YouTube",
+		Schemes:  []string{"youtube.com/watch", "*.youtube.com/watch", "youtube.com/shorts/*", "*.youtube.com/shorts/*", "youtu.be/*"},
+		Endpoint: "https://www.youtube.com/oembed",
+	},
+	{
+		Name:     "Twitter",
+		Schemes:  []string{"twitter.com/*/status/*", "*.twitter.com/*/status/*", "x.com/*/status/*"},
+		Endpoint: "https://publish.twitter.com/oembed",
+	},
+	{
+		Name:     "Vimeo",
+		Schemes:  []string{"vimeo.com/*", "vimeo.com/*/*", "player.vimeo.com/video/*"},
+		Endpoint: "https://vimeo.com/api/oembed.json",
+	},
+	{
+		Name:     "Spotify",
+		Schemes:  []string{"open.spotify.com/*/*"},
+		Endpoint: "https://open.spotify.com/oembed",
+	},
+	{
+		Name:     "SoundCloud",
+		Schemes:  []string{"soundcloud.com/*", "soundcloud.com/*/*"},
+		Endpoint: "https://soundcloud.com/oembed",
+	},
+}
+
+// providerEndpoint returns the oEmbed request for a link of a known
+// provider.
+func (u *Unfurler) providerEndpoint(rawURL string) string {
+	link, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	target := strings.TrimPrefix(link.Hostname(), "www.") + strings.TrimSuffix(link.Path, "/")
+
+	for _, p := range u.Providers {
+		for _, scheme := range p.Schemes {
+			if ok, _ := path.Match(scheme, target); ok {
+				q := url.Values{"url": {rawURL}, "format": {"json"}}
+				return p.Endpoint + "?" + q.Encode()
+			}
+		}
+	}
+	return ""
+}
+
+func (u *Unfurler) oembed(ctx context.Context, endpoint string) (*OEmbed, error) {
+	res, err := u.Fetch(ctx, endpoint, http.Header{"Accept": {"application/json"}})
+	if err != nil {
+		return nil, err
+	}
+
+	var e OEmbed
+	if err := json.Unmarshal(res.Data, &e); err != nil {
+		return nil, err
+	}
+	switch e.Type {
+	case "photo", "video", "link", "rich":
+	default:
+		return nil, errors.New("invalid oEmbed response")
+	}
+	return &e, nil
+}
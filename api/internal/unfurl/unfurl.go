@@ -0,0 +1,256 @@
+// Package unfurl looks up the metadata of links: the title, description,
+// image and favicon a page advertises through Open Graph, Twitter card and
+// plain HTML tags, and the embed HTML of oEmbed providers.
+package unfurl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/collabreef/collabreef/internal/urlfetcher"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// FetchFunc fetches a URL. Unfurlers use urlfetcher.SafeFetch, which
+// refuses private and loopback addresses; a stand-in can be set to unfurl
+// pages served locally.
+type FetchFunc func(ctx context.Context, rawURL string, header http.Header) (urlfetcher.Response, error)
+
+// Metadata describes a link.
+type Metadata struct {
+	URL         string  `json:"url"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Image       string  `json:"image"`
+	Favicon     string  `json:"favicon"`
+	SiteName    string  `json:"site_name"`
+	OEmbed      *OEmbed `json:"oembed,omitempty"`
+}
+
+// Unfurler looks up link metadata.
+type Unfurler struct {
+	Fetch     FetchFunc
+	Providers []Provider
+}
+
+func New() *Unfurler {
+	return &Unfurler{
+		Fetch:     urlfetcher.SafeFetch,
+		Providers: DefaultProviders,
+	}
+}
+
+// NormalizeURL checks that a URL is an absolute http or https URL and
+// returns it in a canonical form, so that a link is looked up once.
+func NormalizeURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errors.New("url must be an http or https URL")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// Unfurl looks up the metadata of a link. Links of a known oEmbed provider
+// are also looked up with the provider, and a lookup succeeds when either
+// the page or the provider answers.
+func (u *Unfurler) Unfurl(ctx context.Context, rawURL string) (Metadata, error) {
+	pageURL, err := NormalizeURL(rawURL)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var embed *OEmbed
+	if endpoint := u.providerEndpoint(pageURL); endpoint != "" {
+		embed, _ = u.oembed(ctx, endpoint)
+	}
+
+	m, err := u.page(ctx, pageURL)
+	if err != nil {
+		if embed == nil {
+			return Metadata{}, err
+		}
+		m = pageMetadata{Metadata: Metadata{URL: pageURL}}
+	}
+
+	if embed == nil && m.oembedURL != "" {
+		embed, _ = u.oembed(ctx, m.oembedURL)
+	}
+
+	md := m.Metadata
+	if embed != nil {
+		md.OEmbed = embed
+		if md.Title == "" {
+			md.Title = embed.Title
+		}
+		if md.Image == "" {
+			md.Image = embed.ThumbnailURL
+		}
+		if md.SiteName == "" {
+			md.SiteName = embed.ProviderName
+		}
+	}
+	return md, nil
+}
+
+// pageMetadata is the metadata read from a page, with the address of the
+// oEmbed endpoint the page links to.
+type pageMetadata struct {
+	Metadata
+	oembedURL string
+}
+
+func (u *Unfurler) page(ctx context.Context, pageURL string) (pageMetadata, error) {
+	res, err := u.Fetch(ctx, pageURL, http.Header{"Accept": {"text/html,application/xhtml+xml"}})
+	if err != nil {
+		return pageMetadata{}, err
+	}
+
+	base, _ := url.Parse(pageURL)
+	m := pageMetadata{Metadata: Metadata{URL: pageURL}}
+
+	mediaType, _, _ := mime.ParseMediaType(res.ContentType)
+	if mediaType != "" && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		// Links to images and other files describe themselves by name.
+		if strings.HasPrefix(mediaType, "image/") {
+			m.Image = pageURL
+		}
+		m.Title = fileTitle(base)
+		m.Favicon = resolve(base, "/favicon.ico")
+		return m, nil
+	}
+
+	r, err := charset.NewReader(bytes.NewReader(res.Data), res.ContentType)
+	if err != nil {
+		return pageMetadata{}, err
+	}
+	doc, err := html.Parse(r)
+	if err != nil {
+		return pageMetadata{}, err
+	}
+
+	readPage(doc, base, &m)
+	return m, nil
+}
+
+// readPage fills m from the head of a page. Open Graph tags win over
+// Twitter cards, which win over plain HTML.
+func readPage(doc *html.Node, base *url.URL, m *pageMetadata) {
+	meta := map[string]string{}
+	var title, icon, touchIcon string
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Body:
+				// Metadata lives in the head; bodies can be large.
+				return
+			case atom.Title:
+				if title == "" && n.FirstChild != nil {
+					title = n.FirstChild.Data
+				}
+			case atom.Meta:
+				key := attr(n, "property")
+				if key == "" {
+					key = attr(n, "name")
+				}
+				key = strings.ToLower(strings.TrimSpace(key))
+				if _, ok := meta[key]; key != "" && !ok {
+					meta[key] = strings.TrimSpace(attr(n, "content"))
+				}
+			case atom.Link:
+				href := strings.TrimSpace(attr(n, "href"))
+				if href == "" {
+					break
+				}
+				rels := strings.Fields(strings.ToLower(attr(n, "rel")))
+				for _, rel := range rels {
+					switch rel {
+					case "icon":
+						if icon == "" {
+							icon = href
+						}
+					case "apple-touch-icon":
+						if touchIcon == "" {
+							touchIcon = href
+						}
+					case "alternate":
+						if strings.EqualFold(attr(n, "type"), "application/json+oembed") && m.oembedURL == "" {
+							m.oembedURL = resolve(base, href)
+						}
+					}
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	m.Title = first(meta["og:title"], meta["twitter:title"], collapse(title))
+	m.Description = first(meta["og:description"], meta["twitter:description"], meta["description"])
+	m.Image = resolve(base, first(meta["og:image"], meta["og:image:url"], meta["twitter:image"], meta["twitter:image:src"]))
+	m.SiteName = first(meta["og:site_name"], meta["application-name"])
+	m.Favicon = resolve(base, first(icon, touchIcon, "/favicon.ico"))
+	if m.Title == "" {
+		m.Title = fileTitle(base)
+	}
+}
+
+// resolve makes ref absolute against base. References that do not resolve
+// to an http or https URL are dropped.
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
+// fileTitle names a link by the last segment of its path, or its host.
+func fileTitle(u *url.URL) string {
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if name := segments[len(segments)-1]; name != "" {
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			return unescaped
+		}
+		return name
+	}
+	return u.Host
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,269 @@
+package unfurl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/urlfetcher"
+)
+
+// pages serves fetches from memory, by URL.
+type pages map[string]urlfetcher.Response
+
+func (p pages) fetch(ctx context.Context, rawURL string, header http.Header) (urlfetcher.Response, error) {
+	res, ok := p[rawURL]
+	if !ok {
+		return urlfetcher.Response{}, errors.New("not found: " + rawURL)
+	}
+	return res, nil
+}
+
+func htmlPage(body string) urlfetcher.Response {
+	return urlfetcher.Response{ContentType: "text/html; charset=utf-8", Data: []byte(body)}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "https://example.com/a", want: "https://example.com/a"},
+		{raw: "  HTTP://Example.COM/Path?q=1#section ", want: "http://example.com/Path?q=1"},
+		{raw: "ftp://example.com/file", wantErr: true},
+		{raw: "/relative/path", wantErr: true},
+		{raw: "https://", wantErr: true},
+		{raw: "javascript:alert(1)", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeURL(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeURL(%q) error = %v, want error %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestUnfurlPage(t *testing.T) {
+	tests := []struct {
+		name string
+		page urlfetcher.Response
+		want Metadata
+	}{
+		{
+			name: "open graph wins over twitter and html",
+			page: htmlPage(`<html><head>
+				<title>HTML title</title>
+				<meta name="description" content="HTML description">
+				<meta name="twitter:title" content="Twitter title">
+				<meta property="og:title" content="OG title">
+				<meta property="og:description" content="OG description">
+				<meta property="og:image" content="/images/card.png">
+				<meta property="og:site_name" content="Example">
+				<link rel="icon" href="/icon.png">
+			</head><body></body></html>`),
+			want: Metadata{
+				Title:       "OG title",
+				Description: "OG description",
+				Image:       "https://example.com/images/card.png",
+				Favicon:     "https://example.com/icon.png",
+				SiteName:    "Example",
+			},
+		},
+		{
+			name: "twitter card without open graph",
+			page: htmlPage(`<head>
+				<title>HTML title</title>
+				<meta name="twitter:title" content="Twitter title">
+				<meta name="twitter:image:src" content="https://cdn.example.com/t.jpg">
+				<link rel="apple-touch-icon" href="touch.png">
+			</head>`),
+			want: Metadata{
+				Title:   "Twitter title",
+				Image:   "https://cdn.example.com/t.jpg",
+				Favicon: "https://example.com/posts/touch.png",
+			},
+		},
+		{
+			name: "plain html",
+			page: htmlPage(`<head><title>
+				A   spaced
+				title </title><meta name="description" content=" Described "></head>`),
+			want: Metadata{
+				Title:       "A spaced title",
+				Description: "Described",
+				Favicon:     "https://example.com/favicon.ico",
+			},
+		},
+		{
+			name: "metadata in the body is ignored",
+			page: htmlPage(`<head></head><body><meta property="og:title" content="Body title"></body>`),
+			want: Metadata{
+				Title:   "page",
+				Favicon: "https://example.com/favicon.ico",
+			},
+		},
+		{
+			name: "unsafe image references are dropped",
+			page: htmlPage(`<head><title>T</title><meta property="og:image" content="javascript:alert(1)"></head>`),
+			want: Metadata{
+				Title:   "T",
+				Favicon: "https://example.com/favicon.ico",
+			},
+		},
+		{
+			name: "image files describe themselves",
+			page: urlfetcher.Response{ContentType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}},
+			want: Metadata{
+				Title:   "page",
+				Image:   "https://example.com/posts/page",
+				Favicon: "https://example.com/favicon.ico",
+			},
+		},
+	}
+
+	const pageURL = "https://example.com/posts/page"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &Unfurler{Fetch: pages{pageURL: tt.page}.fetch}
+			got, err := u.Unfurl(context.Background(), pageURL)
+			if err != nil {
+				t.Fatalf("Unfurl: %v", err)
+			}
+			tt.want.URL = pageURL
+			if got != tt.want {
+				t.Errorf("Unfurl = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnfurlOEmbed(t *testing.T) {
+	const video = `{"type":"video","title":"A video","html":"<iframe></iframe>","width":"640","height":360.0,
+		"provider_name":"YouTube","thumbnail_url":"https://i.ytimg.com/vi/x/hq.jpg","thumbnail_width":null}`
+
+	tests := []struct {
+		name    string
+		pageURL string
+		pages   pages
+		want    *OEmbed
+		title   string
+		wantErr bool
+	}{
+		{
+			name:    "known provider when the page fails",
+			pageURL: "https://www.youtube.com/watch?v=x",
+			pages: pages{
+				"https://www.youtube.com/oembed?format=json&url=https%3A%2F%2Fwww.youtube.com%2Fwatch%3Fv%3Dx": {Data: []byte(video)},
+			},
+			want:  &OEmbed{Type: "video", Title: "A video", HTML: "<iframe></iframe>", Width: 640, Height: 360, ProviderName: "YouTube", ThumbnailURL: "https://i.ytimg.com/vi/x/hq.jpg"},
+			title: "A video",
+		},
+		{
+			name:    "endpoint linked from the page",
+			pageURL: "https://blog.example.com/post",
+			pages: pages{
+				"https://blog.example.com/post": htmlPage(`<head><title>Post</title>
+					<link rel="alternate" type="application/json+oembed" href="/oembed?url=post"></head>`),
+				"https://blog.example.com/oembed?url=post": {Data: []byte(`{"type":"rich","html":"<div></div>","width":"100%"}`)},
+			},
+			want:  &OEmbed{Type: "rich", HTML: "<div></div>"},
+			title: "Post",
+		},
+		{
+			name:    "invalid type is ignored",
+			pageURL: "https://blog.example.com/post",
+			pages: pages{
+				"https://blog.example.com/post": htmlPage(`<head><title>Post</title>
+					<link rel="alternate" type="application/json+oembed" href="/oembed"></head>`),
+				"https://blog.example.com/oembed": {Data: []byte(`{"type":"script"}`)},
+			},
+			title: "Post",
+		},
+		{
+			name:    "page and provider fail",
+			pageURL: "https://youtu.be/x",
+			pages:   pages{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &Unfurler{Fetch: tt.pages.fetch, Providers: DefaultProviders}
+			got, err := u.Unfurl(context.Background(), tt.pageURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unfurl error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Title != tt.title {
+				t.Errorf("Title = %q, want %q", got.Title, tt.title)
+			}
+			if (got.OEmbed == nil) != (tt.want == nil) || (got.OEmbed != nil && *got.OEmbed != *tt.want) {
+				t.Errorf("OEmbed = %+v, want %+v", got.OEmbed, tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderEndpoint(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.youtube.com/watch?v=abc", "https://www.youtube.com/oembed"},
+		{"https://m.youtube.com/watch?v=abc", "https://www.youtube.com/oembed"},
+		{"https://youtu.be/abc", "https://www.youtube.com/oembed"},
+		{"https://x.com/user/status/1", "https://publish.twitter.com/oembed"},
+		{"https://vimeo.com/123/", "https://vimeo.com/api/oembed.json"},
+		{"https://open.spotify.com/track/abc", "https://open.spotify.com/oembed"},
+		{"https://youtube.com/channel/abc", ""},
+		{"https://example.com/watch", ""},
+	}
+	u := New()
+	for _, tt := range tests {
+		got := u.providerEndpoint(tt.url)
+		if tt.want == "" {
+			if got != "" {
+				t.Errorf("providerEndpoint(%q) = %q, want none", tt.url, got)
+			}
+			continue
+		}
+		if want := tt.want + "?format=json&url=" + url.QueryEscape(tt.url); got != want {
+			t.Errorf("providerEndpoint(%q) = %q, want %q", tt.url, got, want)
+		}
+	}
+}
+
+func TestSizeUnmarshal(t *testing.T) {
+	tests := []struct {
+		json string
+		want size
+	}{
+		{`480`, 480},
+		{`"480"`, 480},
+		{`270.5`, 270},
+		{`null`, 0},
+		{`""`, 0},
+		{`"100%"`, 0},
+	}
+	for _, tt := range tests {
+		var s size
+		if err := json.Unmarshal([]byte(tt.json), &s); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.json, err)
+			continue
+		}
+		if s != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.json, s, tt.want)
+		}
+	}
+}
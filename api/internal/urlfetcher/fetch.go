@@ -72,12 +72,32 @@ func validateURL(raw string) (*url.URL, []netip.Addr, error) {
 }
 
 func SafeFetchFile(ctx context.Context, rawURL string) ([]byte, string, error) {
+	res, err := SafeFetch(ctx, rawURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return res.Data, res.ContentType, nil
+}
+
+// Response is the result of SafeFetch. NotModified reports a 304 answer to
+// a conditional request, which has no data.
+type Response struct {
+	Data         []byte
+	ContentType  string
+	ETag         string
+	LastModified string
+	NotModified  bool
+}
+
+// SafeFetch fetches a URL like SafeFetchFile, sending the given request
+// headers, such as If-None-Match and If-Modified-Since.
+func SafeFetch(ctx context.Context, rawURL string, header http.Header) (Response, error) {
 	ctx, cancel := context.WithTimeout(ctx, RequestTimeout)
 	defer cancel()
 
 	u, ips, err := validateURL(rawURL)
 	if err != nil {
-		return nil, "", err
+		return Response{}, err
 	}
 
 	dialer := &net.Dialer{Timeout: 7 * time.Second}
@@ -106,27 +126,41 @@ func SafeFetchFile(ctx context.Context, rawURL string) ([]byte, string, error) {
 	client := &http.Client{Transport: transport}
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
-		return nil, "", err
+		return Response{}, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, "", err
+		return Response{}, err
 	}
 	defer resp.Body.Close()
 
+	res := Response{
+		ContentType:  resp.Header.Get("Content-Type"),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		res.NotModified = true
+		return res, nil
+	}
+
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, "", errors.New("bad status: " + resp.Status)
+		return Response{}, errors.New("bad status: " + resp.Status)
 	}
 
 	lr := &io.LimitedReader{R: resp.Body, N: MaxDownloadBytes + 1}
 	data, err := io.ReadAll(lr)
 	if err != nil {
-		return nil, "", err
+		return Response{}, err
 	}
 	if len(data) > MaxDownloadBytes {
-		return nil, "", errors.New("file too large")
+		return Response{}, errors.New("file too large")
 	}
 
-	return data, resp.Header.Get("Content-Type"), nil
+	res.Data = data
+	return res, nil
 }
@@ -0,0 +1,507 @@
+package util
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLToTipTap converts an HTML fragment or document to TipTap JSON format
+func HTMLToTipTap(s string) (string, error) {
+	root, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return "", err
+	}
+
+	jsonBytes, err := json.Marshal(HTMLNodeToTipTap(root))
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
+// HTMLNodeToTipTap converts a parsed HTML tree to a TipTap document. Markup
+// the editor has no node for is reduced to its text; scripts, styles and
+// forms are dropped.
+func HTMLNodeToTipTap(n *html.Node) TipTapNode {
+	b := &htmlBlocks{}
+	b.children(n, nil)
+	b.flush()
+
+	// If there's no content, add an empty paragraph
+	if len(b.nodes) == 0 {
+		b.nodes = append(b.nodes, TipTapNode{Type: "paragraph"})
+	}
+
+	return TipTapNode{Type: "doc", Content: b.nodes}
+}
+
+// htmlBlocks collects block nodes. Inline content is gathered into a
+// pending paragraph that is closed by the next block element.
+type htmlBlocks struct {
+	nodes  []TipTapNode
+	inline []TipTapNode
+}
+
+func (b *htmlBlocks) flush() {
+	content := trimInline(b.inline)
+	b.inline = nil
+	if len(content) > 0 {
+		b.nodes = append(b.nodes, TipTapNode{Type: "paragraph", Content: content})
+	}
+}
+
+func (b *htmlBlocks) block(node TipTapNode) {
+	b.flush()
+	b.nodes = append(b.nodes, node)
+}
+
+func (b *htmlBlocks) children(n *html.Node, marks []TipTapMark) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.node(child, marks)
+	}
+}
+
+func (b *htmlBlocks) node(n *html.Node, marks []TipTapMark) {
+	switch n.Type {
+	case html.TextNode:
+		if text := collapseSpace(n.Data); text != "" {
+			b.inline = append(b.inline, textNode(text, marks))
+		}
+		return
+	case html.DocumentNode:
+		b.children(n, marks)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Head,
+		atom.Form, atom.Button, atom.Input, atom.Select, atom.Textarea,
+		atom.Iframe, atom.Object, atom.Embed, atom.Svg, atom.Canvas:
+		return
+
+	case atom.P:
+		b.flush()
+		b.children(n, marks)
+		b.flush()
+
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		content := trimInline(inlineContent(n, nil))
+		if len(content) > 0 {
+			b.block(TipTapNode{Type: "heading", Attrs: map[string]interface{}{"level": level}, Content: content})
+		}
+
+	case atom.Blockquote:
+		if content := blockContent(n); len(content) > 0 {
+			b.block(TipTapNode{Type: "blockquote", Content: content})
+		}
+
+	case atom.Pre:
+		code := strings.TrimSuffix(textContent(n), "\n")
+		node := TipTapNode{Type: "codeBlock"}
+		if code != "" {
+			node.Content = []TipTapNode{{Type: "text", Text: code}}
+		}
+		if lang := codeLanguage(n); lang != "" {
+			node.Attrs = map[string]interface{}{"language": lang}
+		}
+		b.block(node)
+
+	case atom.Ul, atom.Ol:
+		if attr(n, "data-type") == "taskList" {
+			b.taskList(n)
+		} else {
+			b.list(n)
+		}
+
+	case atom.Li:
+		// A list item outside a list reads as a paragraph.
+		b.flush()
+		b.children(n, marks)
+		b.flush()
+
+	case atom.Hr:
+		b.block(TipTapNode{Type: "horizontalRule"})
+
+	case atom.Img:
+		if src := attr(n, "src"); src != "" {
+			b.block(TipTapNode{Type: "image", Attrs: map[string]interface{}{"src": src, "name": attr(n, "alt")}})
+		}
+
+	case atom.Br:
+		b.inline = append(b.inline, TipTapNode{Type: "hardBreak"})
+
+	case atom.Table:
+		b.table(n)
+
+	case atom.Strong, atom.B:
+		b.children(n, withMark(marks, TipTapMark{Type: "bold"}))
+	case atom.Em, atom.I, atom.Cite:
+		b.children(n, withMark(marks, TipTapMark{Type: "italic"}))
+	case atom.S, atom.Del, atom.Strike:
+		b.children(n, withMark(marks, TipTapMark{Type: "strike"}))
+	case atom.Code, atom.Kbd, atom.Samp:
+		b.children(n, withMark(marks, TipTapMark{Type: "code"}))
+	case atom.A:
+		if mark, ok := linkMark(n); ok {
+			b.children(n, withMark(marks, mark))
+		} else {
+			b.children(n, marks)
+		}
+
+	default:
+		if el, ok := editorElements[n.Data]; ok {
+			b.editorNode(n, el)
+			return
+		}
+		if isBlockElement(n) {
+			b.flush()
+			b.children(n, marks)
+			b.flush()
+		} else {
+			b.children(n, marks)
+		}
+	}
+}
+
+func (b *htmlBlocks) list(n *html.Node) {
+	listType := "bulletList"
+	var attrs map[string]interface{}
+	if n.DataAtom == atom.Ol {
+		listType = "orderedList"
+		if start, err := strconv.Atoi(attr(n, "start")); err == nil && start != 1 {
+			attrs = map[string]interface{}{"start": start}
+		}
+	}
+
+	list := TipTapNode{Type: listType, Attrs: attrs}
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+		content := blockContent(li)
+		// If list item has no block content, add an empty paragraph
+		if len(content) == 0 {
+			content = []TipTapNode{{Type: "paragraph"}}
+		}
+		list.Content = append(list.Content, TipTapNode{Type: "listItem", Content: content})
+	}
+	if len(list.Content) > 0 {
+		b.block(list)
+	}
+}
+
+// editorElement is an element the editor renders one of its nodes to. The
+// first attribute is required.
+type editorElement struct {
+	node  string
+	attrs []string
+}
+
+var editorElements = map[string]editorElement{
+	"file-node":     {node: "attachment", attrs: []string{"src", "name"}},
+	"sub-page-node": {node: "subPage", attrs: []string{"noteId", "title"}},
+	"view-node":     {node: "viewNode", attrs: []string{"viewId", "viewType", "name"}},
+}
+
+func (b *htmlBlocks) editorNode(n *html.Node, el editorElement) {
+	attrs := map[string]interface{}{}
+	for _, key := range el.attrs {
+		// The parser lowercases attribute names.
+		attrs[key] = attr(n, strings.ToLower(key))
+	}
+	if attrs[el.attrs[0]] != "" {
+		b.block(TipTapNode{Type: el.node, Attrs: attrs})
+	}
+}
+
+// taskList converts a list written the way the editor renders task lists,
+// with the state of each item in data-checked.
+func (b *htmlBlocks) taskList(n *html.Node) {
+	list := TipTapNode{Type: "taskList"}
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+		content := blockContent(li)
+		if len(content) == 0 {
+			content = []TipTapNode{{Type: "paragraph"}}
+		}
+		list.Content = append(list.Content, TipTapNode{
+			Type:    "taskItem",
+			Attrs:   map[string]interface{}{"checked": attr(li, "data-checked") == "true"},
+			Content: content,
+		})
+	}
+	if len(list.Content) > 0 {
+		b.block(list)
+	}
+}
+
+// table converts the rows of a table, including those of its head, body
+// and foot sections.
+func (b *htmlBlocks) table(n *html.Node) {
+	table := TipTapNode{Type: "table"}
+	var rows func(*html.Node)
+	rows = func(parent *html.Node) {
+		for child := parent.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.DataAtom {
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				rows(child)
+			case atom.Tr:
+				if row := tableRow(child); len(row.Content) > 0 {
+					table.Content = append(table.Content, row)
+				}
+			}
+		}
+	}
+	rows(n)
+
+	if len(table.Content) > 0 {
+		b.block(table)
+	} else {
+		b.flush()
+	}
+}
+
+func tableRow(tr *html.Node) TipTapNode {
+	row := TipTapNode{Type: "tableRow"}
+	for cell := tr.FirstChild; cell != nil; cell = cell.NextSibling {
+		if cell.Type != html.ElementNode || (cell.DataAtom != atom.Td && cell.DataAtom != atom.Th) {
+			continue
+		}
+		cellType := "tableCell"
+		if cell.DataAtom == atom.Th {
+			cellType = "tableHeader"
+		}
+		content := blockContent(cell)
+		if len(content) == 0 {
+			content = []TipTapNode{{Type: "paragraph"}}
+		}
+		var attrs map[string]interface{}
+		for _, key := range []string{"colspan", "rowspan"} {
+			if span, err := strconv.Atoi(attr(cell, key)); err == nil && span > 1 {
+				if attrs == nil {
+					attrs = map[string]interface{}{}
+				}
+				attrs[key] = span
+			}
+		}
+		row.Content = append(row.Content, TipTapNode{Type: cellType, Attrs: attrs, Content: content})
+	}
+	return row
+}
+
+// blockContent converts the children of n to block nodes.
+func blockContent(n *html.Node) []TipTapNode {
+	inner := &htmlBlocks{}
+	inner.children(n, nil)
+	inner.flush()
+	return inner.nodes
+}
+
+// inlineContent converts the children of n to inline nodes only; block
+// markup inside them is flattened.
+func inlineContent(n *html.Node, marks []TipTapMark) []TipTapNode {
+	inner := &htmlBlocks{}
+	inner.children(n, marks)
+	content := inner.inline
+	for _, node := range inner.nodes {
+		for _, child := range node.Content {
+			if child.Type == "text" || child.Type == "hardBreak" {
+				content = append(content, child)
+			}
+		}
+	}
+	return content
+}
+
+func textNode(text string, marks []TipTapMark) TipTapNode {
+	node := TipTapNode{Type: "text", Text: text}
+	if len(marks) > 0 {
+		node.Marks = marks
+	}
+	return node
+}
+
+// withMark returns marks with m added. Code excludes other formatting in
+// the editor, so it replaces all marks but links.
+func withMark(marks []TipTapMark, m TipTapMark) []TipTapMark {
+	out := make([]TipTapMark, 0, len(marks)+1)
+	for _, existing := range marks {
+		if existing.Type == m.Type {
+			return marks
+		}
+		if m.Type == "code" && existing.Type != "link" {
+			continue
+		}
+		if existing.Type == "code" && m.Type != "link" {
+			return marks
+		}
+		out = append(out, existing)
+	}
+	return append(out, m)
+}
+
+// linkMark builds a link mark for an anchor with a safe target: a web or
+// mail address, or a path on this site.
+func linkMark(n *html.Node) (TipTapMark, bool) {
+	href := strings.TrimSpace(attr(n, "href"))
+	u, err := url.Parse(href)
+	if href == "" || err != nil {
+		return TipTapMark{}, false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+	case "":
+		if u.Host != "" || !strings.HasPrefix(u.Path, "/") {
+			return TipTapMark{}, false
+		}
+	default:
+		return TipTapMark{}, false
+	}
+	return TipTapMark{
+		Type: "link",
+		Attrs: map[string]interface{}{
+			"href":   href,
+			"target": "_blank",
+		},
+	}, true
+}
+
+// trimInline drops leading and trailing whitespace and line breaks from
+// the inline content of a block, and the space doubled where text nodes
+// meet.
+func trimInline(nodes []TipTapNode) []TipTapNode {
+	out := make([]TipTapNode, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Type == "text" {
+			// Text at the start of the block or a line, or after a space,
+			// starts without one.
+			if len(out) == 0 || out[len(out)-1].Type != "text" || strings.HasSuffix(out[len(out)-1].Text, " ") {
+				node.Text = strings.TrimLeft(node.Text, " ")
+			}
+			if node.Text == "" {
+				continue
+			}
+		} else if node.Type == "hardBreak" && len(out) == 0 {
+			continue
+		}
+		out = append(out, node)
+	}
+
+	for len(out) > 0 {
+		last := &out[len(out)-1]
+		if last.Type == "hardBreak" {
+			out = out[:len(out)-1]
+			continue
+		}
+		if last.Type == "text" {
+			last.Text = strings.TrimRight(last.Text, " ")
+			if last.Text == "" {
+				out = out[:len(out)-1]
+				continue
+			}
+		}
+		break
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// collapseSpace turns runs of whitespace into single spaces, as browsers
+// render them.
+func collapseSpace(s string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range s {
+		switch r {
+		case ' ', '\t', '\n', '\r', '\f':
+			space = true
+			continue
+		}
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+		sb.WriteRune(r)
+	}
+	if space {
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.Br {
+			sb.WriteByte('\n')
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// codeLanguage reads a "language-x" or "lang-x" class from a pre element or
+// its code child.
+func codeLanguage(pre *html.Node) string {
+	nodes := []*html.Node{pre}
+	for child := pre.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == atom.Code {
+			nodes = append(nodes, child)
+		}
+	}
+	for _, n := range nodes {
+		for _, class := range strings.Fields(attr(n, "class")) {
+			for _, prefix := range []string{"language-", "lang-"} {
+				if strings.HasPrefix(class, prefix) && len(class) > len(prefix) {
+					return class[len(prefix):]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func isBlockElement(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Div, atom.Section, atom.Article, atom.Main, atom.Header, atom.Footer,
+		atom.Aside, atom.Nav, atom.Figure, atom.Figcaption, atom.Table, atom.Thead,
+		atom.Tbody, atom.Tfoot, atom.Dl, atom.Dt, atom.Dd, atom.Address, atom.Details,
+		atom.Summary, atom.Body, atom.Html, atom.Center:
+		return true
+	}
+	return false
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
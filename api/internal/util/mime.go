@@ -0,0 +1,26 @@
+package util
+
+import (
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// DetectContentType returns the MIME type of a file from the first bytes of
+// its content, sniffed as browsers do. The extension of the file name is
+// used when the content alone is not telling, as for plain text formats
+// and most audio and video containers.
+func DetectContentType(name string, head []byte) string {
+	sniffed := http.DetectContentType(head)
+	byExt := mime.TypeByExtension(strings.ToLower(filepath.Ext(name)))
+	if byExt == "" {
+		return sniffed
+	}
+
+	switch mediaType, _, _ := mime.ParseMediaType(sniffed); mediaType {
+	case "application/octet-stream", "text/plain", "application/zip", "application/xml", "text/xml":
+		return byExt
+	}
+	return sniffed
+}
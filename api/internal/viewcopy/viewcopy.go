@@ -0,0 +1,112 @@
+// Package viewcopy copies the data and view objects of views, for duplicated
+// views and view templates.
+package viewcopy
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/model"
+	"github.com/collabreef/collabreef/internal/util"
+	"github.com/collabreef/collabreef/internal/whiteboard"
+)
+
+// Object is the content of a view object, without its ownership.
+type Object struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+// Load reads the objects of a view.
+func Load(d db.DB, viewID string) ([]Object, error) {
+	// A page size of -1 disables the limit.
+	objects, err := d.FindViewObjects(model.ViewObjectFilter{ViewID: viewID, PageNumber: 1, PageSize: -1})
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Object, len(objects))
+	for i, o := range objects {
+		list[i] = Object{ID: o.ID, Name: o.Name, Type: o.Type, Data: o.Data}
+	}
+	return list, nil
+}
+
+// Encode stores objects as a JSON array.
+func Encode(objects []Object) (string, error) {
+	if objects == nil {
+		objects = []Object{}
+	}
+	b, err := json.Marshal(objects)
+	return string(b), err
+}
+
+// Decode reads objects stored by Encode.
+func Decode(s string) ([]Object, error) {
+	var list []Object
+	if s == "" {
+		return list, nil
+	}
+	err := json.Unmarshal([]byte(s), &list)
+	return list, err
+}
+
+// Create saves view v with copies of objects. Every object gets a new id,
+// and the old ids are rewritten in the view data and in the data of the
+// objects, so that references between them, such as the kanban column
+// order, whiteboard edges and timeline dependencies, point at the copies.
+// The objects are owned by the creator of v. Run it in a transaction.
+func Create(d db.DB, v model.View, objects []Object) (model.View, error) {
+	ids := make([]string, len(objects))
+	for i := range objects {
+		ids[i] = util.NewId()
+	}
+	remap := remapper(objects, ids)
+
+	v.Data = remap.Replace(v.Data)
+	if err := d.CreateView(v); err != nil {
+		return model.View{}, err
+	}
+	if v.Type == "whiteboard" {
+		if err := whiteboard.IndexCanvas(d, v.ID, v.Data); err != nil {
+			return model.View{}, err
+		}
+	}
+
+	for i, o := range objects {
+		vo := whiteboard.PrepareObject(model.ViewObject{
+			ID:        ids[i],
+			ViewID:    v.ID,
+			Name:      o.Name,
+			Type:      o.Type,
+			Data:      remap.Replace(o.Data),
+			CreatedAt: v.CreatedAt,
+			CreatedBy: v.CreatedBy,
+			UpdatedAt: v.CreatedAt,
+			UpdatedBy: v.CreatedBy,
+		})
+		if err := d.CreateViewObject(vo); err != nil {
+			return model.View{}, err
+		}
+	}
+
+	return v, nil
+}
+
+// remapper rewrites the ids of objects to ids where they appear as a whole
+// JSON string, also inside JSON that is stored as a string. Templates may
+// hold ids such as "1", which must not change every 1 in the data.
+func remapper(objects []Object, ids []string) *strings.Replacer {
+	pairs := make([]string, 0, len(objects)*4)
+	for i, o := range objects {
+		if o.ID == "" {
+			continue
+		}
+		pairs = append(pairs,
+			`"`+o.ID+`"`, `"`+ids[i]+`"`,
+			`\"`+o.ID+`\"`, `\"`+ids[i]+`\"`)
+	}
+	return strings.NewReplacer(pairs...)
+}
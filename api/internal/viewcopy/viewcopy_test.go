@@ -0,0 +1,119 @@
+package viewcopy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/db/dbtest"
+	"github.com/collabreef/collabreef/internal/model"
+)
+
+func TestCreateRemapsIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		viewData string
+		objects  []Object
+		// want are the expected view data and object data, with {id} for
+		// the new id of the object with that old id.
+		wantView string
+		want     map[string]string
+	}{
+		{
+			name:     "kanban column order",
+			viewData: `{"order":["col-a","col-b"]}`,
+			objects: []Object{
+				{ID: "col-a", Name: "To do", Type: "kanban_column", Data: `{"items":[]}`},
+				{ID: "col-b", Name: "Done", Type: "kanban_column", Data: `{"items":[]}`},
+			},
+			wantView: `{"order":["{col-a}","{col-b}"]}`,
+			want:     map[string]string{"To do": `{"items":[]}`, "Done": `{"items":[]}`},
+		},
+		{
+			name: "short ids leave numbers and text alone",
+			objects: []Object{
+				{ID: "1", Name: "Design", Type: "timeline_task", Data: `{"start":"2024-01-01","end":"2024-01-10"}`},
+				{ID: "2", Name: "Build", Type: "timeline_task", Data: `{"start":"2024-01-11","end":"2024-01-21","dependencies":["1"],"progress":12}`},
+			},
+			want: map[string]string{
+				"Design": `{"start":"2024-01-01","end":"2024-01-10"}`,
+				"Build":  `{"start":"2024-01-11","end":"2024-01-21","dependencies":["{1}"],"progress":12}`,
+			},
+		},
+		{
+			name: "ids inside JSON stored as a string",
+			objects: []Object{
+				{ID: "n1", Name: "Start", Type: "whiteboard_note", Data: `"{\"text\":\"n1 starts\"}"`},
+				{ID: "e1", Name: "Edge", Type: "whiteboard_edge", Data: `"{\"from\":\"n1\",\"label\":\"after n1\"}"`},
+			},
+			want: map[string]string{
+				"Start": `"{\"text\":\"n1 starts\"}"`,
+				"Edge":  `"{\"from\":\"{n1}\",\"label\":\"after n1\"}"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := dbtest.New(t)
+			v := model.View{WorkspaceID: "w1", ID: "copy", Name: "Copy", Type: "kanban", Data: tt.viewData, CreatedAt: "now", CreatedBy: "u1"}
+			created, err := Create(d, v, tt.objects)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stored, err := d.FindViewObjects(model.ViewObjectFilter{ViewID: "copy", PageNumber: 1, PageSize: -1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(stored) != len(tt.objects) {
+				t.Fatalf("stored %d objects, want %d", len(stored), len(tt.objects))
+			}
+			newIDs := map[string]string{}
+			for _, o := range stored {
+				for _, old := range tt.objects {
+					if old.Name == o.Name {
+						newIDs[old.ID] = o.ID
+					}
+				}
+				if o.CreatedBy != "u1" || o.UpdatedBy != "u1" {
+					t.Errorf("%s is owned by %q and %q, want u1", o.Name, o.CreatedBy, o.UpdatedBy)
+				}
+			}
+			expand := func(s string) string {
+				for old, id := range newIDs {
+					if old == id {
+						t.Errorf("object %s kept its id", old)
+					}
+					s = strings.ReplaceAll(s, "{"+old+"}", id)
+				}
+				return s
+			}
+
+			if want := expand(tt.wantView); created.Data != want {
+				t.Errorf("view data = %s, want %s", created.Data, want)
+			}
+			for _, o := range stored {
+				if want := expand(tt.want[o.Name]); o.Data != want {
+					t.Errorf("data of %s = %s, want %s", o.Name, o.Data, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	encoded, err := Encode(nil)
+	if err != nil || encoded != "[]" {
+		t.Errorf("Encode(nil) = %q, %v, want []", encoded, err)
+	}
+	objects := []Object{{ID: "a", Name: "A", Type: "map_marker", Data: `{"lat":1,"lng":2}`}}
+	if encoded, err = Encode(objects); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(encoded)
+	if err != nil || len(decoded) != 1 || decoded[0] != objects[0] {
+		t.Errorf("Decode(Encode(objects)) = %+v, %v, want %+v", decoded, err, objects)
+	}
+	if decoded, err := Decode(""); err != nil || len(decoded) != 0 {
+		t.Errorf("Decode(\"\") = %+v, %v, want none", decoded, err)
+	}
+}
@@ -0,0 +1,383 @@
+package viewobject
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// schemas holds the data schema of each object type. They follow the data
+// types of the web client (web/src/types/view.ts). Styling fields are
+// optional because the clients fall back to defaults, but when present they
+// must have the right type.
+var schemas = map[string]func(*checker, object){
+	"calendar_slot":     calendarSlot,
+	"map_marker":        mapMarker,
+	"kanban_column":     kanbanColumn,
+	"whiteboard_stroke": whiteboardStroke,
+	"whiteboard_shape":  whiteboardShape,
+	"whiteboard_text":   whiteboardText,
+	"whiteboard_note":   whiteboardNote,
+	"whiteboard_view":   whiteboardView,
+	"whiteboard_edge":   whiteboardEdge,
+	"table_property":    tableProperty,
+	"timeline_task":     timelineTask,
+}
+
+const (
+	dateLayout  = "2006-01-02"
+	clockLayout = "15:04"
+)
+
+func calendarSlot(c *checker, o object) {
+	start, hasStart := c.date(o, "date", true)
+	end, hasEnd := c.date(o, "end_date", false)
+	if hasStart && hasEnd && end.Before(start) {
+		c.add(o.at("end_date"), "must not be before date")
+	}
+
+	startTime, hasStartTime := c.clock(o, "start_time")
+	endTime, hasEndTime := c.clock(o, "end_time")
+	sameDay := !hasEnd || end.Equal(start)
+	if hasStartTime && hasEndTime && sameDay && endTime.Before(startTime) {
+		c.add(o.at("end_time"), "must not be before start_time")
+	}
+
+	c.boolean(o, "is_all_day")
+	c.str(o, "color", false)
+}
+
+func mapMarker(c *checker, o object) {
+	c.between(o, "lat", -90, 90)
+	c.between(o, "lng", -180, 180)
+	c.str(o, "color", false)
+}
+
+func kanbanColumn(c *checker, o object) {
+	c.str(o, "color", false)
+
+	items, ok := c.array(o, "items", false)
+	if !ok {
+		return
+	}
+	seen := map[string]bool{}
+	for _, item := range items {
+		if id, ok := c.str(item, "id", true); ok {
+			if id == "" {
+				c.add(item.at("id"), "must not be empty")
+			} else if seen[id] {
+				c.add(item.at("id"), "is duplicated")
+			}
+			seen[id] = true
+		}
+		c.str(item, "title", true)
+		c.str(item, "note_id", false)
+		c.date(item, "due_date", false)
+	}
+}
+
+func whiteboardStroke(c *checker, o object) {
+	if points, ok := c.array(o, "points", true); ok {
+		if len(points) == 0 {
+			c.add(o.at("points"), "must not be empty")
+		}
+		for _, p := range points {
+			c.number(p, "x", true)
+			c.number(p, "y", true)
+		}
+	}
+	c.str(o, "color", false)
+	c.positive(o, "width")
+}
+
+func whiteboardShape(c *checker, o object) {
+	c.oneOf(o, "type", true, "rectangle", "circle", "line")
+	c.point(o, "position")
+	if d, ok := c.object(o, "dimensions", true); ok {
+		c.number(d, "width", true)
+		c.number(d, "height", true)
+	}
+	c.str(o, "color", false)
+	c.positive(o, "strokeWidth")
+	c.boolean(o, "filled")
+}
+
+func whiteboardText(c *checker, o object) {
+	c.point(o, "position")
+	c.str(o, "text", true)
+	c.str(o, "color", false)
+	c.positive(o, "fontSize")
+	c.oneOf(o, "fontFamily", false, "sans-serif", "serif", "monospace")
+	c.oneOf(o, "fontWeight", false, "normal", "bold")
+	c.oneOf(o, "fontStyle", false, "normal", "italic")
+	c.oneOf(o, "textDecoration", false, "none", "underline")
+}
+
+func whiteboardNote(c *checker, o object) {
+	c.point(o, "position")
+	c.positive(o, "width")
+	c.positive(o, "height")
+}
+
+func whiteboardView(c *checker, o object) {
+	c.point(o, "position")
+	if id, ok := c.str(o, "viewId", true); ok && id == "" {
+		c.add(o.at("viewId"), "must not be empty")
+	}
+	c.positive(o, "width")
+	c.positive(o, "height")
+}
+
+func whiteboardEdge(c *checker, o object) {
+	c.str(o, "startObjectId", false)
+	c.str(o, "endObjectId", false)
+	c.oneOf(o, "startConnectionPoint", false, "top", "bottom", "left", "right")
+	c.oneOf(o, "endConnectionPoint", false, "top", "bottom", "left", "right")
+	c.point(o, "startPoint")
+	c.point(o, "endPoint")
+	c.oneOf(o, "curveType", false, "straight", "bezier", "elbow")
+	c.oneOf(o, "arrowType", false, "none", "end", "start", "both")
+	c.oneOf(o, "lineStyle", false, "solid", "dashed", "dotted")
+	c.str(o, "color", false)
+	c.positive(o, "strokeWidth")
+}
+
+// tableProperty is a column of a table view. Select kinds list their options;
+// relation properties may restrict their values to the rows of another table.
+func tableProperty(c *checker, o object) {
+	c.oneOf(o, "kind", true, "text", "number", "select", "multi_select", "date", "person", "checkbox", "relation")
+	c.str(o, "view_id", false)
+
+	options, ok := c.array(o, "options", false)
+	if !ok {
+		return
+	}
+	seen := map[string]bool{}
+	for _, opt := range options {
+		if id, ok := c.str(opt, "id", true); ok {
+			if id == "" {
+				c.add(opt.at("id"), "must not be empty")
+			} else if seen[id] {
+				c.add(opt.at("id"), "is duplicated")
+			}
+			seen[id] = true
+		}
+		c.str(opt, "name", true)
+		c.str(opt, "color", false)
+	}
+}
+
+// timelineTask is a task of a timeline view. Links to the parent task and to
+// the tasks it depends on are checked against the other tasks of the view
+// by the timeline package.
+func timelineTask(c *checker, o object) {
+	start, hasStart := c.date(o, "start", true)
+	end, hasEnd := c.date(o, "end", true)
+	if hasStart && hasEnd && end.Before(start) {
+		c.add(o.at("end"), "must not be before start")
+	}
+	if f, ok := c.number(o, "progress", false); ok && (f < 0 || f > 100) {
+		c.add(o.at("progress"), "must be between 0 and 100")
+	}
+	c.str(o, "parent_id", false)
+	c.ids(o, "dependencies")
+	c.str(o, "color", false)
+}
+
+// ---------- Field checks ----------
+
+// object is a decoded JSON object and its path in the view object.
+type object struct {
+	path   string
+	fields map[string]interface{}
+}
+
+func (o object) at(key string) string {
+	return o.path + "." + key
+}
+
+// checker collects the field errors of one view object.
+type checker struct {
+	errs []FieldError
+}
+
+func (c *checker) add(field, message string) {
+	c.errs = append(c.errs, FieldError{Field: field, Message: message})
+}
+
+// value returns the value of a field. A null optional field counts as
+// missing; a missing or null required field is reported.
+func (c *checker) value(o object, key string, required bool) (interface{}, bool) {
+	v, ok := o.fields[key]
+	switch {
+	case ok && v != nil:
+		return v, true
+	case required && ok:
+		c.add(o.at(key), "must not be null")
+	case required:
+		c.add(o.at(key), "is required")
+	}
+	return nil, false
+}
+
+func (c *checker) number(o object, key string, required bool) (float64, bool) {
+	v, ok := c.value(o, key, required)
+	if !ok {
+		return 0, false
+	}
+	f, ok := v.(float64)
+	if !ok || math.IsNaN(f) || math.IsInf(f, 0) {
+		c.add(o.at(key), "must be a finite number")
+		return 0, false
+	}
+	return f, true
+}
+
+// positive checks an optional number that must be greater than zero.
+func (c *checker) positive(o object, key string) {
+	if f, ok := c.number(o, key, false); ok && f <= 0 {
+		c.add(o.at(key), "must be greater than 0")
+	}
+}
+
+// between checks a required number in [min, max].
+func (c *checker) between(o object, key string, min, max float64) {
+	if f, ok := c.number(o, key, true); ok && (f < min || f > max) {
+		c.add(o.at(key), fmt.Sprintf("must be between %g and %g", min, max))
+	}
+}
+
+func (c *checker) str(o object, key string, required bool) (string, bool) {
+	v, ok := c.value(o, key, required)
+	if !ok {
+		return "", false
+	}
+	s, ok := v.(string)
+	if !ok {
+		c.add(o.at(key), "must be a string")
+		return "", false
+	}
+	return s, true
+}
+
+func (c *checker) boolean(o object, key string) {
+	if v, ok := c.value(o, key, false); ok {
+		if _, ok := v.(bool); !ok {
+			c.add(o.at(key), "must be a boolean")
+		}
+	}
+}
+
+func (c *checker) oneOf(o object, key string, required bool, values ...string) {
+	s, ok := c.str(o, key, required)
+	if !ok {
+		return
+	}
+	for _, v := range values {
+		if s == v {
+			return
+		}
+	}
+	c.add(o.at(key), "must be one of "+strings.Join(values, ", "))
+}
+
+func (c *checker) date(o object, key string, required bool) (time.Time, bool) {
+	s, ok := c.str(o, key, required)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		c.add(o.at(key), "must be a date in YYYY-MM-DD format")
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// clock checks an optional time of day. Empty strings count as missing.
+func (c *checker) clock(o object, key string) (time.Time, bool) {
+	s, ok := c.str(o, key, false)
+	if !ok || s == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(clockLayout, s)
+	if err != nil {
+		c.add(o.at(key), "must be a time in HH:MM format")
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func (c *checker) object(o object, key string, required bool) (object, bool) {
+	v, ok := c.value(o, key, required)
+	if !ok {
+		return object{}, false
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		c.add(o.at(key), "must be an object")
+		return object{}, false
+	}
+	return object{path: o.at(key), fields: m}, true
+}
+
+// array returns the elements of an array of objects.
+func (c *checker) array(o object, key string, required bool) ([]object, bool) {
+	v, ok := c.value(o, key, required)
+	if !ok {
+		return nil, false
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		c.add(o.at(key), "must be an array")
+		return nil, false
+	}
+	// Elements that are not objects are reported and skipped.
+	items := make([]object, 0, len(list))
+	for i, e := range list {
+		path := fmt.Sprintf("%s[%d]", o.at(key), i)
+		m, ok := e.(map[string]interface{})
+		if !ok {
+			c.add(path, "must be an object")
+			continue
+		}
+		items = append(items, object{path: path, fields: m})
+	}
+	return items, len(items) > 0 || len(list) == 0
+}
+
+// ids checks an optional array of unique, non-empty ids.
+func (c *checker) ids(o object, key string) {
+	v, ok := c.value(o, key, false)
+	if !ok {
+		return
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		c.add(o.at(key), "must be an array")
+		return
+	}
+	seen := map[string]bool{}
+	for i, e := range list {
+		path := fmt.Sprintf("%s[%d]", o.at(key), i)
+		id, ok := e.(string)
+		switch {
+		case !ok:
+			c.add(path, "must be a string")
+		case id == "":
+			c.add(path, "must not be empty")
+		case seen[id]:
+			c.add(path, "is duplicated")
+		}
+		seen[id] = true
+	}
+}
+
+// point checks a required {x, y} position.
+func (c *checker) point(o object, key string) {
+	if p, ok := c.object(o, key, true); ok {
+		c.number(p, "x", true)
+		c.number(p, "y", true)
+	}
+}
@@ -0,0 +1,81 @@
+// Package viewobject validates the data of view objects. Data is stored as
+// an opaque JSON string, so every write path checks it against the schema of
+// the object type before it reaches the database.
+package viewobject
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// compatibleTypes lists the object types each view type can hold.
+var compatibleTypes = map[string][]string{
+	"calendar":    {"calendar_slot"},
+	"map":         {"map_marker"},
+	"kanban":      {"kanban_column"},
+	"whiteboard":  {"whiteboard_stroke", "whiteboard_shape", "whiteboard_text", "whiteboard_note", "whiteboard_view", "whiteboard_edge"},
+	"spreadsheet": {},
+	"table":       {"table_property"},
+	"timeline":    {"timeline_task"},
+}
+
+// IsCompatible reports whether an object type is valid for a view type.
+func IsCompatible(viewType, objectType string) bool {
+	for _, t := range compatibleTypes[viewType] {
+		if t == objectType {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldError describes one invalid field. Field is a path such as
+// "data.points[2].x".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a view object.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks data against the schema of the object type and returns a
+// *ValidationError listing the invalid fields. Empty data is treated as an
+// empty object, so required fields are still reported.
+func Validate(objectType, data string) error {
+	schema, ok := schemas[objectType]
+	if !ok {
+		return &ValidationError{Fields: []FieldError{{Field: "type", Message: fmt.Sprintf("unknown object type %q", objectType)}}}
+	}
+
+	obj := map[string]interface{}{}
+	if strings.TrimSpace(data) != "" {
+		var v interface{}
+		if err := json.Unmarshal([]byte(data), &v); err != nil {
+			return &ValidationError{Fields: []FieldError{{Field: "data", Message: "must be valid JSON"}}}
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return &ValidationError{Fields: []FieldError{{Field: "data", Message: "must be a JSON object"}}}
+		}
+		obj = m
+	}
+
+	c := &checker{}
+	schema(c, object{path: "data", fields: obj})
+	if len(c.errs) > 0 {
+		return &ValidationError{Fields: c.errs}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package viewobject
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		objectType string
+		data       string
+		want       []FieldError
+	}{
+		{
+			name:       "unknown type",
+			objectType: "sticker",
+			data:       `{}`,
+			want:       []FieldError{{"type", `unknown object type "sticker"`}},
+		},
+		{
+			name:       "invalid json",
+			objectType: "map_marker",
+			data:       `{"lat":`,
+			want:       []FieldError{{"data", "must be valid JSON"}},
+		},
+		{
+			name:       "not an object",
+			objectType: "map_marker",
+			data:       `[1, 2]`,
+			want:       []FieldError{{"data", "must be a JSON object"}},
+		},
+		{
+			name:       "empty data reports required fields",
+			objectType: "map_marker",
+			data:       ``,
+			want:       []FieldError{{"data.lat", "is required"}, {"data.lng", "is required"}},
+		},
+		{
+			name:       "map marker",
+			objectType: "map_marker",
+			data:       `{"lat": 25.03, "lng": 121.56, "color": "#f00"}`,
+		},
+		{
+			name:       "map marker out of range",
+			objectType: "map_marker",
+			data:       `{"lat": 91, "lng": "east"}`,
+			want: []FieldError{
+				{"data.lat", "must be between -90 and 90"},
+				{"data.lng", "must be a finite number"},
+			},
+		},
+		{
+			name:       "calendar slot",
+			objectType: "calendar_slot",
+			data:       `{"date": "2024-03-01", "end_date": "2024-03-02", "start_time": "18:00", "end_time": "09:00", "is_all_day": false}`,
+		},
+		{
+			name:       "calendar slot ending before it starts",
+			objectType: "calendar_slot",
+			data:       `{"date": "2024-03-02", "end_date": "2024-03-01", "start_time": "", "is_all_day": "no"}`,
+			want: []FieldError{
+				{"data.end_date", "must not be before date"},
+				{"data.is_all_day", "must be a boolean"},
+			},
+		},
+		{
+			name:       "calendar slot times on one day",
+			objectType: "calendar_slot",
+			data:       `{"date": "2024-03-01", "start_time": "18:00", "end_time": "9am"}`,
+			want:       []FieldError{{"data.end_time", "must be a time in HH:MM format"}},
+		},
+		{
+			name:       "calendar slot with a null date",
+			objectType: "calendar_slot",
+			data:       `{"date": null, "color": null}`,
+			want:       []FieldError{{"data.date", "must not be null"}},
+		},
+		{
+			name:       "kanban column items",
+			objectType: "kanban_column",
+			data:       `{"items": [{"id": "a", "title": "A"}, {"id": "a", "title": "B", "due_date": "soon"}, {"id": "", "title": "C"}, 7]}`,
+			want: []FieldError{
+				{"data.items[3]", "must be an object"},
+				{"data.items[1].id", "is duplicated"},
+				{"data.items[1].due_date", "must be a date in YYYY-MM-DD format"},
+				{"data.items[2].id", "must not be empty"},
+			},
+		},
+		{
+			name:       "whiteboard stroke",
+			objectType: "whiteboard_stroke",
+			data:       `{"points": [{"x": 0, "y": 0}, {"x": 1.5, "y": -2}], "width": 2}`,
+		},
+		{
+			name:       "whiteboard stroke without points",
+			objectType: "whiteboard_stroke",
+			data:       `{"points": [], "width": 0}`,
+			want: []FieldError{
+				{"data.points", "must not be empty"},
+				{"data.width", "must be greater than 0"},
+			},
+		},
+		{
+			name:       "whiteboard shape",
+			objectType: "whiteboard_shape",
+			data:       `{"type": "hexagon", "position": {"x": 1}, "dimensions": {"width": 10, "height": 10}, "filled": true}`,
+			want: []FieldError{
+				{"data.type", "must be one of rectangle, circle, line"},
+				{"data.position.y", "is required"},
+			},
+		},
+		{
+			name:       "whiteboard view needs a view id",
+			objectType: "whiteboard_view",
+			data:       `{"position": {"x": 0, "y": 0}, "viewId": ""}`,
+			want:       []FieldError{{"data.viewId", "must not be empty"}},
+		},
+		{
+			name:       "whiteboard edge",
+			objectType: "whiteboard_edge",
+			data:       `{"startPoint": {"x": 0, "y": 0}, "endPoint": {"x": 5, "y": 5}, "curveType": "bezier", "arrowType": "end", "lineStyle": "wavy"}`,
+			want:       []FieldError{{"data.lineStyle", "must be one of solid, dashed, dotted"}},
+		},
+		{
+			name:       "table property options",
+			objectType: "table_property",
+			data:       `{"kind": "select", "options": [{"id": "o1", "name": "One"}, {"id": "o1"}]}`,
+			want: []FieldError{
+				{"data.options[1].id", "is duplicated"},
+				{"data.options[1].name", "is required"},
+			},
+		},
+		{
+			name:       "timeline task",
+			objectType: "timeline_task",
+			data:       `{"start": "2024-01-01", "end": "2024-01-31", "progress": 50, "dependencies": ["t1", "t2"]}`,
+		},
+		{
+			name:       "timeline task with invalid fields",
+			objectType: "timeline_task",
+			data:       `{"start": "2024-02-01", "end": "2024-01-31", "progress": 120, "dependencies": ["t1", "t1", "", 3]}`,
+			want: []FieldError{
+				{"data.end", "must not be before start"},
+				{"data.progress", "must be between 0 and 100"},
+				{"data.dependencies[1]", "is duplicated"},
+				{"data.dependencies[2]", "must not be empty"},
+				{"data.dependencies[3]", "must be a string"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.objectType, tt.data)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate = %v, want no error", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate = %v, want a *ValidationError", err)
+			}
+			if !reflect.DeepEqual(verr.Fields, tt.want) {
+				t.Errorf("Validate fields = %v, want %v", verr.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCompatible(t *testing.T) {
+	tests := []struct {
+		viewType   string
+		objectType string
+		want       bool
+	}{
+		{"calendar", "calendar_slot", true},
+		{"whiteboard", "whiteboard_edge", true},
+		{"table", "table_property", true},
+		{"kanban", "calendar_slot", false},
+		{"spreadsheet", "table_property", false},
+		{"unknown", "calendar_slot", false},
+	}
+	for _, tt := range tests {
+		if got := IsCompatible(tt.viewType, tt.objectType); got != tt.want {
+			t.Errorf("IsCompatible(%q, %q) = %v, want %v", tt.viewType, tt.objectType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package whiteboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/collabreef/collabreef/internal/model"
+)
+
+// Layer groups whiteboard objects by kind. Layers are drawn in the order
+// strokes and shapes, text, notes and views, edges — the same stacking the
+// browser uses.
+type Layer string
+
+const (
+	LayerStrokes Layer = "strokes"
+	LayerShapes  Layer = "shapes"
+	LayerText    Layer = "text"
+	LayerNotes   Layer = "notes"
+	LayerViews   Layer = "views"
+	LayerEdges   Layer = "edges"
+)
+
+var allLayers = []Layer{LayerStrokes, LayerShapes, LayerText, LayerNotes, LayerViews, LayerEdges}
+
+// Default sizes the web client uses for objects that do not store them.
+const (
+	defaultStrokeWidth = 2
+	defaultFontSize    = 16
+	defaultCardWidth   = 768
+	defaultCardHeight  = 80
+	defaultColor       = "#000000"
+)
+
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type Size struct {
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+type Stroke struct {
+	Points []Point `json:"points"`
+	Color  string  `json:"color"`
+	Width  float64 `json:"width"`
+}
+
+type Shape struct {
+	Type        string  `json:"type"` // rectangle, circle or line
+	Position    Point   `json:"position"`
+	Dimensions  Size    `json:"dimensions"`
+	Color       string  `json:"color"`
+	StrokeWidth float64 `json:"strokeWidth"`
+	Filled      bool    `json:"filled"`
+}
+
+type Text struct {
+	Position       Point   `json:"position"` // baseline origin
+	Text           string  `json:"text"`
+	Color          string  `json:"color"`
+	FontSize       float64 `json:"fontSize"`
+	FontFamily     string  `json:"fontFamily"`
+	FontWeight     string  `json:"fontWeight"`
+	FontStyle      string  `json:"fontStyle"`
+	TextDecoration string  `json:"textDecoration"`
+}
+
+// Card is a note or an embedded view placed on the board.
+type Card struct {
+	Position Point   `json:"position"`
+	Width    float64 `json:"width"`
+	Height   float64 `json:"height"`
+	ViewID   string  `json:"viewId,omitempty"`
+}
+
+type Edge struct {
+	StartPoint  Point   `json:"startPoint"`
+	EndPoint    Point   `json:"endPoint"`
+	CurveType   string  `json:"curveType"` // straight, bezier or elbow
+	ArrowType   string  `json:"arrowType"` // none, start, end or both
+	LineStyle   string  `json:"lineStyle"` // solid, dashed or dotted
+	Color       string  `json:"color"`
+	StrokeWidth float64 `json:"strokeWidth"`
+}
+
+// Object is a drawable whiteboard element. Exactly one of the data fields
+// is set, matching Layer.
+type Object struct {
+	ID     string
+	Name   string
+	Layer  Layer
+	Stroke *Stroke
+	Shape  *Shape
+	Text   *Text
+	Card   *Card
+	Edge   *Edge
+}
+
+// Board is the drawable content of a whiteboard view in paint order.
+type Board struct {
+	Objects []Object
+}
+
+// canvasObject is an entry of the canvas-objects map persisted in views.data.
+type canvasObject struct {
+	ID   string          `json:"id"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Load assembles a board from the view data (strokes and shapes drawn in the
+// collaborative canvas) and the whiteboard view objects. Objects that cannot
+// be decoded are skipped so that one bad entry does not break the render.
+func Load(viewData string, objects []model.ViewObject) *Board {
+	var list []Object
+
+	if strings.TrimSpace(viewData) != "" {
+		var canvas map[string]canvasObject
+		if err := json.Unmarshal([]byte(viewData), &canvas); err == nil {
+			for key, co := range canvas {
+				if co.ID == "" {
+					co.ID = key
+				}
+				if obj, ok := decodeObject(co.ID, "", "whiteboard_"+co.Type, co.Data); ok {
+					list = append(list, obj)
+				}
+			}
+		}
+	}
+
+	for _, vo := range objects {
+		if obj, ok := decodeObject(vo.ID, vo.Name, vo.Type, json.RawMessage(vo.Data)); ok {
+			list = append(list, obj)
+		}
+	}
+
+	order := make(map[Layer]int, len(allLayers))
+	for i, l := range allLayers {
+		order[l] = i
+	}
+	// Strokes and shapes share one canvas; ids start with a creation
+	// timestamp, so sorting by id keeps the drawing order.
+	order[LayerShapes] = order[LayerStrokes]
+	order[LayerViews] = order[LayerNotes]
+
+	sort.SliceStable(list, func(i, j int) bool {
+		oi, oj := order[list[i].Layer], order[list[j].Layer]
+		if oi != oj {
+			return oi < oj
+		}
+		return list[i].ID < list[j].ID
+	})
+
+	return &Board{Objects: list}
+}
+
+func decodeObject(id, name, typ string, data json.RawMessage) (Object, bool) {
+	obj := Object{ID: id, Name: name}
+
+	// View objects created through the API store data as a JSON string,
+	// collab stores it as an object; accept both.
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		data = json.RawMessage(s)
+	}
+
+	var err error
+	switch typ {
+	case "whiteboard_stroke":
+		obj.Layer, obj.Stroke = LayerStrokes, &Stroke{}
+		err = json.Unmarshal(data, obj.Stroke)
+		if err == nil && len(obj.Stroke.Points) == 0 {
+			return obj, false
+		}
+	case "whiteboard_shape":
+		obj.Layer, obj.Shape = LayerShapes, &Shape{}
+		err = json.Unmarshal(data, obj.Shape)
+	case "whiteboard_text":
+		obj.Layer, obj.Text = LayerText, &Text{}
+		err = json.Unmarshal(data, obj.Text)
+	case "whiteboard_note":
+		obj.Layer, obj.Card = LayerNotes, &Card{}
+		err = json.Unmarshal(data, obj.Card)
+	case "whiteboard_view":
+		obj.Layer, obj.Card = LayerViews, &Card{}
+		err = json.Unmarshal(data, obj.Card)
+	case "whiteboard_edge":
+		obj.Layer, obj.Edge = LayerEdges, &Edge{}
+		err = json.Unmarshal(data, obj.Edge)
+	default:
+		return obj, false
+	}
+
+	return obj, err == nil
+}
+
+// ParseLayers parses a comma-separated layer list. Both layer names
+// ("strokes") and object types ("whiteboard_stroke") are accepted. An empty
+// string selects every layer.
+func ParseLayers(s string) (map[Layer]bool, error) {
+	layers := map[Layer]bool{}
+	if strings.TrimSpace(s) == "" {
+		for _, l := range allLayers {
+			layers[l] = true
+		}
+		return layers, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		if name == "" {
+			continue
+		}
+		if strings.HasPrefix(name, "whiteboard_") {
+			name = strings.TrimPrefix(name, "whiteboard_")
+			if name != "text" {
+				name += "s"
+			}
+		}
+		found := false
+		for _, l := range allLayers {
+			if string(l) == name {
+				layers[l] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown layer %q", part)
+		}
+	}
+
+	return layers, nil
+}
+
+// Filter returns a board with only the objects of the given layers.
+func (b *Board) Filter(layers map[Layer]bool) *Board {
+	out := &Board{}
+	for _, obj := range b.Objects {
+		if layers[obj.Layer] {
+			out.Objects = append(out.Objects, obj)
+		}
+	}
+	return out
+}
+
+// Rect is an axis-aligned rectangle in board coordinates.
+type Rect struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+func (r Rect) Width() float64  { return r.MaxX - r.MinX }
+func (r Rect) Height() float64 { return r.MaxY - r.MinY }
+func (r Rect) Empty() bool     { return r.MaxX <= r.MinX || r.MaxY <= r.MinY }
+
+func (r Rect) union(o Rect) Rect {
+	return Rect{
+		MinX: math.Min(r.MinX, o.MinX),
+		MinY: math.Min(r.MinY, o.MinY),
+		MaxX: math.Max(r.MaxX, o.MaxX),
+		MaxY: math.Max(r.MaxY, o.MaxY),
+	}
+}
+
+func (r Rect) intersects(o Rect) bool {
+	return r.MinX <= o.MaxX && o.MinX <= r.MaxX && r.MinY <= o.MaxY && o.MinY <= r.MaxY
+}
+
+func (r Rect) inset(d float64) Rect {
+	return Rect{MinX: r.MinX + d, MinY: r.MinY + d, MaxX: r.MaxX - d, MaxY: r.MaxY - d}
+}
+
+// Bounds returns the area covered by all objects, or false if the board has
+// nothing to draw.
+func (b *Board) Bounds() (Rect, bool) {
+	var r Rect
+	found := false
+	for _, obj := range b.Objects {
+		ob, ok := obj.Bounds()
+		if !ok {
+			continue
+		}
+		if !found {
+			r, found = ob, true
+		} else {
+			r = r.union(ob)
+		}
+	}
+	return r, found
+}
+
+// Bounds returns the area an object paints, including line width.
+func (o Object) Bounds() (Rect, bool) {
+	switch {
+	case o.Stroke != nil:
+		r, ok := pointsBounds(o.Stroke.Points)
+		return r.inset(-strokeWidth(o.Stroke.Width) / 2), ok
+	case o.Shape != nil:
+		s := o.Shape
+		pad := -strokeWidth(s.StrokeWidth) / 2
+		if s.Type == "circle" {
+			radius := s.radius()
+			return Rect{
+				MinX: s.Position.X - radius, MinY: s.Position.Y - radius,
+				MaxX: s.Position.X + radius, MaxY: s.Position.Y + radius,
+			}.inset(pad), true
+		}
+		r, _ := pointsBounds([]Point{s.Position, {X: s.Position.X + s.Dimensions.Width, Y: s.Position.Y + s.Dimensions.Height}})
+		return r.inset(pad), true
+	case o.Text != nil:
+		size := o.Text.fontSize()
+		width := measureText(o.Text.face(size), o.Text.displayText())
+		return Rect{
+			MinX: o.Text.Position.X, MinY: o.Text.Position.Y - size,
+			MaxX: o.Text.Position.X + width, MaxY: o.Text.Position.Y + size*0.25,
+		}, true
+	case o.Card != nil:
+		w, h := o.Card.size()
+		return Rect{MinX: o.Card.Position.X, MinY: o.Card.Position.Y, MaxX: o.Card.Position.X + w, MaxY: o.Card.Position.Y + h}, true
+	case o.Edge != nil:
+		r, _ := pointsBounds([]Point{o.Edge.StartPoint, o.Edge.EndPoint})
+		pad := math.Max(arrowSize, strokeWidth(o.Edge.StrokeWidth)/2)
+		return r.inset(-pad), true
+	}
+	return Rect{}, false
+}
+
+func pointsBounds(points []Point) (Rect, bool) {
+	if len(points) == 0 {
+		return Rect{}, false
+	}
+	r := Rect{MinX: points[0].X, MinY: points[0].Y, MaxX: points[0].X, MaxY: points[0].Y}
+	for _, p := range points[1:] {
+		r.MinX, r.MaxX = math.Min(r.MinX, p.X), math.Max(r.MaxX, p.X)
+		r.MinY, r.MaxY = math.Min(r.MinY, p.Y), math.Max(r.MaxY, p.Y)
+	}
+	return r, true
+}
+
+func (s *Shape) radius() float64 {
+	return math.Hypot(s.Dimensions.Width, s.Dimensions.Height)
+}
+
+func (t *Text) fontSize() float64 {
+	if t.FontSize > 0 {
+		return t.FontSize
+	}
+	return defaultFontSize
+}
+
+// displayText mirrors the browser, which shows a placeholder for empty text.
+func (t *Text) displayText() string {
+	if s := strings.TrimSpace(t.Text); s != "" {
+		return s
+	}
+	return "Text"
+}
+
+func (t *Text) color() string {
+	if strings.TrimSpace(t.Text) == "" {
+		return "#9ca3af"
+	}
+	return colorOr(t.Color)
+}
+
+func (c *Card) size() (float64, float64) {
+	w, h := c.Width, c.Height
+	if w <= 0 {
+		w = defaultCardWidth
+	}
+	if h <= 0 {
+		h = defaultCardHeight
+	}
+	return w, h
+}
+
+func strokeWidth(w float64) float64 {
+	if w > 0 {
+		return w
+	}
+	return defaultStrokeWidth
+}
+
+func colorOr(c string) string {
+	if c = strings.TrimSpace(c); c != "" {
+		return c
+	}
+	return defaultColor
+}
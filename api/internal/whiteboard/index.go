@@ -0,0 +1,248 @@
+package whiteboard
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/collabreef/collabreef/internal/db"
+	"github.com/collabreef/collabreef/internal/model"
+)
+
+// SimplifyTolerance is the largest distance, in board units, a stroke point
+// may be from the simplified line. It is below what the canvas can show at
+// normal zoom.
+const SimplifyTolerance = 0.5
+
+// PrepareObject readies a whiteboard view object for storage: stroke points
+// are simplified and the bounds of the object are recorded for viewport
+// queries. Other objects are returned unchanged.
+func PrepareObject(o model.ViewObject) model.ViewObject {
+	if !strings.HasPrefix(o.Type, "whiteboard_") {
+		return o
+	}
+	if o.Type == "whiteboard_stroke" {
+		if data, ok := simplifyStrokeData(json.RawMessage(o.Data)); ok {
+			o.Data = string(data)
+		}
+	}
+	if obj, ok := decodeObject(o.ID, o.Name, o.Type, json.RawMessage(o.Data)); ok {
+		if r, ok := obj.Bounds(); ok {
+			o.MinX, o.MinY, o.MaxX, o.MaxY = &r.MinX, &r.MinY, &r.MaxX, &r.MaxY
+		}
+	}
+	return o
+}
+
+// SimplifyCanvas simplifies the strokes of the canvas-objects map persisted
+// in the data of a whiteboard view. Data that is not a canvas map is returned
+// unchanged.
+func SimplifyCanvas(viewData string) string {
+	var canvas map[string]map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(viewData), &canvas); err != nil {
+		return viewData
+	}
+	changed := false
+	for _, co := range canvas {
+		var typ string
+		if json.Unmarshal(co["type"], &typ) != nil || typ != "stroke" {
+			continue
+		}
+		if data, ok := simplifyStrokeData(co["data"]); ok {
+			co["data"] = data
+			changed = true
+		}
+	}
+	if !changed {
+		return viewData
+	}
+	b, err := json.Marshal(canvas)
+	if err != nil {
+		return viewData
+	}
+	return string(b)
+}
+
+// CanvasObjects splits the canvas-objects map persisted in the data of a
+// whiteboard view into entries with their bounds. Data that is not a canvas
+// map has no entries.
+func CanvasObjects(viewID, viewData string) []model.CanvasObject {
+	var canvas map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(viewData), &canvas); err != nil {
+		return nil
+	}
+	list := make([]model.CanvasObject, 0, len(canvas))
+	for key, entry := range canvas {
+		o := model.CanvasObject{ViewID: viewID, ID: key, Data: string(entry)}
+		var co canvasObject
+		if json.Unmarshal(entry, &co) == nil {
+			if co.ID == "" {
+				co.ID = key
+			}
+			if obj, ok := decodeObject(co.ID, "", "whiteboard_"+co.Type, co.Data); ok {
+				if r, ok := obj.Bounds(); ok {
+					o.MinX, o.MinY, o.MaxX, o.MaxY = &r.MinX, &r.MinY, &r.MaxX, &r.MaxY
+				}
+			}
+		}
+		list = append(list, o)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// IndexCanvas brings the canvas objects recorded for a whiteboard view in
+// step with its data. Only entries that changed are written. Run it in the
+// transaction that writes the data.
+func IndexCanvas(d db.DB, viewID, viewData string) error {
+	recorded, err := d.FindCanvasObjects(model.CanvasObjectFilter{ViewID: viewID})
+	if err != nil {
+		return err
+	}
+	stale := make(map[string]string, len(recorded))
+	for _, o := range recorded {
+		stale[o.ID] = o.Data
+	}
+
+	for _, o := range CanvasObjects(viewID, viewData) {
+		data, ok := stale[o.ID]
+		delete(stale, o.ID)
+		if ok && data == o.Data {
+			continue
+		}
+		if err := d.SaveCanvasObject(o); err != nil {
+			return err
+		}
+	}
+
+	ids := make([]string, 0, len(stale))
+	for id := range stale {
+		ids = append(ids, id)
+	}
+	return d.DeleteCanvasObjects(viewID, ids)
+}
+
+// simplifyStrokeData simplifies the points of stroke data, keeping its other
+// fields. Data stored as a JSON string stays a string. It reports false when
+// nothing changed.
+func simplifyStrokeData(data json.RawMessage) (json.RawMessage, bool) {
+	var s string
+	quoted := json.Unmarshal(data, &s) == nil
+	if quoted {
+		data = json.RawMessage(s)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, false
+	}
+	// Points may carry more than x and y, such as pressure; keep them as is.
+	var raw []json.RawMessage
+	if err := json.Unmarshal(fields["points"], &raw); err != nil || len(raw) <= 2 {
+		return nil, false
+	}
+	points := make([]Point, len(raw))
+	for i, r := range raw {
+		if err := json.Unmarshal(r, &points[i]); err != nil {
+			return nil, false
+		}
+	}
+
+	keep := simplify(points, SimplifyTolerance)
+	if len(keep) == len(points) {
+		return nil, false
+	}
+	kept := make([]json.RawMessage, len(keep))
+	for i, k := range keep {
+		kept[i] = raw[k]
+	}
+	fields["points"], _ = json.Marshal(kept)
+
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return nil, false
+	}
+	if quoted {
+		out, _ = json.Marshal(string(out))
+	}
+	return out, true
+}
+
+// simplify runs Douglas-Peucker over a polyline and returns the indexes of
+// the points to keep, in order. The end points are always kept.
+func simplify(points []Point, tolerance float64) []int {
+	n := len(points)
+	if n <= 2 {
+		keep := make([]int, n)
+		for i := range keep {
+			keep[i] = i
+		}
+		return keep
+	}
+
+	marked := make([]bool, n)
+	marked[0], marked[n-1] = true, true
+
+	// Long strokes would recurse deeply; use an explicit stack of spans.
+	type span struct{ first, last int }
+	stack := []span{{0, n - 1}}
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		farthest, maxDist := -1, tolerance
+		for i := s.first + 1; i < s.last; i++ {
+			if d := segmentDistance(points[i], points[s.first], points[s.last]); d > maxDist {
+				farthest, maxDist = i, d
+			}
+		}
+		if farthest < 0 {
+			continue
+		}
+		marked[farthest] = true
+		stack = append(stack, span{s.first, farthest}, span{farthest, s.last})
+	}
+
+	var keep []int
+	for i, m := range marked {
+		if m {
+			keep = append(keep, i)
+		}
+	}
+	return keep
+}
+
+// segmentDistance returns the distance from p to the segment a-b.
+func segmentDistance(p, a, b Point) float64 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p.X-a.X, p.Y-a.Y)
+	}
+	t := ((p.X-a.X)*dx + (p.Y-a.Y)*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(p.X-(a.X+t*dx), p.Y-(a.Y+t*dy))
+}
+
+// ParseBBox parses a viewport given as "minX,minY,maxX,maxY".
+func ParseBBox(s string) (model.BBox, error) {
+	invalid := errors.New("bbox must be minX,minY,maxX,maxY with min <= max")
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return model.BBox{}, invalid
+	}
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return model.BBox{}, invalid
+		}
+		v[i] = f
+	}
+	if v[2] < v[0] || v[3] < v[1] {
+		return model.BBox{}, invalid
+	}
+	return model.BBox{MinX: v[0], MinY: v[1], MaxX: v[2], MaxY: v[3]}, nil
+}
@@ -0,0 +1,361 @@
+package whiteboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/collabreef/collabreef/internal/db/dbtest"
+	"github.com/collabreef/collabreef/internal/model"
+)
+
+func TestParseBBox(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    model.BBox
+		wantErr bool
+	}{
+		{in: "0,0,100,50", want: model.BBox{MaxX: 100, MaxY: 50}},
+		{in: " -10.5, -20 ,3e2, 4 ", want: model.BBox{MinX: -10.5, MinY: -20, MaxX: 300, MaxY: 4}},
+		{in: "5,5,5,5", want: model.BBox{MinX: 5, MinY: 5, MaxX: 5, MaxY: 5}},
+		{in: "10,0,0,10", wantErr: true},
+		{in: "0,10,10,0", wantErr: true},
+		{in: "0,0,10", wantErr: true},
+		{in: "0,0,10,10,10", wantErr: true},
+		{in: "0,0,x,10", wantErr: true},
+		{in: "NaN,0,10,10", wantErr: true},
+		{in: "0,0,Inf,10", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBBox(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBBox(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseBBox(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		name      string
+		points    []Point
+		tolerance float64
+		want      []int
+	}{
+		{name: "empty", points: nil, want: []int{}},
+		{name: "single point", points: []Point{{1, 1}}, want: []int{0}},
+		{name: "two points", points: []Point{{0, 0}, {5, 5}}, want: []int{0, 1}},
+		{
+			name:      "collinear points collapse to the ends",
+			points:    []Point{{0, 0}, {1, 0}, {2, 0}, {3, 0}, {4, 0}},
+			tolerance: 0.5,
+			want:      []int{0, 4},
+		},
+		{
+			name:      "jitter within the tolerance is dropped",
+			points:    []Point{{0, 0}, {1, 0.2}, {2, -0.3}, {3, 0.1}, {4, 0}},
+			tolerance: 0.5,
+			want:      []int{0, 4},
+		},
+		{
+			name:      "corners are kept",
+			points:    []Point{{0, 0}, {5, 0}, {10, 0}, {10, 5}, {10, 10}},
+			tolerance: 0.5,
+			want:      []int{0, 2, 4},
+		},
+		{
+			name:      "zigzag beyond the tolerance is kept",
+			points:    []Point{{0, 0}, {1, 2}, {2, 0}, {3, 2}, {4, 0}},
+			tolerance: 0.5,
+			want:      []int{0, 1, 2, 3, 4},
+		},
+		{
+			name:      "closed loop keeps its far point",
+			points:    []Point{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+			tolerance: 0.5,
+			want:      []int{0, 1, 2, 3, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := simplify(tt.points, tt.tolerance)
+			if got == nil {
+				got = []int{}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("simplify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimplifyLongStroke(t *testing.T) {
+	// A straight stroke of many points must not recurse per point.
+	points := make([]Point, 100000)
+	for i := range points {
+		points[i] = Point{X: float64(i)}
+	}
+	if got := simplify(points, SimplifyTolerance); !reflect.DeepEqual(got, []int{0, len(points) - 1}) {
+		t.Errorf("simplify kept %d points, want 2", len(got))
+	}
+}
+
+func TestSegmentDistance(t *testing.T) {
+	tests := []struct {
+		p, a, b Point
+		want    float64
+	}{
+		{p: Point{5, 3}, a: Point{0, 0}, b: Point{10, 0}, want: 3},
+		{p: Point{-4, 3}, a: Point{0, 0}, b: Point{10, 0}, want: 5},
+		{p: Point{13, 4}, a: Point{0, 0}, b: Point{10, 0}, want: 5},
+		{p: Point{3, 4}, a: Point{0, 0}, b: Point{0, 0}, want: 5},
+	}
+	for _, tt := range tests {
+		if got := segmentDistance(tt.p, tt.a, tt.b); got != tt.want {
+			t.Errorf("segmentDistance(%v, %v, %v) = %g, want %g", tt.p, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestPrepareObject(t *testing.T) {
+	tests := []struct {
+		name string
+		in   model.ViewObject
+		// wantData is the stored data; empty when it is unchanged.
+		wantData string
+		want     *model.BBox
+	}{
+		{
+			name:     "stroke is simplified and bounded",
+			in:       model.ViewObject{Type: "whiteboard_stroke", Data: `{"points":[{"x":0,"y":0},{"x":5,"y":0.1},{"x":10,"y":0}],"color":"#f00","width":4}`},
+			wantData: `{"color":"#f00","points":[{"x":0,"y":0},{"x":10,"y":0}],"width":4}`,
+			want:     &model.BBox{MinX: -2, MinY: -2, MaxX: 12, MaxY: 2},
+		},
+		{
+			name:     "stroke stored as a string stays a string",
+			in:       model.ViewObject{Type: "whiteboard_stroke", Data: `"{\"points\":[{\"x\":0,\"y\":0,\"p\":0.5},{\"x\":1,\"y\":0},{\"x\":2,\"y\":0}]}"`},
+			wantData: `"{\"points\":[{\"x\":0,\"y\":0,\"p\":0.5},{\"x\":2,\"y\":0}]}"`,
+			want:     &model.BBox{MinX: -1, MinY: -1, MaxX: 3, MaxY: 1},
+		},
+		{
+			name: "note uses the default card size",
+			in:   model.ViewObject{Type: "whiteboard_note", Data: `{"position":{"x":10,"y":20}}`},
+			want: &model.BBox{MinX: 10, MinY: 20, MaxX: 10 + defaultCardWidth, MaxY: 20 + defaultCardHeight},
+		},
+		{
+			name: "circle is bounded by its radius",
+			in:   model.ViewObject{Type: "whiteboard_shape", Data: `{"type":"circle","position":{"x":0,"y":0},"dimensions":{"width":3,"height":4},"strokeWidth":2}`},
+			want: &model.BBox{MinX: -6, MinY: -6, MaxX: 6, MaxY: 6},
+		},
+		{
+			name: "edge leaves room for arrows",
+			in:   model.ViewObject{Type: "whiteboard_edge", Data: `{"startPoint":{"x":0,"y":0},"endPoint":{"x":100,"y":50}}`},
+			want: &model.BBox{MinX: -arrowSize, MinY: -arrowSize, MaxX: 100 + arrowSize, MaxY: 50 + arrowSize},
+		},
+		{
+			name: "undecodable data has no bounds",
+			in:   model.ViewObject{Type: "whiteboard_note", Data: `{"position":"here"}`},
+		},
+		{
+			name: "stroke without points has no bounds",
+			in:   model.ViewObject{Type: "whiteboard_stroke", Data: `{"points":[]}`},
+		},
+		{
+			name: "other view objects are unchanged",
+			in:   model.ViewObject{Type: "map_marker", Data: `{"lat":1,"lng":2}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PrepareObject(tt.in)
+			wantData := tt.wantData
+			if wantData == "" {
+				wantData = tt.in.Data
+			}
+			if got.Data != wantData {
+				t.Errorf("Data = %s, want %s", got.Data, wantData)
+			}
+
+			if tt.want == nil {
+				if got.MinX != nil || got.MinY != nil || got.MaxX != nil || got.MaxY != nil {
+					t.Errorf("bounds set, want none")
+				}
+				return
+			}
+			if got.MinX == nil || got.MinY == nil || got.MaxX == nil || got.MaxY == nil {
+				t.Fatalf("bounds not set")
+			}
+			if b := (model.BBox{MinX: *got.MinX, MinY: *got.MinY, MaxX: *got.MaxX, MaxY: *got.MaxY}); b != *tt.want {
+				t.Errorf("bounds = %+v, want %+v", b, *tt.want)
+			}
+		})
+	}
+}
+
+func TestSimplifyCanvas(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "strokes are simplified",
+			in:   `{"s1":{"id":"s1","type":"stroke","data":{"points":[{"x":0,"y":0},{"x":1,"y":0},{"x":2,"y":0}]}},"r1":{"id":"r1","type":"shape","data":{"type":"rectangle"}}}`,
+			want: `{"r1":{"data":{"type":"rectangle"},"id":"r1","type":"shape"},"s1":{"data":{"points":[{"x":0,"y":0},{"x":2,"y":0}]},"id":"s1","type":"stroke"}}`,
+		},
+		{
+			name: "unchanged data is returned as is",
+			in:   `{"s1": {"type": "stroke", "data": {"points": [{"x": 0, "y": 0}, {"x": 2, "y": 2}]}}}`,
+		},
+		{
+			name: "data that is not a canvas is returned as is",
+			in:   `[1, 2, 3]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want == "" {
+				want = tt.in
+			}
+			if got := SimplifyCanvas(tt.in); got != want {
+				t.Errorf("SimplifyCanvas = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestCanvasObjects(t *testing.T) {
+	data := `{
+		"b": {"id": "b", "type": "shape", "data": {"type": "rectangle", "position": {"x": 0, "y": 0}, "dimensions": {"width": 10, "height": 20}, "strokeWidth": 2}},
+		"a": {"type": "stroke", "data": {"points": [{"x": 5, "y": 5}, {"x": 15, "y": 25}]}},
+		"c": {"id": "c", "type": "unknown", "data": {}}
+	}`
+
+	got := CanvasObjects("v1", data)
+	if len(got) != 3 {
+		t.Fatalf("CanvasObjects returned %d entries, want 3", len(got))
+	}
+
+	wantBounds := map[string]*model.BBox{
+		"a": {MinX: 4, MinY: 4, MaxX: 16, MaxY: 26},
+		"b": {MinX: -1, MinY: -1, MaxX: 11, MaxY: 21},
+		"c": nil,
+	}
+	for i, id := range []string{"a", "b", "c"} {
+		o := got[i]
+		if o.ID != id || o.ViewID != "v1" {
+			t.Errorf("entry %d = %s/%s, want v1/%s", i, o.ViewID, o.ID, id)
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(o.Data), &entry); err != nil {
+			t.Errorf("entry %s data is not the canvas entry: %v", id, err)
+		}
+		want := wantBounds[id]
+		if want == nil {
+			if o.MinX != nil {
+				t.Errorf("entry %s has bounds, want none", id)
+			}
+			continue
+		}
+		if o.MinX == nil {
+			t.Errorf("entry %s has no bounds", id)
+			continue
+		}
+		if b := (model.BBox{MinX: *o.MinX, MinY: *o.MinY, MaxX: *o.MaxX, MaxY: *o.MaxY}); b != *want {
+			t.Errorf("entry %s bounds = %+v, want %+v", id, b, *want)
+		}
+	}
+
+	if got := CanvasObjects("v1", `not json`); got != nil {
+		t.Errorf("CanvasObjects of invalid data = %v, want none", got)
+	}
+}
+
+func TestIndexCanvas(t *testing.T) {
+	const (
+		a  = `{"id":"a","type":"stroke","data":{"points":[{"x":0,"y":0},{"x":1,"y":1}]}}`
+		b  = `{"id":"b","type":"shape","data":{"type":"rectangle"}}`
+		b2 = `{"id":"b","type":"shape","data":{"type":"circle"}}`
+		c  = `{"id":"c","type":"shape","data":{"type":"line"}}`
+	)
+	d := dbtest.New(t)
+	if err := d.CreateView(model.View{ID: "v1", WorkspaceID: "w1", Type: "whiteboard"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := IndexCanvas(d, "v1", `{"a":`+a+`,"b":`+b+`,"stale":`+c+`}`); err != nil {
+		t.Fatalf("IndexCanvas: %v", err)
+	}
+	if err := IndexCanvas(d, "v1", `{"a":`+a+`,"b":`+b2+`,"c":`+c+`}`); err != nil {
+		t.Fatalf("IndexCanvas: %v", err)
+	}
+
+	got, err := d.FindCanvasObjects(model.CanvasObjectFilter{ViewID: "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]string{}
+	for _, o := range got {
+		data[o.ID] = o.Data
+	}
+	if want := map[string]string{"a": a, "b": b2, "c": c}; !reflect.DeepEqual(data, want) {
+		t.Errorf("canvas objects = %v, want %v", data, want)
+	}
+}
+
+func TestFindCanvasObjectsInBBox(t *testing.T) {
+	d := dbtest.New(t)
+	for _, id := range []string{"v1", "v2"} {
+		if err := d.CreateView(model.View{ID: id, WorkspaceID: "w1", Type: "whiteboard"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stroke := func(x0, y0, x1, y1 float64) string {
+		return fmt.Sprintf(`{"type":"stroke","data":{"points":[{"x":%g,"y":%g},{"x":%g,"y":%g}]}}`, x0, y0, x1, y1)
+	}
+	canvas := `{"near":` + stroke(0, 0, 10, 10) +
+		`,"far":` + stroke(1000, 1000, 1010, 1010) +
+		`,"edge":` + stroke(100, 100, 200, 200) +
+		`,"moved":` + stroke(0, 0, 5, 5) +
+		`,"unbounded":{"type":"note"}}`
+	if err := IndexCanvas(d, "v1", canvas); err != nil {
+		t.Fatal(err)
+	}
+	if err := IndexCanvas(d, "v2", `{"other":`+stroke(0, 0, 10, 10)+`}`); err != nil {
+		t.Fatal(err)
+	}
+	// Updated bounds replace the old ones in the index.
+	if err := IndexCanvas(d, "v1", strings.Replace(canvas, stroke(0, 0, 5, 5), stroke(500, 500, 505, 505), 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		bbox model.BBox
+		want []string
+	}{
+		{bbox: model.BBox{MinX: -50, MinY: -50, MaxX: 100, MaxY: 100}, want: []string{"edge", "near", "unbounded"}},
+		{bbox: model.BBox{MinX: 400, MinY: 400, MaxX: 600, MaxY: 600}, want: []string{"moved", "unbounded"}},
+		{bbox: model.BBox{MinX: 2000, MinY: 2000, MaxX: 3000, MaxY: 3000}, want: []string{"unbounded"}},
+	}
+	for _, tt := range tests {
+		objects, err := d.FindCanvasObjects(model.CanvasObjectFilter{ViewID: "v1", BBox: &tt.bbox})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, o := range objects {
+			got = append(got, o.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("objects in %+v = %v, want %v", tt.bbox, got, tt.want)
+		}
+	}
+}
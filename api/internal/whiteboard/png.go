@@ -0,0 +1,413 @@
+package whiteboard
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// RenderPNG rasterizes the board. Lines are anti-aliased and text is drawn
+// with the Go fonts, so the result closely matches but is not identical to
+// the browser rendering.
+func (b *Board) RenderPNG(w io.Writer, opts Options) error {
+	board, f, err := b.prepare(opts)
+	if err != nil {
+		return err
+	}
+
+	c := &canvas{
+		img:   image.NewRGBA(image.Rect(0, 0, f.width, f.height)),
+		frame: f,
+	}
+
+	if bg := background(opts.Background); bg != "" {
+		if col, ok := parseColor(bg); ok {
+			draw.Draw(c.img, c.img.Bounds(), image.NewUniform(col), image.Point{}, draw.Src)
+		}
+	}
+
+	for _, obj := range board.Objects {
+		switch {
+		case obj.Stroke != nil:
+			c.drawStroke(obj.Stroke)
+		case obj.Shape != nil:
+			c.drawShape(obj.Shape)
+		case obj.Text != nil:
+			c.drawText(obj.Text)
+		case obj.Card != nil:
+			c.drawCard(obj)
+		case obj.Edge != nil:
+			c.drawEdge(obj.Edge)
+		}
+	}
+
+	return png.Encode(w, c.img)
+}
+
+type canvas struct {
+	img *image.RGBA
+	frame
+}
+
+// px converts board coordinates to output pixels.
+func (c *canvas) px(p Point) Point {
+	return Point{(p.X - c.region.MinX) * c.scale, (p.Y - c.region.MinY) * c.scale}
+}
+
+func (c *canvas) pxAll(points []Point) []Point {
+	out := make([]Point, len(points))
+	for i, p := range points {
+		out[i] = c.px(p)
+	}
+	return out
+}
+
+func (c *canvas) drawStroke(s *Stroke) {
+	col, ok := parseColor(colorOr(s.Color))
+	if !ok {
+		return
+	}
+	c.fill(col, strokePolygons(c.pxAll(s.Points), strokeWidth(s.Width)*c.scale, false))
+}
+
+func (c *canvas) drawShape(s *Shape) {
+	col, ok := parseColor(colorOr(s.Color))
+	if !ok {
+		return
+	}
+	outline, closed := shapeOutline(s)
+	points := c.pxAll(outline)
+	if s.Filled && closed {
+		c.fill(col, [][]Point{points})
+	}
+	width := strokeWidth(s.StrokeWidth) * c.scale
+	if closed && s.Type != "circle" {
+		// Rectangles have mitred corners in the browser.
+		c.fill(col, rectOutline(points, width))
+		return
+	}
+	c.fill(col, strokePolygons(points, width, closed))
+}
+
+func (c *canvas) drawText(t *Text) {
+	col, ok := parseColor(t.color())
+	if !ok {
+		return
+	}
+	size := t.fontSize() * c.scale
+	if size < 1 {
+		return
+	}
+	face := t.face(size)
+	defer face.Close()
+
+	p := c.px(t.Position)
+	text := t.displayText()
+	d := font.Drawer{
+		Dst:  c.img,
+		Src:  image.NewUniform(col),
+		Face: face,
+		Dot:  fixed.Point26_6{X: fixed.Int26_6(p.X * 64), Y: fixed.Int26_6(p.Y * 64)},
+	}
+	d.DrawString(text)
+
+	if t.TextDecoration == "underline" {
+		width := measureText(face, text)
+		y := p.Y + size*0.1
+		thickness := math.Max(1, size/16)
+		c.fill(col, [][]Point{rectPoints(p.X, y, p.X+width, y+thickness)})
+	}
+}
+
+func (c *canvas) drawCard(o Object) {
+	style := cardStyles[o.Layer]
+	card := o.Card
+	w, h := card.size()
+
+	min := c.px(card.Position)
+	max := c.px(Point{card.Position.X + w, card.Position.Y + h})
+	outline := roundedRect(min, max, cardRadius*c.scale)
+
+	if fill, ok := parseColor(style.fill); ok {
+		c.fill(fill, [][]Point{outline})
+	}
+	if border, ok := parseColor(style.border); ok {
+		c.fill(border, strokePolygons(outline, cardBorder*c.scale, true))
+	}
+
+	size := cardLabelSize * c.scale
+	if size < 1 {
+		return
+	}
+	label, ok := parseColor(style.label)
+	if !ok {
+		return
+	}
+	face := fontFace(fontVariant{bold: true}, size)
+	defer face.Close()
+
+	text := truncateText(face, o.cardLabel(), (w-cardPadding*2)*c.scale)
+	origin := c.px(Point{card.Position.X + cardPadding, card.Position.Y + cardPadding + cardLabelSize})
+	d := font.Drawer{
+		Dst:  c.img,
+		Src:  image.NewUniform(label),
+		Face: face,
+		Dot:  fixed.Point26_6{X: fixed.Int26_6(origin.X * 64), Y: fixed.Int26_6(origin.Y * 64)},
+	}
+	d.DrawString(text)
+}
+
+func (c *canvas) drawEdge(e *Edge) {
+	col, ok := parseColor(colorOr(e.Color))
+	if !ok {
+		return
+	}
+
+	width := strokeWidth(e.StrokeWidth) * c.scale
+	path := c.pxAll(flatten(edgePath(e)))
+
+	var polys [][]Point
+	pattern := dashPatterns[e.LineStyle]
+	scaled := make([]float64, len(pattern))
+	for i, v := range pattern {
+		scaled[i] = v * c.scale
+	}
+	for _, piece := range dash(path, scaled) {
+		polys = append(polys, strokePolygons(piece, width, false)...)
+	}
+
+	startAngle, endAngle := edgeArrowAngles(e)
+	if e.ArrowType == "start" || e.ArrowType == "both" {
+		polys = append(polys, c.pxAll(arrowHead(e.StartPoint, startAngle)))
+	}
+	if e.ArrowType == "end" || e.ArrowType == "both" {
+		polys = append(polys, c.pxAll(arrowHead(e.EndPoint, endAngle)))
+	}
+
+	c.fill(col, polys)
+}
+
+// fill paints the union of the polygons, given in output pixels.
+func (c *canvas) fill(col color.Color, polys [][]Point) {
+	bounds := image.Rectangle{}
+	for _, poly := range polys {
+		r, ok := pointsBounds(poly)
+		if !ok {
+			continue
+		}
+		pr := image.Rect(int(math.Floor(r.MinX)), int(math.Floor(r.MinY)), int(math.Ceil(r.MaxX))+1, int(math.Ceil(r.MaxY))+1)
+		bounds = bounds.Union(pr)
+	}
+	bounds = bounds.Intersect(c.img.Bounds())
+	if bounds.Empty() {
+		return
+	}
+
+	z := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	ox, oy := float64(bounds.Min.X), float64(bounds.Min.Y)
+	for _, poly := range polys {
+		if len(poly) < 3 {
+			continue
+		}
+		// Overlapping polygons must share a winding direction, otherwise
+		// their coverage cancels out.
+		if signedArea(poly) < 0 {
+			poly = reversed(poly)
+		}
+		z.MoveTo(float32(poly[0].X-ox), float32(poly[0].Y-oy))
+		for _, p := range poly[1:] {
+			z.LineTo(float32(p.X-ox), float32(p.Y-oy))
+		}
+		z.ClosePath()
+	}
+	z.Draw(c.img, bounds, image.NewUniform(col), image.Point{})
+}
+
+// strokePolygons outlines a polyline of the given width with round joins
+// and caps, as the union of one quad per segment and a disc per vertex.
+func strokePolygons(points []Point, width float64, closed bool) [][]Point {
+	if len(points) == 0 || width <= 0 {
+		return nil
+	}
+	half := width / 2
+
+	var polys [][]Point
+	n := len(points)
+	segments := n - 1
+	if closed {
+		segments = n
+	}
+	for i := range segments {
+		a, b := points[i], points[(i+1)%n]
+		dx, dy := b.X-a.X, b.Y-a.Y
+		length := math.Hypot(dx, dy)
+		if length == 0 {
+			continue
+		}
+		nx, ny := -dy/length*half, dx/length*half
+		polys = append(polys, []Point{
+			{a.X + nx, a.Y + ny}, {b.X + nx, b.Y + ny},
+			{b.X - nx, b.Y - ny}, {a.X - nx, a.Y - ny},
+		})
+	}
+	for _, p := range points {
+		polys = append(polys, disc(p, half))
+	}
+
+	return polys
+}
+
+// rectOutline returns the border of a rectangle with mitred corners.
+func rectOutline(corners []Point, width float64) [][]Point {
+	r, ok := pointsBounds(corners)
+	if !ok {
+		return nil
+	}
+	half := width / 2
+	outer := r.inset(-half)
+	inner := r.inset(half)
+	if inner.Empty() {
+		return [][]Point{rectPoints(outer.MinX, outer.MinY, outer.MaxX, outer.MaxY)}
+	}
+	return [][]Point{
+		rectPoints(outer.MinX, outer.MinY, outer.MaxX, inner.MinY),
+		rectPoints(outer.MinX, inner.MaxY, outer.MaxX, outer.MaxY),
+		rectPoints(outer.MinX, inner.MinY, inner.MinX, inner.MaxY),
+		rectPoints(inner.MaxX, inner.MinY, outer.MaxX, inner.MaxY),
+	}
+}
+
+func rectPoints(x1, y1, x2, y2 float64) []Point {
+	return []Point{{x1, y1}, {x2, y1}, {x2, y2}, {x1, y2}}
+}
+
+func roundedRect(min, max Point, radius float64) []Point {
+	radius = math.Min(radius, math.Min(max.X-min.X, max.Y-min.Y)/2)
+	if radius <= 0 {
+		return rectPoints(min.X, min.Y, max.X, max.Y)
+	}
+	const steps = 8
+	corners := []struct {
+		cx, cy, start float64
+	}{
+		{max.X - radius, min.Y + radius, -math.Pi / 2},
+		{max.X - radius, max.Y - radius, 0},
+		{min.X + radius, max.Y - radius, math.Pi / 2},
+		{min.X + radius, min.Y + radius, math.Pi},
+	}
+	var points []Point
+	for _, c := range corners {
+		for i := 0; i <= steps; i++ {
+			a := c.start + math.Pi/2*float64(i)/steps
+			points = append(points, Point{c.cx + radius*math.Cos(a), c.cy + radius*math.Sin(a)})
+		}
+	}
+	return points
+}
+
+func disc(center Point, radius float64) []Point {
+	steps := int(math.Min(64, math.Max(8, radius*2)))
+	points := make([]Point, steps)
+	for i := range points {
+		a := 2 * math.Pi * float64(i) / float64(steps)
+		points[i] = Point{center.X + radius*math.Cos(a), center.Y + radius*math.Sin(a)}
+	}
+	return points
+}
+
+func signedArea(poly []Point) float64 {
+	area := 0.0
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		area += p.X*q.Y - q.X*p.Y
+	}
+	return area / 2
+}
+
+func reversed(poly []Point) []Point {
+	out := make([]Point, len(poly))
+	for i, p := range poly {
+		out[len(poly)-1-i] = p
+	}
+	return out
+}
+
+var namedColors = map[string]color.NRGBA{
+	"black": {0, 0, 0, 255},
+	"white": {255, 255, 255, 255},
+	"red":   {255, 0, 0, 255},
+	"green": {0, 128, 0, 255},
+	"blue":  {0, 0, 255, 255},
+	"gray":  {128, 128, 128, 255},
+	"grey":  {128, 128, 128, 255},
+}
+
+// parseColor understands the color notations the web client produces:
+// #rgb, #rrggbb, #rrggbbaa, rgb()/rgba() and a few names.
+func parseColor(s string) (color.NRGBA, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := namedColors[s]; ok {
+		return c, true
+	}
+
+	if strings.HasPrefix(s, "#") {
+		hex := s[1:]
+		if len(hex) == 3 || len(hex) == 4 {
+			var expanded strings.Builder
+			for _, r := range hex {
+				expanded.WriteRune(r)
+				expanded.WriteRune(r)
+			}
+			hex = expanded.String()
+		}
+		if len(hex) == 6 {
+			hex += "ff"
+		}
+		if len(hex) != 8 {
+			return color.NRGBA{}, false
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return color.NRGBA{}, false
+		}
+		return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, true
+	}
+
+	if strings.HasPrefix(s, "rgb") {
+		open, end := strings.Index(s, "("), strings.LastIndex(s, ")")
+		if open < 0 || end < open {
+			return color.NRGBA{}, false
+		}
+		parts := strings.FieldsFunc(s[open+1:end], func(r rune) bool { return r == ',' || r == ' ' || r == '/' })
+		if len(parts) < 3 {
+			return color.NRGBA{}, false
+		}
+		var ch [4]uint8
+		ch[3] = 255
+		for i, part := range parts[:min(len(parts), 4)] {
+			v, err := strconv.ParseFloat(strings.TrimSuffix(part, "%"), 64)
+			if err != nil {
+				return color.NRGBA{}, false
+			}
+			switch {
+			case strings.HasSuffix(part, "%"):
+				v = v / 100 * 255
+			case i == 3:
+				v *= 255
+			}
+			ch[i] = uint8(math.Max(0, math.Min(255, math.Round(v))))
+		}
+		return color.NRGBA{R: ch[0], G: ch[1], B: ch[2], A: ch[3]}, true
+	}
+
+	return color.NRGBA{}, false
+}
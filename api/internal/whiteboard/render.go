@@ -0,0 +1,283 @@
+package whiteboard
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	// contentPadding surrounds the content when no region is given.
+	contentPadding = 20
+	// MaxDimension caps the output size in pixels on either axis, unless
+	// Options sets a lower cap.
+	MaxDimension = 8192
+	// maxPixels caps the output area, so that a wide render is not also
+	// tall: 16 megapixels take 64 MB as RGBA.
+	maxPixels = 16 << 20
+	// emptyBoardSize is the region rendered for a board without objects.
+	emptyBoardSize = 100
+)
+
+var ErrInvalidRegion = errors.New("region must have a positive width and height")
+
+// Options controls which part of a board is rendered and at what size.
+type Options struct {
+	// Region is the part of the board to render in board coordinates.
+	// Nil fits all selected content.
+	Region *Rect
+	// Scale is the number of output pixels per board unit. Zero derives it
+	// from Width and Height, or uses 1.
+	Scale float64
+	// Width and Height bound the output size in pixels when Scale is zero.
+	Width  int
+	Height int
+	// MaxDimension caps the output size in pixels on either axis. Zero or
+	// values over the package MaxDimension use MaxDimension.
+	MaxDimension int
+	// Layers selects what to draw. Nil draws every layer.
+	Layers map[Layer]bool
+	// Background is a CSS color, or "transparent". Empty means white.
+	Background string
+}
+
+// frame is the resolved output geometry of a render.
+type frame struct {
+	region Rect
+	scale  float64
+	width  int
+	height int
+}
+
+// prepare applies the layer selection and computes the output frame.
+func (b *Board) prepare(opts Options) (*Board, frame, error) {
+	board := b
+	if opts.Layers != nil {
+		board = b.Filter(opts.Layers)
+	}
+
+	var region Rect
+	if opts.Region != nil {
+		region = *opts.Region
+		if region.Empty() {
+			return nil, frame{}, ErrInvalidRegion
+		}
+	} else if r, ok := board.Bounds(); ok {
+		region = r.inset(-contentPadding)
+	} else {
+		region = Rect{MaxX: emptyBoardSize, MaxY: emptyBoardSize}
+	}
+
+	scale := opts.Scale
+	if scale <= 0 {
+		scale = 1
+		if opts.Width > 0 || opts.Height > 0 {
+			scale = math.Inf(1)
+			if opts.Width > 0 {
+				scale = math.Min(scale, float64(opts.Width)/region.Width())
+			}
+			if opts.Height > 0 {
+				scale = math.Min(scale, float64(opts.Height)/region.Height())
+			}
+		}
+	}
+	limit := float64(MaxDimension)
+	if opts.MaxDimension > 0 && opts.MaxDimension < MaxDimension {
+		limit = float64(opts.MaxDimension)
+	}
+	if longest := math.Max(region.Width(), region.Height()) * scale; longest > limit {
+		scale *= limit / longest
+	}
+	if area := region.Width() * region.Height() * scale * scale; area > maxPixels {
+		scale *= math.Sqrt(maxPixels / area)
+	}
+
+	f := frame{
+		region: region,
+		scale:  scale,
+		width:  max(1, int(math.Ceil(region.Width()*scale))),
+		height: max(1, int(math.Ceil(region.Height()*scale))),
+	}
+
+	// Skip objects that are entirely outside the region.
+	visible := &Board{}
+	for _, obj := range board.Objects {
+		if ob, ok := obj.Bounds(); ok && ob.intersects(region) {
+			visible.Objects = append(visible.Objects, obj)
+		}
+	}
+
+	return visible, f, nil
+}
+
+// ---------- Shared geometry ----------
+
+// cardStyle is how note and view cards are drawn: a rounded box with the
+// object name as its label, like the note overlay of the web client.
+type cardStyle struct {
+	fill, border, label string
+	placeholder         string
+}
+
+var cardStyles = map[Layer]cardStyle{
+	LayerNotes: {fill: "#fefce8", border: "#facc15", label: "#a16207", placeholder: "Note"},
+	LayerViews: {fill: "#fafafa", border: "#d4d4d4", label: "#404040", placeholder: "View"},
+}
+
+const (
+	cardRadius    = 8
+	cardBorder    = 2
+	cardPadding   = 16
+	cardLabelSize = 14
+)
+
+func (o Object) cardLabel() string {
+	if o.Name != "" {
+		return o.Name
+	}
+	return cardStyles[o.Layer].placeholder
+}
+
+const (
+	arrowSize   = 10
+	bezierSteps = 24
+	circleSteps = 64
+)
+
+// dashPatterns mirrors the stroke-dasharray values of the web client.
+var dashPatterns = map[string][]float64{
+	"dashed": {10, 5},
+	"dotted": {2, 4},
+}
+
+// segment is a straight line or a quadratic curve to To.
+type segment struct {
+	quad bool
+	ctrl Point
+	to   Point
+}
+
+// edgePath returns the path of an edge as drawn by the web client.
+func edgePath(e *Edge) (Point, []segment) {
+	s, t := e.StartPoint, e.EndPoint
+	midX, midY := (s.X+t.X)/2, (s.Y+t.Y)/2
+
+	switch e.CurveType {
+	case "bezier":
+		return s, []segment{
+			{quad: true, ctrl: Point{midX, s.Y}, to: Point{midX, midY}},
+			{quad: true, ctrl: Point{midX, t.Y}, to: t},
+		}
+	case "elbow":
+		return s, []segment{{to: Point{midX, s.Y}}, {to: Point{midX, t.Y}}, {to: t}}
+	}
+	return s, []segment{{to: t}}
+}
+
+// edgeArrowAngles returns the directions, in radians, the arrow heads at the
+// start and end of an edge point to.
+func edgeArrowAngles(e *Edge) (float64, float64) {
+	s, t := e.StartPoint, e.EndPoint
+	midX := (s.X + t.X) / 2
+
+	switch e.CurveType {
+	case "bezier":
+		return math.Atan2(0, midX-s.X) + math.Pi, math.Atan2(0, t.X-midX)
+	case "elbow":
+		start, end := 0.0, math.Pi
+		if s.X < midX {
+			start = math.Pi
+		}
+		if t.X > midX {
+			end = 0
+		}
+		return start, end
+	}
+	angle := math.Atan2(t.Y-s.Y, t.X-s.X)
+	return angle + math.Pi, angle
+}
+
+// arrowHead returns the triangle of an arrow head with its tip at p.
+func arrowHead(p Point, angle float64) []Point {
+	sin, cos := math.Sincos(angle)
+	rot := func(x, y float64) Point {
+		return Point{p.X + x*cos - y*sin, p.Y + x*sin + y*cos}
+	}
+	return []Point{rot(0, 0), rot(-arrowSize, arrowSize/2), rot(-arrowSize, -arrowSize/2)}
+}
+
+// flatten approximates a path with a polyline.
+func flatten(start Point, segs []segment) []Point {
+	points := []Point{start}
+	cur := start
+	for _, seg := range segs {
+		if !seg.quad {
+			points = append(points, seg.to)
+			cur = seg.to
+			continue
+		}
+		for i := 1; i <= bezierSteps; i++ {
+			t := float64(i) / bezierSteps
+			u := 1 - t
+			points = append(points, Point{
+				X: u*u*cur.X + 2*u*t*seg.ctrl.X + t*t*seg.to.X,
+				Y: u*u*cur.Y + 2*u*t*seg.ctrl.Y + t*t*seg.to.Y,
+			})
+		}
+		cur = seg.to
+	}
+	return points
+}
+
+// shapeOutline returns the outline of a shape as a polyline, closed for
+// rectangles and circles.
+func shapeOutline(s *Shape) (points []Point, closed bool) {
+	p, d := s.Position, s.Dimensions
+	switch s.Type {
+	case "circle":
+		radius := s.radius()
+		for i := range circleSteps {
+			a := 2 * math.Pi * float64(i) / circleSteps
+			points = append(points, Point{p.X + radius*math.Cos(a), p.Y + radius*math.Sin(a)})
+		}
+		return points, true
+	case "line":
+		return []Point{p, {p.X + d.Width, p.Y + d.Height}}, false
+	}
+	return []Point{p, {p.X + d.Width, p.Y}, {p.X + d.Width, p.Y + d.Height}, {p.X, p.Y + d.Height}}, true
+}
+
+// dash splits a polyline into the visible pieces of a dash pattern.
+func dash(points []Point, pattern []float64) [][]Point {
+	if len(pattern) == 0 || len(points) < 2 {
+		return [][]Point{points}
+	}
+
+	var pieces [][]Point
+	idx, remaining, on := 0, pattern[0], true
+	current := []Point{points[0]}
+
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		length := math.Hypot(b.X-a.X, b.Y-a.Y)
+		pos := 0.0
+		for length-pos > remaining {
+			pos += remaining
+			t := pos / length
+			p := Point{a.X + (b.X-a.X)*t, a.Y + (b.Y-a.Y)*t}
+			if on {
+				pieces = append(pieces, append(current, p))
+			}
+			current = []Point{p}
+			on = !on
+			idx = (idx + 1) % len(pattern)
+			remaining = pattern[idx]
+		}
+		remaining -= length - pos
+		current = append(current, b)
+	}
+	if on && len(current) > 1 {
+		pieces = append(pieces, current)
+	}
+
+	return pieces
+}
@@ -0,0 +1,186 @@
+package whiteboard
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RenderSVG draws the board as a standalone SVG document. Output pixels map
+// to board units through the view box, so the result scales cleanly.
+func (b *Board) RenderSVG(w io.Writer, opts Options) error {
+	board, f, err := b.prepare(opts)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	r := f.region
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="%s %s %s %s">`,
+		f.width, f.height, num(r.MinX), num(r.MinY), num(r.Width()), num(r.Height()))
+
+	if bg := background(opts.Background); bg != "" {
+		fmt.Fprintf(&buf, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`,
+			num(r.MinX), num(r.MinY), num(r.Width()), num(r.Height()), attr(bg))
+	}
+
+	for _, obj := range board.Objects {
+		switch {
+		case obj.Stroke != nil:
+			svgStroke(&buf, obj.Stroke)
+		case obj.Shape != nil:
+			svgShape(&buf, obj.Shape)
+		case obj.Text != nil:
+			svgText(&buf, obj.Text)
+		case obj.Card != nil:
+			svgCard(&buf, obj)
+		case obj.Edge != nil:
+			svgEdge(&buf, obj.Edge)
+		}
+	}
+
+	buf.WriteString(`</svg>`)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func svgStroke(buf *bytes.Buffer, s *Stroke) {
+	if len(s.Points) == 1 {
+		p := s.Points[0]
+		fmt.Fprintf(buf, `<circle cx="%s" cy="%s" r="%s" fill="%s"/>`,
+			num(p.X), num(p.Y), num(strokeWidth(s.Width)/2), attr(colorOr(s.Color)))
+		return
+	}
+	fmt.Fprintf(buf, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%s" stroke-linecap="round" stroke-linejoin="round"/>`,
+		pointList(s.Points), attr(colorOr(s.Color)), num(strokeWidth(s.Width)))
+}
+
+func svgShape(buf *bytes.Buffer, s *Shape) {
+	color := attr(colorOr(s.Color))
+	fill := "none"
+	if s.Filled {
+		fill = color
+	}
+	sw := num(strokeWidth(s.StrokeWidth))
+
+	p, d := s.Position, s.Dimensions
+	switch s.Type {
+	case "circle":
+		fmt.Fprintf(buf, `<circle cx="%s" cy="%s" r="%s" fill="%s" stroke="%s" stroke-width="%s"/>`,
+			num(p.X), num(p.Y), num(s.radius()), fill, color, sw)
+	case "line":
+		fmt.Fprintf(buf, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="%s" stroke-width="%s" stroke-linecap="round"/>`,
+			num(p.X), num(p.Y), num(p.X+d.Width), num(p.Y+d.Height), color, sw)
+	default:
+		r, _ := pointsBounds([]Point{p, {p.X + d.Width, p.Y + d.Height}})
+		fmt.Fprintf(buf, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s" stroke="%s" stroke-width="%s"/>`,
+			num(r.MinX), num(r.MinY), num(r.Width()), num(r.Height()), fill, color, sw)
+	}
+}
+
+func svgText(buf *bytes.Buffer, t *Text) {
+	family := t.FontFamily
+	if family == "" {
+		family = "sans-serif"
+	}
+	fmt.Fprintf(buf, `<text x="%s" y="%s" font-family="%s" font-size="%s" fill="%s" xml:space="preserve"`,
+		num(t.Position.X), num(t.Position.Y), attr(family), num(t.fontSize()), attr(t.color()))
+	if t.FontWeight == "bold" {
+		buf.WriteString(` font-weight="bold"`)
+	}
+	if t.FontStyle == "italic" {
+		buf.WriteString(` font-style="italic"`)
+	}
+	if t.TextDecoration == "underline" {
+		buf.WriteString(` text-decoration="underline"`)
+	}
+	buf.WriteString(`>`)
+	xml.EscapeText(buf, []byte(t.displayText()))
+	buf.WriteString(`</text>`)
+}
+
+func svgCard(buf *bytes.Buffer, o Object) {
+	style := cardStyles[o.Layer]
+	c := o.Card
+	w, h := c.size()
+	fmt.Fprintf(buf, `<rect x="%s" y="%s" width="%s" height="%s" rx="%d" fill="%s" stroke="%s" stroke-width="%d"/>`,
+		num(c.Position.X), num(c.Position.Y), num(w), num(h), cardRadius, style.fill, style.border, cardBorder)
+
+	labelX := c.Position.X + cardPadding
+	face := fontFace(fontVariant{bold: true}, cardLabelSize)
+	label := truncateText(face, o.cardLabel(), w-cardPadding*2)
+	fmt.Fprintf(buf, `<text x="%s" y="%s" font-family="sans-serif" font-size="%d" font-weight="500" fill="%s">`,
+		num(labelX), num(c.Position.Y+cardPadding+cardLabelSize), cardLabelSize, style.label)
+	xml.EscapeText(buf, []byte(label))
+	buf.WriteString(`</text>`)
+}
+
+func svgEdge(buf *bytes.Buffer, e *Edge) {
+	color := attr(colorOr(e.Color))
+
+	start, segs := edgePath(e)
+	var d strings.Builder
+	fmt.Fprintf(&d, "M %s %s", num(start.X), num(start.Y))
+	for _, seg := range segs {
+		if seg.quad {
+			fmt.Fprintf(&d, " Q %s %s %s %s", num(seg.ctrl.X), num(seg.ctrl.Y), num(seg.to.X), num(seg.to.Y))
+		} else {
+			fmt.Fprintf(&d, " L %s %s", num(seg.to.X), num(seg.to.Y))
+		}
+	}
+
+	fmt.Fprintf(buf, `<path d="%s" fill="none" stroke="%s" stroke-width="%s" stroke-linecap="round" stroke-linejoin="round"`,
+		d.String(), color, num(strokeWidth(e.StrokeWidth)))
+	if pattern, ok := dashPatterns[e.LineStyle]; ok {
+		fmt.Fprintf(buf, ` stroke-dasharray="%s %s"`, num(pattern[0]), num(pattern[1]))
+	}
+	buf.WriteString(`/>`)
+
+	startAngle, endAngle := edgeArrowAngles(e)
+	if e.ArrowType == "start" || e.ArrowType == "both" {
+		fmt.Fprintf(buf, `<polygon points="%s" fill="%s"/>`, pointList(arrowHead(e.StartPoint, startAngle)), color)
+	}
+	if e.ArrowType == "end" || e.ArrowType == "both" {
+		fmt.Fprintf(buf, `<polygon points="%s" fill="%s"/>`, pointList(arrowHead(e.EndPoint, endAngle)), color)
+	}
+}
+
+// background resolves the background option to a fill, or "" for none.
+func background(bg string) string {
+	switch strings.ToLower(strings.TrimSpace(bg)) {
+	case "":
+		return "#ffffff"
+	case "transparent", "none":
+		return ""
+	}
+	return bg
+}
+
+func pointList(points []Point) string {
+	var sb strings.Builder
+	for i, p := range points {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(num(p.X))
+		sb.WriteByte(',')
+		sb.WriteString(num(p.Y))
+	}
+	return sb.String()
+}
+
+// num formats a coordinate compactly, to a hundredth of a unit.
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+// attr escapes an attribute value taken from user data.
+func attr(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,99 @@
+package whiteboard
+
+import (
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/gomonobolditalic"
+	"golang.org/x/image/font/gofont/gomonoitalic"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+)
+
+// The Go fonts stand in for the browser's generic font families when
+// measuring and rasterizing text: proportional for sans-serif and serif,
+// Go Mono for monospace.
+var fontData = map[fontVariant][]byte{
+	{}:                                     goregular.TTF,
+	{bold: true}:                           gobold.TTF,
+	{italic: true}:                         goitalic.TTF,
+	{bold: true, italic: true}:             gobolditalic.TTF,
+	{mono: true}:                           gomono.TTF,
+	{mono: true, bold: true}:               gomonobold.TTF,
+	{mono: true, italic: true}:             gomonoitalic.TTF,
+	{mono: true, bold: true, italic: true}: gomonobolditalic.TTF,
+}
+
+type fontVariant struct {
+	mono   bool
+	bold   bool
+	italic bool
+}
+
+var (
+	fontsOnce sync.Once
+	fonts     map[fontVariant]*opentype.Font
+)
+
+func loadFonts() {
+	fonts = make(map[fontVariant]*opentype.Font, len(fontData))
+	for v, data := range fontData {
+		f, err := opentype.Parse(data)
+		if err != nil {
+			// The embedded fonts are known to be valid.
+			panic(err)
+		}
+		fonts[v] = f
+	}
+}
+
+func (t *Text) variant() fontVariant {
+	return fontVariant{
+		mono:   t.FontFamily == "monospace",
+		bold:   t.FontWeight == "bold",
+		italic: t.FontStyle == "italic",
+	}
+}
+
+// face returns the font face of the text at the given pixel size.
+func (t *Text) face(size float64) font.Face {
+	return fontFace(t.variant(), size)
+}
+
+func fontFace(v fontVariant, size float64) font.Face {
+	fontsOnce.Do(loadFonts)
+	face, err := opentype.NewFace(fonts[v], &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingNone,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return face
+}
+
+// measureText returns the advance width of s in pixels.
+func measureText(face font.Face, s string) float64 {
+	return float64(font.MeasureString(face, s)) / 64
+}
+
+// truncateText shortens s with an ellipsis so that it fits in width pixels.
+func truncateText(face font.Face, s string, width float64) string {
+	if measureText(face, s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for n := len(runes) - 1; n > 0; n-- {
+		candidate := string(runes[:n]) + "…"
+		if measureText(face, candidate) <= width {
+			return candidate
+		}
+	}
+	return ""
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_note_properties_property_id;
+DROP TABLE IF EXISTS note_properties;
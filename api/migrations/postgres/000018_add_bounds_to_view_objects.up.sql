@@ -0,0 +1,6 @@
+ALTER TABLE view_objects ADD COLUMN min_x DOUBLE PRECISION;
+ALTER TABLE view_objects ADD COLUMN min_y DOUBLE PRECISION;
+ALTER TABLE view_objects ADD COLUMN max_x DOUBLE PRECISION;
+ALTER TABLE view_objects ADD COLUMN max_y DOUBLE PRECISION;
+
+CREATE INDEX idx_view_objects_bounds ON view_objects (view_id, min_x, max_x, min_y, max_y);
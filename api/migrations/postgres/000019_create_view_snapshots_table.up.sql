@@ -0,0 +1,16 @@
+CREATE TABLE view_snapshots (
+    id VARCHAR(255),
+    view_id VARCHAR(255),
+    name TEXT,
+    kind VARCHAR(255),
+    hash VARCHAR(255),
+    data TEXT,
+    objects TEXT,
+    object_count INTEGER,
+    created_at TEXT,
+    created_by VARCHAR(255),
+    PRIMARY KEY (id),
+    CONSTRAINT fk_view_snapshots_view FOREIGN KEY (view_id) REFERENCES views(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_view_snapshots_view_id_created_at ON view_snapshots (view_id, created_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_view_templates_workspace_id;
+DROP TABLE IF EXISTS view_templates;
@@ -0,0 +1,18 @@
+CREATE TABLE view_templates (
+    id VARCHAR(255),
+    workspace_id VARCHAR(255),
+    name TEXT,
+    description TEXT,
+    type VARCHAR(255),
+    data TEXT,
+    objects TEXT,
+    object_count INTEGER,
+    created_at TEXT,
+    created_by VARCHAR(255),
+    updated_at TEXT,
+    updated_by VARCHAR(255),
+    PRIMARY KEY (id),
+    CONSTRAINT fk_view_templates_workspace FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_view_templates_workspace_id ON view_templates (workspace_id);
@@ -0,0 +1,55 @@
+CREATE TABLE rss_feeds (
+    id VARCHAR(255),
+    url TEXT,
+    title TEXT,
+    description TEXT,
+    link TEXT,
+    etag TEXT,
+    last_modified TEXT,
+    fetched_at TEXT,
+    next_fetch_at TEXT,
+    failures INTEGER DEFAULT 0,
+    last_error TEXT,
+    created_at TEXT,
+    PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX idx_rss_feeds_url ON rss_feeds (url);
+
+CREATE TABLE rss_items (
+    id VARCHAR(255),
+    feed_id VARCHAR(255),
+    guid TEXT,
+    title TEXT,
+    link TEXT,
+    description TEXT,
+    pub_date TEXT,
+    image_url TEXT,
+    published_at VARCHAR(255),
+    created_at TEXT,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_rss_items_feed FOREIGN KEY (feed_id) REFERENCES rss_feeds(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_rss_items_feed_id_guid ON rss_items (feed_id, guid);
+CREATE INDEX idx_rss_items_feed_id_published_at ON rss_items (feed_id, published_at);
+
+CREATE TABLE rss_subscriptions (
+    user_id VARCHAR(255),
+    feed_id VARCHAR(255),
+    created_at TEXT,
+    PRIMARY KEY (user_id, feed_id),
+    CONSTRAINT fk_rss_subscriptions_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_rss_subscriptions_feed FOREIGN KEY (feed_id) REFERENCES rss_feeds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_rss_subscriptions_feed_id ON rss_subscriptions (feed_id);
+
+CREATE TABLE rss_item_reads (
+    user_id VARCHAR(255),
+    item_id VARCHAR(255),
+    read_at TEXT,
+    PRIMARY KEY (user_id, item_id),
+    CONSTRAINT fk_rss_item_reads_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_rss_item_reads_item FOREIGN KEY (item_id) REFERENCES rss_items(id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+ALTER TABLE notes ADD COLUMN source_url TEXT;
@@ -0,0 +1,15 @@
+CREATE TABLE link_previews (
+    url TEXT,
+    title TEXT,
+    description TEXT,
+    image TEXT,
+    favicon TEXT,
+    site_name TEXT,
+    oembed TEXT,
+    error TEXT,
+    fetched_at TEXT,
+    expires_at TEXT,
+    PRIMARY KEY (url)
+);
+
+CREATE INDEX idx_link_previews_expires_at ON link_previews (expires_at);
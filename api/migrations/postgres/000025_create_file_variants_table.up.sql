@@ -0,0 +1,11 @@
+CREATE TABLE file_variants (
+    workspace_id VARCHAR(255) NOT NULL,
+    file_name VARCHAR(255),
+    variant VARCHAR(255),
+    name VARCHAR(255),
+    content_type VARCHAR(255),
+    size BIGINT,
+    created_at TEXT,
+    PRIMARY KEY (workspace_id, file_name, variant),
+    CONSTRAINT fk_file_variants_workspace FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+ALTER TABLE files ADD COLUMN hash VARCHAR(64);
+
+CREATE INDEX idx_files_hash ON files (hash);
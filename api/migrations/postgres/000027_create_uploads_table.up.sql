@@ -0,0 +1,20 @@
+CREATE TABLE uploads (
+    id VARCHAR(255) NOT NULL,
+    workspace_id VARCHAR(255) NOT NULL,
+    filename TEXT,
+    length BIGINT NOT NULL DEFAULT 0,
+    received BIGINT NOT NULL DEFAULT 0,
+    stored BIGINT NOT NULL DEFAULT 0,
+    storage_id TEXT,
+    parts TEXT,
+    hash_state TEXT,
+    file_id VARCHAR(255),
+    file_name VARCHAR(255),
+    created_at VARCHAR(255),
+    created_by VARCHAR(255),
+    updated_at VARCHAR(255),
+    PRIMARY KEY (id),
+    CONSTRAINT fk_uploads_workspace FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_uploads_updated_at ON uploads (updated_at);
@@ -0,0 +1 @@
+ALTER TABLE uploads ADD COLUMN method VARCHAR(255) NOT NULL DEFAULT 'tus';
@@ -0,0 +1,13 @@
+CREATE TABLE canvas_objects (
+    view_id VARCHAR(255) NOT NULL,
+    id VARCHAR(255) NOT NULL,
+    data TEXT,
+    min_x DOUBLE PRECISION,
+    min_y DOUBLE PRECISION,
+    max_x DOUBLE PRECISION,
+    max_y DOUBLE PRECISION,
+    PRIMARY KEY (view_id, id),
+    CONSTRAINT fk_canvas_objects_view FOREIGN KEY (view_id) REFERENCES views(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_canvas_objects_bounds ON canvas_objects (view_id, min_x, max_x, min_y, max_y);
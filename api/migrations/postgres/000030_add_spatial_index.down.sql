@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_canvas_objects_bounds;
+DROP INDEX IF EXISTS idx_view_objects_bounds;
+DROP INDEX IF EXISTS idx_canvas_objects_view_id;
+DROP INDEX IF EXISTS idx_view_objects_view_id;
+
+CREATE INDEX idx_view_objects_bounds ON view_objects (view_id, min_x, max_x, min_y, max_y);
+CREATE INDEX idx_canvas_objects_bounds ON canvas_objects (view_id, min_x, max_x, min_y, max_y);
@@ -0,0 +1,11 @@
+-- The bounds of view objects and canvas objects are indexed as boxes with
+-- GiST. A B-tree on the bounds can only narrow one side of a viewport, so a
+-- viewport query read every object of the view. The B-tree keeps
+-- (view_id, min_x) to find the objects of a view and those without bounds.
+DROP INDEX IF EXISTS idx_view_objects_bounds;
+DROP INDEX IF EXISTS idx_canvas_objects_bounds;
+CREATE INDEX idx_view_objects_view_id ON view_objects (view_id, min_x);
+CREATE INDEX idx_canvas_objects_view_id ON canvas_objects (view_id, min_x);
+
+CREATE INDEX idx_view_objects_bounds ON view_objects USING gist (box(point(min_x, min_y), point(max_x, max_y))) WHERE min_x IS NOT NULL;
+CREATE INDEX idx_canvas_objects_bounds ON canvas_objects USING gist (box(point(min_x, min_y), point(max_x, max_y))) WHERE min_x IS NOT NULL;
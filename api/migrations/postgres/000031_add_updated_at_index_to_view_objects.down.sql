@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_view_objects_view_id_updated_at;
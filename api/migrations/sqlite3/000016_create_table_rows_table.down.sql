@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS `idx_table_rows_note_id`;
+DROP TABLE IF EXISTS `table_rows`;
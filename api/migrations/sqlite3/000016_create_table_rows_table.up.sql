@@ -0,0 +1,11 @@
+CREATE TABLE `table_rows` (
+    `view_id` text,
+    `note_id` text,
+    `created_at` text,
+    `created_by` text,
+    PRIMARY KEY (`view_id`, `note_id`),
+    CONSTRAINT `fk_table_rows_view` FOREIGN KEY (`view_id`) REFERENCES `views`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_table_rows_note` FOREIGN KEY (`note_id`) REFERENCES `notes`(`id`) ON DELETE CASCADE
+);
+
+CREATE INDEX `idx_table_rows_note_id` ON `table_rows` (`note_id`);
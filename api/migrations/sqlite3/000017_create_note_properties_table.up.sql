@@ -0,0 +1,12 @@
+CREATE TABLE `note_properties` (
+    `note_id` text,
+    `property_id` text,
+    `value` text,
+    `updated_at` text,
+    `updated_by` text,
+    PRIMARY KEY (`note_id`, `property_id`),
+    CONSTRAINT `fk_note_properties_note` FOREIGN KEY (`note_id`) REFERENCES `notes`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_note_properties_property` FOREIGN KEY (`property_id`) REFERENCES `view_objects`(`id`) ON DELETE CASCADE
+);
+
+CREATE INDEX `idx_note_properties_property_id` ON `note_properties` (`property_id`);
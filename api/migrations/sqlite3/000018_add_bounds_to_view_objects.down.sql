@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_view_objects_bounds;
+ALTER TABLE view_objects DROP COLUMN max_y;
+ALTER TABLE view_objects DROP COLUMN max_x;
+ALTER TABLE view_objects DROP COLUMN min_y;
+ALTER TABLE view_objects DROP COLUMN min_x;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_view_snapshots_view_id_created_at;
+DROP TABLE IF EXISTS view_snapshots;
@@ -0,0 +1,16 @@
+CREATE TABLE `view_snapshots` (
+    `id` text,
+    `view_id` text,
+    `name` text,
+    `kind` text,
+    `hash` text,
+    `data` text,
+    `objects` text,
+    `object_count` integer,
+    `created_at` text,
+    `created_by` text,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_view_snapshots_view` FOREIGN KEY (`view_id`) REFERENCES `views`(`id`) ON DELETE CASCADE
+);
+
+CREATE INDEX `idx_view_snapshots_view_id_created_at` ON `view_snapshots` (`view_id`, `created_at`);
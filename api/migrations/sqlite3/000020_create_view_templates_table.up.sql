@@ -0,0 +1,18 @@
+CREATE TABLE `view_templates` (
+    `id` text,
+    `workspace_id` text,
+    `name` text,
+    `description` text,
+    `type` text,
+    `data` text,
+    `objects` text,
+    `object_count` integer,
+    `created_at` text,
+    `created_by` text,
+    `updated_at` text,
+    `updated_by` text,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_view_templates_workspace` FOREIGN KEY (`workspace_id`) REFERENCES `workspaces`(`id`) ON DELETE CASCADE
+);
+
+CREATE INDEX `idx_view_templates_workspace_id` ON `view_templates` (`workspace_id`);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS rss_item_reads;
+DROP INDEX IF EXISTS idx_rss_subscriptions_feed_id;
+DROP TABLE IF EXISTS rss_subscriptions;
+DROP INDEX IF EXISTS idx_rss_items_feed_id_published_at;
+DROP INDEX IF EXISTS idx_rss_items_feed_id_guid;
+DROP TABLE IF EXISTS rss_items;
+DROP INDEX IF EXISTS idx_rss_feeds_url;
+DROP TABLE IF EXISTS rss_feeds;
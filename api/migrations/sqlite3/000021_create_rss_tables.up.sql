@@ -0,0 +1,55 @@
+CREATE TABLE `rss_feeds` (
+    `id` text,
+    `url` text,
+    `title` text,
+    `description` text,
+    `link` text,
+    `etag` text,
+    `last_modified` text,
+    `fetched_at` text,
+    `next_fetch_at` text,
+    `failures` integer DEFAULT 0,
+    `last_error` text,
+    `created_at` text,
+    PRIMARY KEY (`id`)
+);
+
+CREATE UNIQUE INDEX `idx_rss_feeds_url` ON `rss_feeds` (`url`);
+
+CREATE TABLE `rss_items` (
+    `id` text,
+    `feed_id` text,
+    `guid` text,
+    `title` text,
+    `link` text,
+    `description` text,
+    `pub_date` text,
+    `image_url` text,
+    `published_at` text,
+    `created_at` text,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_rss_items_feed` FOREIGN KEY (`feed_id`) REFERENCES `rss_feeds`(`id`) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX `idx_rss_items_feed_id_guid` ON `rss_items` (`feed_id`, `guid`);
+CREATE INDEX `idx_rss_items_feed_id_published_at` ON `rss_items` (`feed_id`, `published_at`);
+
+CREATE TABLE `rss_subscriptions` (
+    `user_id` text,
+    `feed_id` text,
+    `created_at` text,
+    PRIMARY KEY (`user_id`, `feed_id`),
+    CONSTRAINT `fk_rss_subscriptions_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_rss_subscriptions_feed` FOREIGN KEY (`feed_id`) REFERENCES `rss_feeds`(`id`) ON DELETE CASCADE
+);
+
+CREATE INDEX `idx_rss_subscriptions_feed_id` ON `rss_subscriptions` (`feed_id`);
+
+CREATE TABLE `rss_item_reads` (
+    `user_id` text,
+    `item_id` text,
+    `read_at` text,
+    PRIMARY KEY (`user_id`, `item_id`),
+    CONSTRAINT `fk_rss_item_reads_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_rss_item_reads_item` FOREIGN KEY (`item_id`) REFERENCES `rss_items`(`id`) ON DELETE CASCADE
+);
@@ -0,0 +1,15 @@
+CREATE TABLE `link_previews` (
+    `url` text,
+    `title` text,
+    `description` text,
+    `image` text,
+    `favicon` text,
+    `site_name` text,
+    `oembed` text,
+    `error` text,
+    `fetched_at` text,
+    `expires_at` text,
+    PRIMARY KEY (`url`)
+);
+
+CREATE INDEX `idx_link_previews_expires_at` ON `link_previews` (`expires_at`);
@@ -0,0 +1 @@
+ALTER TABLE files ADD COLUMN content_type TEXT;
@@ -0,0 +1,11 @@
+CREATE TABLE `file_variants` (
+    `workspace_id` text NOT NULL,
+    `file_name` text,
+    `variant` text,
+    `name` text,
+    `content_type` text,
+    `size` integer,
+    `created_at` text,
+    PRIMARY KEY (`workspace_id`, `file_name`, `variant`),
+    CONSTRAINT `fk_file_variants_workspace` FOREIGN KEY (`workspace_id`) REFERENCES `workspaces`(`id`) ON DELETE CASCADE
+);
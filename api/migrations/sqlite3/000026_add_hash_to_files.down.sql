@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_files_hash;
+ALTER TABLE files DROP COLUMN hash;
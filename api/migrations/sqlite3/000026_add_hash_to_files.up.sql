@@ -0,0 +1,3 @@
+ALTER TABLE `files` ADD COLUMN `hash` text;
+
+CREATE INDEX `idx_files_hash` ON `files` (`hash`);
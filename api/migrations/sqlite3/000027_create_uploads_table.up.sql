@@ -0,0 +1,20 @@
+CREATE TABLE `uploads` (
+    `id` text NOT NULL,
+    `workspace_id` text NOT NULL,
+    `filename` text,
+    `length` integer NOT NULL DEFAULT 0,
+    `received` integer NOT NULL DEFAULT 0,
+    `stored` integer NOT NULL DEFAULT 0,
+    `storage_id` text,
+    `parts` text,
+    `hash_state` text,
+    `file_id` text,
+    `file_name` text,
+    `created_at` text,
+    `created_by` text,
+    `updated_at` text,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_uploads_workspace` FOREIGN KEY (`workspace_id`) REFERENCES `workspaces`(`id`) ON DELETE CASCADE
+);
+
+CREATE INDEX `idx_uploads_updated_at` ON `uploads` (`updated_at`);
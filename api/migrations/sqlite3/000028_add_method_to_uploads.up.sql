@@ -0,0 +1 @@
+ALTER TABLE `uploads` ADD COLUMN `method` text NOT NULL DEFAULT 'tus';
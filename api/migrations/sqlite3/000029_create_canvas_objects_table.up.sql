@@ -0,0 +1,13 @@
+CREATE TABLE `canvas_objects` (
+    `view_id` text NOT NULL,
+    `id` text NOT NULL,
+    `data` text,
+    `min_x` real,
+    `min_y` real,
+    `max_x` real,
+    `max_y` real,
+    PRIMARY KEY (`view_id`, `id`),
+    CONSTRAINT `fk_canvas_objects_view` FOREIGN KEY (`view_id`) REFERENCES `views`(`id`) ON DELETE CASCADE
+);
+
+CREATE INDEX `idx_canvas_objects_bounds` ON `canvas_objects` (`view_id`, `min_x`, `max_x`, `min_y`, `max_y`);
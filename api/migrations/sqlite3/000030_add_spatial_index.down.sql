@@ -0,0 +1,18 @@
+DROP TRIGGER IF EXISTS `canvas_objects_bounds_delete`;
+DROP TRIGGER IF EXISTS `canvas_objects_bounds_update`;
+DROP TRIGGER IF EXISTS `canvas_objects_bounds_insert`;
+DROP TRIGGER IF EXISTS `view_objects_bounds_delete`;
+DROP TRIGGER IF EXISTS `view_objects_bounds_update`;
+DROP TRIGGER IF EXISTS `view_objects_bounds_insert`;
+
+DROP INDEX IF EXISTS `idx_canvas_objects_bounds_id`;
+DROP INDEX IF EXISTS `idx_view_objects_bounds_id`;
+DROP INDEX IF EXISTS `idx_canvas_objects_view_id`;
+DROP INDEX IF EXISTS `idx_view_objects_view_id`;
+DROP TABLE IF EXISTS `canvas_objects_bounds`;
+DROP TABLE IF EXISTS `view_objects_bounds`;
+ALTER TABLE `canvas_objects` DROP COLUMN `bounds_id`;
+ALTER TABLE `view_objects` DROP COLUMN `bounds_id`;
+
+CREATE INDEX `idx_view_objects_bounds` ON `view_objects` (`view_id`, `min_x`, `max_x`, `min_y`, `max_y`);
+CREATE INDEX `idx_canvas_objects_bounds` ON `canvas_objects` (`view_id`, `min_x`, `max_x`, `min_y`, `max_y`);
@@ -0,0 +1,68 @@
+-- The bounds of view objects and canvas objects are indexed with R*Trees.
+-- A B-tree on the bounds can only narrow one side of a viewport, so a
+-- viewport query read every object of the view. Each indexed row keeps the
+-- id of its R*Tree entry in bounds_id, which unlike the rowid of a table
+-- without an integer primary key is not renumbered by VACUUM. The B-tree
+-- keeps (view_id, min_x) to find the objects of a view and those without
+-- bounds.
+DROP INDEX IF EXISTS `idx_view_objects_bounds`;
+DROP INDEX IF EXISTS `idx_canvas_objects_bounds`;
+CREATE INDEX `idx_view_objects_view_id` ON `view_objects` (`view_id`, `min_x`);
+CREATE INDEX `idx_canvas_objects_view_id` ON `canvas_objects` (`view_id`, `min_x`);
+
+ALTER TABLE `view_objects` ADD COLUMN `bounds_id` integer;
+ALTER TABLE `canvas_objects` ADD COLUMN `bounds_id` integer;
+
+CREATE VIRTUAL TABLE `view_objects_bounds` USING rtree(`id`, `min_x`, `max_x`, `min_y`, `max_y`);
+CREATE VIRTUAL TABLE `canvas_objects_bounds` USING rtree(`id`, `min_x`, `max_x`, `min_y`, `max_y`);
+
+UPDATE `view_objects` SET `bounds_id` = rowid WHERE `min_x` IS NOT NULL;
+INSERT INTO `view_objects_bounds` SELECT `bounds_id`, `min_x`, `max_x`, `min_y`, `max_y` FROM `view_objects` WHERE `bounds_id` IS NOT NULL;
+UPDATE `canvas_objects` SET `bounds_id` = rowid WHERE `min_x` IS NOT NULL;
+INSERT INTO `canvas_objects_bounds` SELECT `bounds_id`, `min_x`, `max_x`, `min_y`, `max_y` FROM `canvas_objects` WHERE `bounds_id` IS NOT NULL;
+
+CREATE INDEX `idx_view_objects_bounds_id` ON `view_objects` (`bounds_id`);
+CREATE INDEX `idx_canvas_objects_bounds_id` ON `canvas_objects` (`bounds_id`);
+
+-- Rows without bounds have no entry; they match every viewport.
+CREATE TRIGGER `view_objects_bounds_insert` AFTER INSERT ON `view_objects`
+WHEN new.`min_x` IS NOT NULL
+BEGIN
+    INSERT INTO `view_objects_bounds` VALUES (NULL, new.`min_x`, new.`max_x`, new.`min_y`, new.`max_y`);
+    UPDATE `view_objects` SET `bounds_id` = last_insert_rowid() WHERE rowid = new.rowid;
+END;
+
+CREATE TRIGGER `view_objects_bounds_update` AFTER UPDATE OF `min_x`, `min_y`, `max_x`, `max_y` ON `view_objects`
+BEGIN
+    DELETE FROM `view_objects_bounds` WHERE `id` = old.`bounds_id`;
+    UPDATE `view_objects` SET `bounds_id` = NULL WHERE rowid = new.rowid;
+    INSERT INTO `view_objects_bounds` SELECT NULL, new.`min_x`, new.`max_x`, new.`min_y`, new.`max_y` WHERE new.`min_x` IS NOT NULL;
+    UPDATE `view_objects` SET `bounds_id` = last_insert_rowid() WHERE rowid = new.rowid AND new.`min_x` IS NOT NULL;
+END;
+
+CREATE TRIGGER `view_objects_bounds_delete` AFTER DELETE ON `view_objects`
+WHEN old.`bounds_id` IS NOT NULL
+BEGIN
+    DELETE FROM `view_objects_bounds` WHERE `id` = old.`bounds_id`;
+END;
+
+CREATE TRIGGER `canvas_objects_bounds_insert` AFTER INSERT ON `canvas_objects`
+WHEN new.`min_x` IS NOT NULL
+BEGIN
+    INSERT INTO `canvas_objects_bounds` VALUES (NULL, new.`min_x`, new.`max_x`, new.`min_y`, new.`max_y`);
+    UPDATE `canvas_objects` SET `bounds_id` = last_insert_rowid() WHERE rowid = new.rowid;
+END;
+
+CREATE TRIGGER `canvas_objects_bounds_update` AFTER UPDATE OF `min_x`, `min_y`, `max_x`, `max_y` ON `canvas_objects`
+BEGIN
+    DELETE FROM `canvas_objects_bounds` WHERE `id` = old.`bounds_id`;
+    UPDATE `canvas_objects` SET `bounds_id` = NULL WHERE rowid = new.rowid;
+    INSERT INTO `canvas_objects_bounds` SELECT NULL, new.`min_x`, new.`max_x`, new.`min_y`, new.`max_y` WHERE new.`min_x` IS NOT NULL;
+    UPDATE `canvas_objects` SET `bounds_id` = last_insert_rowid() WHERE rowid = new.rowid AND new.`min_x` IS NOT NULL;
+END;
+
+CREATE TRIGGER `canvas_objects_bounds_delete` AFTER DELETE ON `canvas_objects`
+WHEN old.`bounds_id` IS NOT NULL
+BEGIN
+    DELETE FROM `canvas_objects_bounds` WHERE `id` = old.`bounds_id`;
+END;
@@ -0,0 +1,3 @@
+-- The snapshot scheduler compares the updated_at of the objects of each view
+-- on every round; this index covers it.
+CREATE INDEX `idx_view_objects_view_id_updated_at` ON `view_objects` (`view_id`, `updated_at`);
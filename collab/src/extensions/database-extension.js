@@ -15,9 +15,40 @@ import * as Y from 'yjs'
  *   whiteboard:{viewId}   - Whiteboard documents
  *   spreadsheet:{viewId}  - Spreadsheet documents
  */
+const MAX_OPS_HISTORY = 200
+
+/**
+ * Parse a viewport given as "minX,minY,maxX,maxY". Returns null when it is
+ * missing or invalid, and the whole board is loaded at once.
+ */
+function parseBBox(value) {
+  if (!value) return null
+  const v = value.split(',').map(Number)
+  if (v.length !== 4 || v.some(n => !Number.isFinite(n)) || v[2] < v[0] || v[3] < v[1]) {
+    return null
+  }
+  return { min_x: v[0], min_y: v[1], max_x: v[2], max_y: v[3] }
+}
+
+function yViewObject(obj) {
+  return {
+    id: obj.id,
+    type: obj.type,
+    name: obj.name,
+    data: obj.data,
+    created_by: obj.created_by,
+    updated_by: obj.updated_by,
+    created_at: obj.created_at,
+    updated_at: obj.updated_at,
+  }
+}
+
 export class DatabaseExtension {
   constructor({ db }) {
     this.db = db
+    // Whiteboards still loading the objects outside of the first viewport,
+    // by document. They are not stored until the load is done.
+    this.pendingLoads = new WeakMap()
   }
 
   /**
@@ -48,7 +79,7 @@ export class DatabaseExtension {
           await this.initializeNote(document, id)
           break
         case 'whiteboard':
-          await this.initializeWhiteboard(document, id)
+          await this.initializeWhiteboard(document, id, parseBBox(data.requestParameters?.get('bbox')))
           break
         case 'spreadsheet':
           await this.initializeSpreadsheet(document, id)
@@ -86,6 +117,114 @@ export class DatabaseExtension {
     }
   }
 
+  /**
+   * Apply a view change made outside of collab (e.g. a REST API write) to an
+   * open document, so connected clients see it and the next store does not
+   * overwrite it with stale state.
+   */
+  applyViewChange(document, change) {
+    switch (change.type) {
+      case 'spreadsheet':
+        // Spreadsheet documents do not hold view objects.
+        if (!change.objects_only) {
+          this.applySpreadsheetChange(document, change)
+        }
+        break
+      case 'whiteboard':
+        this.applyWhiteboardChange(document, change).catch((err) => {
+          console.error(`[DB] Error applying whiteboard change:`, err)
+        })
+        break
+    }
+  }
+
+  /**
+   * Replace the canvas objects and reload the view objects of a whiteboard,
+   * e.g. after a snapshot was restored. A change of the view objects only
+   * reloads them.
+   */
+  async applyWhiteboardChange(document, change) {
+    // Objects loaded after the change would be stale.
+    await this.pendingLoads.get(document)
+
+    let canvas = {}
+    if (change.objects_only) {
+      canvas = null
+    } else if (change.data) {
+      try {
+        canvas = JSON.parse(change.data) || {}
+      } catch (e) {
+        console.error(`[DB] Error parsing whiteboard change:`, e)
+        return
+      }
+    }
+    const viewObjects = await this.db.findViewObjectsByViewId(change.view_id)
+
+    document.transact(() => {
+      // Changes to the view objects alone leave the canvas as it is.
+      if (canvas) {
+        const canvasObjects = document.getMap('canvas-objects')
+        for (const key of Array.from(canvasObjects.keys())) {
+          if (!(key in canvas)) {
+            canvasObjects.delete(key)
+          }
+        }
+        for (const [key, value] of Object.entries(canvas)) {
+          canvasObjects.set(key, value)
+        }
+      }
+
+      const yViewObjects = document.getMap('view-objects')
+      const ids = new Set(viewObjects.map(o => o.id))
+      for (const key of Array.from(yViewObjects.keys())) {
+        if (!ids.has(key)) {
+          yViewObjects.delete(key)
+        }
+      }
+      for (const obj of viewObjects) {
+        yViewObjects.set(obj.id, yViewObject(obj))
+      }
+    })
+  }
+
+  /**
+   * Replace the sheets in the spreadsheet Y.Map and forward the fortune-sheet
+   * ops so that open editors update cells in place.
+   */
+  applySpreadsheetChange(document, change) {
+    let sheets
+    try {
+      sheets = JSON.parse(change.data || '[]')
+    } catch (e) {
+      console.error(`[DB] Error parsing spreadsheet change:`, e)
+      return
+    }
+    if (!Array.isArray(sheets)) return
+
+    document.transact(() => {
+      const ySpreadsheet = document.getMap('spreadsheet')
+      const ids = new Set(sheets.map(s => s.id))
+      for (const key of Array.from(ySpreadsheet.keys())) {
+        if (!key.startsWith('_') && !ids.has(key)) {
+          ySpreadsheet.delete(key)
+        }
+      }
+      for (const sheet of sheets) {
+        if (sheet.id) {
+          ySpreadsheet.set(sheet.id, sheet)
+        }
+      }
+
+      if (change.ops && change.ops.length > 0) {
+        const yOps = document.getArray('ops')
+        yOps.push([{ ops: change.ops }])
+        if (yOps.length > MAX_OPS_HISTORY) {
+          yOps.delete(0, yOps.length - MAX_OPS_HISTORY)
+        }
+      }
+    })
+  }
+
   /**
    * Initialize a note Y.Doc from the notes table
    */
@@ -109,9 +248,56 @@ export class DatabaseExtension {
   }
 
   /**
-   * Initialize a whiteboard Y.Doc from views + view_objects tables
+   * Initialize a whiteboard Y.Doc from views + view_objects tables.
+   *
+   * With a viewport, the objects in it are loaded first, from the indexed
+   * canvas objects, so that the client syncs them without waiting for the
+   * whole board. The rest is loaded after.
+   */
+  async initializeWhiteboard(document, viewId, bbox) {
+    const loaded = { canvas: new Set(), viewObjects: new Set() }
+    if (!bbox) {
+      await this.loadWhiteboard(document, viewId, loaded)
+      return
+    }
+
+    try {
+      const [canvasObjects, viewObjects] = await Promise.all([
+        this.db.findCanvasObjects(viewId, bbox),
+        this.db.findViewObjectsByViewId(viewId, bbox),
+      ])
+      document.transact(() => {
+        const yCanvas = document.getMap('canvas-objects')
+        for (const obj of canvasObjects) {
+          yCanvas.set(obj.id, JSON.parse(obj.data))
+          loaded.canvas.add(obj.id)
+        }
+        const yViewObjects = document.getMap('view-objects')
+        for (const obj of viewObjects) {
+          yViewObjects.set(obj.id, yViewObject(obj))
+          loaded.viewObjects.add(obj.id)
+        }
+      })
+    } catch (e) {
+      console.error(`[DB] Error loading whiteboard viewport:`, e)
+    }
+
+    // The document is incomplete until the rest is loaded; storing it
+    // before would delete the objects not loaded yet, so a failed load
+    // keeps it from being stored at all.
+    const pending = this.loadWhiteboard(document, viewId, loaded)
+    this.pendingLoads.set(document, pending)
+    pending.then(
+      () => this.pendingLoads.delete(document),
+      (err) => console.error(`[DB] Error loading whiteboard ${viewId}, it will not be stored:`, err)
+    )
+  }
+
+  /**
+   * Load the canvas objects and view objects of a whiteboard, except those
+   * already loaded: clients may have changed or deleted them since.
    */
-  async initializeWhiteboard(document, viewId) {
+  async loadWhiteboard(document, viewId, loaded) {
     const view = await this.db.findView(viewId)
     if (!view) {
       return
@@ -124,7 +310,9 @@ export class DatabaseExtension {
           const parsed = JSON.parse(view.data)
           if (parsed && typeof parsed === 'object') {
             for (const [key, value] of Object.entries(parsed)) {
-              canvasObjects.set(key, value)
+              if (!loaded.canvas.has(key)) {
+                canvasObjects.set(key, value)
+              }
             }
           }
         } catch (e) {
@@ -134,27 +322,16 @@ export class DatabaseExtension {
     })
 
     // Load view objects (async gRPC call)
-    try {
-      const viewObjects = await this.db.findViewObjectsByViewId(viewId)
-      if (viewObjects && viewObjects.length > 0) {
-        document.transact(() => {
-          const yViewObjects = document.getMap('view-objects')
-          for (const obj of viewObjects) {
-            yViewObjects.set(obj.id, {
-              id: obj.id,
-              type: obj.type,
-              name: obj.name,
-              data: obj.data,
-              created_by: obj.created_by,
-              updated_by: obj.updated_by,
-              created_at: obj.created_at,
-              updated_at: obj.updated_at,
-            })
+    const viewObjects = await this.db.findViewObjectsByViewId(viewId)
+    if (viewObjects && viewObjects.length > 0) {
+      document.transact(() => {
+        const yViewObjects = document.getMap('view-objects')
+        for (const obj of viewObjects) {
+          if (!loaded.viewObjects.has(obj.id)) {
+            yViewObjects.set(obj.id, yViewObject(obj))
           }
-        })
-      }
-    } catch (e) {
-      console.error(`[DB] Error loading view objects:`, e)
+        }
+      })
     }
   }
 
@@ -217,6 +394,8 @@ export class DatabaseExtension {
    * Persist whiteboard Y.Doc back to views + view_objects tables
    */
   async persistWhiteboard(document, viewId) {
+    await this.pendingLoads.get(document)
+
     const canvasObjects = document.getMap('canvas-objects')
     const yViewObjects = document.getMap('view-objects')
     const now = new Date().toISOString()
@@ -245,28 +424,33 @@ export class DatabaseExtension {
       }
     }
 
-    // Create or update objects from Y.js
+    // Create or update objects from Y.js. The API rejects objects whose data
+    // does not match their type; skip those so the rest are still saved.
     for (const [id, obj] of Object.entries(currentViewObjects)) {
-      if (dbObjectIds.has(id)) {
-        await this.db.updateViewObject(id, {
-          name: obj.name || '',
-          type: obj.type || '',
-          data: typeof obj.data === 'string' ? obj.data : JSON.stringify(obj.data || {}),
-          updated_by: obj.updated_by || 'system',
-          updated_at: now,
-        })
-      } else {
-        await this.db.createViewObject({
-          id,
-          view_id: viewId,
-          name: obj.name || '',
-          type: obj.type || '',
-          data: typeof obj.data === 'string' ? obj.data : JSON.stringify(obj.data || {}),
-          created_by: obj.created_by || 'system',
-          updated_by: obj.updated_by || 'system',
-          created_at: obj.created_at || now,
-          updated_at: now,
-        })
+      try {
+        if (dbObjectIds.has(id)) {
+          await this.db.updateViewObject(id, {
+            name: obj.name || '',
+            type: obj.type || '',
+            data: typeof obj.data === 'string' ? obj.data : JSON.stringify(obj.data || {}),
+            updated_by: obj.updated_by || 'system',
+            updated_at: now,
+          })
+        } else {
+          await this.db.createViewObject({
+            id,
+            view_id: viewId,
+            name: obj.name || '',
+            type: obj.type || '',
+            data: typeof obj.data === 'string' ? obj.data : JSON.stringify(obj.data || {}),
+            created_by: obj.created_by || 'system',
+            updated_by: obj.updated_by || 'system',
+            created_at: obj.created_at || now,
+            updated_at: now,
+          })
+        }
+      } catch (e) {
+        console.error(`[DB] Error saving view object ${id}:`, e.details || e.message)
       }
     }
   }
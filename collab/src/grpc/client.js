@@ -23,11 +23,15 @@ const METHODS = {
   UpdateNote:          '/collab.CollabService/UpdateNote',
   UpdateViewData:      '/collab.CollabService/UpdateViewData',
   GetViewObjects:      '/collab.CollabService/GetViewObjects',
+  GetCanvasObjects:    '/collab.CollabService/GetCanvasObjects',
   CreateViewObject:    '/collab.CollabService/CreateViewObject',
   UpdateViewObject:    '/collab.CollabService/UpdateViewObject',
   DeleteViewObject:    '/collab.CollabService/DeleteViewObject',
+  WatchViewChanges:    '/collab.CollabService/WatchViewChanges',
 }
 
+const WATCH_RETRY_MS = 3000
+
 function createGrpcClient(address) {
   const rawClient = new grpc.Client(address, grpc.credentials.createInsecure())
 
@@ -101,8 +105,21 @@ function createGrpcClient(address) {
       await call(METHODS.UpdateViewData, { id, data, updated_at })
     },
 
-    async findViewObjectsByViewId(viewId) {
-      const res = await call(METHODS.GetViewObjects, { view_id: viewId })
+    /**
+     * bbox ({ min_x, min_y, max_x, max_y }) limits a whiteboard to the
+     * objects in the viewport.
+     */
+    async findViewObjectsByViewId(viewId, bbox) {
+      const res = await call(METHODS.GetViewObjects, { view_id: viewId, bbox })
+      return res.objects || []
+    },
+
+    /**
+     * Returns the indexed entries of a whiteboard canvas as { id, data },
+     * with data the JSON of the entry.
+     */
+    async findCanvasObjects(viewId, bbox) {
+      const res = await call(METHODS.GetCanvasObjects, { view_id: viewId, bbox })
       return res.objects || []
     },
 
@@ -120,6 +137,48 @@ function createGrpcClient(address) {
       await call(METHODS.DeleteViewObject, { id })
     },
 
+    // --- change feed ---
+
+    /**
+     * Subscribes to view data changes made outside of collab (e.g. REST API
+     * writes). Reconnects automatically; returns a function that stops it.
+     */
+    watchViewChanges(onChange) {
+      let stopped = false
+      let stream = null
+      let retryTimer = null
+
+      const connect = () => {
+        if (stopped) return
+        stream = rawClient.makeServerStreamRequest(
+          METHODS.WatchViewChanges,
+          serialize,
+          deserialize,
+          {}
+        )
+        stream.on('data', (change) => {
+          Promise.resolve(onChange(change)).catch((err) => {
+            console.error('[gRPC] Error handling view change:', err)
+          })
+        })
+        stream.on('error', () => {})
+        stream.on('close', () => {
+          stream = null
+          if (!stopped) {
+            retryTimer = setTimeout(connect, WATCH_RETRY_MS)
+          }
+        })
+      }
+
+      connect()
+
+      return () => {
+        stopped = true
+        clearTimeout(retryTimer)
+        if (stream) stream.cancel()
+      }
+    },
+
     close() {
       rawClient.close()
     },
@@ -9,12 +9,14 @@ const GRPC_ADDR = process.env.GRPC_ADDR || 'localhost:50051'
 // Initialize gRPC client (replaces direct DB access)
 const db = createGrpcClient(GRPC_ADDR)
 
+const database = new DatabaseExtension({ db })
+
 // Configure Hocuspocus server
 const server = new Server({
   port: PORT,
   extensions: [
     new AuthExtension({ db }),
-    new DatabaseExtension({ db }),
+    database,
   ],
   async onListen() {
   },
@@ -22,8 +24,17 @@ const server = new Server({
 
 server.listen()
 
+// Push changes made through the API into open documents
+const stopWatching = db.watchViewChanges((change) => {
+  const document = server.hocuspocus.documents.get(`${change.type}:${change.view_id}`)
+  if (document) {
+    database.applyViewChange(document, change)
+  }
+})
+
 // Graceful shutdown
 async function shutdown() {
+  stopWatching()
   await server.destroy()
   db.close()
   process.exit(0)
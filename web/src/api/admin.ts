@@ -25,6 +25,22 @@ export interface UpdateUserPasswordRequest {
     password: string;
 }
 
+export interface StorageUsageEntry {
+    files: number;
+    size: number;
+}
+
+export interface StorageUsage extends StorageUsageEntry {
+    limits: {
+        max_file_size: number;
+        workspace: number;
+        user: number;
+    };
+    workspaces: (StorageUsageEntry & { workspace_id: string; name: string })[];
+    users: (StorageUsageEntry & { user_id: string; name: string; email: string })[];
+    types: (StorageUsageEntry & { content_type: string })[];
+}
+
 export const listUsers = async (): Promise<AdminUser[]> => {
     const response = await axios.get('/api/v1/admin/users', { withCredentials: true });
     return response.data;
@@ -58,3 +74,8 @@ export const enableUser = async (userId: string): Promise<AdminUser> => {
 export const deleteUser = async (userId: string): Promise<void> => {
     await axios.delete(`/api/v1/admin/users/${userId}`, { withCredentials: true });
 };
+
+export const getStorageUsage = async (): Promise<StorageUsage> => {
+    const response = await axios.get('/api/v1/admin/storage/usage', { withCredentials: true });
+    return response.data;
+};
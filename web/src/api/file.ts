@@ -6,6 +6,7 @@ export interface FileInfo {
     original_name: string;
     size: number;
     ext: string;
+    content_type?: string;
     created_at: string;
     updated_at: string;
 }
@@ -15,6 +16,11 @@ export const uploadFile = async (
     file: File,
     onUploadProgress?: (progressPercent: number) => void
 ) => {
+    const uploaded = await uploadFilePresigned(workspaceId, file, onUploadProgress);
+    if (uploaded) {
+        return uploaded;
+    }
+
     const formData = new FormData();
     formData.append("file", file)
     const response = await axios.post(`/api/v1/workspaces/${workspaceId}/files`, formData, {
@@ -32,6 +38,151 @@ export const uploadFile = async (
     return response.data;
 };
 
+// Set once the server answers that it does not presign uploads
+let presignedUploadsDisabled = false;
+
+// Uploads a file directly to the storage with a presigned URL, so that its
+// content does not pass through the API. Returns null when the server does
+// not presign uploads.
+const uploadFilePresigned = async (
+    workspaceId: string,
+    file: File,
+    onUploadProgress?: (progressPercent: number) => void
+) => {
+    if (presignedUploadsDisabled) {
+        return null;
+    }
+
+    let upload: { id: string; url: string };
+    try {
+        const res = await axios.post(`/api/v1/workspaces/${workspaceId}/uploads/presigned`, {
+            filename: file.name,
+            length: file.size,
+        }, { withCredentials: true });
+        upload = res.data;
+    } catch (err) {
+        if (axios.isAxiosError(err) && err.response?.status === 501) {
+            presignedUploadsDisabled = true;
+            return null;
+        }
+        throw err;
+    }
+
+    await axios.put(upload.url, file, {
+        headers: {
+            'Content-Type': file.type || 'application/octet-stream',
+        },
+        onUploadProgress: (progressEvent) => {
+            if (onUploadProgress && progressEvent.total) {
+                const percentCompleted = Math.round((progressEvent.loaded * 100) / progressEvent.total);
+                onUploadProgress(percentCompleted);
+            }
+        },
+    });
+
+    const res = await axios.post(`/api/v1/workspaces/${workspaceId}/uploads/${upload.id}/finalize`, null, {
+        withCredentials: true,
+    });
+    return res.data;
+};
+
+// Resumable uploads are sent in chunks of this size; a failed chunk is
+// retried from the offset the server received
+const UPLOAD_CHUNK_SIZE = 8 * 1024 * 1024;
+const UPLOAD_RETRY_DELAYS = [1000, 3000, 5000, 10000, 20000];
+
+const tusHeaders = { 'Tus-Resumable': '1.0.0' };
+
+const encodeMetadata = (value: string) => {
+    const bytes = new TextEncoder().encode(value);
+    let binary = '';
+    bytes.forEach((b) => { binary += String.fromCharCode(b); });
+    return btoa(binary);
+};
+
+const sleep = (ms: number) => new Promise((resolve) => setTimeout(resolve, ms));
+
+// Uploads a file in chunks with the tus protocol, so that large files such as
+// videos survive flaky connections. An upload interrupted by a reload is
+// resumed when the same file is uploaded again.
+export const uploadFileResumable = async (
+    workspaceId: string,
+    file: File,
+    onUploadProgress?: (progressPercent: number) => void
+) => {
+    const fingerprint = `upload:${workspaceId}:${file.name}:${file.size}:${file.lastModified}`;
+    let url = localStorage.getItem(fingerprint);
+    let offset = 0;
+
+    if (url) {
+        try {
+            const res = await axios.head(url, { withCredentials: true, headers: tusHeaders });
+            offset = parseInt(String(res.headers['upload-offset']), 10);
+        } catch {
+            url = null;
+        }
+    }
+    if (!url) {
+        const res = await axios.post(`/api/v1/workspaces/${workspaceId}/uploads`, null, {
+            withCredentials: true,
+            headers: {
+                ...tusHeaders,
+                'Upload-Length': file.size.toString(),
+                'Upload-Metadata': `filename ${encodeMetadata(file.name)}`,
+            },
+        });
+        url = res.headers['location'] as string;
+        offset = parseInt(String(res.headers['upload-offset']), 10) || 0;
+        localStorage.setItem(fingerprint, url);
+    }
+
+    let attempt = 0;
+    while (offset < file.size) {
+        const chunk = file.slice(offset, offset + UPLOAD_CHUNK_SIZE);
+        const start = offset;
+        try {
+            const res = await axios.patch(url, chunk, {
+                withCredentials: true,
+                headers: {
+                    ...tusHeaders,
+                    'Content-Type': 'application/offset+octet-stream',
+                    'Upload-Offset': start.toString(),
+                },
+                onUploadProgress: (progressEvent) => {
+                    if (onUploadProgress && file.size > 0) {
+                        onUploadProgress(Math.round(((start + progressEvent.loaded) * 100) / file.size));
+                    }
+                },
+            });
+            offset = parseInt(String(res.headers['upload-offset']), 10);
+            attempt = 0;
+        } catch (err) {
+            const status = axios.isAxiosError(err) ? err.response?.status : undefined;
+            // Client errors other than an offset conflict will not succeed on retry
+            if (status && status < 500 && status !== 409 && status !== 423) {
+                localStorage.removeItem(fingerprint);
+                throw err;
+            }
+            if (attempt >= UPLOAD_RETRY_DELAYS.length) {
+                throw err;
+            }
+            await sleep(UPLOAD_RETRY_DELAYS[attempt++]);
+            const res = await axios.head(url, { withCredentials: true, headers: tusHeaders });
+            offset = parseInt(String(res.headers['upload-offset']), 10);
+        }
+    }
+
+    const res = await axios.get(url, { withCredentials: true, headers: tusHeaders });
+    localStorage.removeItem(fingerprint);
+    onUploadProgress?.(100);
+    return {
+        id: res.data.file_id,
+        filename: res.data.file_name,
+        original_name: res.data.filename,
+        size: res.data.length,
+    };
+};
+
 export const listFiles = async (
     workspaceId: string,
     query?: string,
@@ -70,3 +221,25 @@ export const renameFile = async (workspaceId: string, fileId: string, originalFi
 export const getFileDownloadUrl = (workspaceId: string, fileName: string) => {
     return `/api/v1/workspaces/${workspaceId}/files/${fileName}`;
 };
+
+// Thumbnails of this width are made when images are uploaded
+export const THUMBNAIL_WIDTH = 480;
+
+// Returns the URL of a resized workspace image; other URLs are left as they are
+export const getThumbnailUrl = (src: string, width: number = THUMBNAIL_WIDTH) => {
+    if (!/^\/api\/v1\/workspaces\/[^/]+\/files\/[^/?#]+$/.test(src)) {
+        return src;
+    }
+    return `${src}?w=${width}`;
+};
+
+// Mints a short-lived URL for embedding a file where the session cookie is not sent
+export const getSignedFileUrl = async (
+    workspaceId: string,
+    fileName: string
+): Promise<{ url: string; expires_at: string }> => {
+    const response = await axios.post(`/api/v1/workspaces/${workspaceId}/files/${fileName}/signed-url`, null, {
+        withCredentials: true,
+    });
+    return response.data;
+};
@@ -1,18 +1,25 @@
 import axios from 'axios';
 
 export interface RSSItem {
+  id?: string;
   title: string;
   link: string;
   description?: string;
   pubDate?: string;
   guid?: string;
   imageUrl?: string;
+  read?: boolean;
 }
 
 export interface RSSFeed {
+  id?: string;
+  url?: string;
   title: string;
   description?: string;
   link?: string;
+  fetched_at?: string;
+  last_error?: string;
+  unread?: number;
   items: RSSItem[];
 }
 
@@ -22,3 +29,11 @@ export const fetchRSSFeed = async (feedUrl: string): Promise<RSSFeed> => {
   });
   return response.data;
 };
+
+export const markRSSItemRead = async (itemId: string): Promise<void> => {
+  await axios.put(`/api/v1/rss/items/${itemId}/read`);
+};
+
+export const markRSSItemUnread = async (itemId: string): Promise<void> => {
+  await axios.delete(`/api/v1/rss/items/${itemId}/read`);
+};
@@ -20,3 +20,33 @@ export const textGen = async (prompt: string, model: TextGenModel) => {
     return response.data
 };
 
+
+export interface OEmbed {
+    type: string
+    title?: string
+    html?: string
+    width?: number
+    height?: number
+    author_name?: string
+    author_url?: string
+    provider_name?: string
+    provider_url?: string
+    thumbnail_url?: string
+    thumbnail_width?: number
+    thumbnail_height?: number
+}
+
+export interface LinkMetadata {
+    url: string
+    title: string
+    description: string
+    image: string
+    favicon: string
+    site_name: string
+    oembed?: OEmbed
+}
+
+export const unfurl = async (url: string): Promise<LinkMetadata> => {
+    const response = await axios.get(`/api/v1/tools/unfurl`, { params: { url } });
+    return response.data
+};
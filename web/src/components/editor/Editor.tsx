@@ -25,7 +25,7 @@ import { LocationNode } from './extensions/locationnode/LocationNode'
 import { TagsNode } from './extensions/tagsnode/TagsNode'
 import { RatingNode } from './extensions/ratingnode/RatingNode'
 import { CarouselNode } from './extensions/carouselnode/CarouselNode'
-import { uploadFile, listFiles } from '@/api/file'
+import { uploadFile, uploadFileResumable, listFiles } from '@/api/file'
 import useCurrentWorkspaceId from '@/hooks/use-currentworkspace-id'
 import { createNote, NoteData } from '@/api/note'
 import * as Y from 'yjs'
@@ -147,7 +147,7 @@ const Editor: FC<Props> = ({
       RatingNode,
       VideoNode.configure({
         upload: async (f: File, onProgress?: (percent: number) => void) => {
-          const res = await uploadFile(currentWorkspaceId, f, onProgress)
+          const res = await uploadFileResumable(currentWorkspaceId, f, onProgress)
           return {
             src: `/api/v1/workspaces/${currentWorkspaceId}/files/${res.filename}`,
             name: res.original_name
@@ -2,7 +2,7 @@ import { FC, useState, useEffect, useCallback, useRef } from "react"
 import * as Dialog from "@radix-ui/react-dialog"
 import { Search, Loader2, Image as ImageIcon, Video as VideoIcon, Check, Upload, X } from "lucide-react"
 import { useTranslation } from "react-i18next"
-import { FileInfo } from "@/api/file"
+import { FileInfo, getThumbnailUrl } from "@/api/file"
 import { CarouselItem } from "./CarouselNode"
 
 interface CarouselMediaPickerDialogProps {
@@ -182,7 +182,7 @@ const CarouselMediaPickerDialog: FC<CarouselMediaPickerDialogProps> = ({
                                         >
                                             {type === 'image' ? (
                                                 <img
-                                                    src={getFileUrl(file.name)}
+                                                    src={getThumbnailUrl(getFileUrl(file.name))}
                                                     alt={file.original_name}
                                                     className="w-full aspect-square object-cover"
                                                 />
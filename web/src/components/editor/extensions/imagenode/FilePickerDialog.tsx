@@ -1,7 +1,7 @@
 import { FC, useState, useEffect, useCallback } from "react"
 import * as Dialog from "@radix-ui/react-dialog"
 import { Search, Loader2, Image as ImageIcon } from "lucide-react"
-import { FileInfo } from "@/api/file"
+import { FileInfo, getThumbnailUrl } from "@/api/file"
 
 interface FilePickerDialogProps {
     open: boolean
@@ -98,7 +98,7 @@ const FilePickerDialog: FC<FilePickerDialogProps> = ({
                                     className="relative aspect-square rounded-lg overflow-hidden border-2 border-transparent hover:border-blue-500 transition-colors group"
                                 >
                                     <img
-                                        src={getFileUrl(file.name)}
+                                        src={getThumbnailUrl(getFileUrl(file.name))}
                                         alt={file.original_name}
                                         className="w-full h-full object-cover"
                                     />
@@ -5,6 +5,7 @@ import { useTranslation } from 'react-i18next'
 import { FileText, ChevronDown, LoaderCircle, CalendarDays, ExternalLink, Star, Map, MapPin, Kanban, PenTool, Sheet } from 'lucide-react'
 import { useParams } from 'react-router-dom'
 import { getNote, NoteData } from '@/api/note'
+import { getThumbnailUrl } from '@/api/file'
 import { ViewType } from '@/types/view'
 import { MapContainer, TileLayer, Marker } from 'react-leaflet'
 import { Icon } from 'leaflet'
@@ -370,11 +371,11 @@ const CarouselNodeRenderer: React.FC<{ items: Array<{ src: string; name: string;
                                 {item.type === 'image' ? (
                                     isOverlay ? (
                                         <PhotoView src={item.src}>
-                                            <img src={item.src} alt={item.name} className="w-full h-full object-cover pointer-events-none" />
+                                            <img src={getThumbnailUrl(item.src)} alt={item.name} className="w-full h-full object-cover pointer-events-none" />
                                         </PhotoView>
                                     ) : (
                                         <PhotoView src={item.src}>
-                                            <img src={item.src} alt={item.name} className="w-full h-full object-cover cursor-zoom-in" />
+                                            <img src={getThumbnailUrl(item.src)} alt={item.name} className="w-full h-full object-cover cursor-zoom-in" />
                                         </PhotoView>
                                     )
                                 ) : (
@@ -406,7 +407,7 @@ const CarouselNodeRenderer: React.FC<{ items: Array<{ src: string; name: string;
                 <div key={`${item.src}-${idx}`} className="relative flex-shrink-0 aspect-square rounded-lg overflow-hidden bg-gray-100 dark:bg-neutral-800" style={{ width: 'calc((100% - 1rem) / 3)' }}>
                     {item.type === 'image' ? (
                         <PhotoView src={item.src}>
-                            <img src={item.src} alt={item.name} className="w-full h-full object-cover cursor-zoom-in" />
+                            <img src={getThumbnailUrl(item.src)} alt={item.name} className="w-full h-full object-cover cursor-zoom-in" />
                         </PhotoView>
                     ) : (
                         <video src={item.src} className="w-full h-full object-cover" controls preload="metadata" />
@@ -594,7 +595,7 @@ const Renderer: React.FC<RendererProps> = ({ content, maxNodes, workspaceId: wor
             case 'image':
                 return <div className="" key={key}>
                     <PhotoView src={node.attrs?.src}>
-                        <img className="rounded overflow-hidden max-w-full max-h-[620px]" alt={node.attrs?.alt || ''} src={node.attrs?.src} />
+                        <img className="rounded overflow-hidden max-w-full max-h-[620px]" alt={node.attrs?.alt || ''} src={getThumbnailUrl(node.attrs?.src, 1280)} />
                     </PhotoView>
                 </div>
             case 'attachment':
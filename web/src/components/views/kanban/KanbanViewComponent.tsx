@@ -1,9 +1,9 @@
 import { useMemo, useState, useRef, useEffect } from 'react'
-import { useParams } from 'react-router-dom'
+import { Link, useParams } from 'react-router-dom'
 import { useQueryClient, useMutation } from '@tanstack/react-query'
 import { KanbanCardData, KanbanColumnData, KanbanViewData, View } from '../../../types/view'
 import { deleteViewObject, updateViewObject, updateView } from '../../../api/view'
-import { MoreVertical, Edit2, Trash2, ChevronLeft, ChevronRight, Plus, X } from 'lucide-react'
+import { MoreVertical, Edit2, Trash2, ChevronLeft, ChevronRight, Plus, X, Calendar, FileText } from 'lucide-react'
 import * as DropdownMenu from '@radix-ui/react-dropdown-menu'
 import { Dialog } from 'radix-ui'
 import { useToastStore } from '../../../stores/toast'
@@ -302,12 +302,33 @@ const KanbanViewComponent = ({
                                                     </div>
                                                 ) : (
                                                     <div className="flex items-start justify-between gap-1">
-                                                        <span
-                                                            className="text-sm flex-1 cursor-pointer"
-                                                            onClick={() => !isPublic && handleStartEditCard(column.id, card)}
-                                                        >
-                                                            {card.title}
-                                                        </span>
+                                                        <div className="flex-1">
+                                                            <span
+                                                                className="text-sm cursor-pointer"
+                                                                onClick={() => !isPublic && handleStartEditCard(column.id, card)}
+                                                            >
+                                                                {card.title}
+                                                            </span>
+                                                            {(card.due_date || (card.note_id && !isPublic)) && (
+                                                                <div className="flex items-center gap-2 mt-1 text-xs text-neutral-500 dark:text-neutral-400">
+                                                                    {card.due_date && (
+                                                                        <span className="flex items-center gap-1">
+                                                                            <Calendar size={12} />
+                                                                            {card.due_date}
+                                                                        </span>
+                                                                    )}
+                                                                    {card.note_id && !isPublic && (
+                                                                        <Link
+                                                                            to={`/workspaces/${currentWorkspaceId}/notes/${card.note_id}`}
+                                                                            className="flex items-center gap-1 hover:text-blue-600"
+                                                                        >
+                                                                            <FileText size={12} />
+                                                                            {t('views.kanbanCardNote', 'Note')}
+                                                                        </Link>
+                                                                    )}
+                                                                </div>
+                                                            )}
+                                                        </div>
                                                         {!isPublic && (
                                                             <button
                                                                 onClick={() => handleDeleteCard(column, card.id)}
@@ -26,6 +26,14 @@ export interface ViewObject {
   updated_at?: string
 }
 
+// The board opens at the origin at zoom 1. Collab loads the objects around
+// that viewport first, and the rest of the board after.
+function initialBBox(): string {
+  const w = window.innerWidth
+  const h = window.innerHeight
+  return `${-w},${-h},${2 * w},${2 * h}`
+}
+
 export function useWhiteboardCollab(options: UseWhiteboardCollabOptions) {
   const { viewId, workspaceId, enabled, isPublic = false } = options
 
@@ -62,6 +70,7 @@ export function useWhiteboardCollab(options: UseWhiteboardCollabOptions) {
       url,
       name: `whiteboard:${viewId}`,
       document: yDoc,
+      parameters: { bbox: initialBBox() },
       onConnect() {
         setIsConnected(true)
       },
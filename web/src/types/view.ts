@@ -108,6 +108,8 @@ export interface MapMarkerData {
 export interface KanbanCardData {
   id: string;
   title: string;
+  note_id?: string; // Note holding the details of the card
+  due_date?: string; // YYYY-MM-DD format
 }
 
 export interface KanbanColumnData {